@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/rsa"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc"
 	grpcClient "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc/client"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http"
@@ -26,6 +28,7 @@ import (
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/provider/gopsutil"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/provider/runtime"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/pusher"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/worker"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/runner"
 )
@@ -38,37 +41,76 @@ var (
 	defaultPushTimeout           = 10 * time.Second
 	defaultSendMetricsInterval   = 10 * time.Second
 	defaultUpdateMetricsInterval = 2 * time.Second
+	defaultAgentIDFile           = "/tmp/devops-metrics-agent.id"
 	errUnkwnownChannelType       = errors.New("unknown metric channel type")
 )
 
 type config struct {
+	ID                    string `env:"AGENT_ID" json:"agent_id,omitempty"`
+	IDFile                string `env:"AGENT_ID_FILE" json:"agent_id_file,omitempty"`
 	ChannelType           string `env:"CHANNEL_TYPE" json:"channel_type,omitempty"`
 	ConfigPath            string `env:"CONFIG"`
 	CryptoKey             string `env:"CRYPTO_KEY" json:"crypto_key,omitempty"`
 	Key                   string `env:"KEY" json:"key,omitempty"`
 	ServerURL             string `env:"ADDRESS" json:"address,omitempty"`
+	GrpcCert              string `env:"GRPC_TLS_CERT" json:"grpc_tls_cert,omitempty"`
+	GrpcKey               string `env:"GRPC_TLS_KEY" json:"grpc_tls_key,omitempty"`
+	GrpcCA                string `env:"GRPC_TLS_CA" json:"grpc_tls_ca,omitempty"`
+	HTTPSCert             string `env:"HTTPS_CERT" json:"https_cert,omitempty"`
+	HTTPSKey              string `env:"HTTPS_KEY" json:"https_key,omitempty"`
+	HTTPSCA               string `env:"HTTPS_CA" json:"https_ca,omitempty"`
 	CollectMetricsList    []string
 	PushRateLimit         int           `env:"RATE_LIMIT" json:"rate_limit,omitempty" `
 	PushTimeout           time.Duration `env:"PUSH_TIMEOUT" json:"push_timeout,omitempty"`
 	SendMetricsInterval   time.Duration `env:"REPORT_INTERVAL" json:"report_interval,omitempty"`
 	UpdateMetricsInterval time.Duration `env:"POLL_INTERVAL" json:"poll_interval,omitempty"`
+	LogLevelName          string        `env:"LOG_LEVEL" json:"log_level,omitempty"`
+	LogFormatName         string        `env:"LOG_FORMAT" json:"log_format,omitempty"`
+	LogOutput             string        `env:"LOG_OUTPUT" json:"log_output,omitempty"`
+	LogWrapped            bool          `env:"LOG_WRAPPED_ERRORS" json:"log_wrapped_errors,omitempty"`
+	TraceOutput           string        `env:"TRACE_OUTPUT" json:"trace_output,omitempty"`
+	SignRequests          bool          `env:"SIGN_REQUESTS" json:"sign_requests,omitempty"`
 }
 
 func main() {
-	logger.InfoFormat("Build version: %s\n", buildVersion)
-	logger.InfoFormat("Build date: %s\n", buildDate)
-	logger.InfoFormat("Build commit: %s\n", buildCommit)
-
 	conf, err := createConfig()
 	if err != nil {
 		panic(logger.WrapError("initialize config", err))
 	}
 
+	err = logger.Configure(conf)
+	if err != nil {
+		panic(logger.WrapError("configure logger", err))
+	}
+	defer logger.Close()
+
+	logger.InfoFormat("Build version: %s\n", buildVersion)
+	logger.InfoFormat("Build date: %s\n", buildDate)
+	logger.InfoFormat("Build commit: %s\n", buildCommit)
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 
+	var exporter tracing.Exporter
+	if conf.TraceOutput != "" {
+		exporter, err = tracing.NewExporter(conf.TraceOutput)
+		if err != nil {
+			panic(logger.WrapError("create trace exporter", err))
+		}
+		defer exporter.Close()
+	}
+	tracer := tracing.NewTracer(exporter)
+
 	signer := hash.NewSigner(conf)
 
+	var requestSigner *hash.Signer
+	if conf.SignRequests {
+		if conf.Key == "" {
+			panic(logger.WrapError("create request signer", hash.ErrMissedSecretKey))
+		}
+		requestSigner = signer
+	}
+
 	var encryptor crypto.Encryptor
 	if conf.CryptoKey != "" {
 		encryptor, err = rsa.NewEncryptor(conf.CryptoKey)
@@ -80,9 +122,9 @@ func main() {
 	var metricPusher pusher.MetricsPusher
 	switch conf.ChannelType {
 	case "http":
-		metricPusher, err = httpClient.NewPusher(conf, http.NewMetricsConverter(conf, signer), encryptor)
+		metricPusher, err = httpClient.NewPusher(conf, http.NewMetricsConverter(conf, signer), encryptor, requestSigner, tracer)
 	case "grpc":
-		metricPusher, err = grpcClient.NewPusher(conf, grpc.NewMetricsConverter(conf, signer))
+		metricPusher, err = grpcClient.NewPusher(conf, grpc.NewMetricsConverter(conf, signer), encryptor, requestSigner, tracer)
 	default:
 		err = logger.WrapError(fmt.Sprintf("create new metrics pusher with type %s", conf.ChannelType), errUnkwnownChannelType)
 	}
@@ -155,15 +197,29 @@ func createConfig() (*config, error) {
 	}}
 
 	flag.StringVar(&conf.ChannelType, "ch", "http", "Push metrics channel type")
+	flag.StringVar(&conf.ID, "id", "", "Agent identifier")
+	flag.StringVar(&conf.IDFile, "id-file", defaultAgentIDFile, "Generated agent identifier file path")
 	flag.StringVar(&conf.ConfigPath, "c", "", "Json config file path")
 	flag.StringVar(&conf.ConfigPath, "config", "", "Json config file path")
 	flag.StringVar(&conf.CryptoKey, "crypto-key", "", "Agent public crypto key path")
 	flag.StringVar(&conf.Key, "k", "", "Signer secret key")
 	flag.StringVar(&conf.ServerURL, "a", "127.0.0.1:8080", "Metrics server URL")
+	flag.StringVar(&conf.GrpcCert, "grpc-tls-cert", "", "Agent grpc TLS certificate path for mutual TLS")
+	flag.StringVar(&conf.GrpcKey, "grpc-tls-key", "", "Agent grpc TLS private key path")
+	flag.StringVar(&conf.GrpcCA, "grpc-tls-ca", "", "Server CA certificate path, system roots are used if empty")
+	flag.StringVar(&conf.HTTPSCert, "https-cert", "", "Agent HTTPS client certificate path, server URL defaults to https if set")
+	flag.StringVar(&conf.HTTPSKey, "https-key", "", "Agent HTTPS client private key path")
+	flag.StringVar(&conf.HTTPSCA, "https-ca", "", "Server HTTPS CA certificate path, server URL defaults to https if set")
 	flag.IntVar(&conf.PushRateLimit, "l", defaultPushRateLimit, "Push metrics parallel workers limit")
 	flag.DurationVar(&conf.PushTimeout, "t", defaultPushTimeout, "Push metrics timeout")
 	flag.DurationVar(&conf.SendMetricsInterval, "r", defaultSendMetricsInterval, "Send metrics interval")
 	flag.DurationVar(&conf.UpdateMetricsInterval, "p", defaultUpdateMetricsInterval, "Update metrics interval")
+	flag.StringVar(&conf.LogLevelName, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&conf.LogFormatName, "log-format", logger.FormatText, "Log format: text or json")
+	flag.StringVar(&conf.LogOutput, "log-output", logger.OutputStderr, "Comma separated log outputs: stdout, stderr or file path")
+	flag.BoolVar(&conf.LogWrapped, "log-wrapped-errors", true, "Log every wrapped error, disable to log failures once")
+	flag.StringVar(&conf.TraceOutput, "trace-output", "", "Trace spans output: stdout, stderr or file path, disabled if empty")
+	flag.BoolVar(&conf.SignRequests, "sign-requests", false, "Sign whole requests with timestamp and nonce, signer secret key is required")
 	flag.Parse()
 
 	err := env.Parse(conf)
@@ -183,9 +239,20 @@ func createConfig() (*config, error) {
 		}
 	}
 
+	if conf.ID == "" {
+		conf.ID, err = agents.LoadOrCreateID(conf.IDFile)
+		if err != nil {
+			return nil, logger.WrapError("load agent id", err)
+		}
+	}
+
 	return conf, nil
 }
 
+func (c *config) AgentID() string {
+	return c.ID
+}
+
 func (c *config) MetricsList() []string {
 	return c.CollectMetricsList
 }
@@ -198,6 +265,30 @@ func (c *config) GrpcServerURL() string {
 	return c.ServerURL
 }
 
+func (c *config) GrpcCertPath() string {
+	return c.GrpcCert
+}
+
+func (c *config) GrpcKeyPath() string {
+	return c.GrpcKey
+}
+
+func (c *config) GrpcCAPath() string {
+	return c.GrpcCA
+}
+
+func (c *config) HTTPCertPath() string {
+	return c.HTTPSCert
+}
+
+func (c *config) HTTPKeyPath() string {
+	return c.HTTPSKey
+}
+
+func (c *config) HTTPCAPath() string {
+	return c.HTTPSCA
+}
+
 func (c *config) PushMetricsTimeout() time.Duration {
 	return c.PushTimeout
 }
@@ -213,3 +304,19 @@ func (c *config) GetKey() []byte {
 func (c *config) SignMetrics() bool {
 	return c.Key != ""
 }
+
+func (c *config) LogLevel() string {
+	return c.LogLevelName
+}
+
+func (c *config) LogFormat() string {
+	return c.LogFormatName
+}
+
+func (c *config) LogOutputs() []string {
+	return strings.Split(c.LogOutput, ",")
+}
+
+func (c *config) LogWrappedErrors() bool {
+	return c.LogWrapped
+}
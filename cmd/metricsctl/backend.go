@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/aes"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/postgres"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/sqlite"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/db"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/file"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/tsdb"
+)
+
+const (
+	backendFile     = "file"
+	backendPostgres = "postgres"
+	backendSQLite   = "sqlite"
+	backendTSDB     = "tsdb"
+)
+
+var (
+	errInvalidBackend = errors.New("invalid backend, expected file:<path>, postgres:<dsn>, sqlite:<path> or tsdb:<dir>")
+	errBackendMissed  = errors.New("backend does not exist")
+)
+
+// backendOptions are applied to file backends.
+type backendOptions struct {
+	backups     int
+	compression string
+	keyPath     string
+}
+
+type backend struct {
+	storage.MetricsStorage
+	closer io.Closer
+}
+
+type fileConfig struct {
+	path        string
+	generations int
+	compression string
+}
+
+type databaseConfig struct {
+	connectionString string
+}
+
+type tsdbConfig struct {
+	path string
+}
+
+// openBackend opens storage by specification <kind>:<location>.
+// Source backends must exist, the empty one is created for targets only.
+func openBackend(ctx context.Context, spec string, options *backendOptions, mustExist bool) (*backend, error) {
+	kind, location, found := strings.Cut(spec, ":")
+	if !found || location == "" {
+		return nil, logger.WrapError(fmt.Sprintf("parse backend '%s'", spec), errInvalidBackend)
+	}
+
+	switch kind {
+	case backendFile:
+		if mustExist {
+			if _, err := os.Stat(location); err != nil {
+				return nil, logger.WrapError(fmt.Sprintf("open file backend '%s'", location), errBackendMissed)
+			}
+		}
+
+		var encryptor crypto.Encryptor
+		var decryptor crypto.Decryptor
+		if options.keyPath != "" {
+			cipher, err := aes.NewCipher(options.keyPath)
+			if err != nil {
+				return nil, logger.WrapError("create backup cipher", err)
+			}
+
+			encryptor = cipher
+			decryptor = cipher
+		}
+
+		conf := &fileConfig{path: location, generations: options.backups, compression: options.compression}
+		return &backend{MetricsStorage: file.NewFileStorage(conf, encryptor, decryptor)}, nil
+	case backendPostgres:
+		base, err := postgres.NewPostgresDataBase(ctx, &databaseConfig{connectionString: location})
+		if err != nil {
+			return nil, logger.WrapError("create database", err)
+		}
+
+		return &backend{MetricsStorage: db.NewDBStorage(base), closer: base}, nil
+	case backendSQLite:
+		if mustExist {
+			if _, err := os.Stat(location); err != nil {
+				return nil, logger.WrapError(fmt.Sprintf("open sqlite backend '%s'", location), errBackendMissed)
+			}
+		}
+
+		base, err := sqlite.NewSQLiteDataBase(ctx, &databaseConfig{connectionString: sqlite.Scheme + location})
+		if err != nil {
+			return nil, logger.WrapError("create database", err)
+		}
+
+		return &backend{MetricsStorage: db.NewDBStorage(base), closer: base}, nil
+	case backendTSDB:
+		if mustExist {
+			if _, err := os.Stat(location); err != nil {
+				return nil, logger.WrapError(fmt.Sprintf("open tsdb backend '%s'", location), errBackendMissed)
+			}
+		}
+
+		tsdbStorage, err := tsdb.NewTSDBStorage(&tsdbConfig{path: location})
+		if err != nil {
+			return nil, logger.WrapError("create tsdb storage", err)
+		}
+
+		return &backend{MetricsStorage: tsdbStorage, closer: tsdbStorage}, nil
+	default:
+		return nil, logger.WrapError(fmt.Sprintf("parse backend '%s'", spec), errInvalidBackend)
+	}
+}
+
+func (b *backend) Close() error {
+	if b.closer == nil {
+		return nil
+	}
+
+	return b.closer.Close()
+}
+
+func (c *fileConfig) StoreFilePath() string {
+	return c.path
+}
+
+func (c *fileConfig) StoreFileGenerations() int {
+	return c.generations
+}
+
+func (c *fileConfig) StoreFileCompression() string {
+	return c.compression
+}
+
+func (c *databaseConfig) GetConnectionString() string {
+	return c.connectionString
+}
+
+func (c *tsdbConfig) TSDBPath() string {
+	return c.path
+}
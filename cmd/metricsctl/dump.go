@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+var (
+	errUnknownFormat = errors.New("unknown dump format, expected json or csv")
+	errInvalidDump   = errors.New("invalid dump")
+	csvHeader        = []string{"type", "name", "labels", "value"}
+)
+
+type dumpRecord struct {
+	Type   string            `json:"type"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  string            `json:"value"`
+}
+
+type difference struct {
+	metricType string
+	seriesKey  string
+	left       *string
+	right      *string
+}
+
+// dumpFormat returns explicitly requested format or detects it by the file extension.
+func dumpFormat(format string, path string) (string, error) {
+	if format == "" {
+		if strings.EqualFold(filepath.Ext(path), "."+formatCSV) {
+			return formatCSV, nil
+		}
+
+		return formatJSON, nil
+	}
+
+	if format != formatJSON && format != formatCSV {
+		return "", logger.WrapError(fmt.Sprintf("parse format '%s'", format), errUnknownFormat)
+	}
+
+	return format, nil
+}
+
+// toDumpRecords converts storage state to the list of records ordered by type and series.
+func toDumpRecords(values map[string]map[string]string) ([]*dumpRecord, error) {
+	records := []*dumpRecord{}
+	for metricType, typedValues := range values {
+		for seriesKey, value := range typedValues {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return nil, logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			records = append(records, &dumpRecord{
+				Type:   metricType,
+				Name:   metricName,
+				Labels: labels.Map(),
+				Value:  value,
+			})
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].seriesKey() < records[j].seriesKey()
+	})
+
+	return records, nil
+}
+
+// fromDumpRecords converts records to the storage state.
+func fromDumpRecords(records []*dumpRecord) (map[string]map[string]string, error) {
+	result := map[string]map[string]string{}
+	for _, record := range records {
+		if record.Type == "" || record.Value == "" {
+			return nil, logger.WrapError(fmt.Sprintf("read record '%s'", record.Name), errInvalidDump)
+		}
+
+		labels := metrics.LabelsFromMap(record.Labels)
+		err := metrics.ValidateSeries(record.Name, labels)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("validate record '%s'", record.Name), err)
+		}
+
+		typedValues, ok := result[record.Type]
+		if !ok {
+			typedValues = map[string]string{}
+			result[record.Type] = typedValues
+		}
+
+		typedValues[metrics.SeriesKey(record.Name, labels)] = record.Value
+	}
+
+	return result, nil
+}
+
+func writeDump(writer io.Writer, format string, records []*dumpRecord) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", " ")
+		err := encoder.Encode(records)
+		if err != nil {
+			return logger.WrapError("encode json dump", err)
+		}
+	case formatCSV:
+		csvWriter := csv.NewWriter(writer)
+		err := csvWriter.Write(csvHeader)
+		if err != nil {
+			return logger.WrapError("write csv header", err)
+		}
+
+		for _, record := range records {
+			err = csvWriter.Write([]string{record.Type, record.Name, metrics.LabelsFromMap(record.Labels).String(), record.Value})
+			if err != nil {
+				return logger.WrapError("write csv record", err)
+			}
+		}
+
+		csvWriter.Flush()
+		err = csvWriter.Error()
+		if err != nil {
+			return logger.WrapError("flush csv dump", err)
+		}
+	default:
+		return logger.WrapError(fmt.Sprintf("write dump in '%s' format", format), errUnknownFormat)
+	}
+
+	return nil
+}
+
+func readDump(reader io.Reader, format string) ([]*dumpRecord, error) {
+	switch format {
+	case formatJSON:
+		var records []*dumpRecord
+		err := json.NewDecoder(reader).Decode(&records)
+		if err != nil {
+			return nil, logger.WrapError("decode json dump", err)
+		}
+
+		return records, nil
+	case formatCSV:
+		rows, err := csv.NewReader(reader).ReadAll()
+		if err != nil {
+			return nil, logger.WrapError("read csv dump", err)
+		}
+
+		if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+			return nil, logger.WrapError("read csv header", errInvalidDump)
+		}
+
+		records := make([]*dumpRecord, len(rows)-1)
+		for i, row := range rows[1:] {
+			labels, err := metrics.ParseLabels(row[2])
+			if err != nil {
+				return nil, logger.WrapError(fmt.Sprintf("parse labels of row %d", i+1), err)
+			}
+
+			records[i] = &dumpRecord{Type: row[0], Name: row[1], Labels: labels.Map(), Value: row[3]}
+		}
+
+		return records, nil
+	default:
+		return nil, logger.WrapError(fmt.Sprintf("read dump in '%s' format", format), errUnknownFormat)
+	}
+}
+
+// diffValues returns ordered differences between two storage states.
+func diffValues(left map[string]map[string]string, right map[string]map[string]string) []*difference {
+	series := map[string]map[string]bool{}
+	for _, source := range []map[string]map[string]string{left, right} {
+		for metricType, typedValues := range source {
+			if _, ok := series[metricType]; !ok {
+				series[metricType] = map[string]bool{}
+			}
+			for seriesKey := range typedValues {
+				series[metricType][seriesKey] = true
+			}
+		}
+	}
+
+	result := []*difference{}
+	for metricType, typedSeries := range series {
+		for seriesKey := range typedSeries {
+			leftValue, leftOk := left[metricType][seriesKey]
+			rightValue, rightOk := right[metricType][seriesKey]
+			if leftOk && rightOk && leftValue == rightValue {
+				continue
+			}
+
+			diff := &difference{metricType: metricType, seriesKey: seriesKey}
+			if leftOk {
+				diff.left = &leftValue
+			}
+			if rightOk {
+				diff.right = &rightValue
+			}
+			result = append(result, diff)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].metricType != result[j].metricType {
+			return result[i].metricType < result[j].metricType
+		}
+		return result[i].seriesKey < result[j].seriesKey
+	})
+
+	return result
+}
+
+func (d *difference) String() string {
+	switch {
+	case d.right == nil:
+		return fmt.Sprintf("- %s %s %s", d.metricType, d.seriesKey, *d.left)
+	case d.left == nil:
+		return fmt.Sprintf("+ %s %s %s", d.metricType, d.seriesKey, *d.right)
+	default:
+		return fmt.Sprintf("~ %s %s %s -> %s", d.metricType, d.seriesKey, *d.left, *d.right)
+	}
+}
+
+func (r *dumpRecord) seriesKey() string {
+	return metrics.SeriesKey(r.Name, metrics.LabelsFromMap(r.Labels))
+}
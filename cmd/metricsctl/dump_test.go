@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump_RoundTrip(t *testing.T) {
+	values := map[string]map[string]string{
+		"counter": {"PollCount": "10"},
+		"gauge":   {`cpu{core="0",host="a,b"}`: "0.5", "Alloc": "100"},
+	}
+
+	for _, format := range []string{formatJSON, formatCSV} {
+		t.Run(format, func(t *testing.T) {
+			records, err := toDumpRecords(values)
+			require.NoError(t, err)
+
+			var buffer bytes.Buffer
+			require.NoError(t, writeDump(&buffer, format, records))
+
+			actualRecords, err := readDump(&buffer, format)
+			require.NoError(t, err)
+
+			actual, err := fromDumpRecords(actualRecords)
+			require.NoError(t, err)
+			assert.Equal(t, values, actual)
+		})
+	}
+}
+
+func TestDump_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+	}{
+		{
+			name:    "csv_without_header",
+			format:  formatCSV,
+			content: "gauge,cpu,,1\n",
+		},
+		{
+			name:    "csv_invalid_labels",
+			format:  formatCSV,
+			content: "type,name,labels,value\ngauge,cpu,{core},1\n",
+		},
+		{
+			name:    "json_missed_value",
+			format:  formatJSON,
+			content: `[{"type":"gauge","name":"cpu"}]`,
+		},
+		{
+			name:    "json_invalid_name",
+			format:  formatJSON,
+			content: `[{"type":"gauge","name":"cpu{","value":"1"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := readDump(bytes.NewBufferString(tt.content), tt.format)
+			if err == nil {
+				_, err = fromDumpRecords(records)
+			}
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	left := map[string]map[string]string{
+		"counter": {"same": "1", "changed": "1", "removed": "1"},
+	}
+	right := map[string]map[string]string{
+		"counter": {"same": "1", "changed": "2"},
+		"gauge":   {"added": "3"},
+	}
+
+	actual := []string{}
+	for _, diff := range diffValues(left, right) {
+		actual = append(actual, diff.String())
+	}
+
+	assert.Equal(t, []string{
+		"~ counter changed 1 -> 2",
+		"- counter removed 1",
+		"+ gauge added 3",
+	}, actual)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+)
+
+const stdStream = "-"
+
+var (
+	buildVersion = "N/A"
+	buildDate    = "N/A"
+	buildCommit  = "N/A"
+
+	errUnknownCommand = errors.New("unknown command")
+	errInvalidArgs    = errors.New("invalid arguments")
+)
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout)
+	if err != nil {
+		fail(err)
+	}
+}
+
+// fail reports error and terminates the process with non-zero exit code.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return logger.WrapError("run", errUnknownCommand)
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "export":
+		return exportCommand(ctx, args, stdout)
+	case "import":
+		return importCommand(ctx, args, stdin)
+	case "diff":
+		return diffCommand(ctx, args, stdout)
+	case "migrate":
+		return migrateCommand(ctx, args)
+	case "version":
+		_, err := fmt.Fprintf(stdout, "Build version: %s\nBuild date: %s\nBuild commit: %s\n", buildVersion, buildDate, buildCommit)
+		return err
+	default:
+		return logger.WrapError(fmt.Sprintf("run '%s'", command), errUnknownCommand)
+	}
+}
+
+func exportCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("export")
+	from := flags.String("from", "", "Source backend")
+	format := flags.String("format", "", "Dump format: json or csv, detected by output file extension if empty")
+	out := flags.String("out", stdStream, "Output file path, '-' for stdout")
+	err := flags.Parse(args)
+	if err != nil {
+		return logger.WrapError("parse export flags", err)
+	}
+
+	dumpFormat, err := dumpFormat(*format, *out)
+	if err != nil {
+		return logger.WrapError("detect dump format", err)
+	}
+
+	values, err := readBackend(ctx, *from, options)
+	if err != nil {
+		return logger.WrapError("read source backend", err)
+	}
+
+	records, err := toDumpRecords(values)
+	if err != nil {
+		return logger.WrapError("convert values", err)
+	}
+
+	if *out == stdStream {
+		return writeDump(stdout, dumpFormat, records)
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return logger.WrapError("create output file", err)
+	}
+	defer file.Close()
+
+	err = writeDump(file, dumpFormat, records)
+	if err != nil {
+		return logger.WrapError("write dump", err)
+	}
+
+	return file.Close()
+}
+
+func importCommand(ctx context.Context, args []string, stdin io.Reader) error {
+	flags, options := newFlagSet("import")
+	to := flags.String("to", "", "Target backend, its current state is replaced")
+	format := flags.String("format", "", "Dump format: json or csv, detected by input file extension if empty")
+	in := flags.String("in", stdStream, "Input file path, '-' for stdin")
+	err := flags.Parse(args)
+	if err != nil {
+		return logger.WrapError("parse import flags", err)
+	}
+
+	dumpFormat, err := dumpFormat(*format, *in)
+	if err != nil {
+		return logger.WrapError("detect dump format", err)
+	}
+
+	reader := stdin
+	if *in != stdStream {
+		file, err := os.Open(*in)
+		if err != nil {
+			return logger.WrapError("open input file", err)
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	records, err := readDump(reader, dumpFormat)
+	if err != nil {
+		return logger.WrapError("read dump", err)
+	}
+
+	values, err := fromDumpRecords(records)
+	if err != nil {
+		return logger.WrapError("convert records", err)
+	}
+
+	target, err := openBackend(ctx, *to, options, false)
+	if err != nil {
+		return logger.WrapError("open target backend", err)
+	}
+	defer target.Close()
+
+	err = target.Restore(ctx, values)
+	if err != nil {
+		return logger.WrapError("restore target backend", err)
+	}
+
+	return nil
+}
+
+func diffCommand(ctx context.Context, args []string, stdout io.Writer) error {
+	flags, options := newFlagSet("diff")
+	err := flags.Parse(args)
+	if err != nil {
+		return logger.WrapError("parse diff flags", err)
+	}
+
+	if flags.NArg() != 2 {
+		return logger.WrapError("diff expects two backends", errInvalidArgs)
+	}
+
+	left, err := readBackend(ctx, flags.Arg(0), options)
+	if err != nil {
+		return logger.WrapError("read left backend", err)
+	}
+
+	right, err := readBackend(ctx, flags.Arg(1), options)
+	if err != nil {
+		return logger.WrapError("read right backend", err)
+	}
+
+	for _, diff := range diffValues(left, right) {
+		_, err = fmt.Fprintln(stdout, diff)
+		if err != nil {
+			return logger.WrapError("write difference", err)
+		}
+	}
+
+	return nil
+}
+
+func migrateCommand(ctx context.Context, args []string) error {
+	flags, options := newFlagSet("migrate")
+	from := flags.String("from", "", "Source backend")
+	to := flags.String("to", "", "Target backend, its current state is replaced")
+	err := flags.Parse(args)
+	if err != nil {
+		return logger.WrapError("parse migrate flags", err)
+	}
+
+	source, err := openBackend(ctx, *from, options, true)
+	if err != nil {
+		return logger.WrapError("open source backend", err)
+	}
+	defer source.Close()
+
+	target, err := openBackend(ctx, *to, options, false)
+	if err != nil {
+		return logger.WrapError("open target backend", err)
+	}
+	defer target.Close()
+
+	values, err := source.GetMetricValues(ctx)
+	if err != nil {
+		return logger.WrapError("get source metric values", err)
+	}
+
+	err = target.Restore(ctx, values)
+	if err != nil {
+		return logger.WrapError("restore target backend", err)
+	}
+
+	sourceHistory, ok := source.MetricsStorage.(storage.HistoryStorage)
+	if !ok {
+		return nil
+	}
+
+	targetHistory, ok := target.MetricsStorage.(storage.HistoryStorage)
+	if !ok {
+		return nil
+	}
+
+	history, err := sourceHistory.GetHistory(ctx)
+	if err != nil {
+		return logger.WrapError("get source history", err)
+	}
+
+	err = targetHistory.RestoreHistory(ctx, history)
+	if err != nil {
+		return logger.WrapError("restore target history", err)
+	}
+
+	return nil
+}
+
+func readBackend(ctx context.Context, spec string, options *backendOptions) (map[string]map[string]string, error) {
+	source, err := openBackend(ctx, spec, options, true)
+	if err != nil {
+		return nil, logger.WrapError("open backend", err)
+	}
+	defer source.Close()
+
+	return source.GetMetricValues(ctx)
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *backendOptions) {
+	options := &backendOptions{}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.IntVar(&options.backups, "store-backups", 0, "Count of rotated backup file generations kept on write")
+	flags.StringVar(&options.compression, "store-compression", "", "Backup file compression on write: gzip, zstd or empty")
+	flags.StringVar(&options.keyPath, "store-key", "", "Backup file AES key path")
+
+	return flags, options
+}
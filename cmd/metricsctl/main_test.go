@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+)
+
+func TestRun_Migrate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "source.json")
+	basePath := filepath.Join(dir, "metrics.db")
+	targetPath := filepath.Join(dir, "target.json")
+
+	histogram := types.NewHistogramMetric("latency", []float64{0.1, 1})
+	histogram.SetValue(0.05)
+	histogram.SetValue(0.5)
+	summary := types.NewSummaryMetricFromObservations("duration", []float64{1, 2, 3})
+
+	values := map[string]map[string]string{
+		"counter":   {"PollCount": "10", `requests{code="200"}`: "3"},
+		"gauge":     {"Alloc": "100.5"},
+		"histogram": {"latency": histogram.GetStringValue()},
+		"summary":   {"duration": summary.GetStringValue()},
+	}
+	history := map[string]map[string][]metrics.Sample{
+		"counter": {"PollCount": {
+			{Timestamp: time.UnixMilli(1700000000000).UTC(), Value: 5},
+			{Timestamp: time.UnixMilli(1700000060000).UTC(), Value: 10},
+		}},
+		"gauge": {"Alloc": {{Timestamp: time.UnixMilli(1700000000000).UTC(), Value: 100.5}}},
+	}
+
+	source, err := openBackend(ctx, backendFile+":"+sourcePath, &backendOptions{}, false)
+	require.NoError(t, err)
+	require.NoError(t, source.Restore(ctx, values))
+	require.NoError(t, source.MetricsStorage.(storage.HistoryStorage).RestoreHistory(ctx, history))
+	require.NoError(t, source.Close())
+
+	require.NoError(t, run(ctx, []string{"migrate", "-from", "file:" + sourcePath, "-to", "sqlite:" + basePath}, nil, nil))
+	require.NoError(t, run(ctx, []string{"migrate", "-from", "sqlite:" + basePath, "-to", "file:" + targetPath}, nil, nil))
+
+	target, err := openBackend(ctx, backendFile+":"+targetPath, &backendOptions{}, true)
+	require.NoError(t, err)
+	defer target.Close()
+
+	actualValues, err := target.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, values, actualValues)
+
+	actualHistory, err := target.MetricsStorage.(storage.HistoryStorage).GetHistory(ctx)
+	require.NoError(t, err)
+	for _, typedSamples := range actualHistory {
+		for _, samples := range typedSamples {
+			for i := range samples {
+				samples[i].Timestamp = samples[i].Timestamp.UTC()
+			}
+		}
+	}
+	assert.Equal(t, history, actualHistory)
+}
+
+func TestRun_MigrateSourceMissed(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		from string
+	}{
+		{
+			name: "file",
+			from: "file:" + filepath.Join(dir, "missed.json"),
+		},
+		{
+			name: "sqlite",
+			from: "sqlite:" + filepath.Join(dir, "missed.db"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(dir, tt.name+".json")
+			err := run(context.Background(), []string{"migrate", "-from", tt.from, "-to", "file:" + target}, nil, nil)
+			assert.ErrorIs(t, err, errBackendMissed)
+			assert.NoFileExists(t, target)
+		})
+	}
+}
@@ -3,34 +3,44 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v7"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/aes"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/rsa"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	memoryDataBase "github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/memory"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/postgres"
-	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/stub"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/sqlite"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc"
 	grpcServer "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc/server"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/html"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http"
 	httpServer "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http/server"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/prometheus"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/server/handler"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/db"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/file"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/memory"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/tsdb"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/worker"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/runner"
 )
@@ -40,6 +50,17 @@ var (
 	buildDate            = "N/A"
 	buildCommit          = "N/A"
 	defaultStoreInterval = 300 * time.Second
+	defaultStoreBackups  = 3
+
+	defaultHistoryCleanupInterval = time.Minute
+	defaultSeriesExpiryInterval   = time.Minute
+	defaultRequestMaxSkew         = time.Minute
+	defaultNonceCacheSize         = 100000
+
+	errDatabaseNotConfigured  = errors.New("database connection string is not configured")
+	errMigrationsNotSupported = errors.New("database does not support versioned migrations")
+	errInvalidSeriesTypeTTL   = errors.New("invalid series type ttl, expected <type>=<duration>[,<type>=<duration>]")
+	errSharedSeriesTTL        = errors.New("series ttl is not supported with shared database")
 )
 
 type config struct {
@@ -48,18 +69,39 @@ type config struct {
 	Key           string        `env:"KEY" json:"key,omitempty"`
 	ServerURL     string        `env:"ADDRESS" json:"address,omitempty"`
 	GrpcURL       string        `env:"GRPC_ADDRESS" json:"grpc_address,omitempty"`
+	GrpcCert      string        `env:"GRPC_TLS_CERT" json:"grpc_tls_cert,omitempty"`
+	GrpcKey       string        `env:"GRPC_TLS_KEY" json:"grpc_tls_key,omitempty"`
+	GrpcCA        string        `env:"GRPC_TLS_CA" json:"grpc_tls_ca,omitempty"`
+	HTTPSCert     string        `env:"HTTPS_CERT" json:"https_cert,omitempty"`
+	HTTPSKey      string        `env:"HTTPS_KEY" json:"https_key,omitempty"`
+	HTTPSCA       string        `env:"HTTPS_CA" json:"https_ca,omitempty"`
+	HTTPRedirect  string        `env:"HTTPS_REDIRECT_ADDRESS" json:"https_redirect_address,omitempty"`
 	StoreFile     string        `env:"STORE_FILE" json:"store_file,omitempty"`
+	StoreBackups  int           `env:"STORE_BACKUPS" json:"store_backups,omitempty"`
+	StoreCompress string        `env:"STORE_COMPRESSION" json:"store_compression,omitempty"`
+	StoreKey      string        `env:"STORE_KEY" json:"store_key,omitempty"`
 	DB            string        `env:"DATABASE_DSN" json:"database_dsn,omitempty"`
+	DBShared      bool          `env:"DATABASE_SHARED" json:"database_shared,omitempty"`
+	TSDB          string        `env:"TSDB_PATH" json:"tsdb_path,omitempty"`
 	StoreInterval time.Duration `env:"STORE_INTERVAL" json:"store_interval,omitempty"`
+	History       time.Duration `env:"HISTORY_RETENTION" json:"history_retention,omitempty"`
+	TTL           time.Duration `env:"SERIES_TTL" json:"series_ttl,omitempty"`
+	TypeTTL       string        `env:"SERIES_TYPE_TTL" json:"series_type_ttl,omitempty"`
 	Restore       bool          `env:"RESTORE" json:"restore,omitempty"`
 	TrustedSubnet string        `env:"TRUSTED_SUBNET" json:"trusted_subnet,omitempty"`
+	LogLevelName  string        `env:"LOG_LEVEL" json:"log_level,omitempty"`
+	LogFormatName string        `env:"LOG_FORMAT" json:"log_format,omitempty"`
+	LogOutput     string        `env:"LOG_OUTPUT" json:"log_output,omitempty"`
+	LogWrapped    bool          `env:"LOG_WRAPPED_ERRORS" json:"log_wrapped_errors,omitempty"`
+	TraceOutput   string        `env:"TRACE_OUTPUT" json:"trace_output,omitempty"`
+	RequireSign   bool          `env:"REQUIRE_REQUEST_SIGN" json:"require_request_sign,omitempty"`
+	MaxSkew       time.Duration `env:"REQUEST_MAX_SKEW" json:"request_max_skew,omitempty"`
+	NonceCache    int           `env:"NONCE_CACHE_SIZE" json:"nonce_cache_size,omitempty"`
+	Migrate       string
+	seriesTTL     map[string]time.Duration
 }
 
 func main() {
-	logger.InfoFormat("Build version: %s\n", buildVersion)
-	logger.InfoFormat("Build date: %s\n", buildDate)
-	logger.InfoFormat("Build commit: %s\n", buildCommit)
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -67,6 +109,25 @@ func main() {
 	if err != nil {
 		panic(logger.WrapError("create config file", err))
 	}
+
+	err = logger.Configure(conf)
+	if err != nil {
+		panic(logger.WrapError("configure logger", err))
+	}
+	defer logger.Close()
+
+	logger.InfoFormat("Build version: %s\n", buildVersion)
+	logger.InfoFormat("Build date: %s\n", buildDate)
+	logger.InfoFormat("Build commit: %s\n", buildCommit)
+
+	if conf.Migrate != "" {
+		err = migrate(ctx, conf)
+		if err != nil {
+			panic(logger.WrapError("migrate database schema", err))
+		}
+		return
+	}
+
 	logger.InfoFormat("Starting server with the following configuration:%v", conf)
 
 	interrupt := make(chan os.Signal, 1)
@@ -74,41 +135,103 @@ func main() {
 
 	var base database.DataBase
 	var backupStorage storage.MetricsStorage
-	if conf.DB == "" {
-		base = &stub.StubDataBase{}
-		backupStorage = file.NewFileStorage(conf)
-	} else {
-		base, err = postgres.NewPostgresDataBase(ctx, conf)
+	var primaryStorage storage.MetricsStorage = memory.NewInMemoryStorage()
+	switch {
+	case conf.DB == "" && conf.TSDB != "":
+		base = memoryDataBase.NewInMemoryDataBase()
+		tsdbStorage, err := tsdb.NewTSDBStorage(conf)
+		if err != nil {
+			panic(logger.WrapError("create tsdb storage", err))
+		}
+		defer tsdbStorage.Close()
+
+		backupStorage = tsdbStorage
+	case conf.DB == "":
+		base = memoryDataBase.NewInMemoryDataBase()
+		var backupEncryptor crypto.Encryptor
+		var backupDecryptor crypto.Decryptor
+		if conf.StoreKey != "" {
+			backupCipher, err := aes.NewCipher(conf.StoreKey)
+			if err != nil {
+				panic(logger.WrapError("create backup cipher", err))
+			}
+
+			backupEncryptor = backupCipher
+			backupDecryptor = backupCipher
+		}
+
+		backupStorage = file.NewFileStorage(conf, backupEncryptor, backupDecryptor)
+	default:
+		base, err = newDataBase(ctx, conf)
 		if err != nil {
 			panic(logger.WrapError("create database", err))
 		}
 
-		backupStorage = db.NewDBStorage(base)
+		if conf.DBShared {
+			// replicas read and accumulate metrics in the database directly
+			primaryStorage = db.NewSharedDBStorage(base)
+		} else {
+			backupStorage = db.NewDBStorage(base)
+		}
 	}
 	defer base.Close()
 
-	inMemoryStorage := memory.NewInMemoryStorage()
-	storageStrategy := storage.NewStorageStrategy(conf, inMemoryStorage, backupStorage)
+	// server own metrics are kept apart from the received ones and never backed up
+	recorder := telemetry.NewRecorder(memory.NewInMemoryStorage())
+	agentsRegistry := agents.NewRegistry()
+	storageStrategy := storage.NewStorageStrategy(conf, primaryStorage, backupStorage, recorder, agentsRegistry)
 	defer storageStrategy.Close()
 
+	var exporter tracing.Exporter
+	if conf.TraceOutput != "" {
+		exporter, err = tracing.NewExporter(conf.TraceOutput)
+		if err != nil {
+			panic(logger.WrapError("create trace exporter", err))
+		}
+		defer exporter.Close()
+	}
+	tracer := tracing.NewTracer(exporter)
+
 	signer := hash.NewSigner(conf)
 	grpcConverter := grpc.NewMetricsConverter(conf, signer)
 	httpConverter := http.NewMetricsConverter(conf, signer)
 	htmlPageBuilder := html.NewSimplePageBuilder()
-	requestHandler := handler.NewHandler(base, htmlPageBuilder, storageStrategy)
+	prometheusPageBuilder := prometheus.NewTextPageBuilder()
+	requestHandler := handler.NewHandler(base, storageStrategy, recorder, agentsRegistry, htmlPageBuilder, prometheusPageBuilder)
 
 	var decryptor crypto.Decryptor
 	if conf.CryptoKey != "" {
-		decryptor, err = rsa.NewDecryptor(conf.CryptoKey)
+		decryptor, err = rsa.NewDecryptor(conf.CryptoKeyPaths()...)
 		if err != nil {
 			panic(logger.WrapError("create decryptor", err))
 		}
 	}
 
-	grpcMetricsServer := grpcServer.New(conf, grpcConverter, requestHandler)
-	httpMetricsServer := httpServer.New(conf, httpConverter, decryptor, requestHandler)
+	var verifier *hash.RequestVerifier
+	if conf.Key != "" {
+		verifier = hash.NewRequestVerifier(conf, signer)
+	} else if conf.RequireSign {
+		panic(logger.WrapError("create request verifier", hash.ErrMissedSecretKey))
+	}
+
+	grpcMetricsServer, err := grpcServer.New(conf, grpcConverter, decryptor, verifier, recorder, tracer, requestHandler)
+	if err != nil {
+		panic(logger.WrapError("create grpc server", err))
+	}
+	httpMetricsServer, err := httpServer.New(conf, httpConverter, decryptor, verifier, recorder, tracer, requestHandler)
+	if err != nil {
+		panic(logger.WrapError("create http server", err))
+	}
 	runners := []runner.Runner{grpcMetricsServer, httpMetricsServer}
 
+	if conf.HTTPRedirect != "" {
+		if conf.HTTPSCert == "" {
+			logger.Warn("HTTPS redirect is ignored, HTTPS is not configured")
+		} else {
+			runners = append(runners, httpServer.NewRedirect(conf))
+		}
+	}
+
 	if conf.Restore {
 		logger.Info("Restore metrics from backup")
 		err = storageStrategy.RestoreFromBackup(ctx)
@@ -123,6 +246,18 @@ func main() {
 		runners = append(runners, &backgroundStore)
 	}
 
+	if conf.History > 0 {
+		logger.Info("Start history cleanup service")
+		historyCleanup := worker.NewPeriodicWorker(defaultHistoryCleanupInterval, storageStrategy.RemoveExpiredSamples)
+		runners = append(runners, &historyCleanup)
+	}
+
+	if conf.seriesExpiryEnabled() {
+		logger.Info("Start series expiry service")
+		seriesExpiry := worker.NewPeriodicWorker(defaultSeriesExpiryInterval, storageStrategy.RemoveStaleMetrics)
+		runners = append(runners, &seriesExpiry)
+	}
+
 	multiRunner := runner.NewMultiWorker(runners...)
 	gracefulRunner := runner.NewGracefulRunner(multiRunner)
 	gracefulRunner.Start(ctx)
@@ -145,15 +280,39 @@ func createConfig() (*config, error) {
 
 	flag.StringVar(&conf.ConfigPath, "c", "", "Json config file path")
 	flag.StringVar(&conf.ConfigPath, "config", "", "Json config file path")
-	flag.StringVar(&conf.CryptoKey, "crypto-key", "", "Server private crypto key path")
+	flag.StringVar(&conf.CryptoKey, "crypto-key", "", "Comma separated server private crypto key paths, several keys are accepted during key rotation")
 	flag.StringVar(&conf.Key, "k", "", "Signer secret key")
 	flag.BoolVar(&conf.Restore, "r", true, "Restore metric values from the server backup file")
 	flag.DurationVar(&conf.StoreInterval, "i", defaultStoreInterval, "Store backup interval")
+	flag.DurationVar(&conf.History, "history-retention", 0, "Metric samples history retention, 0 disables history")
+	flag.DurationVar(&conf.TTL, "series-ttl", 0, "Series not updated within ttl are removed, 0 keeps series forever")
+	flag.StringVar(&conf.TypeTTL, "series-type-ttl", "", "Series ttl overrides by metric type, e.g. counter=168h,gauge=1h")
 	flag.StringVar(&conf.ServerURL, "a", "127.0.0.1:8080", "Server listen URL")
 	flag.StringVar(&conf.GrpcURL, "g", "127.0.0.1:3200", "Server grpc URL")
+	flag.StringVar(&conf.GrpcCert, "grpc-tls-cert", "", "Server grpc TLS certificate path, TLS is disabled if empty")
+	flag.StringVar(&conf.GrpcKey, "grpc-tls-key", "", "Server grpc TLS private key path")
+	flag.StringVar(&conf.GrpcCA, "grpc-tls-ca", "", "Agents CA certificate path, agent certificates are required if set")
+	flag.StringVar(&conf.HTTPSCert, "https-cert", "", "Server HTTPS certificate path, HTTPS is disabled if empty")
+	flag.StringVar(&conf.HTTPSKey, "https-key", "", "Server HTTPS private key path")
+	flag.StringVar(&conf.HTTPSCA, "https-ca", "", "Agents CA certificate path, HTTPS client certificates are required if set")
+	flag.StringVar(&conf.HTTPRedirect, "https-redirect", "", "Plain HTTP listen address redirecting requests to HTTPS, disabled if empty")
 	flag.StringVar(&conf.StoreFile, "f", "/tmp/devops-metrics-dataBase.json", "Backup storage file path")
-	flag.StringVar(&conf.DB, "d", "", "Database connection stirng")
+	flag.IntVar(&conf.StoreBackups, "store-backups", defaultStoreBackups, "Count of rotated backup file generations")
+	flag.StringVar(&conf.StoreCompress, "store-compression", file.CompressionNone, "Backup file compression: gzip, zstd or empty")
+	flag.StringVar(&conf.StoreKey, "store-key", "", "Backup file AES key path, backup is not encrypted if empty")
+	flag.StringVar(&conf.TSDB, "tsdb", "", "Append-only storage directory, used instead of backup file")
+	flag.StringVar(&conf.DB, "d", "", "Database connection stirng, postgres by default or sqlite://<path>")
+	flag.BoolVar(&conf.DBShared, "db-shared", false, "Use database as the primary storage shared by server replicas, series ttl is not supported")
 	flag.StringVar(&conf.TrustedSubnet, "t", "", "Clients trusted subnet")
+	flag.StringVar(&conf.Migrate, "migrate", "", "Run database schema migration and exit: up, down, version or target version number")
+	flag.StringVar(&conf.LogLevelName, "log-level", "info", "Log level: debug, info, warn or error")
+	flag.StringVar(&conf.LogFormatName, "log-format", logger.FormatText, "Log format: text or json")
+	flag.StringVar(&conf.LogOutput, "log-output", logger.OutputStderr, "Comma separated log outputs: stdout, stderr or file path")
+	flag.BoolVar(&conf.LogWrapped, "log-wrapped-errors", true, "Log every wrapped error, disable to log failures once")
+	flag.StringVar(&conf.TraceOutput, "trace-output", "", "Trace spans output: stdout, stderr or file path, disabled if empty")
+	flag.BoolVar(&conf.RequireSign, "require-request-sign", false, "Reject requests without whole request signature, signer secret key is required")
+	flag.DurationVar(&conf.MaxSkew, "request-max-skew", defaultRequestMaxSkew, "Allowed clock skew of the signed request timestamp")
+	flag.IntVar(&conf.NonceCache, "nonce-cache-size", defaultNonceCacheSize, "Count of remembered signed request nonces")
 	flag.Parse()
 
 	err := env.Parse(conf)
@@ -173,28 +332,186 @@ func createConfig() (*config, error) {
 		}
 	}
 
+	conf.seriesTTL, err = parseSeriesTypeTTL(conf.TypeTTL)
+	if err != nil {
+		return nil, logger.WrapError("parse series type ttl", err)
+	}
+
+	if conf.DB != "" && conf.DBShared && conf.seriesExpiryEnabled() {
+		// update time is tracked by the memory storage only
+		return nil, logger.WrapError("validate series ttl", errSharedSeriesTTL)
+	}
+
 	return conf, nil
 }
 
+// parseSeriesTypeTTL parses comma separated <type>=<duration> pairs.
+func parseSeriesTypeTTL(str string) (map[string]time.Duration, error) {
+	result := map[string]time.Duration{}
+	if str == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(str, ",") {
+		metricType, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || metricType == "" {
+			return nil, logger.WrapError(fmt.Sprintf("parse pair '%s'", pair), errInvalidSeriesTypeTTL)
+		}
+
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse duration of type '%s'", metricType), err)
+		}
+
+		result[metricType] = ttl
+	}
+
+	return result, nil
+}
+
+// newDataBase selects database implementation by the connection string scheme.
+func newDataBase(ctx context.Context, conf *config) (database.DataBase, error) {
+	if strings.HasPrefix(conf.DB, sqlite.Scheme) {
+		return sqlite.NewSQLiteDataBase(ctx, conf)
+	}
+
+	return postgres.NewPostgresDataBase(ctx, conf)
+}
+
+func migrate(ctx context.Context, conf *config) error {
+	if conf.DB == "" {
+		return logger.WrapError("migrate database", errDatabaseNotConfigured)
+	}
+
+	if strings.HasPrefix(conf.DB, sqlite.Scheme) {
+		return logger.WrapError("migrate sqlite database, its schema is applied on start", errMigrationsNotSupported)
+	}
+
+	conn, err := postgres.OpenDB(ctx, conf.DB)
+	if err != nil {
+		return logger.WrapError("open database", err)
+	}
+	defer conn.Close()
+
+	migrator, err := postgres.NewMigrator(conn)
+	if err != nil {
+		return logger.WrapError("create migrator", err)
+	}
+
+	switch conf.Migrate {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "version":
+	default:
+		var target int64
+		target, err = strconv.ParseInt(conf.Migrate, 10, 64)
+		if err != nil {
+			return logger.WrapError(fmt.Sprintf("parse target version '%s'", conf.Migrate), err)
+		}
+
+		err = migrator.Migrate(ctx, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return logger.WrapError("get schema version", err)
+	}
+
+	logger.InfoFormat("Database schema version: %d", version)
+	return nil
+}
+
 func (c *config) ListenURL() string {
 	return c.ServerURL
 }
 
+func (c *config) HTTPCertPath() string {
+	return c.HTTPSCert
+}
+
+func (c *config) HTTPKeyPath() string {
+	return c.HTTPSKey
+}
+
+func (c *config) HTTPCAPath() string {
+	return c.HTTPSCA
+}
+
+func (c *config) HTTPRedirectURL() string {
+	return c.HTTPRedirect
+}
+
 func (c *config) ListenTCP() string {
 	return c.GrpcURL
 }
 
+func (c *config) GrpcCertPath() string {
+	return c.GrpcCert
+}
+
+func (c *config) GrpcKeyPath() string {
+	return c.GrpcKey
+}
+
+func (c *config) GrpcCAPath() string {
+	return c.GrpcCA
+}
+
 func (c *config) StoreFilePath() string {
 	return c.StoreFile
 }
 
 func (c *config) SyncMode() bool {
-	return c.DB != "" || c.StoreInterval == 0
+	return c.DB != "" || c.TSDB != "" || c.StoreInterval == 0
+}
+
+func (c *config) StoreFileGenerations() int {
+	return c.StoreBackups
+}
+
+func (c *config) StoreFileCompression() string {
+	return c.StoreCompress
+}
+
+func (c *config) TSDBPath() string {
+	return c.TSDB
+}
+
+func (c *config) HistoryRetention() time.Duration {
+	return c.History
+}
+
+func (c *config) SeriesTTL(metricType string) time.Duration {
+	ttl, ok := c.seriesTTL[metricType]
+	if ok {
+		return ttl
+	}
+
+	return c.TTL
+}
+
+func (c *config) seriesExpiryEnabled() bool {
+	if c.TTL > 0 {
+		return true
+	}
+
+	for _, ttl := range c.seriesTTL {
+		if ttl > 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (c *config) String() string {
-	return fmt.Sprintf("\nServerURL:\t%v\nStoreInterval:\t%v\nStoreFile:\t%v\nRestore:\t%v\nDb:\t%v",
-		c.ServerURL, c.StoreInterval, c.StoreFile, c.Restore, c.DB)
+	return fmt.Sprintf("\nServerURL:\t%v\nStoreInterval:\t%v\nStoreFile:\t%v\nRestore:\t%v\nDb:\t%v\nDbShared:\t%v\nTSDB:\t%v\nHistoryRetention:\t%v\nSeriesTTL:\t%v\nSeriesTypeTTL:\t%v\nLogLevel:\t%v\nLogFormat:\t%v\nTraceOutput:\t%v",
+		c.ServerURL, c.StoreInterval, c.StoreFile, c.Restore, c.DB, c.DBShared, c.TSDB, c.History, c.TTL, c.TypeTTL, c.LogLevelName, c.LogFormatName, c.TraceOutput)
 }
 
 func (c *config) GetKey() []byte {
@@ -205,6 +522,18 @@ func (c *config) SignMetrics() bool {
 	return c.Key != ""
 }
 
+func (c *config) RequireRequestSign() bool {
+	return c.RequireSign
+}
+
+func (c *config) RequestMaxSkew() time.Duration {
+	return c.MaxSkew
+}
+
+func (c *config) NonceCacheSize() int {
+	return c.NonceCache
+}
+
 func (c *config) GetConnectionString() string {
 	return c.DB
 }
@@ -221,3 +550,30 @@ func (c *config) ClientsTrustedSubnet() *net.IPNet {
 
 	return subnet
 }
+
+func (c *config) LogLevel() string {
+	return c.LogLevelName
+}
+
+func (c *config) LogFormat() string {
+	return c.LogFormatName
+}
+
+func (c *config) CryptoKeyPaths() []string {
+	result := []string{}
+	for _, path := range strings.Split(c.CryptoKey, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			result = append(result, path)
+		}
+	}
+
+	return result
+}
+
+func (c *config) LogOutputs() []string {
+	return strings.Split(c.LogOutput, ",")
+}
+
+func (c *config) LogWrappedErrors() bool {
+	return c.LogWrapped
+}
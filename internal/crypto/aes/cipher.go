@@ -0,0 +1,76 @@
+package aes
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+type aesCipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates AES-GCM encryptor and decryptor.
+// The key file contains 16, 24 or 32 bytes key, raw or hex encoded.
+func NewCipher(keyPath string) (*aesCipher, error) {
+	content, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, logger.WrapError("read key file", err)
+	}
+
+	key := content
+	decoded, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err == nil && isValidKeySize(len(decoded)) {
+		key = decoded
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, logger.WrapError("create block cipher", crypto.ErrInvalidKey)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, logger.WrapError("create gcm", err)
+	}
+
+	return &aesCipher{
+		aead: aead,
+	}, nil
+}
+
+// Encrypt seals message with the random nonce prepended to the result.
+func (a *aesCipher) Encrypt(bytes []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize(), a.aead.NonceSize()+len(bytes)+a.aead.Overhead())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, logger.WrapError("generate nonce", err)
+	}
+
+	return a.aead.Seal(nonce, nonce, bytes, nil), nil
+}
+
+func (a *aesCipher) Decrypt(bytes []byte) ([]byte, error) {
+	nonceSize := a.aead.NonceSize()
+	if len(bytes) < nonceSize {
+		return nil, logger.WrapError("decrypt message", crypto.ErrInvalidCiphertext)
+	}
+
+	result, err := a.aead.Open(nil, bytes[:nonceSize], bytes[nonceSize:], nil)
+	if err != nil {
+		return nil, logger.WrapError("decrypt message", crypto.ErrInvalidCiphertext)
+	}
+
+	return result, nil
+}
+
+func isValidKeySize(size int) bool {
+	return size == 16 || size == 24 || size == 32
+}
@@ -0,0 +1,61 @@
+package aes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+)
+
+func TestCipher(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		expectedErr error
+	}{
+		{
+			name: "hex_key",
+			key:  "000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f\n",
+		},
+		{
+			name: "raw_key",
+			key:  "0123456789abcdef",
+		},
+		{
+			name:        "invalid_key",
+			key:         "short",
+			expectedErr: crypto.ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyPath := filepath.Join(t.TempDir(), "key")
+			require.NoError(t, os.WriteFile(keyPath, []byte(tt.key), 0o600))
+
+			cipher, err := NewCipher(keyPath)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			message := []byte("secret message")
+			encrypted, err := cipher.Encrypt(message)
+			require.NoError(t, err)
+			assert.NotContains(t, string(encrypted), string(message))
+
+			decrypted, err := cipher.Decrypt(encrypted)
+			require.NoError(t, err)
+			assert.Equal(t, message, decrypted)
+
+			encrypted[len(encrypted)-1] ^= 0xFF
+			_, err = cipher.Decrypt(encrypted)
+			assert.ErrorIs(t, err, crypto.ErrInvalidCiphertext)
+		})
+	}
+}
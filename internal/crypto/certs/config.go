@@ -0,0 +1,101 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const (
+	// HTTP2Protocol is an HTTP/2 protocol name negotiated by ALPN, gRPC requires it.
+	HTTP2Protocol = "h2"
+	// HTTP1Protocol is an HTTP/1.1 protocol name negotiated by ALPN.
+	HTTP1Protocol = "http/1.1"
+)
+
+// NewServerConfig creates server TLS configuration offering protocols in the order of preference.
+// Client certificates are required and verified when ca path is set.
+// Files are reloaded on the next handshake after they are changed.
+func NewServerConfig(certPath string, keyPath string, caPath string, protocols ...string) (*tls.Config, error) {
+	if certPath == "" {
+		return nil, logger.WrapError("create server tls config", ErrCertificateRequired)
+	}
+
+	certificates, err := newSource(certPath, keyPath, caPath)
+	if err != nil {
+		return nil, logger.WrapError("load server certificates", err)
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: protocols,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, pool := certificates.current()
+			result := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certificate},
+				NextProtos:   protocols,
+			}
+			if pool != nil {
+				result.ClientCAs = pool
+				result.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return result, nil
+		},
+	}, nil
+}
+
+// NewClientConfig creates client TLS configuration. Server certificate is verified by the ca file or by system roots.
+// Client certificate is optional, it is sent for mutual TLS. Files are reloaded on the next handshake after they are changed.
+func NewClientConfig(certPath string, keyPath string, caPath string) (*tls.Config, error) {
+	certificates, err := newSource(certPath, keyPath, caPath)
+	if err != nil {
+		return nil, logger.WrapError("load client certificates", err)
+	}
+
+	result := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if certPath != "" {
+		result.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _ := certificates.current()
+			return certificate, nil
+		}
+	}
+
+	if caPath != "" {
+		// default verification uses fixed roots, server chain is verified against the reloaded pool instead
+		result.InsecureSkipVerify = true //nolint:gosec
+		result.VerifyConnection = func(state tls.ConnectionState) error {
+			_, pool := certificates.current()
+			return verifyServer(state, pool)
+		}
+	}
+
+	return result, nil
+}
+
+func verifyServer(state tls.ConnectionState, pool *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return logger.WrapError("verify server certificate", ErrCertificateRequired)
+	}
+
+	options := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       state.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, certificate := range state.PeerCertificates[1:] {
+		options.Intermediates.AddCert(certificate)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(options)
+	if err != nil {
+		return logger.WrapError("verify server certificate", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package certs
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+)
+
+type handshakeResult struct {
+	clientState tls.ConnectionState
+	serverState tls.ConnectionState
+	clientErr   error
+	serverErr   error
+}
+
+func TestConfig_Handshake(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverHost    string
+		mutual        bool
+		clientCert    bool
+		untrustedCA   bool
+		expectedError bool
+		expectedAgent string
+	}{
+		{
+			name:       "tls",
+			serverHost: "localhost",
+		},
+		{
+			name:       "tls_client_certificate_ignored",
+			serverHost: "localhost",
+			clientCert: true,
+		},
+		{
+			name:          "mutual_tls",
+			serverHost:    "localhost",
+			mutual:        true,
+			clientCert:    true,
+			expectedAgent: "agent1",
+		},
+		{
+			name:          "mutual_tls_without_client_certificate",
+			serverHost:    "localhost",
+			mutual:        true,
+			expectedError: true,
+		},
+		{
+			name:          "unknown_authority",
+			serverHost:    "localhost",
+			untrustedCA:   true,
+			expectedError: true,
+		},
+		{
+			name:          "wrong_host",
+			serverHost:    "metrics.example.com",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			authority := test.NewAuthority(t, dir, "ca")
+			serverCert, serverKey := authority.Issue(t, "server", tt.serverHost)
+
+			serverCA := ""
+			if tt.mutual {
+				serverCA = authority.CertPath
+			}
+			serverConfig, err := NewServerConfig(serverCert, serverKey, serverCA, HTTP2Protocol)
+			require.NoError(t, err)
+
+			clientCA := authority.CertPath
+			if tt.untrustedCA {
+				clientCA = test.NewAuthority(t, dir, "other").CertPath
+			}
+			clientCert, clientKey := "", ""
+			if tt.clientCert {
+				clientCert, clientKey = authority.Issue(t, "agent1")
+			}
+			clientConfig, err := NewClientConfig(clientCert, clientKey, clientCA)
+			require.NoError(t, err)
+
+			result := handshake(serverConfig, clientConfig)
+			if tt.expectedError {
+				// tls 1.3 client completes handshake before the server verifies client certificate
+				assert.Error(t, result.serverErr)
+				return
+			}
+
+			require.NoError(t, result.clientErr)
+			require.NoError(t, result.serverErr)
+			assert.Equal(t, "server", result.clientState.PeerCertificates[0].Subject.CommonName)
+			if tt.expectedAgent != "" {
+				require.NotEmpty(t, result.serverState.VerifiedChains)
+				assert.Equal(t, tt.expectedAgent, result.serverState.VerifiedChains[0][0].Subject.CommonName)
+			} else {
+				assert.Empty(t, result.serverState.PeerCertificates)
+			}
+		})
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	dir := t.TempDir()
+	authority := test.NewAuthority(t, dir, "ca")
+	serverCert, serverKey := authority.Issue(t, "server", "localhost")
+	clientCert, clientKey := authority.Issue(t, "agent1")
+
+	serverConfig, err := NewServerConfig(serverCert, serverKey, authority.CertPath, HTTP2Protocol)
+	require.NoError(t, err)
+	clientConfig, err := NewClientConfig(clientCert, clientKey, authority.CertPath)
+	require.NoError(t, err)
+
+	result := handshake(serverConfig, clientConfig)
+	require.NoError(t, result.clientErr)
+	require.NoError(t, result.serverErr)
+	previousSerial := result.clientState.PeerCertificates[0].SerialNumber
+
+	// rotate the whole chain in place, both sides pick new files on the next handshake
+	rotated := test.NewAuthority(t, dir, "ca")
+	rotated.Issue(t, "server", "localhost")
+	rotated.Issue(t, "agent1")
+	touch(t, serverCert, serverKey, clientCert, clientKey, authority.CertPath)
+
+	result = handshake(serverConfig, clientConfig)
+	require.NoError(t, result.clientErr)
+	require.NoError(t, result.serverErr)
+	assert.NotEqual(t, previousSerial, result.clientState.PeerCertificates[0].SerialNumber)
+	assert.Equal(t, "agent1", result.serverState.VerifiedChains[0][0].Subject.CommonName)
+
+	// broken files keep the previous certificates
+	require.NoError(t, os.WriteFile(serverKey, []byte("invalid"), 0o600))
+	touch(t, serverKey)
+
+	result = handshake(serverConfig, clientConfig)
+	require.NoError(t, result.clientErr)
+	require.NoError(t, result.serverErr)
+}
+
+func TestNewServerConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	authority := test.NewAuthority(t, dir, "ca")
+	serverCert, serverKey := authority.Issue(t, "server", "localhost")
+
+	tests := []struct {
+		name          string
+		certPath      string
+		keyPath       string
+		caPath        string
+		expectedError error
+	}{
+		{
+			name:          "no_certificate",
+			expectedError: ErrCertificateRequired,
+		},
+		{
+			name:          "no_key",
+			certPath:      serverCert,
+			expectedError: ErrKeyPairRequired,
+		},
+		{
+			name:     "invalid_key",
+			certPath: serverCert,
+			keyPath:  serverCert,
+		},
+		{
+			name:          "missing_ca",
+			certPath:      serverCert,
+			keyPath:       serverKey,
+			caPath:        filepath.Join(dir, "missing.crt"),
+			expectedError: os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServerConfig(tt.certPath, tt.keyPath, tt.caPath)
+			require.Error(t, err)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func handshake(serverConfig *tls.Config, clientConfig *tls.Config) handshakeResult {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	clientConfig = clientConfig.Clone()
+	clientConfig.ServerName = "localhost"
+
+	result := handshakeResult{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server := tls.Server(serverConn, serverConfig)
+		result.serverErr = server.Handshake()
+		result.serverState = server.ConnectionState()
+		serverConn.Close()
+	}()
+
+	client := tls.Client(clientConn, clientConfig)
+	result.clientErr = client.Handshake()
+	result.clientState = client.ConnectionState()
+	clientConn.Close()
+	<-done
+
+	return result
+}
+
+func touch(t *testing.T, paths ...string) {
+	modTime := time.Now().Add(time.Minute)
+	for _, path := range paths {
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+}
@@ -0,0 +1,8 @@
+package certs
+
+import "errors"
+
+var (
+	ErrCertificateRequired = errors.New("certificate is required")
+	ErrKeyPairRequired     = errors.New("certificate and key paths should be set together")
+)
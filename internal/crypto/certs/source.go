@@ -0,0 +1,132 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// source keeps certificate and CA pool loaded from files and reloads them once the files are changed.
+type source struct {
+	certPath    string
+	keyPath     string
+	caPath      string
+	certificate *tls.Certificate
+	pool        *x509.CertPool
+	modTimes    map[string]time.Time
+	lock        sync.Mutex
+}
+
+func newSource(certPath string, keyPath string, caPath string) (*source, error) {
+	if (certPath == "") != (keyPath == "") {
+		return nil, logger.WrapError("load certificate", ErrKeyPairRequired)
+	}
+
+	result := &source{
+		certPath: certPath,
+		keyPath:  keyPath,
+		caPath:   caPath,
+	}
+
+	modTimes, err := result.readModTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = result.load(modTimes)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// current returns actual certificate and CA pool.
+// Reload failures are logged and the previous state is kept, the reload is retried on the next call.
+func (s *source) current() (*tls.Certificate, *x509.CertPool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	modTimes, err := s.readModTimes()
+	if err != nil {
+		logger.ErrorFormat("Failed to check certificates: %v", err)
+		return s.certificate, s.pool
+	}
+
+	if !s.changed(modTimes) {
+		return s.certificate, s.pool
+	}
+
+	err = s.load(modTimes)
+	if err != nil {
+		logger.ErrorFormat("Failed to reload certificates: %v", err)
+	} else {
+		logger.Info("Certificates reloaded")
+	}
+
+	return s.certificate, s.pool
+}
+
+func (s *source) load(modTimes map[string]time.Time) error {
+	var certificate *tls.Certificate
+	if s.certPath != "" {
+		keyPair, err := tls.LoadX509KeyPair(s.certPath, s.keyPath)
+		if err != nil {
+			return logger.WrapError("load certificate key pair", err)
+		}
+
+		certificate = &keyPair
+	}
+
+	var pool *x509.CertPool
+	if s.caPath != "" {
+		content, err := os.ReadFile(s.caPath)
+		if err != nil {
+			return logger.WrapError("read ca file", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return logger.WrapError(fmt.Sprintf("parse ca file '%s'", s.caPath), crypto.ErrInvalidKey)
+		}
+	}
+
+	s.certificate = certificate
+	s.pool = pool
+	s.modTimes = modTimes
+	return nil
+}
+
+func (s *source) readModTimes() (map[string]time.Time, error) {
+	result := map[string]time.Time{}
+	for _, path := range []string{s.certPath, s.keyPath, s.caPath} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("stat file '%s'", path), err)
+		}
+
+		result[path] = info.ModTime()
+	}
+
+	return result, nil
+}
+
+func (s *source) changed(modTimes map[string]time.Time) bool {
+	for path, modTime := range modTimes {
+		if !s.modTimes[path].Equal(modTime) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,4 +2,8 @@ package crypto
 
 import "errors"
 
-var ErrInvalidKey = errors.New("invalid key")
+var (
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	ErrInvalidKey        = errors.New("invalid key")
+	ErrUnknownKey        = errors.New("unknown key")
+)
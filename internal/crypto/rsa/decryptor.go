@@ -1,11 +1,13 @@
 package rsa
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
+	"fmt"
 	"os"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
@@ -13,42 +15,157 @@ import (
 	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/chunk"
 )
 
+type privateKey struct {
+	key *rsa.PrivateKey
+	id  []byte
+}
+
 type rsaDecryptor struct {
-	privateKey *rsa.PrivateKey
+	privateKeys []*privateKey
 }
 
-func NewDecryptor(privateKeyPAth string) (*rsaDecryptor, error) {
-	privateKeyContent, err := os.ReadFile(privateKeyPAth)
-	if err != nil {
-		return nil, logger.WrapError("read private key file", err)
+// NewDecryptor creates decryptor of envelope and legacy chunked messages.
+// Several keys are accepted during key rotation, envelopes are decrypted by the key with the matching id.
+func NewDecryptor(privateKeyPaths ...string) (*rsaDecryptor, error) {
+	if len(privateKeyPaths) == 0 {
+		return nil, logger.WrapError("create decryptor", crypto.ErrInvalidKey)
 	}
 
-	privatePem, _ := pem.Decode(privateKeyContent)
-	if privatePem == nil || privatePem.Type != "RSA PRIVATE KEY" {
-		return nil, logger.WrapError("decode PEM block containing private key", crypto.ErrInvalidKey)
-	}
+	privateKeys := make([]*privateKey, len(privateKeyPaths))
+	for i, path := range privateKeyPaths {
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("load private key '%s'", path), err)
+		}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(privatePem.Bytes)
-	if err != nil {
-		return nil, logger.WrapError("parse public key", err)
+		privateKeys[i] = key
 	}
 
 	return &rsaDecryptor{
-		privateKey: privateKey,
+		privateKeys: privateKeys,
 	}, nil
 }
 
+// Decrypt opens envelope messages, messages of the legacy agents are decrypted as raw RSA-OAEP chunks.
 func (r *rsaDecryptor) Decrypt(bytes []byte) ([]byte, error) {
+	if !isEnvelope(bytes) {
+		return r.decryptLegacy(bytes)
+	}
+
+	result, err := r.decryptEnvelope(bytes)
+	if err != nil {
+		// legacy message may start with the envelope magic by chance
+		legacyResult, legacyErr := r.decryptLegacy(bytes)
+		if legacyErr == nil {
+			return legacyResult, nil
+		}
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *rsaDecryptor) decryptEnvelope(message []byte) ([]byte, error) {
+	parsed, err := parseEnvelope(message)
+	if err != nil {
+		return nil, err
+	}
+
+	key := r.findKey(parsed.keyID)
+	if key == nil {
+		return nil, logger.WrapError(fmt.Sprintf("find key %x", parsed.keyID), crypto.ErrUnknownKey)
+	}
+
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key.key, parsed.wrappedKey, nil)
+	if err != nil {
+		return nil, logger.WrapError("unwrap data key", crypto.ErrInvalidCiphertext)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, logger.WrapError("unwrap data key", crypto.ErrInvalidCiphertext)
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(parsed.payload) < nonceSize {
+		return nil, logger.WrapError("decrypt message", crypto.ErrInvalidCiphertext)
+	}
+
+	result, err := aead.Open(nil, parsed.payload[:nonceSize], parsed.payload[nonceSize:], parsed.header)
+	if err != nil {
+		return nil, logger.WrapError("decrypt message", crypto.ErrInvalidCiphertext)
+	}
+
+	return result, nil
+}
+
+func (r *rsaDecryptor) decryptLegacy(message []byte) ([]byte, error) {
+	var lastErr error = crypto.ErrInvalidCiphertext
+	for _, key := range r.privateKeys {
+		blockSize := key.key.Size()
+		if len(message)%blockSize != 0 {
+			continue
+		}
+
+		result, err := decryptChunks(key.key, message, blockSize)
+		if err == nil {
+			return result, nil
+		}
+
+		lastErr = err
+	}
+
+	return nil, logger.WrapError("decrypt legacy message", lastErr)
+}
+
+func (r *rsaDecryptor) findKey(keyID []byte) *privateKey {
+	for _, key := range r.privateKeys {
+		if bytes.Equal(key.id, keyID) {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func decryptChunks(key *rsa.PrivateKey, message []byte, blockSize int) ([]byte, error) {
 	var result []byte
 	hash := sha256.New()
-	blockSize := r.privateKey.Size()
-	for _, messageChunk := range chunk.SliceToChunks(bytes, blockSize) {
-		decryptedBlock, err := rsa.DecryptOAEP(hash, rand.Reader, r.privateKey, messageChunk, nil)
+	for _, messageChunk := range chunk.SliceToChunks(message, blockSize) {
+		decryptedBlock, err := rsa.DecryptOAEP(hash, rand.Reader, key, messageChunk, nil)
 		if err != nil {
-			return nil, logger.WrapError("decrypt message", err)
+			return nil, err
 		}
 		result = append(result, decryptedBlock...)
 	}
 
 	return result, nil
 }
+
+func loadPrivateKey(path string) (*privateKey, error) {
+	privateKeyContent, err := os.ReadFile(path)
+	if err != nil {
+		return nil, logger.WrapError("read private key file", err)
+	}
+
+	privatePem, _ := pem.Decode(privateKeyContent)
+	if privatePem == nil || privatePem.Type != "RSA PRIVATE KEY" {
+		return nil, logger.WrapError("decode PEM block containing private key", crypto.ErrInvalidKey)
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(privatePem.Bytes)
+	if err != nil {
+		return nil, logger.WrapError("parse private key", err)
+	}
+
+	keyID, err := publicKeyID(&key.PublicKey)
+	if err != nil {
+		return nil, logger.WrapError("calculate key id", err)
+	}
+
+	return &privateKey{
+		key: key,
+		id:  keyID,
+	}, nil
+}
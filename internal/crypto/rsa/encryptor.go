@@ -1,20 +1,23 @@
 package rsa
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
+	"io"
 	"os"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
-	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/chunk"
 )
 
 type rsaEncryptor struct {
 	publicKey *rsa.PublicKey
+	keyID     []byte
 }
 
 func NewEncryptor(publicCertPath string) (*rsaEncryptor, error) {
@@ -33,22 +36,63 @@ func NewEncryptor(publicCertPath string) (*rsaEncryptor, error) {
 		return nil, logger.WrapError("parse public key", err)
 	}
 
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, logger.WrapError("parse public key", crypto.ErrInvalidKey)
+	}
+
+	keyID, err := publicKeyID(rsaPublicKey)
+	if err != nil {
+		return nil, logger.WrapError("calculate key id", err)
+	}
+
 	return &rsaEncryptor{
-		publicKey: publicKey.(*rsa.PublicKey),
+		publicKey: rsaPublicKey,
+		keyID:     keyID,
 	}, nil
 }
 
+// Encrypt seals message with a random AES-256-GCM data key and wraps the key with RSA-OAEP into the envelope.
 func (r *rsaEncryptor) Encrypt(bytes []byte) ([]byte, error) {
-	var result []byte
-	hash := sha256.New()
-	blockSize := r.publicKey.Size()
-	for _, messageChunk := range chunk.SliceToChunks(bytes, blockSize) {
-		encryptedBlock, err := rsa.EncryptOAEP(hash, rand.Reader, r.publicKey, messageChunk, nil)
-		if err != nil {
-			return nil, logger.WrapError("encrypt message", err)
-		}
-		result = append(result, encryptedBlock...)
-	}
-
-	return result, nil
+	dataKey := make([]byte, dataKeySize)
+	_, err := io.ReadFull(rand.Reader, dataKey)
+	if err != nil {
+		return nil, logger.WrapError("generate data key", err)
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, r.publicKey, dataKey, nil)
+	if err != nil {
+		return nil, logger.WrapError("wrap data key", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := newEnvelopeHeader(r.keyID, wrappedKey)
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, logger.WrapError("generate nonce", err)
+	}
+
+	result := make([]byte, 0, len(header)+len(nonce)+len(bytes)+aead.Overhead())
+	result = append(result, header...)
+	result = append(result, nonce...)
+	return aead.Seal(result, nonce, bytes, header), nil
+}
+
+func newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, logger.WrapError("create block cipher", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, logger.WrapError("create gcm", err)
+	}
+
+	return aead, nil
 }
@@ -0,0 +1,82 @@
+package rsa
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// Envelope layout, all integers are big endian:
+//
+//	magic (3) | version (1) | key id (8) | wrapped key length (2) | wrapped key | nonce (12) | AES-GCM ciphertext
+//
+// The data key is a random AES-256 key per message wrapped with RSA-OAEP SHA-256.
+// Everything before the nonce is authenticated as additional data.
+const (
+	envelopeVersion = 1
+	keyIDSize       = 8
+	dataKeySize     = 32
+	wrappedLenSize  = 2
+)
+
+var envelopeMagic = []byte("ENV")
+
+type envelope struct {
+	keyID      []byte
+	wrappedKey []byte
+	header     []byte
+	payload    []byte
+}
+
+// publicKeyID identifies the key pair, so the server picks the matching private key during rotation.
+func publicKeyID(publicKey *rsa.PublicKey) ([]byte, error) {
+	content, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, logger.WrapError("marshal public key", err)
+	}
+
+	sum := sha256.Sum256(content)
+	return sum[:keyIDSize], nil
+}
+
+func isEnvelope(message []byte) bool {
+	return len(message) > len(envelopeMagic) && bytes.HasPrefix(message, envelopeMagic)
+}
+
+func newEnvelopeHeader(keyID []byte, wrappedKey []byte) []byte {
+	header := make([]byte, 0, len(envelopeMagic)+1+keyIDSize+wrappedLenSize+len(wrappedKey))
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeVersion)
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrappedKey)))
+	return append(header, wrappedKey...)
+}
+
+func parseEnvelope(message []byte) (*envelope, error) {
+	offset := len(envelopeMagic)
+	if len(message) < offset+1+keyIDSize+wrappedLenSize || message[offset] != envelopeVersion {
+		return nil, logger.WrapError("parse envelope header", crypto.ErrInvalidCiphertext)
+	}
+	offset++
+
+	keyID := message[offset : offset+keyIDSize]
+	offset += keyIDSize
+
+	wrappedLen := int(binary.BigEndian.Uint16(message[offset:]))
+	offset += wrappedLenSize
+	if len(message) < offset+wrappedLen {
+		return nil, logger.WrapError("parse envelope wrapped key", crypto.ErrInvalidCiphertext)
+	}
+
+	return &envelope{
+		keyID:      keyID,
+		wrappedKey: message[offset : offset+wrappedLen],
+		header:     message[:offset+wrappedLen],
+		payload:    message[offset+wrappedLen:],
+	}, nil
+}
@@ -0,0 +1,183 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+)
+
+type testKey struct {
+	key            *rsa.PrivateKey
+	publicKeyPath  string
+	privateKeyPath string
+}
+
+func TestEnvelope(t *testing.T) {
+	dir := t.TempDir()
+	current := createKey(t, dir, "current")
+	previous := createKey(t, dir, "previous")
+
+	tests := []struct {
+		name        string
+		message     string
+		keys        []*testKey
+		expectedErr error
+	}{
+		{
+			name:    "small_message",
+			message: "secret message",
+			keys:    []*testKey{current},
+		},
+		{
+			name:    "large_message",
+			message: strings.Repeat("secret message ", 100_000),
+			keys:    []*testKey{current},
+		},
+		{
+			name:    "empty_message",
+			message: "",
+			keys:    []*testKey{current},
+		},
+		{
+			name:    "key_rotation",
+			message: "secret message",
+			keys:    []*testKey{previous, current},
+		},
+		{
+			name:        "unknown_key",
+			message:     "secret message",
+			keys:        []*testKey{previous},
+			expectedErr: crypto.ErrUnknownKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptor, err := NewEncryptor(current.publicKeyPath)
+			require.NoError(t, err)
+
+			decryptor, err := NewDecryptor(privateKeyPaths(tt.keys)...)
+			require.NoError(t, err)
+
+			encrypted, err := encryptor.Encrypt([]byte(tt.message))
+			require.NoError(t, err)
+			assert.True(t, isEnvelope(encrypted))
+			if tt.message != "" {
+				assert.NotContains(t, string(encrypted), tt.message)
+			}
+
+			decrypted, err := decryptor.Decrypt(encrypted)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.message, string(decrypted))
+
+			encrypted[len(encrypted)-1] ^= 0xFF
+			_, err = decryptor.Decrypt(encrypted)
+			assert.ErrorIs(t, err, crypto.ErrInvalidCiphertext)
+		})
+	}
+}
+
+func TestDecrypt_Legacy(t *testing.T) {
+	dir := t.TempDir()
+	current := createKey(t, dir, "current")
+	previous := createKey(t, dir, "previous")
+
+	tests := []struct {
+		name        string
+		encryptKey  *testKey
+		keys        []*testKey
+		expectedErr bool
+	}{
+		{
+			name:       "single_key",
+			encryptKey: current,
+			keys:       []*testKey{current},
+		},
+		{
+			name:       "key_rotation",
+			encryptKey: previous,
+			keys:       []*testKey{current, previous},
+		},
+		{
+			name:        "unknown_key",
+			encryptKey:  previous,
+			keys:        []*testKey{current},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := []byte(`[{"id":"counter","type":"counter","delta":1}]`)
+			encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &tt.encryptKey.key.PublicKey, message, nil)
+			require.NoError(t, err)
+
+			decryptor, err := NewDecryptor(privateKeyPaths(tt.keys)...)
+			require.NoError(t, err)
+
+			decrypted, err := decryptor.Decrypt(encrypted)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, message, decrypted)
+		})
+	}
+}
+
+func TestNewDecryptor_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalidPath := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("invalid"), 0o600))
+
+	_, err := NewDecryptor()
+	assert.ErrorIs(t, err, crypto.ErrInvalidKey)
+
+	_, err = NewDecryptor(createKey(t, dir, "valid").privateKeyPath, invalidPath)
+	assert.ErrorIs(t, err, crypto.ErrInvalidKey)
+}
+
+func createKey(t *testing.T, dir string, name string) *testKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicContent, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	result := &testKey{
+		key:            key,
+		publicKeyPath:  filepath.Join(dir, name+".pub"),
+		privateKeyPath: filepath.Join(dir, name+".pem"),
+	}
+	require.NoError(t, os.WriteFile(result.publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicContent}), 0o600))
+	require.NoError(t, os.WriteFile(result.privateKeyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600))
+
+	return result
+}
+
+func privateKeyPaths(keys []*testKey) []string {
+	result := make([]string, len(keys))
+	for i, key := range keys {
+		result[i] = key.privateKeyPath
+	}
+
+	return result
+}
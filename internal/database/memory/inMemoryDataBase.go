@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+type recordKey [3]string
+
+type inMemoryDataBase struct {
+	records map[recordKey]*database.DBRecord
+	samples map[recordKey][]*database.DBSample
+	lock    sync.RWMutex
+}
+
+// NewInMemoryDataBase creates database keeping records in process memory, it is used when no database is configured.
+// Records are identified by type, name and labels like in postgres, stored and returned records are copies.
+func NewInMemoryDataBase() *inMemoryDataBase {
+	return &inMemoryDataBase{
+		records: map[recordKey]*database.DBRecord{},
+		samples: map[recordKey][]*database.DBSample{},
+	}
+}
+
+func (d *inMemoryDataBase) UpdateRecords(_ context.Context, records []*database.DBRecord) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, record := range records {
+		d.records[toRecordKey(record)] = copyRecord(record)
+	}
+
+	return nil
+}
+
+func (d *inMemoryDataBase) AccumulateRecords(_ context.Context, records []*database.DBRecord, merge database.MergeFunc) ([]*database.DBRecord, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// changes are applied only if the whole batch succeeded
+	states := map[recordKey]*database.DBRecord{}
+	for _, record := range records {
+		key := toRecordKey(record)
+		current, ok := states[key]
+		if !ok {
+			current = d.records[key]
+		}
+
+		var state *database.DBRecord
+		switch {
+		case current == nil:
+			state = copyRecord(record)
+		case record.Payload.Valid:
+			merged, err := merge(copyRecord(current), copyRecord(record))
+			if err != nil {
+				return nil, logger.WrapError(fmt.Sprintf("merge record '%s%s'", record.Name.String, record.Labels.String), err)
+			}
+
+			state = copyRecord(merged)
+		default:
+			state = copyRecord(current)
+			state.Value.Float64 += record.Value.Float64
+			state.Value.Valid = true
+		}
+
+		states[key] = state
+	}
+
+	for key, state := range states {
+		d.records[key] = state
+	}
+
+	result := make([]*database.DBRecord, len(records))
+	for i, record := range records {
+		result[i] = copyRecord(states[toRecordKey(record)])
+	}
+
+	return result, nil
+}
+
+func (d *inMemoryDataBase) RemoveRecords(_ context.Context, records []*database.DBRecord) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, record := range records {
+		key := toRecordKey(record)
+		delete(d.records, key)
+		delete(d.samples, key)
+	}
+
+	return nil
+}
+
+func (d *inMemoryDataBase) ReadRecord(_ context.Context, metricType string, metricName string, metricLabels string) (*database.DBRecord, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	record, ok := d.records[recordKey{metricType, metricName, metricLabels}]
+	if !ok {
+		return nil, nil
+	}
+
+	return copyRecord(record), nil
+}
+
+func (d *inMemoryDataBase) ReadAll(context.Context) ([]*database.DBRecord, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	keys := make([]recordKey, 0, len(d.records))
+	for key := range d.records {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+
+	result := make([]*database.DBRecord, len(keys))
+	for i, key := range keys {
+		result[i] = copyRecord(d.records[key])
+	}
+
+	return result, nil
+}
+
+func (d *inMemoryDataBase) AddSamples(_ context.Context, samples []*database.DBSample) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.addSamples(samples)
+	return nil
+}
+
+func (d *inMemoryDataBase) ReadSamples(
+	_ context.Context,
+	metricType string,
+	metricName string,
+	metricLabels string,
+	start time.Time,
+	end time.Time,
+) ([]*database.DBSample, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	result := []*database.DBSample{}
+	for _, sample := range d.samples[recordKey{metricType, metricName, metricLabels}] {
+		if sample.Timestamp.Time.Before(start) || sample.Timestamp.Time.After(end) {
+			continue
+		}
+
+		result = append(result, copySample(sample))
+	}
+
+	return result, nil
+}
+
+func (d *inMemoryDataBase) ReadAllSamples(context.Context) ([]*database.DBSample, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	keys := make([]recordKey, 0, len(d.samples))
+	for key := range d.samples {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+
+	result := []*database.DBSample{}
+	for _, key := range keys {
+		for _, sample := range d.samples[key] {
+			result = append(result, copySample(sample))
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Time.Before(result[j].Timestamp.Time) })
+	return result, nil
+}
+
+func (d *inMemoryDataBase) ReplaceSamples(_ context.Context, samples []*database.DBSample) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.samples = map[recordKey][]*database.DBSample{}
+	d.addSamples(samples)
+	return nil
+}
+
+func (d *inMemoryDataBase) RemoveSamples(_ context.Context, before time.Time) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for key, samples := range d.samples {
+		// samples are ordered by time
+		index := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Time.Before(before) })
+		if index == len(samples) {
+			delete(d.samples, key)
+			continue
+		}
+
+		d.samples[key] = samples[index:]
+	}
+
+	return nil
+}
+
+func (d *inMemoryDataBase) Ping(context.Context) error {
+	return nil
+}
+
+func (d *inMemoryDataBase) Close() error {
+	return nil
+}
+
+func (d *inMemoryDataBase) addSamples(samples []*database.DBSample) {
+	changed := map[recordKey]bool{}
+	for _, sample := range samples {
+		key := recordKey{sample.MetricType.String, sample.Name.String, sample.Labels.String}
+		d.samples[key] = append(d.samples[key], copySample(sample))
+		changed[key] = true
+	}
+
+	for key := range changed {
+		typedSamples := d.samples[key]
+		sort.SliceStable(typedSamples, func(i, j int) bool { return typedSamples[i].Timestamp.Time.Before(typedSamples[j].Timestamp.Time) })
+	}
+}
+
+func toRecordKey(record *database.DBRecord) recordKey {
+	return recordKey{record.MetricType.String, record.Name.String, record.Labels.String}
+}
+
+func sortKeys(keys []recordKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		for k := range keys[i] {
+			if keys[i][k] != keys[j][k] {
+				return keys[i][k] < keys[j][k]
+			}
+		}
+
+		return false
+	})
+}
+
+func copyRecord(record *database.DBRecord) *database.DBRecord {
+	result := *record
+	return &result
+}
+
+func copySample(sample *database.DBSample) *database.DBSample {
+	result := *sample
+	return &result
+}
@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+)
+
+func TestInMemoryDataBase_UpdateRecords(t *testing.T) {
+	ctx := context.Background()
+	base := NewInMemoryDataBase()
+
+	counter := createRecord("counter", "counterMetricName", "", 100)
+	gauge := createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 200)
+	require.NoError(t, base.UpdateRecords(ctx, []*database.DBRecord{gauge, counter}))
+
+	updatedGauge := createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 300)
+	require.NoError(t, base.UpdateRecords(ctx, []*database.DBRecord{updatedGauge}))
+
+	// stored records are not affected by the caller
+	updatedGauge.Value.Float64 = 400
+
+	actual, err := base.ReadRecord(ctx, "gauge", "gaugeMetricName", `{cpu="1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 300), actual)
+
+	actual, err = base.ReadRecord(ctx, "gauge", "gaugeMetricName", "")
+	require.NoError(t, err)
+	assert.Nil(t, actual)
+
+	all, err := base.ReadAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBRecord{counter, createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 300)}, all)
+}
+
+func TestInMemoryDataBase_AccumulateRecords(t *testing.T) {
+	ctx := context.Background()
+	base := NewInMemoryDataBase()
+
+	merge := func(current *database.DBRecord, update *database.DBRecord) (*database.DBRecord, error) {
+		current.Payload.String += update.Payload.String
+		return current, nil
+	}
+
+	composite := createRecord("histogram", "histogramMetricName", "", 1)
+	composite.Payload = sql.NullString{Valid: true, String: "a"}
+	result, err := base.AccumulateRecords(ctx, []*database.DBRecord{
+		createRecord("counter", "counterMetricName", "", 10),
+		composite,
+		createRecord("counter", "counterMetricName", "", 5),
+		composite,
+	}, merge)
+	require.NoError(t, err)
+
+	expectedCounter := createRecord("counter", "counterMetricName", "", 15)
+	expectedComposite := createRecord("histogram", "histogramMetricName", "", 1)
+	expectedComposite.Payload = sql.NullString{Valid: true, String: "aa"}
+	assert.Equal(t, []*database.DBRecord{expectedCounter, expectedComposite, expectedCounter, expectedComposite}, result)
+
+	// failed merge leaves the state untouched
+	_, err = base.AccumulateRecords(ctx, []*database.DBRecord{createRecord("counter", "counterMetricName", "", 10), composite},
+		func(*database.DBRecord, *database.DBRecord) (*database.DBRecord, error) {
+			return nil, test.ErrTest
+		})
+	assert.ErrorIs(t, err, test.ErrTest)
+
+	all, err := base.ReadAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBRecord{expectedCounter, expectedComposite}, all)
+}
+
+func TestInMemoryDataBase_ConcurrentAccumulate(t *testing.T) {
+	ctx := context.Background()
+	base := NewInMemoryDataBase()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := base.AccumulateRecords(ctx, []*database.DBRecord{createRecord("counter", "counterMetricName", "", 1)}, nil)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	actual, err := base.ReadRecord(ctx, "counter", "counterMetricName", "")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1000), actual.Value.Float64)
+}
+
+func TestInMemoryDataBase_Samples(t *testing.T) {
+	ctx := context.Background()
+	base := NewInMemoryDataBase()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []*database.DBSample{
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start.Add(2*time.Minute), 3),
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start, 1),
+		createSample("counter", "counterMetricName", "", start.Add(time.Minute), 10),
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start.Add(time.Minute), 2),
+	}
+	require.NoError(t, base.AddSamples(ctx, samples))
+
+	actual, err := base.ReadSamples(ctx, "gauge", "gaugeMetricName", `{cpu="1"}`, start.Add(time.Minute), start.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBSample{samples[3], samples[0]}, actual)
+
+	require.NoError(t, base.RemoveSamples(ctx, start.Add(time.Minute)))
+	actual, err = base.ReadAllSamples(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBSample{samples[2], samples[3], samples[0]}, actual)
+
+	replacement := []*database.DBSample{createSample("counter", "counterMetricName", "", start.Add(time.Hour), 20)}
+	require.NoError(t, base.ReplaceSamples(ctx, replacement))
+	actual, err = base.ReadAllSamples(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, replacement, actual)
+}
+
+func TestInMemoryDataBase_RemoveRecords(t *testing.T) {
+	ctx := context.Background()
+	base := NewInMemoryDataBase()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	counter := createRecord("counter", "counterMetricName", "", 100)
+	gauge := createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 200)
+	require.NoError(t, base.UpdateRecords(ctx, []*database.DBRecord{counter, gauge}))
+	require.NoError(t, base.AddSamples(ctx, []*database.DBSample{
+		createSample("counter", "counterMetricName", "", start, 100),
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start, 200),
+	}))
+
+	require.NoError(t, base.RemoveRecords(ctx, []*database.DBRecord{gauge, createRecord("gauge", "missedMetricName", "", 0)}))
+
+	all, err := base.ReadAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBRecord{counter}, all)
+
+	samples, err := base.ReadAllSamples(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBSample{createSample("counter", "counterMetricName", "", start, 100)}, samples)
+}
+
+func createRecord(metricType string, name string, labels string, value float64) *database.DBRecord {
+	return &database.DBRecord{
+		MetricType: sql.NullString{Valid: true, String: metricType},
+		Name:       sql.NullString{Valid: true, String: name},
+		Labels:     sql.NullString{Valid: true, String: labels},
+		Value:      sql.NullFloat64{Valid: true, Float64: value},
+	}
+}
+
+func createSample(metricType string, name string, labels string, timestamp time.Time, value float64) *database.DBSample {
+	return &database.DBSample{
+		MetricType: sql.NullString{Valid: true, String: metricType},
+		Name:       sql.NullString{Valid: true, String: name},
+		Labels:     sql.NullString{Valid: true, String: labels},
+		Timestamp:  sql.NullTime{Valid: true, Time: timestamp},
+		Value:      sql.NullFloat64{Valid: true, Float64: value},
+	}
+}
@@ -0,0 +1,8 @@
+package postgres
+
+import "errors"
+
+var (
+	ErrInvalidMigration = errors.New("invalid migration")
+	ErrUnknownVersion   = errors.New("unknown schema version")
+)
@@ -0,0 +1,11 @@
+DROP PROCEDURE IF EXISTS
+	AddMetricSample(TEXT, TEXT, TEXT, TIMESTAMPTZ, double precision),
+	UpdateOrCreateMetric(TEXT, TEXT, TEXT, double precision, TEXT),
+	GetOrCreateMetricId(IN TEXT, IN TEXT, IN TEXT, OUT INT),
+	GetOrCreateMetricTypeId(IN TEXT, OUT SMALLINT);
+
+DROP TABLE IF EXISTS metricSample;
+
+DROP TABLE IF EXISTS metric;
+
+DROP TABLE IF EXISTS metricType;
@@ -0,0 +1,82 @@
+-- baseline schema, statements are idempotent to adopt databases created before versioned migrations
+CREATE TABLE IF NOT EXISTS metricType (
+	id SMALLSERIAL PRIMARY KEY,
+	name TEXT
+);
+
+CREATE TABLE IF NOT EXISTS metric (
+	id SERIAL PRIMARY KEY,
+	name TEXT,
+	typeId SMALLSERIAL,
+	value DOUBLE PRECISION
+);
+
+ALTER TABLE metric ADD COLUMN IF NOT EXISTS payload TEXT;
+
+ALTER TABLE metric ADD COLUMN IF NOT EXISTS labels TEXT NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS metric_name_type_idx;
+
+CREATE UNIQUE INDEX IF NOT EXISTS metric_name_type_labels_idx ON metric (name, typeId, labels);
+
+CREATE OR REPLACE PROCEDURE GetOrCreateMetricTypeId(typeName IN TEXT, typeId OUT SMALLINT)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+	typeId := (SELECT id FROM metricType WHERE name = typeName);
+	IF typeId IS null THEN
+		BEGIN
+			INSERT INTO metricType(name) VALUES (typeName);
+			typeId := (SELECT currval(pg_get_serial_sequence('metricType','id')));
+		END;
+	END IF;
+END;$$;
+
+DROP PROCEDURE IF EXISTS
+	UpdateOrCreateMetric(TEXT, TEXT, double precision),
+	UpdateOrCreateMetric(TEXT, TEXT, double precision, TEXT),
+	GetOrCreateMetricId(IN TEXT, IN TEXT, OUT INT);
+
+CREATE OR REPLACE PROCEDURE GetOrCreateMetricId(metricTypeName IN TEXT, metricName IN TEXT, metricLabels IN TEXT, metricId OUT INT)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	metricTypeId smallint;
+BEGIN
+	CALL GetOrCreateMetricTypeId(metricTypeName, metricTypeId);
+	metricId := (SELECT id FROM metric WHERE name = metricName AND typeId = metricTypeId AND labels = metricLabels);
+	IF metricId IS null THEN
+		BEGIN
+			INSERT INTO metric(name, typeId, labels) VALUES (metricName, metricTypeId, metricLabels);
+			metricId := (SELECT currval(pg_get_serial_sequence('metric','id')));
+		END;
+	END IF;
+END;$$;
+
+CREATE OR REPLACE PROCEDURE UpdateOrCreateMetric(metricTypeName IN TEXT, metricName IN TEXT, metricLabels IN TEXT, metricValue IN double precision, metricPayload IN TEXT)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	metricId int;
+BEGIN
+	CALL GetOrCreateMetricId(metricTypeName, metricName, metricLabels, metricId);
+	UPDATE metric SET value = metricValue, payload = metricPayload WHERE id = metricId;
+END;$$;
+
+CREATE TABLE IF NOT EXISTS metricSample (
+	metricId INT NOT NULL REFERENCES metric(id),
+	timestamp TIMESTAMPTZ NOT NULL,
+	value DOUBLE PRECISION
+);
+
+CREATE INDEX IF NOT EXISTS metric_sample_metric_timestamp_idx ON metricSample (metricId, timestamp);
+
+CREATE OR REPLACE PROCEDURE AddMetricSample(metricTypeName IN TEXT, metricName IN TEXT, metricLabels IN TEXT, sampleTimestamp IN TIMESTAMPTZ, sampleValue IN double precision)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	metricId int;
+BEGIN
+	CALL GetOrCreateMetricId(metricTypeName, metricName, metricLabels, metricId);
+	INSERT INTO metricSample(metricId, timestamp, value) VALUES (metricId, sampleTimestamp, sampleValue);
+END;$$;
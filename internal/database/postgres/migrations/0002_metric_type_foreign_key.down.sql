@@ -0,0 +1,5 @@
+ALTER TABLE metric DROP CONSTRAINT IF EXISTS metric_type_fk;
+
+CREATE SEQUENCE IF NOT EXISTS metric_typeid_seq AS SMALLINT OWNED BY metric.typeId;
+
+ALTER TABLE metric ALTER COLUMN typeId SET DEFAULT nextval('metric_typeid_seq');
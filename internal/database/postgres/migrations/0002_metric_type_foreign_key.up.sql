@@ -0,0 +1,8 @@
+-- typeId was created as SMALLSERIAL, replace its own sequence with the reference to metricType
+ALTER TABLE metric ALTER COLUMN typeId DROP DEFAULT;
+
+DROP SEQUENCE IF EXISTS metric_typeid_seq;
+
+ALTER TABLE metric ALTER COLUMN typeId SET NOT NULL;
+
+ALTER TABLE metric ADD CONSTRAINT metric_type_fk FOREIGN KEY (typeId) REFERENCES metricType(id);
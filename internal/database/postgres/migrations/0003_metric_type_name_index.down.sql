@@ -0,0 +1 @@
+DROP INDEX IF EXISTS metric_type_name_idx;
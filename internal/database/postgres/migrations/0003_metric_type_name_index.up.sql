@@ -0,0 +1,2 @@
+-- set-based upserts resolve metric types by name with ON CONFLICT
+CREATE UNIQUE INDEX IF NOT EXISTS metric_type_name_idx ON metricType (name);
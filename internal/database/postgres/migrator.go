@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// migrationsLockID is a key of the advisory lock held while migrations are applied,
+// so concurrently started servers don't race.
+const migrationsLockID = 7261737
+
+var (
+	//go:embed migrations/*.sql
+	migrationFiles embed.FS
+
+	migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+)
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+type migrator struct {
+	conn       *sql.DB
+	migrations []*migration
+}
+
+// NewMigrator creates schema migrator using the embedded migrations.
+func NewMigrator(conn *sql.DB) (*migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, logger.WrapError("load migrations", err)
+	}
+
+	return &migrator{
+		conn:       conn,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations.
+// Schema of the newer version is left as is, so the previous server version is able to start during rollout.
+func (m *migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.version(ctx, conn)
+		if err != nil {
+			return logger.WrapError("get schema version", err)
+		}
+
+		latest := m.migrations[len(m.migrations)-1].version
+		if version > latest {
+			logger.InfoFormat("Schema version %d is newer than the latest known migration %d", version, latest)
+			return nil
+		}
+
+		return m.migrateTo(ctx, conn, version, latest)
+	})
+}
+
+// Down reverts the last applied migration.
+func (m *migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.version(ctx, conn)
+		if err != nil {
+			return logger.WrapError("get schema version", err)
+		}
+
+		if version == 0 {
+			logger.Info("No migrations to revert")
+			return nil
+		}
+
+		return m.migrateTo(ctx, conn, version, m.previousVersion(version))
+	})
+}
+
+// Migrate applies or reverts migrations up to the target version, 0 reverts all of them.
+func (m *migrator) Migrate(ctx context.Context, target int64) error {
+	return m.migrate(ctx, target)
+}
+
+// Version returns the last applied migration version.
+func (m *migrator) Version(ctx context.Context) (int64, error) {
+	var result int64
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		result, err = m.version(ctx, conn)
+		return err
+	})
+
+	return result, err
+}
+
+func (m *migrator) migrate(ctx context.Context, target int64) error {
+	if target != 0 && m.find(target) == nil {
+		return logger.WrapError(fmt.Sprintf("migrate to %d", target), ErrUnknownVersion)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := m.version(ctx, conn)
+		if err != nil {
+			return logger.WrapError("get schema version", err)
+		}
+
+		return m.migrateTo(ctx, conn, version, target)
+	})
+}
+
+func (m *migrator) migrateTo(ctx context.Context, conn *sql.Conn, version int64, target int64) error {
+	if version != 0 && m.find(version) == nil {
+		return logger.WrapError(fmt.Sprintf("find applied migration %d", version), ErrUnknownVersion)
+	}
+
+	for _, current := range m.migrations {
+		if current.version <= version || current.version > target {
+			continue
+		}
+
+		logger.InfoFormat("Apply migration %d %s", current.version, current.name)
+		err := m.apply(ctx, conn, current.up, "INSERT INTO schema_migrations(version, name) VALUES ($1, $2)", current.version, current.name)
+		if err != nil {
+			return logger.WrapError(fmt.Sprintf("apply migration %d %s", current.version, current.name), err)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		current := m.migrations[i]
+		if current.version > version || current.version <= target {
+			continue
+		}
+
+		logger.InfoFormat("Revert migration %d %s", current.version, current.name)
+		err := m.apply(ctx, conn, current.down, "DELETE FROM schema_migrations WHERE version = $1", current.version)
+		if err != nil {
+			return logger.WrapError(fmt.Sprintf("revert migration %d %s", current.version, current.name), err)
+		}
+	}
+
+	return nil
+}
+
+// apply runs migration script and records the result in the same transaction.
+func (m *migrator) apply(ctx context.Context, conn *sql.Conn, script string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: false})
+	if err != nil {
+		return logger.WrapError("begin transaction", err)
+	}
+
+	_, err = tx.ExecContext(ctx, script)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, record, args...)
+	}
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			logger.ErrorFormat("failed to rollback transaction: %v", rollbackError)
+		}
+
+		return logger.WrapError("execute migration", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return logger.WrapError("commit transaction", err)
+	}
+
+	return nil
+}
+
+func (m *migrator) version(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var version sql.NullInt64
+	err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, logger.WrapError("select schema version", err)
+	}
+
+	return version.Int64, nil
+}
+
+// withLock runs work on the single connection holding the migrations advisory lock.
+func (m *migrator) withLock(ctx context.Context, work func(conn *sql.Conn) error) error {
+	conn, err := m.conn.Conn(ctx)
+	if err != nil {
+		return logger.WrapError("get db connection", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationsLockID)
+	if err != nil {
+		return logger.WrapError("acquire migrations lock", err)
+	}
+	defer func() {
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationsLockID)
+		if err != nil {
+			logger.ErrorFormat("failed to release migrations lock: %v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, ""+
+		"CREATE TABLE IF NOT EXISTS schema_migrations ( "+
+		"	version BIGINT PRIMARY KEY, "+
+		"	name TEXT NOT NULL, "+
+		"	appliedAt TIMESTAMPTZ NOT NULL DEFAULT now() "+
+		");")
+	if err != nil {
+		return logger.WrapError("create schema migrations table", err)
+	}
+
+	return work(conn)
+}
+
+func (m *migrator) find(version int64) *migration {
+	for _, current := range m.migrations {
+		if current.version == version {
+			return current
+		}
+	}
+
+	return nil
+}
+
+func (m *migrator) previousVersion(version int64) int64 {
+	var result int64
+	for _, current := range m.migrations {
+		if current.version < version {
+			result = current.version
+		}
+	}
+
+	return result
+}
+
+// loadMigrations reads embedded migrations ordered by version.
+// Every migration must have both up and down scripts.
+func loadMigrations() ([]*migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, logger.WrapError("read migrations directory", err)
+	}
+
+	migrationsByVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse migration file name '%s'", entry.Name()), ErrInvalidMigration)
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, logger.WrapError(fmt.Sprintf("parse migration version '%s'", entry.Name()), ErrInvalidMigration)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("read migration '%s'", entry.Name()), err)
+		}
+
+		current, ok := migrationsByVersion[version]
+		if !ok {
+			current = &migration{version: version, name: match[2]}
+			migrationsByVersion[version] = current
+		}
+
+		if current.name != match[2] {
+			return nil, logger.WrapError(fmt.Sprintf("match migration %d names", version), ErrInvalidMigration)
+		}
+
+		if match[3] == "up" {
+			current.up = string(content)
+		} else {
+			current.down = string(content)
+		}
+	}
+
+	result := make([]*migration, 0, len(migrationsByVersion))
+	for _, current := range migrationsByVersion {
+		if current.up == "" || current.down == "" {
+			return nil, logger.WrapError(fmt.Sprintf("load migration %d scripts", current.version), ErrInvalidMigration)
+		}
+
+		result = append(result, current)
+	}
+
+	if len(result) == 0 {
+		return nil, logger.WrapError("load migrations", ErrInvalidMigration)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	return result, nil
+}
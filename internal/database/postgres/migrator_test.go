@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, current := range migrations {
+		assert.Equal(t, int64(i+1), current.version, "migration versions must be sequential")
+		assert.NotEmpty(t, current.name)
+		assert.NotEmpty(t, current.up)
+		assert.NotEmpty(t, current.down)
+	}
+}
+
+func TestMigrator_PreviousVersion(t *testing.T) {
+	m := &migrator{migrations: []*migration{{version: 1}, {version: 2}, {version: 5}}}
+
+	assert.Equal(t, int64(0), m.previousVersion(1))
+	assert.Equal(t, int64(1), m.previousVersion(2))
+	assert.Equal(t, int64(2), m.previousVersion(5))
+	assert.Nil(t, m.find(3))
+	assert.NotNil(t, m.find(5))
+}
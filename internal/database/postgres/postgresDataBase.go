@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -16,10 +18,95 @@ type PostgresDataaBaseConfig interface {
 	GetConnectionString() string
 }
 
+// Batches are written with set-based statements over unnest arrays,
+// so every batch takes a constant number of round trips regardless of its size.
+const (
+	upsertMetricTypesCommand = "" +
+		"INSERT INTO metricType(name) " +
+		"SELECT DISTINCT t.name FROM unnest($1::TEXT[]) AS t(name) " +
+		"ON CONFLICT (name) DO NOTHING"
+
+	upsertMetricsCommand = "" +
+		"INSERT INTO metric(name, typeId, labels, value, payload) " +
+		"SELECT r.name, mt.id, r.labels, r.value, r.payload " +
+		"FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $4::DOUBLE PRECISION[], $5::TEXT[]) AS r(type, name, labels, value, payload) " +
+		"JOIN metricType mt ON mt.name = r.type " +
+		"ON CONFLICT (name, typeId, labels) DO UPDATE SET value = EXCLUDED.value, payload = EXCLUDED.payload"
+
+	incrementMetricsCommand = "" +
+		"WITH upserted AS (" +
+		"	INSERT INTO metric(name, typeId, labels, value) " +
+		"	SELECT r.name, mt.id, r.labels, r.value " +
+		"	FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $4::DOUBLE PRECISION[]) AS r(type, name, labels, value) " +
+		"	JOIN metricType mt ON mt.name = r.type " +
+		"	ON CONFLICT (name, typeId, labels) DO UPDATE SET value = metric.value + EXCLUDED.value " +
+		"	RETURNING typeId, name, labels, value, payload" +
+		") " +
+		"SELECT mt.name, u.name, u.labels, u.value, u.payload " +
+		"FROM upserted u " +
+		"JOIN metricType mt ON mt.id = u.typeId"
+
+	insertMissingMetricsCommand = "" +
+		"WITH inserted AS (" +
+		"	INSERT INTO metric(name, typeId, labels, value, payload) " +
+		"	SELECT r.name, mt.id, r.labels, r.value, r.payload " +
+		"	FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $4::DOUBLE PRECISION[], $5::TEXT[]) AS r(type, name, labels, value, payload) " +
+		"	JOIN metricType mt ON mt.name = r.type " +
+		"	ON CONFLICT (name, typeId, labels) DO NOTHING " +
+		"	RETURNING typeId, name, labels" +
+		") " +
+		"SELECT mt.name, i.name, i.labels " +
+		"FROM inserted i " +
+		"JOIN metricType mt ON mt.id = i.typeId"
+
+	lockMetricsCommand = "" +
+		"SELECT mt.name, m.name, m.labels, m.value, m.payload " +
+		"FROM metric m " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"JOIN (SELECT DISTINCT * FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[])) AS r(type, name, labels) " +
+		"	ON mt.name = r.type AND m.name = r.name AND m.labels = r.labels " +
+		"FOR UPDATE OF m"
+
+	createSampleMetricsCommand = "" +
+		"INSERT INTO metric(name, typeId, labels) " +
+		"SELECT DISTINCT s.name, mt.id, s.labels " +
+		"FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[]) AS s(type, name, labels) " +
+		"JOIN metricType mt ON mt.name = s.type " +
+		"ON CONFLICT (name, typeId, labels) DO NOTHING"
+
+	insertSamplesCommand = "" +
+		"INSERT INTO metricSample(metricId, timestamp, value) " +
+		"SELECT m.id, s.timestamp, s.value " +
+		"FROM unnest($1::TEXT[], $2::TEXT[], $3::TEXT[], $4::TIMESTAMPTZ[], $5::DOUBLE PRECISION[]) AS s(type, name, labels, timestamp, value) " +
+		"JOIN metricType mt ON mt.name = s.type " +
+		"JOIN metric m ON m.typeId = mt.id AND m.name = s.name AND m.labels = s.labels"
+
+	removeSamplesByMetricsCommand = "" +
+		"DELETE FROM metricSample s " +
+		"USING metric m, metricType mt, unnest($1::TEXT[], $2::TEXT[], $3::TEXT[]) AS r(type, name, labels) " +
+		"WHERE s.metricId = m.id AND m.typeId = mt.id AND mt.name = r.type AND m.name = r.name AND m.labels = r.labels"
+
+	removeMetricsCommand = "" +
+		"DELETE FROM metric m " +
+		"USING metricType mt, unnest($1::TEXT[], $2::TEXT[], $3::TEXT[]) AS r(type, name, labels) " +
+		"WHERE m.typeId = mt.id AND mt.name = r.type AND m.name = r.name AND m.labels = r.labels"
+)
+
 type postgresDataBase struct {
-	conn *sql.DB
+	conn                 *sql.DB
+	upsertMetricTypes    *sql.Stmt
+	upsertMetrics        *sql.Stmt
+	incrementMetrics     *sql.Stmt
+	insertMissingMetrics *sql.Stmt
+	lockMetrics          *sql.Stmt
+	createSampleMetric   *sql.Stmt
+	insertSamples        *sql.Stmt
+	removeSamples        *sql.Stmt
+	removeMetrics        *sql.Stmt
 }
 
+type recordKey [3]string
+
 // NewPostgresDataBase create new instance of postgres db connector.
 func NewPostgresDataBase(ctx context.Context, conf PostgresDataaBaseConfig) (*postgresDataBase, error) {
 	conn, err := initDB(ctx, conf.GetConnectionString())
@@ -27,40 +114,118 @@ func NewPostgresDataBase(ctx context.Context, conf PostgresDataaBaseConfig) (*po
 		return nil, logger.WrapError("init postgresql database", err)
 	}
 
-	return &postgresDataBase{conn: conn}, nil
+	result := &postgresDataBase{conn: conn}
+	statements := []struct {
+		target  **sql.Stmt
+		command string
+	}{
+		{target: &result.upsertMetricTypes, command: upsertMetricTypesCommand},
+		{target: &result.upsertMetrics, command: upsertMetricsCommand},
+		{target: &result.incrementMetrics, command: incrementMetricsCommand},
+		{target: &result.insertMissingMetrics, command: insertMissingMetricsCommand},
+		{target: &result.lockMetrics, command: lockMetricsCommand},
+		{target: &result.createSampleMetric, command: createSampleMetricsCommand},
+		{target: &result.insertSamples, command: insertSamplesCommand},
+		{target: &result.removeSamples, command: removeSamplesByMetricsCommand},
+		{target: &result.removeMetrics, command: removeMetricsCommand},
+	}
+	for _, statement := range statements {
+		*statement.target, err = conn.PrepareContext(ctx, statement.command)
+		if err != nil {
+			_ = result.Close()
+			return nil, logger.WrapError("prepare statement", err)
+		}
+	}
+
+	return result, nil
 }
 
 func (p *postgresDataBase) UpdateRecords(ctx context.Context, records []*database.DBRecord) error {
+	// the same row can't be updated twice by one upsert, the last record wins
+	records = uniqueRecords(records)
+
 	return p.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return p.updateRecords(ctx, tx, records)
+	})
+}
+
+// AccumulateRecords increments plain values with a single upsert,
+// composite records are merged under the row locks, missed rows are inserted first to be locked as well.
+func (p *postgresDataBase) AccumulateRecords(ctx context.Context, records []*database.DBRecord, merge database.MergeFunc) ([]*database.DBRecord, error) {
+	return p.callInTransactionResult(ctx, func(ctx context.Context, tx *sql.Tx) ([]*database.DBRecord, error) {
+		increments := []*database.DBRecord{}
+		incrementIndexes := map[recordKey]int{}
+		composites := []*database.DBRecord{}
 		for _, record := range records {
-			// statements for stored procedure are stored in a db
-			_, err := tx.ExecContext(ctx, "CALL UpdateOrCreateMetric(@metricType, @metricName, @metricValue)", pgx.NamedArgs{
-				"metricType":  record.MetricType.String,
-				"metricName":  record.Name.String,
-				"metricValue": record.Value.Float64,
-			})
-			if err != nil {
-				return logger.WrapError("update records in postgresql database", err)
+			if record.Payload.Valid {
+				composites = append(composites, record)
+				continue
+			}
+
+			// the same row can't be updated twice by one upsert, so deltas are summed up in advance
+			key := toRecordKey(record)
+			if i, ok := incrementIndexes[key]; ok {
+				increments[i].Value.Float64 += record.Value.Float64
+				continue
 			}
+
+			increment := *record
+			incrementIndexes[key] = len(increments)
+			increments = append(increments, &increment)
+		}
+
+		states := map[recordKey]*database.DBRecord{}
+		err := p.incrementRecords(ctx, tx, increments, states)
+		if err != nil {
+			return nil, logger.WrapError("increment records in postgresql database", err)
+		}
+
+		err = p.mergeRecords(ctx, tx, composites, merge, states)
+		if err != nil {
+			return nil, logger.WrapError("merge records in postgresql database", err)
+		}
+
+		result := make([]*database.DBRecord, len(records))
+		for i, record := range records {
+			result[i] = states[toRecordKey(record)]
+		}
+
+		return result, nil
+	})
+}
+
+func (p *postgresDataBase) RemoveRecords(ctx context.Context, records []*database.DBRecord) error {
+	types, names, labels, _, _ := recordColumns(records)
+	return p.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.StmtContext(ctx, p.removeSamples).ExecContext(ctx, types, names, labels)
+		if err != nil {
+			return logger.WrapError("remove samples from postgresql database", err)
+		}
+
+		_, err = tx.StmtContext(ctx, p.removeMetrics).ExecContext(ctx, types, names, labels)
+		if err != nil {
+			return logger.WrapError("remove metrics from postgresql database", err)
 		}
 
 		return nil
 	})
 }
 
-func (p *postgresDataBase) ReadRecord(ctx context.Context, metricType string, metricName string) (*database.DBRecord, error) {
+func (p *postgresDataBase) ReadRecord(ctx context.Context, metricType string, metricName string, metricLabels string) (*database.DBRecord, error) {
 	result, err := p.callInTransactionResult(ctx, func(ctx context.Context, tx *sql.Tx) ([]*database.DBRecord, error) {
 		const command = "" +
-			"SELECT mt.name, m.name, m.value " +
+			"SELECT mt.name, m.name, m.labels, m.value, m.payload " +
 			"FROM metric m " +
 			"JOIN metricType mt ON m.typeId = mt.id " +
 			"WHERE " +
 			"	m.name = @metricName " +
+			"	and m.labels = @metricLabels " +
 			"	and mt.name = @metricType"
 
 		return p.readRecords(ctx, tx, command, pgx.NamedArgs{
-			"metricType": metricType,
-			"metricName": metricName,
+			"metricType":   metricType,
+			"metricName":   metricName,
+			"metricLabels": metricLabels,
 		})
 	})
 	if err != nil {
@@ -73,7 +238,7 @@ func (p *postgresDataBase) ReadRecord(ctx context.Context, metricType string, me
 	}
 
 	if count > 1 {
-		logger.ErrorFormat("More than one metric in logical primary key: %v, %v", metricType, metricName)
+		logger.ErrorFormat("More than one metric in logical primary key: %v, %v, %v", metricType, metricName, metricLabels)
 	}
 
 	return result[0], nil
@@ -82,7 +247,7 @@ func (p *postgresDataBase) ReadRecord(ctx context.Context, metricType string, me
 func (p *postgresDataBase) ReadAll(ctx context.Context) ([]*database.DBRecord, error) {
 	return p.callInTransactionResult(ctx, func(ctx context.Context, tx *sql.Tx) ([]*database.DBRecord, error) {
 		const command = "" +
-			"SELECT mt.name, m.name, m.value " +
+			"SELECT mt.name, m.name, m.labels, m.value, m.payload " +
 			"FROM metric m " +
 			"JOIN metricType mt on m.typeId = mt.id"
 
@@ -90,11 +255,107 @@ func (p *postgresDataBase) ReadAll(ctx context.Context) ([]*database.DBRecord, e
 	})
 }
 
+func (p *postgresDataBase) AddSamples(ctx context.Context, samples []*database.DBSample) error {
+	return p.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return p.addSamples(ctx, tx, samples)
+	})
+}
+
+func (p *postgresDataBase) ReadSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	metricLabels string,
+	start time.Time,
+	end time.Time,
+) ([]*database.DBSample, error) {
+	const command = "" +
+		"SELECT mt.name, m.name, m.labels, s.timestamp, s.value " +
+		"FROM metricSample s " +
+		"JOIN metric m ON s.metricId = m.id " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"WHERE " +
+		"	m.name = @metricName " +
+		"	and m.labels = @metricLabels " +
+		"	and mt.name = @metricType " +
+		"	and s.timestamp >= @start " +
+		"	and s.timestamp <= @end " +
+		"ORDER BY s.timestamp"
+
+	result, err := p.readSamples(ctx, command, pgx.NamedArgs{
+		"metricType":   metricType,
+		"metricName":   metricName,
+		"metricLabels": metricLabels,
+		"start":        start,
+		"end":          end,
+	})
+	if err != nil {
+		return nil, logger.WrapError("read samples from postgresql database", err)
+	}
+
+	return result, nil
+}
+
+func (p *postgresDataBase) ReadAllSamples(ctx context.Context) ([]*database.DBSample, error) {
+	const command = "" +
+		"SELECT mt.name, m.name, m.labels, s.timestamp, s.value " +
+		"FROM metricSample s " +
+		"JOIN metric m ON s.metricId = m.id " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"ORDER BY s.timestamp"
+
+	result, err := p.readSamples(ctx, command)
+	if err != nil {
+		return nil, logger.WrapError("read samples from postgresql database", err)
+	}
+
+	return result, nil
+}
+
+func (p *postgresDataBase) ReplaceSamples(ctx context.Context, samples []*database.DBSample) error {
+	return p.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM metricSample")
+		if err != nil {
+			return logger.WrapError("delete samples from postgresql database", err)
+		}
+
+		return p.addSamples(ctx, tx, samples)
+	})
+}
+
+func (p *postgresDataBase) RemoveSamples(ctx context.Context, before time.Time) error {
+	_, err := p.conn.ExecContext(ctx, "DELETE FROM metricSample WHERE timestamp < @before", pgx.NamedArgs{
+		"before": before,
+	})
+	if err != nil {
+		return logger.WrapError("remove samples from postgresql database", err)
+	}
+
+	return nil
+}
+
 func (p *postgresDataBase) Ping(ctx context.Context) error {
 	return p.conn.PingContext(ctx)
 }
 
 func (p *postgresDataBase) Close() error {
+	statements := []*sql.Stmt{
+		p.upsertMetricTypes,
+		p.upsertMetrics,
+		p.incrementMetrics,
+		p.insertMissingMetrics,
+		p.lockMetrics,
+		p.createSampleMetric,
+		p.insertSamples,
+		p.removeSamples,
+		p.removeMetrics,
+	}
+	for _, statement := range statements {
+		if statement != nil {
+			_ = statement.Close()
+		}
+	}
+
 	return p.conn.Close()
 }
 
@@ -135,12 +396,17 @@ func (p *postgresDataBase) readRecords(ctx context.Context, tx *sql.Tx, command
 	if err != nil {
 		return nil, logger.WrapError("call query", err)
 	}
+
+	return scanRecords(rows)
+}
+
+func scanRecords(rows *sql.Rows) ([]*database.DBRecord, error) {
 	defer rows.Close()
 
 	result := []*database.DBRecord{}
 	for rows.Next() {
 		var record database.DBRecord
-		err = rows.Scan(&record.MetricType, &record.Name, &record.Value)
+		err := rows.Scan(&record.MetricType, &record.Name, &record.Labels, &record.Value, &record.Payload)
 		if err != nil {
 			return nil, logger.WrapError("scan rows", err)
 		}
@@ -148,6 +414,259 @@ func (p *postgresDataBase) readRecords(ctx context.Context, tx *sql.Tx, command
 		result = append(result, &record)
 	}
 
+	err := rows.Err()
+	if err != nil {
+		return nil, logger.WrapError("get rows", err)
+	}
+
+	return result, nil
+}
+
+func (p *postgresDataBase) updateRecords(ctx context.Context, tx *sql.Tx, records []*database.DBRecord) error {
+	types, names, labels, values, payloads := recordColumns(records)
+	_, err := tx.StmtContext(ctx, p.upsertMetricTypes).ExecContext(ctx, types)
+	if err != nil {
+		return logger.WrapError("upsert metric types in postgresql database", err)
+	}
+
+	_, err = tx.StmtContext(ctx, p.upsertMetrics).ExecContext(ctx, types, names, labels, values, payloads)
+	if err != nil {
+		return logger.WrapError("update records in postgresql database", err)
+	}
+
+	return nil
+}
+
+func (p *postgresDataBase) incrementRecords(ctx context.Context, tx *sql.Tx, records []*database.DBRecord, states map[recordKey]*database.DBRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	types, names, labels, values, _ := recordColumns(records)
+	_, err := tx.StmtContext(ctx, p.upsertMetricTypes).ExecContext(ctx, types)
+	if err != nil {
+		return logger.WrapError("upsert metric types", err)
+	}
+
+	rows, err := tx.StmtContext(ctx, p.incrementMetrics).QueryContext(ctx, types, names, labels, values)
+	if err != nil {
+		return logger.WrapError("increment metrics", err)
+	}
+
+	incremented, err := scanRecords(rows)
+	if err != nil {
+		return logger.WrapError("read incremented metrics", err)
+	}
+
+	for _, record := range incremented {
+		states[toRecordKey(record)] = record
+	}
+
+	return nil
+}
+
+func (p *postgresDataBase) mergeRecords(
+	ctx context.Context,
+	tx *sql.Tx,
+	records []*database.DBRecord,
+	merge database.MergeFunc,
+	states map[recordKey]*database.DBRecord,
+) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	// only the first record of every series could be inserted as is, the rest are merged into it
+	firstRecords := []*database.DBRecord{}
+	firstKeys := map[recordKey]bool{}
+	for _, record := range records {
+		key := toRecordKey(record)
+		if !firstKeys[key] {
+			firstKeys[key] = true
+			firstRecords = append(firstRecords, record)
+		}
+	}
+
+	types, names, labels, values, payloads := recordColumns(firstRecords)
+	_, err := tx.StmtContext(ctx, p.upsertMetricTypes).ExecContext(ctx, types)
+	if err != nil {
+		return logger.WrapError("upsert metric types", err)
+	}
+
+	inserted, err := p.insertMissing(ctx, tx, types, names, labels, values, payloads)
+	if err != nil {
+		return logger.WrapError("insert missing metrics", err)
+	}
+
+	rows, err := tx.StmtContext(ctx, p.lockMetrics).QueryContext(ctx, types, names, labels)
+	if err != nil {
+		return logger.WrapError("lock metrics", err)
+	}
+
+	locked, err := scanRecords(rows)
+	if err != nil {
+		return logger.WrapError("read locked metrics", err)
+	}
+
+	for _, record := range locked {
+		states[toRecordKey(record)] = record
+	}
+
+	changed := []recordKey{}
+	changedKeys := map[recordKey]bool{}
+	for _, record := range records {
+		key := toRecordKey(record)
+		if inserted[key] {
+			// the record itself is stored already
+			delete(inserted, key)
+			continue
+		}
+
+		state, ok := states[key]
+		if !ok {
+			return logger.WrapError(fmt.Sprintf("lock metric '%s%s'", record.Name.String, record.Labels.String), sql.ErrNoRows)
+		}
+
+		states[key], err = merge(state, record)
+		if err != nil {
+			return logger.WrapError(fmt.Sprintf("merge metric '%s%s'", record.Name.String, record.Labels.String), err)
+		}
+
+		if !changedKeys[key] {
+			changedKeys[key] = true
+			changed = append(changed, key)
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	changedRecords := make([]*database.DBRecord, len(changed))
+	for i, key := range changed {
+		changedRecords[i] = states[key]
+	}
+
+	return p.updateRecords(ctx, tx, changedRecords)
+}
+
+// insertMissing inserts records of absent series and returns their keys.
+func (p *postgresDataBase) insertMissing(ctx context.Context, tx *sql.Tx, columns ...any) (map[recordKey]bool, error) {
+	rows, err := tx.StmtContext(ctx, p.insertMissingMetrics).QueryContext(ctx, columns...)
+	if err != nil {
+		return nil, logger.WrapError("call query", err)
+	}
+	defer rows.Close()
+
+	result := map[recordKey]bool{}
+	for rows.Next() {
+		var key recordKey
+		err = rows.Scan(&key[0], &key[1], &key[2])
+		if err != nil {
+			return nil, logger.WrapError("scan rows", err)
+		}
+
+		result[key] = true
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, logger.WrapError("get rows", err)
+	}
+
+	return result, nil
+}
+
+func (p *postgresDataBase) addSamples(ctx context.Context, tx *sql.Tx, samples []*database.DBSample) error {
+	types := make([]string, len(samples))
+	names := make([]string, len(samples))
+	labels := make([]string, len(samples))
+	timestamps := make([]time.Time, len(samples))
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		types[i] = sample.MetricType.String
+		names[i] = sample.Name.String
+		labels[i] = sample.Labels.String
+		timestamps[i] = sample.Timestamp.Time
+		values[i] = sample.Value.Float64
+	}
+
+	_, err := tx.StmtContext(ctx, p.upsertMetricTypes).ExecContext(ctx, types)
+	if err != nil {
+		return logger.WrapError("upsert metric types in postgresql database", err)
+	}
+
+	_, err = tx.StmtContext(ctx, p.createSampleMetric).ExecContext(ctx, types, names, labels)
+	if err != nil {
+		return logger.WrapError("create sample metrics in postgresql database", err)
+	}
+
+	_, err = tx.StmtContext(ctx, p.insertSamples).ExecContext(ctx, types, names, labels, timestamps, values)
+	if err != nil {
+		return logger.WrapError("add samples to postgresql database", err)
+	}
+
+	return nil
+}
+
+func recordColumns(records []*database.DBRecord) ([]string, []string, []string, []float64, []*string) {
+	types := make([]string, len(records))
+	names := make([]string, len(records))
+	labels := make([]string, len(records))
+	values := make([]float64, len(records))
+	payloads := make([]*string, len(records))
+	for i, record := range records {
+		types[i] = record.MetricType.String
+		names[i] = record.Name.String
+		labels[i] = record.Labels.String
+		values[i] = record.Value.Float64
+		if record.Payload.Valid {
+			payloads[i] = &record.Payload.String
+		}
+	}
+
+	return types, names, labels, values, payloads
+}
+
+func toRecordKey(record *database.DBRecord) recordKey {
+	return recordKey{record.MetricType.String, record.Name.String, record.Labels.String}
+}
+
+func uniqueRecords(records []*database.DBRecord) []*database.DBRecord {
+	indexes := make(map[recordKey]int, len(records))
+	result := make([]*database.DBRecord, 0, len(records))
+	for _, record := range records {
+		key := toRecordKey(record)
+		if i, ok := indexes[key]; ok {
+			result[i] = record
+			continue
+		}
+
+		indexes[key] = len(result)
+		result = append(result, record)
+	}
+
+	return result
+}
+
+func (p *postgresDataBase) readSamples(ctx context.Context, command string, args ...any) ([]*database.DBSample, error) {
+	rows, err := p.conn.QueryContext(ctx, command, args...)
+	if err != nil {
+		return nil, logger.WrapError("call query", err)
+	}
+	defer rows.Close()
+
+	result := []*database.DBSample{}
+	for rows.Next() {
+		var sample database.DBSample
+		err = rows.Scan(&sample.MetricType, &sample.Name, &sample.Labels, &sample.Timestamp, &sample.Value)
+		if err != nil {
+			return nil, logger.WrapError("scan rows", err)
+		}
+
+		result = append(result, &sample)
+	}
+
 	err = rows.Err()
 	if err != nil {
 		return nil, logger.WrapError("get rows", err)
@@ -0,0 +1,5 @@
+package sqlite
+
+import "errors"
+
+var ErrInvalidConnectionString = errors.New("invalid sqlite connection string, expected sqlite://<path>")
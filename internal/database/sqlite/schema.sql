@@ -0,0 +1,25 @@
+-- schema mirrors the postgres one, statements are idempotent and applied on every start
+CREATE TABLE IF NOT EXISTS metricType (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS metric (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	typeId INTEGER NOT NULL REFERENCES metricType(id),
+	labels TEXT NOT NULL DEFAULT '',
+	value REAL,
+	payload TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS metric_name_type_labels_idx ON metric (name, typeId, labels);
+
+-- timestamp is stored as unix time in nanoseconds
+CREATE TABLE IF NOT EXISTS metricSample (
+	metricId INTEGER NOT NULL REFERENCES metric(id),
+	timestamp INTEGER NOT NULL,
+	value REAL
+);
+
+CREATE INDEX IF NOT EXISTS metric_sample_metric_timestamp_idx ON metricSample (metricId, timestamp);
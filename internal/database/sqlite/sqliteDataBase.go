@@ -0,0 +1,473 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// Scheme is a connection string prefix selecting sqlite database.
+const Scheme = "sqlite://"
+
+const (
+	upsertMetricTypeCommand = "INSERT INTO metricType(name) VALUES (?) ON CONFLICT (name) DO NOTHING"
+
+	upsertMetricCommand = "" +
+		"INSERT INTO metric(name, typeId, labels, value, payload) " +
+		"SELECT ?, id, ?, ?, ? FROM metricType WHERE name = ? " +
+		"ON CONFLICT (name, typeId, labels) DO UPDATE SET value = excluded.value, payload = excluded.payload"
+
+	incrementMetricCommand = "" +
+		"INSERT INTO metric(name, typeId, labels, value) " +
+		"SELECT ?, id, ?, ? FROM metricType WHERE name = ? " +
+		"ON CONFLICT (name, typeId, labels) DO UPDATE SET value = metric.value + excluded.value"
+
+	readRecordCommand = "" +
+		"SELECT mt.name, m.name, m.labels, m.value, m.payload " +
+		"FROM metric m " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"WHERE m.name = ? AND m.labels = ? AND mt.name = ?"
+
+	createSampleMetricCommand = "" +
+		"INSERT INTO metric(name, typeId, labels) " +
+		"SELECT ?, id, ? FROM metricType WHERE name = ? " +
+		"ON CONFLICT (name, typeId, labels) DO NOTHING"
+
+	insertSampleCommand = "" +
+		"INSERT INTO metricSample(metricId, timestamp, value) " +
+		"SELECT m.id, ?, ? " +
+		"FROM metric m " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"WHERE m.name = ? AND m.labels = ? AND mt.name = ?"
+
+	removeMetricSamplesCommand = "" +
+		"DELETE FROM metricSample WHERE metricId IN (" +
+		"	SELECT m.id FROM metric m JOIN metricType mt ON m.typeId = mt.id " +
+		"	WHERE m.name = ? AND m.labels = ? AND mt.name = ?" +
+		")"
+
+	removeMetricCommand = "" +
+		"DELETE FROM metric WHERE id IN (" +
+		"	SELECT m.id FROM metric m JOIN metricType mt ON m.typeId = mt.id " +
+		"	WHERE m.name = ? AND m.labels = ? AND mt.name = ?" +
+		")"
+)
+
+//go:embed schema.sql
+var schema string
+
+// SQLiteDataBaseConfig contains sqlite database settings.
+type SQLiteDataBaseConfig interface {
+	GetConnectionString() string
+}
+
+type sqliteDataBase struct {
+	conn               *sql.DB
+	upsertMetricType   *sql.Stmt
+	upsertMetric       *sql.Stmt
+	incrementMetric    *sql.Stmt
+	readRecord         *sql.Stmt
+	createSampleMetric *sql.Stmt
+	insertSample       *sql.Stmt
+	removeSamples      *sql.Stmt
+	removeMetric       *sql.Stmt
+}
+
+// NewSQLiteDataBase opens single file database by sqlite://<path> connection string, the file is created if missed.
+func NewSQLiteDataBase(ctx context.Context, conf SQLiteDataBaseConfig) (*sqliteDataBase, error) {
+	connectionString := conf.GetConnectionString()
+	path := strings.TrimPrefix(connectionString, Scheme)
+	if !strings.HasPrefix(connectionString, Scheme) || path == "" {
+		return nil, logger.WrapError("parse connection string", ErrInvalidConnectionString)
+	}
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, logger.WrapError("open db connection", err)
+	}
+
+	// sqlite allows a single writer, one connection serializes transactions instead of failing them with busy errors
+	conn.SetMaxOpenConns(1)
+
+	result := &sqliteDataBase{conn: conn}
+	_, err = conn.ExecContext(ctx, schema)
+	if err != nil {
+		_ = result.Close()
+		return nil, logger.WrapError("init sqlite database schema", err)
+	}
+
+	statements := []struct {
+		target  **sql.Stmt
+		command string
+	}{
+		{target: &result.upsertMetricType, command: upsertMetricTypeCommand},
+		{target: &result.upsertMetric, command: upsertMetricCommand},
+		{target: &result.incrementMetric, command: incrementMetricCommand},
+		{target: &result.readRecord, command: readRecordCommand},
+		{target: &result.createSampleMetric, command: createSampleMetricCommand},
+		{target: &result.insertSample, command: insertSampleCommand},
+		{target: &result.removeSamples, command: removeMetricSamplesCommand},
+		{target: &result.removeMetric, command: removeMetricCommand},
+	}
+	for _, statement := range statements {
+		*statement.target, err = conn.PrepareContext(ctx, statement.command)
+		if err != nil {
+			_ = result.Close()
+			return nil, logger.WrapError("prepare statement", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *sqliteDataBase) UpdateRecords(ctx context.Context, records []*database.DBRecord) error {
+	return s.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, record := range records {
+			err := s.updateRecord(ctx, tx, record)
+			if err != nil {
+				return logger.WrapError("update records in sqlite database", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *sqliteDataBase) AccumulateRecords(ctx context.Context, records []*database.DBRecord, merge database.MergeFunc) ([]*database.DBRecord, error) {
+	var result []*database.DBRecord
+	err := s.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		states := map[[3]string]*database.DBRecord{}
+		for _, record := range records {
+			state, err := s.accumulateRecord(ctx, tx, record, merge)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("accumulate record '%s%s'", record.Name.String, record.Labels.String), err)
+			}
+
+			states[recordKey(record)] = state
+		}
+
+		result = make([]*database.DBRecord, len(records))
+		for i, record := range records {
+			result[i] = states[recordKey(record)]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, logger.WrapError("accumulate records in sqlite database", err)
+	}
+
+	return result, nil
+}
+
+func (s *sqliteDataBase) RemoveRecords(ctx context.Context, records []*database.DBRecord) error {
+	return s.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, record := range records {
+			args := []any{record.Name.String, record.Labels.String, record.MetricType.String}
+			_, err := tx.StmtContext(ctx, s.removeSamples).ExecContext(ctx, args...)
+			if err != nil {
+				return logger.WrapError("remove samples from sqlite database", err)
+			}
+
+			_, err = tx.StmtContext(ctx, s.removeMetric).ExecContext(ctx, args...)
+			if err != nil {
+				return logger.WrapError("remove metric from sqlite database", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *sqliteDataBase) ReadRecord(ctx context.Context, metricType string, metricName string, metricLabels string) (*database.DBRecord, error) {
+	var record database.DBRecord
+	err := s.readRecord.QueryRowContext(ctx, metricName, metricLabels, metricType).
+		Scan(&record.MetricType, &record.Name, &record.Labels, &record.Value, &record.Payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, logger.WrapError("read record from sqlite database", err)
+	}
+
+	return &record, nil
+}
+
+func (s *sqliteDataBase) ReadAll(ctx context.Context) ([]*database.DBRecord, error) {
+	const command = "" +
+		"SELECT mt.name, m.name, m.labels, m.value, m.payload " +
+		"FROM metric m " +
+		"JOIN metricType mt ON m.typeId = mt.id"
+
+	rows, err := s.conn.QueryContext(ctx, command)
+	if err != nil {
+		return nil, logger.WrapError("read records from sqlite database", err)
+	}
+	defer rows.Close()
+
+	result := []*database.DBRecord{}
+	for rows.Next() {
+		var record database.DBRecord
+		err = rows.Scan(&record.MetricType, &record.Name, &record.Labels, &record.Value, &record.Payload)
+		if err != nil {
+			return nil, logger.WrapError("scan rows", err)
+		}
+
+		result = append(result, &record)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, logger.WrapError("get rows", err)
+	}
+
+	return result, nil
+}
+
+func (s *sqliteDataBase) AddSamples(ctx context.Context, samples []*database.DBSample) error {
+	return s.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return s.addSamples(ctx, tx, samples)
+	})
+}
+
+func (s *sqliteDataBase) ReadSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	metricLabels string,
+	start time.Time,
+	end time.Time,
+) ([]*database.DBSample, error) {
+	const command = "" +
+		"SELECT mt.name, m.name, m.labels, s.timestamp, s.value " +
+		"FROM metricSample s " +
+		"JOIN metric m ON s.metricId = m.id " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"WHERE " +
+		"	m.name = ? " +
+		"	AND m.labels = ? " +
+		"	AND mt.name = ? " +
+		"	AND s.timestamp >= ? " +
+		"	AND s.timestamp <= ? " +
+		"ORDER BY s.timestamp"
+
+	result, err := s.readSamples(ctx, command, metricName, metricLabels, metricType, start.UnixNano(), end.UnixNano())
+	if err != nil {
+		return nil, logger.WrapError("read samples from sqlite database", err)
+	}
+
+	return result, nil
+}
+
+func (s *sqliteDataBase) ReadAllSamples(ctx context.Context) ([]*database.DBSample, error) {
+	const command = "" +
+		"SELECT mt.name, m.name, m.labels, s.timestamp, s.value " +
+		"FROM metricSample s " +
+		"JOIN metric m ON s.metricId = m.id " +
+		"JOIN metricType mt ON m.typeId = mt.id " +
+		"ORDER BY s.timestamp"
+
+	result, err := s.readSamples(ctx, command)
+	if err != nil {
+		return nil, logger.WrapError("read samples from sqlite database", err)
+	}
+
+	return result, nil
+}
+
+func (s *sqliteDataBase) ReplaceSamples(ctx context.Context, samples []*database.DBSample) error {
+	return s.callInTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM metricSample")
+		if err != nil {
+			return logger.WrapError("delete samples from sqlite database", err)
+		}
+
+		return s.addSamples(ctx, tx, samples)
+	})
+}
+
+func (s *sqliteDataBase) RemoveSamples(ctx context.Context, before time.Time) error {
+	_, err := s.conn.ExecContext(ctx, "DELETE FROM metricSample WHERE timestamp < ?", before.UnixNano())
+	if err != nil {
+		return logger.WrapError("remove samples from sqlite database", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteDataBase) Ping(ctx context.Context) error {
+	return s.conn.PingContext(ctx)
+}
+
+func (s *sqliteDataBase) Close() error {
+	statements := []*sql.Stmt{
+		s.upsertMetricType,
+		s.upsertMetric,
+		s.incrementMetric,
+		s.readRecord,
+		s.createSampleMetric,
+		s.insertSample,
+		s.removeSamples,
+		s.removeMetric,
+	}
+	for _, statement := range statements {
+		if statement != nil {
+			_ = statement.Close()
+		}
+	}
+
+	return s.conn.Close()
+}
+
+func (s *sqliteDataBase) callInTransaction(ctx context.Context, action func(context.Context, *sql.Tx) error) error {
+	tx, err := s.conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: false})
+	if err != nil {
+		return logger.WrapError("begin transaction in sqlite database", err)
+	}
+
+	err = action(ctx, tx)
+	if err != nil {
+		rollbackError := tx.Rollback()
+		if rollbackError != nil {
+			logger.ErrorFormat("failed to rollback transaction: %v", rollbackError)
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return logger.WrapError("commit transaction", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteDataBase) updateRecord(ctx context.Context, tx *sql.Tx, record *database.DBRecord) error {
+	_, err := tx.StmtContext(ctx, s.upsertMetricType).ExecContext(ctx, record.MetricType.String)
+	if err != nil {
+		return logger.WrapError("upsert metric type", err)
+	}
+
+	_, err = tx.StmtContext(ctx, s.upsertMetric).ExecContext(ctx,
+		record.Name.String, record.Labels.String, record.Value, record.Payload, record.MetricType.String)
+	if err != nil {
+		return logger.WrapError("upsert metric", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteDataBase) accumulateRecord(ctx context.Context, tx *sql.Tx, record *database.DBRecord, merge database.MergeFunc) (*database.DBRecord, error) {
+	if !record.Payload.Valid {
+		_, err := tx.StmtContext(ctx, s.upsertMetricType).ExecContext(ctx, record.MetricType.String)
+		if err != nil {
+			return nil, logger.WrapError("upsert metric type", err)
+		}
+
+		_, err = tx.StmtContext(ctx, s.incrementMetric).ExecContext(ctx,
+			record.Name.String, record.Labels.String, record.Value, record.MetricType.String)
+		if err != nil {
+			return nil, logger.WrapError("increment metric", err)
+		}
+
+		return s.readTxRecord(ctx, tx, record)
+	}
+
+	// the transaction holds the write lock, so the stored state can't be changed in between
+	current, err := s.readTxRecord(ctx, tx, record)
+	if err != nil {
+		return nil, logger.WrapError("read current record", err)
+	}
+
+	state := record
+	if current != nil {
+		state, err = merge(current, record)
+		if err != nil {
+			return nil, logger.WrapError("merge record", err)
+		}
+	}
+
+	err = s.updateRecord(ctx, tx, state)
+	if err != nil {
+		return nil, logger.WrapError("update merged record", err)
+	}
+
+	return state, nil
+}
+
+func (s *sqliteDataBase) readTxRecord(ctx context.Context, tx *sql.Tx, key *database.DBRecord) (*database.DBRecord, error) {
+	var record database.DBRecord
+	err := tx.StmtContext(ctx, s.readRecord).QueryRowContext(ctx, key.Name.String, key.Labels.String, key.MetricType.String).
+		Scan(&record.MetricType, &record.Name, &record.Labels, &record.Value, &record.Payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, logger.WrapError("read record", err)
+	}
+
+	return &record, nil
+}
+
+func (s *sqliteDataBase) addSamples(ctx context.Context, tx *sql.Tx, samples []*database.DBSample) error {
+	for _, sample := range samples {
+		_, err := tx.StmtContext(ctx, s.upsertMetricType).ExecContext(ctx, sample.MetricType.String)
+		if err != nil {
+			return logger.WrapError("upsert metric type", err)
+		}
+
+		_, err = tx.StmtContext(ctx, s.createSampleMetric).ExecContext(ctx, sample.Name.String, sample.Labels.String, sample.MetricType.String)
+		if err != nil {
+			return logger.WrapError("create sample metric", err)
+		}
+
+		_, err = tx.StmtContext(ctx, s.insertSample).ExecContext(ctx,
+			sample.Timestamp.Time.UnixNano(), sample.Value, sample.Name.String, sample.Labels.String, sample.MetricType.String)
+		if err != nil {
+			return logger.WrapError("add samples to sqlite database", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *sqliteDataBase) readSamples(ctx context.Context, command string, args ...any) ([]*database.DBSample, error) {
+	rows, err := s.conn.QueryContext(ctx, command, args...)
+	if err != nil {
+		return nil, logger.WrapError("call query", err)
+	}
+	defer rows.Close()
+
+	result := []*database.DBSample{}
+	for rows.Next() {
+		var sample database.DBSample
+		var timestamp int64
+		err = rows.Scan(&sample.MetricType, &sample.Name, &sample.Labels, &timestamp, &sample.Value)
+		if err != nil {
+			return nil, logger.WrapError("scan rows", err)
+		}
+
+		sample.Timestamp = sql.NullTime{Time: time.Unix(0, timestamp).UTC(), Valid: true}
+		result = append(result, &sample)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, logger.WrapError("get rows", err)
+	}
+
+	return result, nil
+}
+
+func recordKey(record *database.DBRecord) [3]string {
+	return [3]string{record.MetricType.String, record.Name.String, record.Labels.String}
+}
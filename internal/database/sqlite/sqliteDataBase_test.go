@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+)
+
+type configMock struct {
+	connectionString string
+}
+
+func TestNewSQLiteDataBase_InvalidConnectionString(t *testing.T) {
+	for _, connectionString := range []string{"", "sqlite://", "postgres://localhost:5432/metrics"} {
+		t.Run(connectionString, func(t *testing.T) {
+			_, err := NewSQLiteDataBase(context.Background(), &configMock{connectionString: connectionString})
+			assert.ErrorIs(t, err, ErrInvalidConnectionString)
+		})
+	}
+}
+
+func TestSQLiteDataBase_UpdateRecords(t *testing.T) {
+	ctx := context.Background()
+	conf := &configMock{connectionString: Scheme + filepath.Join(t.TempDir(), "metrics.db")}
+
+	base, err := NewSQLiteDataBase(ctx, conf)
+	require.NoError(t, err)
+
+	counter := createRecord("counter", "counterMetricName", "", 100)
+	gauge := createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 200)
+	histogram := createRecord("histogram", "histogramMetricName", "", 2)
+	histogram.Payload = sql.NullString{Valid: true, String: `{"bounds":[1],"counts":[1],"sum":2,"count":2}`}
+	require.NoError(t, base.UpdateRecords(ctx, []*database.DBRecord{counter, gauge, histogram}))
+
+	updatedGauge := createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 300)
+	require.NoError(t, base.UpdateRecords(ctx, []*database.DBRecord{updatedGauge}))
+	require.NoError(t, base.Close())
+
+	// state is persisted in the file
+	base, err = NewSQLiteDataBase(ctx, conf)
+	require.NoError(t, err)
+	defer base.Close()
+
+	actual, err := base.ReadRecord(ctx, "gauge", "gaugeMetricName", `{cpu="1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, updatedGauge, actual)
+
+	actual, err = base.ReadRecord(ctx, "gauge", "gaugeMetricName", "")
+	require.NoError(t, err)
+	assert.Nil(t, actual)
+
+	all, err := base.ReadAll(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*database.DBRecord{counter, updatedGauge, histogram}, all)
+}
+
+func TestSQLiteDataBase_AccumulateRecords(t *testing.T) {
+	ctx := context.Background()
+	base, err := NewSQLiteDataBase(ctx, &configMock{connectionString: Scheme + filepath.Join(t.TempDir(), "metrics.db")})
+	require.NoError(t, err)
+	defer base.Close()
+
+	merge := func(current *database.DBRecord, update *database.DBRecord) (*database.DBRecord, error) {
+		result := *current
+		result.Value.Float64 += update.Value.Float64
+		result.Payload.String = current.Payload.String + update.Payload.String
+		return &result, nil
+	}
+
+	composite := createRecord("histogram", "histogramMetricName", "", 1)
+	composite.Payload = sql.NullString{Valid: true, String: "a"}
+	result, err := base.AccumulateRecords(ctx, []*database.DBRecord{
+		createRecord("counter", "counterMetricName", "", 10),
+		composite,
+		createRecord("counter", "counterMetricName", "", 5),
+	}, merge)
+	require.NoError(t, err)
+
+	expectedCounter := createRecord("counter", "counterMetricName", "", 15)
+	assert.Equal(t, []*database.DBRecord{expectedCounter, composite, expectedCounter}, result)
+
+	update := createRecord("histogram", "histogramMetricName", "", 2)
+	update.Payload = sql.NullString{Valid: true, String: "b"}
+	result, err = base.AccumulateRecords(ctx, []*database.DBRecord{update}, merge)
+	require.NoError(t, err)
+
+	expectedComposite := createRecord("histogram", "histogramMetricName", "", 3)
+	expectedComposite.Payload = sql.NullString{Valid: true, String: "ab"}
+	assert.Equal(t, []*database.DBRecord{expectedComposite}, result)
+
+	// failed merge rolls back the whole batch
+	_, err = base.AccumulateRecords(ctx, []*database.DBRecord{createRecord("counter", "counterMetricName", "", 10), update},
+		func(*database.DBRecord, *database.DBRecord) (*database.DBRecord, error) {
+			return nil, test.ErrTest
+		})
+	assert.ErrorIs(t, err, test.ErrTest)
+
+	actual, err := base.ReadRecord(ctx, "counter", "counterMetricName", "")
+	require.NoError(t, err)
+	assert.Equal(t, expectedCounter, actual)
+}
+
+func TestSQLiteDataBase_Samples(t *testing.T) {
+	ctx := context.Background()
+	base, err := NewSQLiteDataBase(ctx, &configMock{connectionString: Scheme + filepath.Join(t.TempDir(), "metrics.db")})
+	require.NoError(t, err)
+	defer base.Close()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []*database.DBSample{
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start, 1),
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start.Add(time.Minute), 2),
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start.Add(2*time.Minute), 3),
+		createSample("counter", "counterMetricName", "", start, 10),
+	}
+	require.NoError(t, base.AddSamples(ctx, samples))
+
+	actual, err := base.ReadSamples(ctx, "gauge", "gaugeMetricName", `{cpu="1"}`, start.Add(time.Minute), start.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, samples[1:3], actual)
+
+	require.NoError(t, base.RemoveSamples(ctx, start.Add(time.Minute)))
+	actual, err = base.ReadAllSamples(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, samples[1:3], actual)
+
+	replacement := []*database.DBSample{createSample("counter", "counterMetricName", "", start.Add(time.Hour), 20)}
+	require.NoError(t, base.ReplaceSamples(ctx, replacement))
+	actual, err = base.ReadAllSamples(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, replacement, actual)
+}
+
+func TestSQLiteDataBase_RemoveRecords(t *testing.T) {
+	ctx := context.Background()
+	base, err := NewSQLiteDataBase(ctx, &configMock{connectionString: Scheme + filepath.Join(t.TempDir(), "metrics.db")})
+	require.NoError(t, err)
+	defer base.Close()
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	counter := createRecord("counter", "counterMetricName", "", 100)
+	gauge := createRecord("gauge", "gaugeMetricName", `{cpu="1"}`, 200)
+	require.NoError(t, base.UpdateRecords(ctx, []*database.DBRecord{counter, gauge}))
+	require.NoError(t, base.AddSamples(ctx, []*database.DBSample{
+		createSample("counter", "counterMetricName", "", start, 100),
+		createSample("gauge", "gaugeMetricName", `{cpu="1"}`, start, 200),
+	}))
+
+	require.NoError(t, base.RemoveRecords(ctx, []*database.DBRecord{gauge, createRecord("gauge", "missedMetricName", "", 0)}))
+
+	all, err := base.ReadAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBRecord{counter}, all)
+
+	samples, err := base.ReadAllSamples(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*database.DBSample{createSample("counter", "counterMetricName", "", start, 100)}, samples)
+}
+
+func createRecord(metricType string, name string, labels string, value float64) *database.DBRecord {
+	return &database.DBRecord{
+		MetricType: sql.NullString{Valid: true, String: metricType},
+		Name:       sql.NullString{Valid: true, String: name},
+		Labels:     sql.NullString{Valid: true, String: labels},
+		Value:      sql.NullFloat64{Valid: true, Float64: value},
+	}
+}
+
+func createSample(metricType string, name string, labels string, timestamp time.Time, value float64) *database.DBSample {
+	return &database.DBSample{
+		MetricType: sql.NullString{Valid: true, String: metricType},
+		Name:       sql.NullString{Valid: true, String: name},
+		Labels:     sql.NullString{Valid: true, String: labels},
+		Timestamp:  sql.NullTime{Valid: true, Time: timestamp},
+		Value:      sql.NullFloat64{Valid: true, Float64: value},
+	}
+}
+
+func (c *configMock) GetConnectionString() string {
+	return c.connectionString
+}
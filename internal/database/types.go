@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"io"
+	"time"
 )
 
 // DataBase is a main abstraction for work with metric records.
@@ -15,16 +16,54 @@ type DataBase interface {
 	// UpdateRecords update record values in database.
 	UpdateRecords(ctx context.Context, records []*DBRecord) error
 
+	// AccumulateRecords atomically applies records to the stored ones and returns resulting records in the input order.
+	// Record values are added to the stored values, records with payload are combined with the stored ones by merge function.
+	AccumulateRecords(ctx context.Context, records []*DBRecord, merge MergeFunc) ([]*DBRecord, error)
+
+	// RemoveRecords remove records with the same type, name and labels together with their samples.
+	// Missed records are skipped.
+	RemoveRecords(ctx context.Context, records []*DBRecord) error
+
 	// ReadRecord return metric db record from database.
-	ReadRecord(ctx context.Context, metricType string, metricName string) (*DBRecord, error)
+	// Labels are passed in canonical string representation, empty string means no labels.
+	ReadRecord(ctx context.Context, metricType string, metricName string, metricLabels string) (*DBRecord, error)
 
 	// ReadAll return all metric db records from database.
 	ReadAll(ctx context.Context) ([]*DBRecord, error)
+
+	// AddSamples append metric samples to database.
+	AddSamples(ctx context.Context, samples []*DBSample) error
+
+	// ReadSamples return metric samples in the [start, end] range ordered by time.
+	ReadSamples(ctx context.Context, metricType string, metricName string, metricLabels string, start time.Time, end time.Time) ([]*DBSample, error)
+
+	// ReadAllSamples return all metric samples from database.
+	ReadAllSamples(ctx context.Context) ([]*DBSample, error)
+
+	// ReplaceSamples replace all metric samples in database.
+	ReplaceSamples(ctx context.Context, samples []*DBSample) error
+
+	// RemoveSamples remove metric samples received before the boundary.
+	RemoveSamples(ctx context.Context, before time.Time) error
 }
 
+// MergeFunc combines stored record with the update and returns the resulting record.
+type MergeFunc func(current *DBRecord, update *DBRecord) (*DBRecord, error)
+
 // DBRecord represent metric in data base model.
 type DBRecord struct {
 	MetricType sql.NullString
 	Name       sql.NullString
+	Labels     sql.NullString // canonical labels representation, like {cpu="1"}
+	Value      sql.NullFloat64
+	Payload    sql.NullString // serialized state of composite metrics, like histograms and summaries
+}
+
+// DBSample represent timestamped metric value in data base model.
+type DBSample struct {
+	MetricType sql.NullString
+	Name       sql.NullString
+	Labels     sql.NullString
+	Timestamp  sql.NullTime
 	Value      sql.NullFloat64
 }
@@ -2,4 +2,10 @@ package hash
 
 import "errors"
 
-var ErrMissedSecretKey = errors.New("secret key was not initialized")
+var (
+	ErrInvalidRequestSign = errors.New("invalid request signature")
+	ErrMissedRequestSign  = errors.New("request signature is required")
+	ErrMissedSecretKey    = errors.New("secret key was not initialized")
+	ErrReplayedRequest    = errors.New("request was already received")
+	ErrRequestExpired     = errors.New("request timestamp is out of the allowed clock skew")
+)
@@ -0,0 +1,58 @@
+package hash
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// replayGuard rejects requests outside the clock skew window and requests with already seen nonces.
+// Nonce cache is bounded, the oldest nonces are evicted first, so the cache should hold all requests
+// received within the doubled skew window.
+type replayGuard struct {
+	maxSkew time.Duration
+	nonces  map[string]struct{}
+	order   []string
+	next    int
+	now     func() time.Time
+	lock    sync.Mutex
+}
+
+func newReplayGuard(maxSkew time.Duration, cacheSize int) *replayGuard {
+	if cacheSize < 1 {
+		cacheSize = 1
+	}
+
+	return &replayGuard{
+		maxSkew: maxSkew,
+		nonces:  make(map[string]struct{}, cacheSize),
+		order:   make([]string, 0, cacheSize),
+		now:     time.Now,
+	}
+}
+
+func (g *replayGuard) check(timestamp time.Time, nonce string) error {
+	now := g.now()
+	if timestamp.Before(now.Add(-g.maxSkew)) || timestamp.After(now.Add(g.maxSkew)) {
+		return logger.WrapError("check request timestamp", ErrRequestExpired)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := g.nonces[nonce]; ok {
+		return logger.WrapError("check request nonce", ErrReplayedRequest)
+	}
+
+	if len(g.order) < cap(g.order) {
+		g.order = append(g.order, nonce)
+	} else {
+		delete(g.nonces, g.order[g.next])
+		g.order[g.next] = nonce
+		g.next = (g.next + 1) % len(g.order)
+	}
+	g.nonces[nonce] = struct{}{}
+
+	return nil
+}
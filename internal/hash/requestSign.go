@@ -0,0 +1,102 @@
+package hash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const (
+	// RequestSignHeader is a request header (gRPC metadata key) with the request signature.
+	RequestSignHeader = "X-Request-Sign"
+	// RequestTimestampHeader is a request header (gRPC metadata key) with the signing unix time in milliseconds.
+	RequestTimestampHeader = "X-Request-Timestamp"
+	// RequestNonceHeader is a request header (gRPC metadata key) with the unique request nonce.
+	RequestNonceHeader = "X-Request-Nonce"
+
+	nonceSize = 16
+)
+
+// RequestSign is a whole request signature source, timestamp and nonce protect the request from replay.
+type RequestSign struct {
+	Target    string
+	Body      []byte
+	Timestamp time.Time
+	Nonce     string
+}
+
+// NewRequestSign creates request signature source with the current time and a random nonce.
+// Target identifies the endpoint, so the signed body can not be sent to the other one.
+func NewRequestSign(target string, body []byte) (*RequestSign, error) {
+	nonce := make([]byte, nonceSize)
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, logger.WrapError("generate nonce", err)
+	}
+
+	return &RequestSign{
+		Target:    target,
+		Body:      body,
+		Timestamp: time.UnixMilli(time.Now().UnixMilli()),
+		Nonce:     hex.EncodeToString(nonce),
+	}, nil
+}
+
+// ParseRequestSign creates request signature source from the received timestamp and nonce values.
+func ParseRequestSign(target string, body []byte, timestamp string, nonce string) (*RequestSign, error) {
+	millis, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("parse request timestamp '%s'", timestamp), ErrInvalidRequestSign)
+	}
+
+	if nonce == "" {
+		return nil, logger.WrapError("parse request nonce", ErrInvalidRequestSign)
+	}
+
+	return &RequestSign{
+		Target:    target,
+		Body:      body,
+		Timestamp: time.UnixMilli(millis),
+		Nonce:     nonce,
+	}, nil
+}
+
+// TimestampString returns timestamp header value.
+func (r *RequestSign) TimestampString() string {
+	return strconv.FormatInt(r.Timestamp.UnixMilli(), 10)
+}
+
+func (r *RequestSign) GetHash(hash hash.Hash) ([]byte, error) {
+	_, err := fmt.Fprintf(hash, "%s:%d:%s:", r.Target, r.Timestamp.UnixMilli(), r.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = hash.Write(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// SignRequest returns request signature source with the current timestamp and its signature string.
+func (s *Signer) SignRequest(target string, body []byte) (*RequestSign, string, error) {
+	sign, err := NewRequestSign(target, body)
+	if err != nil {
+		return nil, "", logger.WrapError("create request sign", err)
+	}
+
+	signature, err := s.GetSignString(sign)
+	if err != nil {
+		return nil, "", logger.WrapError("sign request", err)
+	}
+
+	return sign, signature, nil
+}
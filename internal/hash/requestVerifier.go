@@ -0,0 +1,73 @@
+package hash
+
+import (
+	"errors"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// RequestVerifierConfig contains required RequestVerifier settings.
+type RequestVerifierConfig interface {
+	RequireRequestSign() bool
+	RequestMaxSkew() time.Duration
+	NonceCacheSize() int
+}
+
+// RequestVerifier validates whole request signatures and protects from the request replay.
+type RequestVerifier struct {
+	signer   *Signer
+	guard    *replayGuard
+	required bool
+}
+
+// NewRequestVerifier create new instance of RequestVerifier.
+func NewRequestVerifier(config RequestVerifierConfig, signer *Signer) *RequestVerifier {
+	return &RequestVerifier{
+		signer:   signer,
+		guard:    newReplayGuard(config.RequestMaxSkew(), config.NonceCacheSize()),
+		required: config.RequireRequestSign(),
+	}
+}
+
+// Verify validates request signature, unsigned requests are accepted unless the signature is required.
+// Signature is checked before the nonce is remembered, so forged requests do not fill the nonce cache.
+func (v *RequestVerifier) Verify(target string, body []byte, signature string, timestamp string, nonce string) error {
+	if signature == "" {
+		if v.required {
+			return logger.WrapError("verify request", ErrMissedRequestSign)
+		}
+
+		return nil
+	}
+
+	sign, err := ParseRequestSign(target, body, timestamp, nonce)
+	if err != nil {
+		return logger.WrapError("parse request sign", err)
+	}
+
+	ok, err := v.signer.CheckSignString(sign, signature)
+	if err != nil {
+		return logger.WrapError("check request sign", err)
+	}
+
+	if !ok {
+		return logger.WrapError("check request sign", ErrInvalidRequestSign)
+	}
+
+	return v.guard.check(sign.Timestamp, sign.Nonce)
+}
+
+// RejectReason returns short verification failure reason for the telemetry labels.
+func RejectReason(err error) string {
+	switch {
+	case errors.Is(err, ErrReplayedRequest):
+		return "replayed"
+	case errors.Is(err, ErrRequestExpired):
+		return "expired"
+	case errors.Is(err, ErrMissedRequestSign):
+		return "unsigned"
+	default:
+		return "signature"
+	}
+}
@@ -0,0 +1,209 @@
+package hash
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRequestVerifierConfig struct {
+	required  bool
+	maxSkew   time.Duration
+	cacheSize int
+}
+
+func TestRequestVerifier_Verify(t *testing.T) {
+	target := "POST /updates"
+	body := []byte("test request body")
+	signer := NewSigner(&testSignerConfig{key: "test secret key"})
+	otherSigner := NewSigner(&testSignerConfig{key: "other secret key"})
+
+	tests := []struct {
+		name           string
+		required       bool
+		signer         *Signer
+		target         string
+		body           []byte
+		timestampShift time.Duration
+		timestamp      string
+		nonce          string
+		unsigned       bool
+		expectedErr    error
+	}{
+		{
+			name:     "unsigned_optional",
+			unsigned: true,
+		},
+		{
+			name:        "unsigned_required",
+			required:    true,
+			unsigned:    true,
+			expectedErr: ErrMissedRequestSign,
+		},
+		{
+			name: "valid_signature",
+		},
+		{
+			name:     "valid_signature_required",
+			required: true,
+		},
+		{
+			name:        "other_key",
+			signer:      otherSigner,
+			expectedErr: ErrInvalidRequestSign,
+		},
+		{
+			name:        "other_target",
+			target:      "POST /update",
+			expectedErr: ErrInvalidRequestSign,
+		},
+		{
+			name:        "modified_body",
+			body:        []byte("modified request body"),
+			expectedErr: ErrInvalidRequestSign,
+		},
+		{
+			name:        "modified_nonce",
+			nonce:       "modified",
+			expectedErr: ErrInvalidRequestSign,
+		},
+		{
+			name:        "invalid_timestamp",
+			timestamp:   "invalid",
+			expectedErr: ErrInvalidRequestSign,
+		},
+		{
+			name:           "expired",
+			timestampShift: -2 * time.Minute,
+			expectedErr:    ErrRequestExpired,
+		},
+		{
+			name:           "from_future",
+			timestampShift: 2 * time.Minute,
+			expectedErr:    ErrRequestExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewRequestVerifier(&testRequestVerifierConfig{required: tt.required, maxSkew: time.Minute, cacheSize: 10}, signer)
+
+			requestSigner := signer
+			if tt.signer != nil {
+				requestSigner = tt.signer
+			}
+
+			sign, signature, err := requestSigner.SignRequest(target, body)
+			require.NoError(t, err)
+
+			if tt.timestampShift != 0 {
+				sign.Timestamp = sign.Timestamp.Add(tt.timestampShift)
+				signature, err = requestSigner.GetSignString(sign)
+				require.NoError(t, err)
+			}
+
+			timestamp, nonce := sign.TimestampString(), sign.Nonce
+			if tt.timestamp != "" {
+				timestamp = tt.timestamp
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			if tt.unsigned {
+				signature, timestamp, nonce = "", "", ""
+			}
+
+			actualTarget, actualBody := target, body
+			if tt.target != "" {
+				actualTarget = tt.target
+			}
+			if tt.body != nil {
+				actualBody = tt.body
+			}
+
+			actualErr := verifier.Verify(actualTarget, actualBody, signature, timestamp, nonce)
+			if tt.expectedErr == nil {
+				assert.NoError(t, actualErr)
+			} else {
+				assert.ErrorIs(t, actualErr, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRequestVerifier_Replay(t *testing.T) {
+	signer := NewSigner(&testSignerConfig{key: "test secret key"})
+	verifier := NewRequestVerifier(&testRequestVerifierConfig{maxSkew: time.Minute, cacheSize: 2}, signer)
+
+	signRequest := func() (*RequestSign, string) {
+		sign, signature, err := signer.SignRequest("POST /updates", []byte("test request body"))
+		require.NoError(t, err)
+		return sign, signature
+	}
+	verify := func(sign *RequestSign, signature string) error {
+		return verifier.Verify(sign.Target, sign.Body, signature, sign.TimestampString(), sign.Nonce)
+	}
+
+	first, firstSignature := signRequest()
+	assert.NoError(t, verify(first, firstSignature))
+	assert.ErrorIs(t, verify(first, firstSignature), ErrReplayedRequest)
+
+	// forged request is rejected before its nonce is remembered
+	second, secondSignature := signRequest()
+	assert.ErrorIs(t, verify(second, "00"), ErrInvalidRequestSign)
+	assert.NoError(t, verify(second, secondSignature))
+
+	// the oldest nonce is evicted when the cache is full
+	third, thirdSignature := signRequest()
+	assert.NoError(t, verify(third, thirdSignature))
+	assert.NoError(t, verify(first, firstSignature))
+	assert.ErrorIs(t, verify(third, thirdSignature), ErrReplayedRequest)
+}
+
+func TestRejectReason(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: ErrReplayedRequest, expected: "replayed"},
+		{err: ErrRequestExpired, expected: "expired"},
+		{err: ErrMissedRequestSign, expected: "unsigned"},
+		{err: ErrInvalidRequestSign, expected: "signature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RejectReason(tt.err))
+		})
+	}
+}
+
+func TestParseRequestSign(t *testing.T) {
+	timestamp := time.UnixMilli(1700000000123)
+
+	sign, err := ParseRequestSign("POST /updates", nil, strconv.FormatInt(timestamp.UnixMilli(), 10), "nonce")
+	require.NoError(t, err)
+	assert.Equal(t, timestamp, sign.Timestamp)
+	assert.Equal(t, "1700000000123", sign.TimestampString())
+
+	_, err = ParseRequestSign("POST /updates", nil, "", "nonce")
+	assert.ErrorIs(t, err, ErrInvalidRequestSign)
+
+	_, err = ParseRequestSign("POST /updates", nil, "1700000000123", "")
+	assert.ErrorIs(t, err, ErrInvalidRequestSign)
+}
+
+func (c *testRequestVerifierConfig) RequireRequestSign() bool {
+	return c.required
+}
+
+func (c *testRequestVerifierConfig) RequestMaxSkew() time.Duration {
+	return c.maxSkew
+}
+
+func (c *testRequestVerifierConfig) NonceCacheSize() int {
+	return c.cacheSize
+}
@@ -0,0 +1,8 @@
+package logger
+
+import "errors"
+
+var (
+	ErrUnknownFormat = errors.New("unknown log format, expected text or json")
+	ErrUnknownLevel  = errors.New("unknown log level, expected debug, info, warn or error")
+)
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is a log message severity.
+type Level int32
+
+const (
+	// DebugLevel is used for verbose diagnostic messages.
+	DebugLevel Level = iota
+	// InfoLevel is a default level.
+	InfoLevel
+	// WarnLevel is used for unexpected but handled situations.
+	WarnLevel
+	// ErrorLevel is used for failures.
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+// ParseLevel parses level name, names are case-insensitive.
+func ParseLevel(str string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(str))
+	if name == "warning" {
+		return WarnLevel, nil
+	}
+
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+
+	return InfoLevel, fmt.Errorf("parse level '%s': %w", str, ErrUnknownLevel)
+}
+
+func (l Level) String() string {
+	name, ok := levelNames[l]
+	if !ok {
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+
+	return name
+}
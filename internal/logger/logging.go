@@ -1,49 +1,153 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
 )
 
+type fieldsContextKey struct{}
+
+// Field is a structured message attribute.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Entry logs messages with attached fields.
+type Entry struct {
+	fields []Field
+}
+
+var root = &Entry{}
+
+// WithFields returns context with fields attached to messages logged by FromContext entry.
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	current := fieldsFromContext(ctx)
+	merged := make([]Field, 0, len(current)+len(fields))
+	merged = append(merged, current...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsContextKey{}, merged)
+}
+
+// FromContext returns entry with request-scoped fields of the context.
+func FromContext(ctx context.Context) *Entry {
+	fields := fieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return root
+	}
+
+	return &Entry{fields: fields}
+}
+
+// Debug log debug message.
+func Debug(message string) {
+	root.Debug(message)
+}
+
+// DebugFormat log debug message with custom format arguments.
+func DebugFormat(format string, v ...any) {
+	root.DebugFormat(format, v...)
+}
+
 // Info log information message.
 func Info(message string) {
-	log.Printf("[INFO]: %v\r\n", message)
+	root.Info(message)
 }
 
 // InfoFormat log information message with custom format arguments.
 func InfoFormat(format string, v ...any) {
-	Info(fmt.Sprintf(format, v...))
+	root.InfoFormat(format, v...)
 }
 
 // Warn log warning message.
 func Warn(message string) {
-	log.Printf("[WARN]: %v\r\n", message)
+	root.Warn(message)
 }
 
 // WarnFormat log warning message with custom format arguments.
 func WarnFormat(format string, v ...any) {
-	Warn(fmt.Sprintf(format, v...))
+	root.WarnFormat(format, v...)
 }
 
 // Error log error message.
 func Error(message string) {
-	log.Printf("[ERROR]: %v\r\n", message)
+	root.Error(message)
 }
 
 // ErrorObj log error object.
 func ErrorObj(err error) {
-	ErrorFormat("%v", err)
+	root.ErrorObj(err)
 }
 
 // ErrorFormat log error message with custom format arguments.
 func ErrorFormat(format string, v ...any) {
-	Error(fmt.Sprintf(format, v...))
+	root.ErrorFormat(format, v...)
 }
 
-// WrapError log error and return wrapped error object.
+// WrapError return wrapped error object, the error is logged unless wrapped errors logging is disabled.
 func WrapError(message string, err error) error {
 	wrap := fmt.Errorf("failed to "+message+": %w", err) //nolint:goerr113
-	ErrorObj(wrap)
+	if std.wrapErrors.Load() {
+		ErrorObj(wrap)
+	}
 
 	return wrap
 }
+
+// Debug log debug message.
+func (e *Entry) Debug(message string) {
+	std.write(DebugLevel, message, e.fields)
+}
+
+// DebugFormat log debug message with custom format arguments.
+func (e *Entry) DebugFormat(format string, v ...any) {
+	if std.enabled(DebugLevel) {
+		e.Debug(fmt.Sprintf(format, v...))
+	}
+}
+
+// Info log information message.
+func (e *Entry) Info(message string) {
+	std.write(InfoLevel, message, e.fields)
+}
+
+// InfoFormat log information message with custom format arguments.
+func (e *Entry) InfoFormat(format string, v ...any) {
+	if std.enabled(InfoLevel) {
+		e.Info(fmt.Sprintf(format, v...))
+	}
+}
+
+// Warn log warning message.
+func (e *Entry) Warn(message string) {
+	std.write(WarnLevel, message, e.fields)
+}
+
+// WarnFormat log warning message with custom format arguments.
+func (e *Entry) WarnFormat(format string, v ...any) {
+	if std.enabled(WarnLevel) {
+		e.Warn(fmt.Sprintf(format, v...))
+	}
+}
+
+// Error log error message.
+func (e *Entry) Error(message string) {
+	std.write(ErrorLevel, message, e.fields)
+}
+
+// ErrorObj log error object.
+func (e *Entry) ErrorObj(err error) {
+	e.ErrorFormat("%v", err)
+}
+
+// ErrorFormat log error message with custom format arguments.
+func (e *Entry) ErrorFormat(format string, v ...any) {
+	if std.enabled(ErrorLevel) {
+		e.Error(fmt.Sprintf(format, v...))
+	}
+}
+
+func fieldsFromContext(ctx context.Context) []Field {
+	fields, _ := ctx.Value(fieldsContextKey{}).([]Field)
+	return fields
+}
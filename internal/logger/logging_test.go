@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type configMock struct {
+	level   string
+	format  string
+	outputs []string
+	wrapped bool
+}
+
+var errTest = errors.New("test error")
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		str           string
+		expectedLevel Level
+		expectedError error
+	}{
+		{
+			name:          "debug",
+			str:           "debug",
+			expectedLevel: DebugLevel,
+		},
+		{
+			name:          "upper_case",
+			str:           "INFO",
+			expectedLevel: InfoLevel,
+		},
+		{
+			name:          "warning_alias",
+			str:           "warning\n",
+			expectedLevel: WarnLevel,
+		},
+		{
+			name:          "error",
+			str:           "error",
+			expectedLevel: ErrorLevel,
+		},
+		{
+			name:          "unknown",
+			str:           "trace",
+			expectedLevel: InfoLevel,
+			expectedError: ErrUnknownLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseLevel(tt.str)
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Equal(t, tt.expectedLevel, actual)
+		})
+	}
+}
+
+func TestLogger_Text(t *testing.T) {
+	buffer := captureOutput(t, FormatText)
+
+	ctx := WithFields(context.Background(), Field{Key: "request_id", Value: "42"})
+	ctx = WithFields(ctx, Field{Key: "agent_ip", Value: "10.0.0.1"}, Field{Key: "path", Value: "a b"})
+	FromContext(ctx).InfoFormat("Updated %d metrics\n", 2)
+	DebugFormat("skipped %s", "message")
+	Warn("plain message")
+
+	assert.Equal(t, "2023-01-01T00:00:00Z INFO Updated 2 metrics request_id=42 agent_ip=10.0.0.1 path=\"a b\"\n"+
+		"2023-01-01T00:00:00Z WARN plain message\n", buffer.String())
+}
+
+func TestLogger_JSON(t *testing.T) {
+	buffer := captureOutput(t, FormatJSON)
+
+	ctx := WithFields(context.Background(), Field{Key: "request_id", Value: "42"}, Field{Key: "error", Value: errTest})
+	FromContext(ctx).Error("request \"failed\"")
+
+	assert.Equal(t, `{"time":"2023-01-01T00:00:00Z","level":"error","msg":"request \"failed\"","request_id":"42","error":"test error"}`+"\n",
+		buffer.String())
+}
+
+func TestLogger_Level(t *testing.T) {
+	buffer := captureOutput(t, FormatText)
+
+	SetLevel(ErrorLevel)
+	Info("skipped")
+	SetLevel(DebugLevel)
+	Debug("written")
+
+	assert.Equal(t, DebugLevel, GetLevel())
+	assert.Equal(t, "2023-01-01T00:00:00Z DEBUG written\n", buffer.String())
+}
+
+func TestWrapError(t *testing.T) {
+	buffer := captureOutput(t, FormatText)
+
+	err := WrapError("do something", errTest)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, "2023-01-01T00:00:00Z ERROR failed to do something: test error\n", buffer.String())
+
+	buffer.Reset()
+	std.wrapErrors.Store(false)
+	err = WrapError("do something", errTest)
+	assert.EqualError(t, err, "failed to do something: test error")
+	assert.Empty(t, buffer.String())
+}
+
+func TestConfigure(t *testing.T) {
+	captureOutput(t, FormatText)
+	path := filepath.Join(t.TempDir(), "server.log")
+
+	err := Configure(&configMock{level: "warn", format: "json", outputs: []string{path}})
+	require.NoError(t, err)
+	defer Close()
+
+	Info("skipped")
+	Warn("written")
+	WrapError("do something", errTest)
+	require.NoError(t, Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"time":"2023-01-01T00:00:00Z","level":"warn","msg":"written"}`+"\n", string(content))
+
+	assert.ErrorIs(t, Configure(&configMock{level: "trace"}), ErrUnknownLevel)
+	assert.ErrorIs(t, Configure(&configMock{format: "xml"}), ErrUnknownFormat)
+}
+
+func captureOutput(t *testing.T, format string) *bytes.Buffer {
+	buffer := &bytes.Buffer{}
+	previous := std
+	std = newSink()
+	std.format = format
+	std.output = buffer
+	std.now = func() time.Time { return time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { std = previous })
+
+	return buffer
+}
+
+func (c *configMock) LogLevel() string {
+	return c.level
+}
+
+func (c *configMock) LogFormat() string {
+	return c.format
+}
+
+func (c *configMock) LogOutputs() []string {
+	return c.outputs
+}
+
+func (c *configMock) LogWrappedErrors() bool {
+	return c.wrapped
+}
@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// FormatText writes messages as single text lines with key=value fields.
+	FormatText = "text"
+	// FormatJSON writes messages as single line JSON objects.
+	FormatJSON = "json"
+
+	// OutputStdout is an output name of the process standard output.
+	OutputStdout = "stdout"
+	// OutputStderr is an output name of the process standard error.
+	OutputStderr = "stderr"
+)
+
+type loggerConfig interface {
+	LogLevel() string
+	LogFormat() string
+	LogOutputs() []string
+	LogWrappedErrors() bool
+}
+
+type sink struct {
+	level      atomic.Int32
+	wrapErrors atomic.Bool
+	format     string
+	output     io.Writer
+	files      []*os.File
+	now        func() time.Time
+	lock       sync.Mutex
+}
+
+var std = newSink()
+
+func newSink() *sink {
+	result := &sink{
+		format: FormatText,
+		output: os.Stderr,
+		now:    time.Now,
+	}
+	result.level.Store(int32(InfoLevel))
+	result.wrapErrors.Store(true)
+
+	return result
+}
+
+// Configure applies logging configuration. Outputs are stdout, stderr or file paths, files are appended.
+// Messages of the standard log package are redirected to the logger.
+func Configure(conf loggerConfig) error {
+	level := InfoLevel
+	if conf.LogLevel() != "" {
+		var err error
+		level, err = ParseLevel(conf.LogLevel())
+		if err != nil {
+			return err
+		}
+	}
+
+	format := strings.ToLower(conf.LogFormat())
+	if format == "" {
+		format = FormatText
+	}
+
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("set format '%s': %w", conf.LogFormat(), ErrUnknownFormat)
+	}
+
+	writers := []io.Writer{}
+	files := []*os.File{}
+	for _, output := range conf.LogOutputs() {
+		switch output {
+		case "", OutputStderr:
+			writers = append(writers, os.Stderr)
+		case OutputStdout:
+			writers = append(writers, os.Stdout)
+		default:
+			file, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				closeFiles(files)
+				return fmt.Errorf("open log file '%s': %w", output, err)
+			}
+
+			writers = append(writers, file)
+			files = append(files, file)
+		}
+	}
+
+	if len(writers) == 0 {
+		writers = append(writers, os.Stderr)
+	}
+
+	std.lock.Lock()
+	previous := std.files
+	std.format = format
+	std.output = io.MultiWriter(writers...)
+	std.files = files
+	std.lock.Unlock()
+	closeFiles(previous)
+
+	SetLevel(level)
+	std.wrapErrors.Store(conf.LogWrappedErrors())
+
+	log.SetFlags(0)
+	log.SetOutput(stdLogWriter{})
+	return nil
+}
+
+// Close closes log files, messages are written to stderr afterwards.
+func Close() error {
+	std.lock.Lock()
+	files := std.files
+	std.output = os.Stderr
+	std.files = nil
+	std.lock.Unlock()
+
+	return closeFiles(files)
+}
+
+// SetLevel changes minimal level of written messages.
+func SetLevel(level Level) {
+	std.level.Store(int32(level))
+}
+
+// GetLevel returns minimal level of written messages.
+func GetLevel() Level {
+	return Level(std.level.Load())
+}
+
+// SetOutput replaces all outputs with the writer.
+func SetOutput(output io.Writer) {
+	std.lock.Lock()
+	defer std.lock.Unlock()
+
+	std.output = output
+}
+
+func (s *sink) enabled(level Level) bool {
+	return level >= Level(s.level.Load())
+}
+
+func (s *sink) write(level Level, message string, fields []Field) {
+	if !s.enabled(level) {
+		return
+	}
+
+	message = strings.TrimRight(message, "\r\n")
+	buffer := &bytes.Buffer{}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	timestamp := s.now().UTC().Format(time.RFC3339Nano)
+	if s.format == FormatJSON {
+		writeJSON(buffer, timestamp, level, message, fields)
+	} else {
+		writeText(buffer, timestamp, level, message, fields)
+	}
+
+	_, _ = s.output.Write(buffer.Bytes())
+}
+
+func writeText(buffer *bytes.Buffer, timestamp string, level Level, message string, fields []Field) {
+	buffer.WriteString(timestamp)
+	buffer.WriteByte(' ')
+	buffer.WriteString(strings.ToUpper(level.String()))
+	buffer.WriteByte(' ')
+	buffer.WriteString(message)
+	for _, field := range fields {
+		buffer.WriteByte(' ')
+		buffer.WriteString(field.Key)
+		buffer.WriteByte('=')
+
+		value := fmt.Sprint(field.Value)
+		if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+			value = strconv.Quote(value)
+		}
+		buffer.WriteString(value)
+	}
+	buffer.WriteByte('\n')
+}
+
+func writeJSON(buffer *bytes.Buffer, timestamp string, level Level, message string, fields []Field) {
+	buffer.WriteString(`{"time":`)
+	writeJSONValue(buffer, timestamp)
+	buffer.WriteString(`,"level":`)
+	writeJSONValue(buffer, level.String())
+	buffer.WriteString(`,"msg":`)
+	writeJSONValue(buffer, message)
+	for _, field := range fields {
+		buffer.WriteByte(',')
+		writeJSONValue(buffer, field.Key)
+		buffer.WriteByte(':')
+		writeJSONValue(buffer, field.Value)
+	}
+	buffer.WriteString("}\n")
+}
+
+func writeJSONValue(buffer *bytes.Buffer, value any) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	content, err := json.Marshal(value)
+	if err != nil {
+		content, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	buffer.Write(content)
+}
+
+func closeFiles(files []*os.File) error {
+	var result error
+	for _, file := range files {
+		err := file.Close()
+		if err != nil && result == nil {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// stdLogWriter writes messages of the standard log package as information messages.
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) {
+	Info(string(p))
+	return len(p), nil
+}
@@ -0,0 +1,46 @@
+package agents
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const agentIDBytes = 16
+
+// LoadOrCreateID reads the agent identifier from the file or generates and saves a new one on first run.
+func LoadOrCreateID(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.TrimSpace(string(content))
+		if id != "" {
+			return id, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", logger.WrapError("read agent id file", err)
+	}
+
+	buffer := make([]byte, agentIDBytes)
+	_, err = rand.Read(buffer)
+	if err != nil {
+		return "", logger.WrapError("generate agent id", err)
+	}
+
+	id := hex.EncodeToString(buffer)
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return "", logger.WrapError("create agent id directory", err)
+	}
+
+	err = os.WriteFile(path, []byte(id), 0o644)
+	if err != nil {
+		return "", logger.WrapError("write agent id file", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,21 @@
+package agents
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent", "id")
+
+	id, err := LoadOrCreateID(path)
+	require.NoError(t, err)
+	assert.Len(t, id, 2*agentIDBytes)
+
+	loaded, err := LoadOrCreateID(path)
+	require.NoError(t, err)
+	assert.Equal(t, id, loaded)
+}
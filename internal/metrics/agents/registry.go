@@ -0,0 +1,169 @@
+package agents
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+// AgentIDHeader is a request header (gRPC metadata key) with the agent identifier.
+const AgentIDHeader = "X-Agent-ID"
+
+// Source describes the sender of metric values.
+type Source struct {
+	AgentID string
+	Address string
+}
+
+// Agent contains the last known activity of the agent.
+type Agent struct {
+	ID           string
+	Source       string
+	LastSeen     time.Time
+	HeartbeatAge time.Duration
+	SeriesCount  int
+}
+
+// Series contains the last known source of the metric series.
+type Series struct {
+	AgentID  string
+	Source   string
+	LastSeen time.Time
+}
+
+// Registry tracks agents and series activity.
+type Registry interface {
+	// Track records that metric values were received from the source.
+	Track(source Source, metricsList []metrics.Metric)
+
+	// GetAgents returns all known agents sorted by identifier.
+	GetAgents() []*Agent
+
+	// GetSeries returns the last known source of the series by metric type and series key.
+	GetSeries(metricType string, seriesKey string) (*Series, bool)
+
+	// Forget removes series selected by the filter, it is called when series are removed from the storage.
+	Forget(filter *metrics.SeriesFilter)
+}
+
+type agentRecord struct {
+	source   string
+	lastSeen time.Time
+}
+
+type inMemoryRegistry struct {
+	agents map[string]*agentRecord
+	series map[string]map[string]*Series
+	now    func() time.Time
+	lock   sync.RWMutex
+}
+
+func NewRegistry() *inMemoryRegistry {
+	return &inMemoryRegistry{
+		agents: map[string]*agentRecord{},
+		series: map[string]map[string]*Series{},
+		now:    time.Now,
+		lock:   sync.RWMutex{},
+	}
+}
+
+func (r *inMemoryRegistry) Track(source Source, metricsList []metrics.Metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	if source.AgentID != "" {
+		r.agents[source.AgentID] = &agentRecord{
+			source:   source.Address,
+			lastSeen: now,
+		}
+	}
+
+	for _, metric := range metricsList {
+		metricType := metric.GetType()
+		typedSeries, ok := r.series[metricType]
+		if !ok {
+			typedSeries = map[string]*Series{}
+			r.series[metricType] = typedSeries
+		}
+
+		typedSeries[metrics.SeriesKey(metric.GetName(), metric.GetLabels())] = &Series{
+			AgentID:  source.AgentID,
+			Source:   source.Address,
+			LastSeen: now,
+		}
+	}
+}
+
+func (r *inMemoryRegistry) GetAgents() []*Agent {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	seriesCount := map[string]int{}
+	for _, typedSeries := range r.series {
+		for _, series := range typedSeries {
+			seriesCount[series.AgentID]++
+		}
+	}
+
+	now := r.now()
+	result := make([]*Agent, 0, len(r.agents))
+	for id, agent := range r.agents {
+		result = append(result, &Agent{
+			ID:           id,
+			Source:       agent.source,
+			LastSeen:     agent.lastSeen,
+			HeartbeatAge: now.Sub(agent.lastSeen),
+			SeriesCount:  seriesCount[id],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
+
+func (r *inMemoryRegistry) GetSeries(metricType string, seriesKey string) (*Series, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	series, ok := r.series[metricType][seriesKey]
+	if !ok {
+		return nil, false
+	}
+
+	result := *series
+	return &result, true
+}
+
+func (r *inMemoryRegistry) Forget(filter *metrics.SeriesFilter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// evicted series are forgotten one by one, so the exact series is removed without the scan
+	if filter.Type != "" && filter.Name != "" && filter.ExactLabels && filter.NamePrefix == "" && filter.NamePattern == nil {
+		r.forget(filter.Type, metrics.SeriesKey(filter.Name, filter.Labels))
+		return
+	}
+
+	for metricType, typedSeries := range r.series {
+		for seriesKey := range typedSeries {
+			if filter.MatchKey(metricType, seriesKey) {
+				r.forget(metricType, seriesKey)
+			}
+		}
+	}
+}
+
+func (r *inMemoryRegistry) forget(metricType string, seriesKey string) {
+	typedSeries, ok := r.series[metricType]
+	if !ok {
+		return
+	}
+
+	delete(typedSeries, seriesKey)
+	if len(typedSeries) == 0 {
+		delete(r.series, metricType)
+	}
+}
@@ -0,0 +1,130 @@
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+)
+
+func TestRegistry_Track(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	registry := NewRegistry()
+	registry.now = func() time.Time { return now }
+
+	registry.Track(Source{AgentID: "agent1", Address: "10.0.0.1"}, []metrics.Metric{
+		types.NewGaugeMetric("Alloc"),
+		types.NewGaugeMetric("CPUutilization", metrics.Label{Name: "cpu", Value: "0"}),
+		types.NewCounterMetric("PollCount"),
+	})
+
+	now = start.Add(5 * time.Second)
+	registry.Track(Source{AgentID: "agent2", Address: "10.0.0.2"}, []metrics.Metric{
+		types.NewGaugeMetric("Alloc"),
+	})
+
+	now = start.Add(10 * time.Second)
+	registry.Track(Source{Address: "10.0.0.3"}, []metrics.Metric{
+		types.NewCounterMetric("Manual"),
+	})
+
+	assert.Equal(t, []*Agent{
+		{
+			ID:           "agent1",
+			Source:       "10.0.0.1",
+			LastSeen:     start,
+			HeartbeatAge: 10 * time.Second,
+			SeriesCount:  2,
+		},
+		{
+			ID:           "agent2",
+			Source:       "10.0.0.2",
+			LastSeen:     start.Add(5 * time.Second),
+			HeartbeatAge: 5 * time.Second,
+			SeriesCount:  1,
+		},
+	}, registry.GetAgents())
+
+	series, ok := registry.GetSeries("gauge", "Alloc")
+	require.True(t, ok)
+	assert.Equal(t, &Series{AgentID: "agent2", Source: "10.0.0.2", LastSeen: start.Add(5 * time.Second)}, series)
+
+	series, ok = registry.GetSeries("gauge", `CPUutilization{cpu="0"}`)
+	require.True(t, ok)
+	assert.Equal(t, "agent1", series.AgentID)
+
+	series, ok = registry.GetSeries("counter", "Manual")
+	require.True(t, ok)
+	assert.Equal(t, &Series{Source: "10.0.0.3", LastSeen: start.Add(10 * time.Second)}, series)
+
+	_, ok = registry.GetSeries("gauge", "Unknown")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Forget(t *testing.T) {
+	tests := []struct {
+		name           string
+		filter         *metrics.SeriesFilter
+		expectedSeries map[string][]string
+		expectedCount  int
+	}{
+		{
+			name:           "exact_series",
+			filter:         &metrics.SeriesFilter{Type: "gauge", Name: "CPUutilization", Labels: metrics.NewLabels(metrics.Label{Name: "cpu", Value: "0"}), ExactLabels: true},
+			expectedSeries: map[string][]string{"gauge": {"Alloc", `CPUutilization{cpu="1"}`}, "counter": {"PollCount"}},
+			expectedCount:  3,
+		},
+		{
+			name:           "name_prefix",
+			filter:         &metrics.SeriesFilter{NamePrefix: "CPU"},
+			expectedSeries: map[string][]string{"gauge": {"Alloc"}, "counter": {"PollCount"}},
+			expectedCount:  2,
+		},
+		{
+			name:           "metric_type",
+			filter:         &metrics.SeriesFilter{Type: "counter", Name: "PollCount"},
+			expectedSeries: map[string][]string{"gauge": {"Alloc", `CPUutilization{cpu="0"}`, `CPUutilization{cpu="1"}`}},
+			expectedCount:  3,
+		},
+		{
+			name:           "missed_series",
+			filter:         &metrics.SeriesFilter{Type: "gauge", Name: "Unknown", ExactLabels: true},
+			expectedSeries: map[string][]string{"gauge": {"Alloc", `CPUutilization{cpu="0"}`, `CPUutilization{cpu="1"}`}, "counter": {"PollCount"}},
+			expectedCount:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			registry.Track(Source{AgentID: "agent1"}, []metrics.Metric{
+				types.NewGaugeMetric("Alloc"),
+				types.NewGaugeMetric("CPUutilization", metrics.Label{Name: "cpu", Value: "0"}),
+				types.NewGaugeMetric("CPUutilization", metrics.Label{Name: "cpu", Value: "1"}),
+				types.NewCounterMetric("PollCount"),
+			})
+
+			registry.Forget(tt.filter)
+
+			actualSeries := map[string][]string{}
+			for metricType, typedSeries := range registry.series {
+				for seriesKey := range typedSeries {
+					actualSeries[metricType] = append(actualSeries[metricType], seriesKey)
+				}
+			}
+			assert.Len(t, actualSeries, len(tt.expectedSeries))
+			for metricType, seriesKeys := range tt.expectedSeries {
+				assert.ElementsMatch(t, seriesKeys, actualSeries[metricType])
+			}
+
+			agents := registry.GetAgents()
+			require.Len(t, agents, 1)
+			assert.Equal(t, tt.expectedCount, agents[0].SeriesCount)
+		})
+	}
+}
@@ -3,14 +3,27 @@ package metrics
 import "errors"
 
 var (
+	ErrEmptySeriesFilter        = errors.New("series filter matches all series")
 	ErrEmptyURL                 = errors.New("empty url string")
+	ErrEncryptedBackup          = errors.New("backup is encrypted")
 	ErrFieldNameNotFound        = errors.New("field name was not found")
+	ErrHistoryNotSupported      = errors.New("metrics history is not supported")
+	ErrIncompatibleMetrics      = errors.New("incompatible metrics")
+	ErrInvalidHistogram         = errors.New("invalid histogram state")
+	ErrInvalidHistogramBounds   = errors.New("invalid histogram bounds")
+	ErrInvalidLabel             = errors.New("invalid metric label")
+	ErrInvalidMetricName        = errors.New("invalid metric name")
+	ErrInvalidObservation       = errors.New("invalid summary observation")
+	ErrInvalidQuantile          = errors.New("invalid quantile")
+	ErrInvalidRange             = errors.New("invalid range query")
 	ErrInvalidRecordMetricType  = errors.New("invalid record metric type")
 	ErrInvalidRecordMetricName  = errors.New("invalid record metric name")
 	ErrInvalidRecordMetricValue = errors.New("invalid record metric value")
 	ErrInvalidSignature         = errors.New("invalid signature")
+	ErrInvalidSummary           = errors.New("invalid summary state")
 	ErrMetricNotFound           = errors.New("metric not found")
 	ErrMetricValueMissed        = errors.New("metric value is missed")
 	ErrUnexpectedStatusCode     = errors.New("unexpected status code")
 	ErrUnknownMetricType        = errors.New("unknown metric type")
+	ErrUnsupportedBackupFormat  = errors.New("unsupported backup format")
 )
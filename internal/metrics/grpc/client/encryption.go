@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+
+	rpc "google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/proto/generated"
+)
+
+// encryptRequests replaces metrics requests with the serialized request encrypted by the server public key.
+// Other requests carry no metric values and are sent as is.
+func encryptRequests(encryptor crypto.Encryptor) rpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *rpc.ClientConn, invoker rpc.UnaryInvoker, opts ...rpc.CallOption) error {
+		request, ok := req.(*generated.MetricsRequest)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		content, err := proto.Marshal(request)
+		if err != nil {
+			return logger.WrapError("marshal request", err)
+		}
+
+		encrypted, err := encryptor.Encrypt(content)
+		if err != nil {
+			return logger.WrapError("encrypt request", err)
+		}
+
+		return invoker(ctx, method, &generated.MetricsRequest{Encrypted: encrypted}, reply, cc, opts...)
+	}
+}
@@ -3,13 +3,21 @@ package client
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/certs"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/chunk"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/proto/generated"
 )
@@ -17,16 +25,52 @@ import (
 const chunkSize int = 10
 
 type GrpcMetricsPusherConfig interface {
+	AgentID() string
 	GrpcServerURL() string
+	GrpcCertPath() string
+	GrpcKeyPath() string
+	GrpcCAPath() string
 }
 
 type grpcMetricsPusher struct {
 	client    generated.MetricServerClient
 	converter *grpc.Converter
+	tracer    *tracing.Tracer
+	agentID   string
 }
 
-func NewPusher(conf GrpcMetricsPusherConfig, converter *grpc.Converter) (*grpcMetricsPusher, error) {
-	connection, err := rpc.Dial(conf.GrpcServerURL(), rpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewPusher(
+	conf GrpcMetricsPusherConfig,
+	converter *grpc.Converter,
+	encryptor crypto.Encryptor,
+	requestSigner *hash.Signer,
+	tracer *tracing.Tracer,
+) (*grpcMetricsPusher, error) {
+	transportCredentials := insecure.NewCredentials()
+	if conf.GrpcCAPath() != "" || conf.GrpcCertPath() != "" {
+		tlsConfig, err := certs.NewClientConfig(conf.GrpcCertPath(), conf.GrpcKeyPath(), conf.GrpcCAPath())
+		if err != nil {
+			return nil, logger.WrapError("create tls config", err)
+		}
+
+		transportCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	// requests are signed after encryption, so the signature covers the bytes sent
+	interceptors := []rpc.UnaryClientInterceptor{}
+	if encryptor != nil {
+		interceptors = append(interceptors, encryptRequests(encryptor))
+	}
+	if requestSigner != nil {
+		interceptors = append(interceptors, signRequests(requestSigner))
+	}
+
+	options := []rpc.DialOption{
+		rpc.WithTransportCredentials(transportCredentials),
+		rpc.WithChainUnaryInterceptor(interceptors...),
+	}
+
+	connection, err := rpc.Dial(conf.GrpcServerURL(), options...)
 	if err != nil {
 		return nil, logger.WrapError("open grpc connection", err)
 	}
@@ -34,42 +78,79 @@ func NewPusher(conf GrpcMetricsPusherConfig, converter *grpc.Converter) (*grpcMe
 	return &grpcMetricsPusher{
 		client:    generated.NewMetricServerClient(connection),
 		converter: converter,
+		tracer:    tracer,
+		agentID:   conf.AgentID(),
 	}, nil
 }
 
 func (g *grpcMetricsPusher) Push(ctx context.Context, metricsChan <-chan metrics.Metric) error {
-	for _, metricsChunk := range chunk.ChanToChunks(metricsChan, chunkSize) {
-		chunkLen := len(metricsChunk)
-		requestMetrics := make([]*generated.Metric, chunkLen)
-
-		for i := 0; i < chunkLen; i++ {
-			requestMetric, err := g.converter.ToModelMetric(metricsChunk[i])
-			if err != nil {
-				return logger.WrapError("generate update metric request", err)
-			}
+	if g.agentID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, agents.AgentIDHeader, g.agentID)
+	}
 
-			requestMetrics[i] = requestMetric
+	for _, metricsChunk := range chunk.ChanToChunks(metricsChan, chunkSize) {
+		err := g.pushChunk(ctx, metricsChunk)
+		if err != nil {
+			return err
 		}
+	}
 
-		request := &generated.MetricsRequest{
-			Metrics: requestMetrics,
-		}
+	return nil
+}
 
-		response, err := g.client.UpdateValues(ctx, request)
+func (g *grpcMetricsPusher) pushChunk(ctx context.Context, metricsChunk []metrics.Metric) (err error) {
+	ctx, span := g.tracer.Start(ctx, "grpc_push_metrics")
+	defer func() {
+		span.SetError(err)
+		endSpan(span)
+	}()
+
+	spanContext := span.Context()
+	ctx = metadata.AppendToOutgoingContext(ctx, tracing.TraceparentHeader, spanContext.Traceparent())
+	ctx = logger.WithFields(ctx,
+		logger.Field{Key: "request_id", Value: spanContext.TraceID},
+		logger.Field{Key: "span_id", Value: spanContext.SpanID},
+	)
+	log := logger.FromContext(ctx)
+
+	chunkLen := len(metricsChunk)
+	span.SetAttribute("metrics_count", strconv.Itoa(chunkLen))
+	requestMetrics := make([]*generated.Metric, chunkLen)
+
+	for i := 0; i < chunkLen; i++ {
+		requestMetric, err := g.converter.ToModelMetric(metricsChunk[i])
 		if err != nil {
-			return logger.WrapError("call update metrics procedure", err)
+			return logger.WrapError("generate update metric request", err)
 		}
 
-		if response.Status != generated.Status_OK {
-			logger.ErrorFormat("Unexpected response status code: %s %s", response.Status, *response.Error)
-			return logger.WrapError(fmt.Sprintf("push metrics: %s %s", response.Status, *response.Error), metrics.ErrUnexpectedStatusCode)
-		}
+		requestMetrics[i] = requestMetric
+	}
 
-		for _, metric := range metricsChunk {
-			logger.InfoFormat("Pushed metric: %v. value: %v, status: %v", metric.GetName(), metric.GetStringValue(), response.Status)
-			metric.Flush()
-		}
+	request := &generated.MetricsRequest{
+		Metrics: requestMetrics,
+	}
+
+	response, err := g.client.UpdateValues(ctx, request)
+	if err != nil {
+		return logger.WrapError("call update metrics procedure", err)
+	}
+
+	if response.Status != generated.Status_OK {
+		log.ErrorFormat("Unexpected response status code: %s %s", response.Status, *response.Error)
+		return logger.WrapError(fmt.Sprintf("push metrics: %s %s", response.Status, *response.Error), metrics.ErrUnexpectedStatusCode)
+	}
+
+	for _, metric := range metricsChunk {
+		log.InfoFormat("Pushed metric: %v. value: %v, status: %v", metric.GetName(), metric.GetStringValue(), response.Status)
+		metric.Flush()
 	}
 
 	return nil
 }
+
+func endSpan(span *tracing.Span) {
+	err := span.End()
+	if err != nil {
+		logger.ErrorFormat("Failed to export span: %v", err)
+	}
+}
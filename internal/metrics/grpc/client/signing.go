@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+// signRequests attaches whole request signature to the outgoing metadata.
+// The request is serialized deterministically, so the server gets the same bytes re-serializing the received message.
+func signRequests(signer *hash.Signer) rpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *rpc.ClientConn, invoker rpc.UnaryInvoker, opts ...rpc.CallOption) error {
+		message, ok := req.(proto.Message)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		if err != nil {
+			return logger.WrapError("marshal request", err)
+		}
+
+		sign, signature, err := signer.SignRequest(method, body)
+		if err != nil {
+			return logger.WrapError("sign request", err)
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			hash.RequestSignHeader, signature,
+			hash.RequestTimestampHeader, sign.TimestampString(),
+			hash.RequestNonceHeader, sign.Nonce,
+		)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
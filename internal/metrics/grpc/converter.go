@@ -2,10 +2,12 @@ package grpc
 
 import (
 	"fmt"
+	"regexp"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/proto/generated"
 )
@@ -32,7 +34,8 @@ func NewMetricsConverter(conf MetricsConverterConfig, signer *hash.Signer) *Conv
 // ToModelMetric convert internal dsl metric to model metric.
 func (c *Converter) ToModelMetric(metric metrics.Metric) (*generated.Metric, error) {
 	modelMetric := &generated.Metric{
-		Name: metric.GetName(),
+		Name:   metric.GetName(),
+		Labels: metric.GetLabels().Map(),
 	}
 
 	metricType := metric.GetType()
@@ -45,6 +48,30 @@ func (c *Converter) ToModelMetric(metric metrics.Metric) (*generated.Metric, err
 	case "gauge":
 		modelMetric.Value = &metricValue
 		modelMetric.Type = generated.MetricType_GAUGE
+	case "histogram":
+		histogramMetric, ok := metric.(metrics.HistogramMetric)
+		if !ok {
+			return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", metricType), metrics.ErrUnknownMetricType)
+		}
+
+		histogram := histogramMetric.GetHistogram()
+		modelMetric.Buckets = histogram.Bounds
+		modelMetric.Counts = histogram.Counts
+		modelMetric.Sum = &histogram.Sum
+		modelMetric.Count = &histogram.Count
+		modelMetric.Type = generated.MetricType_HISTOGRAM
+	case "summary":
+		summaryMetric, ok := metric.(metrics.SummaryMetric)
+		if !ok {
+			return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", metricType), metrics.ErrUnknownMetricType)
+		}
+
+		summary := summaryMetric.GetSummary()
+		modelMetric.Observations = summaryMetric.GetObservations()
+		modelMetric.Quantiles = ToModelQuantiles(summaryMetric, metrics.DefaultQuantiles...)
+		modelMetric.Sum = &summary.Sum
+		modelMetric.Count = &summary.Count
+		modelMetric.Type = generated.MetricType_SUMMARY
 	default:
 		return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", metricType), metrics.ErrUnknownMetricType)
 	}
@@ -66,27 +93,58 @@ func (c *Converter) FromModelMetric(modelMetric *generated.Metric) (metrics.Metr
 	var metric metrics.Metric
 	var value float64
 
+	labels := metrics.LabelsFromMap(modelMetric.Labels)
+	err := metrics.ValidateSeries(modelMetric.Name, labels)
+	if err != nil {
+		return nil, logger.WrapError("convert metric series", err)
+	}
+
 	switch modelMetric.Type {
 	case generated.MetricType_COUNTER:
 		if modelMetric.Delta == nil {
 			return nil, logger.WrapError("convert metric", metrics.ErrMetricValueMissed)
 		}
 
-		metric = types.NewCounterMetric(modelMetric.Name)
+		metric = types.NewCounterMetric(modelMetric.Name, labels...)
 		value = float64(*modelMetric.Delta)
 	case generated.MetricType_GAUGE:
 		if modelMetric.Value == nil {
 			return nil, logger.WrapError("convert metric", metrics.ErrMetricValueMissed)
 		}
 
-		metric = types.NewGaugeMetric(modelMetric.Name)
+		metric = types.NewGaugeMetric(modelMetric.Name, labels...)
 		value = *modelMetric.Value
+	case generated.MetricType_HISTOGRAM:
+		if modelMetric.Sum == nil || modelMetric.Count == nil {
+			return nil, logger.WrapError("convert metric", metrics.ErrMetricValueMissed)
+		}
+
+		histogramMetric, err := types.RestoreHistogramMetric(modelMetric.Name, &metrics.Histogram{
+			Bounds: modelMetric.Buckets,
+			Counts: modelMetric.Counts,
+			Sum:    *modelMetric.Sum,
+			Count:  *modelMetric.Count,
+		}, labels...)
+		if err != nil {
+			return nil, logger.WrapError("convert metric", err)
+		}
+
+		metric = histogramMetric
+	case generated.MetricType_SUMMARY:
+		err := metrics.ValidateObservations(modelMetric.Observations)
+		if err != nil {
+			return nil, logger.WrapError("convert metric", err)
+		}
+
+		metric = types.NewSummaryMetricFromObservations(modelMetric.Name, modelMetric.Observations, labels...)
 	default:
 
 		return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", modelMetric.Type), metrics.ErrUnknownMetricType)
 	}
 
-	metric.SetValue(value)
+	if _, ok := metric.(metrics.MergeableMetric); !ok {
+		metric.SetValue(value)
+	}
 
 	if c.signMetrics && modelMetric.Hash != nil {
 		ok, err := c.signer.CheckSign(metric, modelMetric.Hash)
@@ -101,3 +159,75 @@ func (c *Converter) FromModelMetric(modelMetric *generated.Metric) (metrics.Metr
 
 	return metric, nil
 }
+
+// FromModelMetricType convert model metric type to internal dsl metric type name.
+func FromModelMetricType(metricType generated.MetricType) (string, error) {
+	switch metricType {
+	case generated.MetricType_COUNTER:
+		return "counter", nil
+	case generated.MetricType_GAUGE:
+		return "gauge", nil
+	case generated.MetricType_HISTOGRAM:
+		return "histogram", nil
+	case generated.MetricType_SUMMARY:
+		return "summary", nil
+	default:
+		return "", logger.WrapError(fmt.Sprintf("convert metric type %s", metricType), metrics.ErrUnknownMetricType)
+	}
+}
+
+// FromSeriesRequest converts series request to the series filter, missed type matches series of any type.
+func FromSeriesRequest(request *generated.SeriesRequest) (*metrics.SeriesFilter, error) {
+	filter := &metrics.SeriesFilter{
+		Name:       request.Name,
+		NamePrefix: request.Prefix,
+		Labels:     metrics.LabelsFromMap(request.Labels),
+	}
+
+	if request.Type != nil {
+		metricType, err := FromModelMetricType(*request.Type)
+		if err != nil {
+			return nil, logger.WrapError("convert series type", err)
+		}
+		filter.Type = metricType
+	}
+
+	if request.Regex != "" {
+		namePattern, err := regexp.Compile(request.Regex)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse regex '%s'", request.Regex), err)
+		}
+		filter.NamePattern = namePattern
+	}
+
+	return filter, nil
+}
+
+// ToModelQuantiles calculates summary metric quantile values.
+// Quantiles are not calculated for an empty summary.
+func ToModelQuantiles(metric metrics.SummaryMetric, quantiles ...float64) []*generated.Quantile {
+	if metric.GetSummary().Count == 0 {
+		return nil
+	}
+
+	result := make([]*generated.Quantile, len(quantiles))
+	for i, quantile := range quantiles {
+		result[i] = &generated.Quantile{
+			Quantile: quantile,
+			Value:    metric.GetQuantile(quantile),
+		}
+	}
+
+	return result
+}
+
+// ToModelAgent converts agent activity to the API model.
+func ToModelAgent(agent *agents.Agent) *generated.Agent {
+	return &generated.Agent{
+		Id:           agent.ID,
+		Source:       agent.Source,
+		LastSeen:     agent.LastSeen.UnixMilli(),
+		HeartbeatAge: agent.HeartbeatAge.Seconds(),
+		SeriesCount:  uint64(agent.SeriesCount),
+	}
+}
@@ -0,0 +1,8 @@
+package server
+
+import "errors"
+
+var (
+	ErrEncryptionNotSupported = errors.New("encrypted request is not supported, server private key is not configured")
+	ErrEncryptionRequired     = errors.New("encrypted request is required")
+)
@@ -2,19 +2,37 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
+	"time"
 
 	rpc "google.golang.org/grpc"
-
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcStatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/certs"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/html"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/server"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/proto/generated"
 )
 
 type GrpcServerConfig interface {
 	ListenTCP() string
+	GrpcCertPath() string
+	GrpcKeyPath() string
+	GrpcCAPath() string
 }
 
 type grpcServer struct {
@@ -22,17 +40,61 @@ type grpcServer struct {
 
 	listenTCP      string
 	converter      *grpc.Converter
+	decryptor      crypto.Decryptor
+	verifier       *hash.RequestVerifier
+	recorder       *telemetry.Recorder
 	requestHandler server.RequestHandler
 	server         *rpc.Server
 }
 
-func New(conf GrpcServerConfig, converter *grpc.Converter, requestHandler server.RequestHandler) *grpcServer {
-	return &grpcServer{
+type statusHolder interface {
+	GetStatus() generated.Status
+}
+
+var grpcTransportLabel = metrics.Label{Name: "transport", Value: "grpc"}
+
+// signedMethods change the server state, so their signatures are verified.
+var signedMethods = map[string]bool{
+	generated.MetricServer_UpdateValues_FullMethodName:  true,
+	generated.MetricServer_Remove_FullMethodName:        true,
+	generated.MetricServer_ResetCounters_FullMethodName: true,
+}
+
+func New(
+	conf GrpcServerConfig,
+	converter *grpc.Converter,
+	decryptor crypto.Decryptor,
+	verifier *hash.RequestVerifier,
+	recorder *telemetry.Recorder,
+	tracer *tracing.Tracer,
+	requestHandler server.RequestHandler,
+) (*grpcServer, error) {
+	result := &grpcServer{
 		listenTCP:      conf.ListenTCP(),
 		converter:      converter,
+		decryptor:      decryptor,
+		verifier:       verifier,
+		recorder:       recorder,
 		requestHandler: requestHandler,
-		server:         rpc.NewServer(),
 	}
+
+	options := []rpc.ServerOption{
+		rpc.ChainUnaryInterceptor(traceRequests(tracer), logRequests, observeRequests(recorder), result.verifyRequests, result.decryptRequests),
+	}
+
+	if conf.GrpcCertPath() != "" {
+		tlsConfig, err := certs.NewServerConfig(conf.GrpcCertPath(), conf.GrpcKeyPath(), conf.GrpcCAPath(), certs.HTTP2Protocol)
+		if err != nil {
+			return nil, logger.WrapError("create tls config", err)
+		}
+
+		options = append(options, rpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	result.server = rpc.NewServer(options...)
+	generated.RegisterMetricServerServer(result.server, result)
+
+	return result, nil
 }
 
 func (g *grpcServer) Start(_ context.Context) error {
@@ -40,7 +102,6 @@ func (g *grpcServer) Start(_ context.Context) error {
 	if err != nil {
 		return logger.WrapError("start listen TCP", err)
 	}
-	generated.RegisterMetricServerServer(g.server, g)
 
 	logger.Info("Start gRPC service")
 	err = g.server.Serve(listen)
@@ -59,21 +120,22 @@ func (g *grpcServer) Stop(ctx context.Context) error {
 
 func (g *grpcServer) GetValue(ctx context.Context, request *generated.MetricsRequest) (*generated.MetricsResponse, error) {
 	if request.Metrics == nil {
-		logger.Error("failed to get metric value: invalid request")
+		logger.FromContext(ctx).Error("failed to get metric value: invalid request")
 		return g.createMetricResponse(generated.Status_ERROR, nil, "invalid request"), nil
 	}
 
 	metricsCount := len(request.Metrics)
-	logger.InfoFormat("%d metrics get value request received", metricsCount)
+	logger.FromContext(ctx).InfoFormat("%d metrics get value request received", metricsCount)
 
 	responseMetrics := make([]*generated.Metric, metricsCount)
 	for i := 0; i < metricsCount; i++ {
 		metric, err := g.converter.FromModelMetric(request.Metrics[i])
 		if err != nil {
+			g.countSignatureFailure(ctx, err)
 			return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("convert metric request", err).Error()), nil
 		}
 
-		result, err := g.requestHandler.GetMetricValue(ctx, metric.GetType(), metric.GetName())
+		result, err := g.requestHandler.GetMetricValue(ctx, metric.GetType(), metric.GetName(), metric.GetLabels())
 		if err != nil {
 			return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("get metric value", err).Error()), nil
 		}
@@ -83,6 +145,20 @@ func (g *grpcServer) GetValue(ctx context.Context, request *generated.MetricsReq
 			return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("generate response", err).Error()), nil
 		}
 
+		if summaryMetric, ok := result.(metrics.SummaryMetric); ok && len(request.Metrics[i].Quantiles) > 0 {
+			quantiles := make([]float64, len(request.Metrics[i].Quantiles))
+			for j, quantile := range request.Metrics[i].Quantiles {
+				err = metrics.ValidateQuantile(quantile.Quantile)
+				if err != nil {
+					return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("get metric quantile", err).Error()), nil
+				}
+
+				quantiles[j] = quantile.Quantile
+			}
+
+			response.Quantiles = grpc.ToModelQuantiles(summaryMetric, quantiles...)
+		}
+
 		responseMetrics[i] = response
 	}
 
@@ -91,24 +167,25 @@ func (g *grpcServer) GetValue(ctx context.Context, request *generated.MetricsReq
 
 func (g *grpcServer) UpdateValues(ctx context.Context, request *generated.MetricsRequest) (*generated.MetricsResponse, error) {
 	if request.Metrics == nil {
-		logger.Error("failed to get metric value: invalid request")
+		logger.FromContext(ctx).Error("failed to get metric value: invalid request")
 		return g.createMetricResponse(generated.Status_ERROR, nil, "invalid request"), nil
 	}
 
 	metricsCount := len(request.Metrics)
-	logger.InfoFormat("%d metrics update value request received", metricsCount)
+	logger.FromContext(ctx).InfoFormat("%d metrics update value request received", metricsCount)
 
 	requestMetrics := make([]metrics.Metric, metricsCount)
 	for i := 0; i < metricsCount; i++ {
 		metric, err := g.converter.FromModelMetric(request.Metrics[i])
 		if err != nil {
+			g.countSignatureFailure(ctx, err)
 			return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("convert metric request", err).Error()), nil
 		}
 
 		requestMetrics[i] = metric
 	}
 
-	resultMetrics, err := g.requestHandler.UpdateMetricValues(ctx, requestMetrics)
+	resultMetrics, err := g.requestHandler.UpdateMetricValues(ctx, requestSource(ctx), requestMetrics)
 	if err != nil {
 		return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("update metrics", err).Error()), nil
 	}
@@ -126,6 +203,75 @@ func (g *grpcServer) UpdateValues(ctx context.Context, request *generated.Metric
 	return g.createMetricResponse(generated.Status_OK, responseMetrics, ""), nil
 }
 
+func (g *grpcServer) QueryRange(ctx context.Context, request *generated.RangeRequest) (*generated.RangeResponse, error) {
+	if request.Metric == nil {
+		logger.FromContext(ctx).Error("failed to query range: invalid request")
+		return g.createRangeResponse(generated.Status_ERROR, nil, "invalid request"), nil
+	}
+
+	metricType, err := grpc.FromModelMetricType(request.Metric.Type)
+	if err != nil {
+		return g.createRangeResponse(generated.Status_ERROR, nil, logger.WrapError("convert metric request", err).Error()), nil
+	}
+
+	labels := metrics.LabelsFromMap(request.Metric.Labels)
+	err = metrics.ValidateSeries(request.Metric.Name, labels)
+	if err != nil {
+		return g.createRangeResponse(generated.Status_ERROR, nil, logger.WrapError("convert metric request", err).Error()), nil
+	}
+
+	samples, err := g.requestHandler.QueryRange(
+		ctx,
+		metricType,
+		request.Metric.Name,
+		labels,
+		time.UnixMilli(request.Start),
+		time.UnixMilli(request.End),
+		time.Duration(request.Step)*time.Millisecond,
+	)
+	if err != nil {
+		return g.createRangeResponse(generated.Status_ERROR, nil, logger.WrapError("query range", err).Error()), nil
+	}
+
+	points := make([]*generated.Point, len(samples))
+	for i, sample := range samples {
+		points[i] = &generated.Point{
+			Timestamp: sample.Timestamp.UnixMilli(),
+			Value:     sample.Value,
+		}
+	}
+
+	return g.createRangeResponse(generated.Status_OK, points, ""), nil
+}
+
+func (g *grpcServer) Remove(ctx context.Context, request *generated.SeriesRequest) (*generated.CountResponse, error) {
+	filter, err := grpc.FromSeriesRequest(request)
+	if err != nil {
+		return g.createCountResponse(generated.Status_ERROR, 0, logger.WrapError("convert series request", err).Error()), nil
+	}
+
+	removed, err := g.requestHandler.RemoveMetrics(ctx, filter)
+	if err != nil {
+		return g.createCountResponse(generated.Status_ERROR, 0, logger.WrapError("remove metrics", err).Error()), nil
+	}
+
+	return g.createCountResponse(generated.Status_OK, removed, ""), nil
+}
+
+func (g *grpcServer) ResetCounters(ctx context.Context, request *generated.SeriesRequest) (*generated.CountResponse, error) {
+	filter, err := grpc.FromSeriesRequest(request)
+	if err != nil {
+		return g.createCountResponse(generated.Status_ERROR, 0, logger.WrapError("convert series request", err).Error()), nil
+	}
+
+	reset, err := g.requestHandler.ResetCounters(ctx, filter)
+	if err != nil {
+		return g.createCountResponse(generated.Status_ERROR, 0, logger.WrapError("reset counters", err).Error()), nil
+	}
+
+	return g.createCountResponse(generated.Status_OK, reset, ""), nil
+}
+
 func (g *grpcServer) Ping(ctx context.Context, _ *generated.Nothing) (*generated.Response, error) {
 	err := g.requestHandler.Ping(ctx)
 	if err != nil {
@@ -136,7 +282,7 @@ func (g *grpcServer) Ping(ctx context.Context, _ *generated.Nothing) (*generated
 }
 
 func (g *grpcServer) Report(ctx context.Context, _ *generated.Nothing) (*generated.ReportResponse, error) {
-	report, err := g.requestHandler.GetReportPage(ctx)
+	report, err := g.requestHandler.GetReportPage(ctx, html.ContentType)
 	if err != nil {
 		return g.createReportResponse(generated.Status_ERROR, "", logger.WrapError("ping database", err).Error()), nil
 	}
@@ -144,6 +290,26 @@ func (g *grpcServer) Report(ctx context.Context, _ *generated.Nothing) (*generat
 	return g.createReportResponse(generated.Status_OK, report, ""), nil
 }
 
+func (g *grpcServer) Agents(ctx context.Context, _ *generated.Nothing) (*generated.AgentsResponse, error) {
+	agentsList, err := g.requestHandler.GetAgents(ctx)
+	if err != nil {
+		return g.createAgentsResponse(generated.Status_ERROR, nil, logger.WrapError("get agents", err).Error()), nil
+	}
+
+	result := make([]*generated.Agent, len(agentsList))
+	for i, agent := range agentsList {
+		result[i] = grpc.ToModelAgent(agent)
+	}
+
+	return g.createAgentsResponse(generated.Status_OK, result, ""), nil
+}
+
+func (g *grpcServer) countSignatureFailure(ctx context.Context, err error) {
+	if errors.Is(err, metrics.ErrInvalidSignature) {
+		g.recorder.Count(ctx, telemetry.SignatureFailures, grpcTransportLabel)
+	}
+}
+
 func (g *grpcServer) createMetricResponse(status generated.Status, metricsResult []*generated.Metric, errorMessage string) *generated.MetricsResponse {
 	response := &generated.MetricsResponse{
 		Status: status,
@@ -180,3 +346,224 @@ func (g *grpcServer) createReportResponse(status generated.Status, report string
 
 	return response
 }
+
+func (g *grpcServer) createRangeResponse(status generated.Status, points []*generated.Point, errorMessage string) *generated.RangeResponse {
+	response := &generated.RangeResponse{
+		Status: status,
+		Points: points,
+	}
+	if errorMessage != "" {
+		response.Error = &errorMessage
+	}
+
+	return response
+}
+
+func (g *grpcServer) createAgentsResponse(status generated.Status, agentsResult []*generated.Agent, errorMessage string) *generated.AgentsResponse {
+	response := &generated.AgentsResponse{
+		Status: status,
+		Agents: agentsResult,
+	}
+	if errorMessage != "" {
+		response.Error = &errorMessage
+	}
+
+	return response
+}
+
+func (g *grpcServer) createCountResponse(status generated.Status, count int, errorMessage string) *generated.CountResponse {
+	response := &generated.CountResponse{
+		Status: status,
+		Count:  uint64(count),
+	}
+	if errorMessage != "" {
+		response.Error = &errorMessage
+	}
+
+	return response
+}
+
+func requestSource(ctx context.Context) agents.Source {
+	source := agents.Source{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(agents.AgentIDHeader); len(values) > 0 {
+			source.AgentID = values[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		source.Address = p.Addr.String()
+		if host, _, err := net.SplitHostPort(source.Address); err == nil {
+			source.Address = host
+		}
+
+		// verified client certificate identifies the agent over the declared id
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			if commonName := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName; commonName != "" {
+				source.AgentID = commonName
+			}
+		}
+	}
+
+	return source
+}
+
+func incomingValue(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// logRequests attaches agent address to the request log fields and logs handled requests.
+func logRequests(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	source := requestSource(ctx)
+	fields := []logger.Field{{Key: "agent_ip", Value: source.Address}}
+	if source.AgentID != "" {
+		fields = append(fields, logger.Field{Key: "agent_id", Value: source.AgentID})
+	}
+
+	ctx = logger.WithFields(ctx, fields...)
+	resp, err := handler(ctx, req)
+
+	message := fmt.Sprintf("%s - %s in %v", info.FullMethod, grpcStatus.Code(err), time.Since(start))
+	if err != nil {
+		logger.FromContext(ctx).Error(message)
+	} else {
+		logger.FromContext(ctx).Info(message)
+	}
+
+	return resp, err
+}
+
+// traceRequests continues the agent trace passed in the traceparent metadata or starts a new one.
+// Trace id is attached to the request log fields and returned in the x-request-id header metadata.
+func traceRequests(tracer *tracing.Tracer) rpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+		ctx, span := tracer.StartRemote(ctx, "grpc_request", incomingValue(ctx, tracing.TraceparentHeader))
+		spanContext := span.Context()
+		ctx = logger.WithFields(ctx,
+			logger.Field{Key: "request_id", Value: spanContext.TraceID},
+			logger.Field{Key: "span_id", Value: spanContext.SpanID},
+		)
+
+		err := rpc.SetHeader(ctx, metadata.Pairs(
+			tracing.RequestIDHeader, spanContext.TraceID,
+			tracing.TraceparentHeader, spanContext.Traceparent(),
+		))
+		if err != nil {
+			logger.FromContext(ctx).ErrorFormat("Failed to set response header: %v", err)
+		}
+
+		resp, err := handler(ctx, req)
+
+		span.SetAttribute("method", info.FullMethod)
+		if holder, ok := resp.(statusHolder); err == nil && ok {
+			span.SetAttribute("status", holder.GetStatus().String())
+			if holder.GetStatus() != generated.Status_OK {
+				span.SetError(fmt.Errorf("%w: %s", metrics.ErrUnexpectedStatusCode, holder.GetStatus()))
+			}
+		} else {
+			span.SetAttribute("status", grpcStatus.Code(err).String())
+			span.SetError(err)
+		}
+
+		endErr := span.End()
+		if endErr != nil {
+			logger.FromContext(ctx).ErrorFormat("Failed to export span: %v", endErr)
+		}
+
+		return resp, err
+	}
+}
+
+// verifyRequests validates whole request signature of the state changing requests before the request is decrypted.
+func (g *grpcServer) verifyRequests(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+	message, ok := req.(proto.Message)
+	if g.verifier == nil || !ok || !signedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return g.createErrorResponse(req, logger.WrapError("marshal request", err).Error()), nil
+	}
+
+	err = g.verifier.Verify(info.FullMethod, body,
+		incomingValue(ctx, hash.RequestSignHeader),
+		incomingValue(ctx, hash.RequestTimestampHeader),
+		incomingValue(ctx, hash.RequestNonceHeader))
+	if err != nil {
+		g.recorder.Count(ctx, telemetry.RejectedRequests, grpcTransportLabel, metrics.Label{Name: "reason", Value: hash.RejectReason(err)})
+		return g.createErrorResponse(req, logger.WrapError("verify request", err).Error()), nil
+	}
+
+	return handler(ctx, req)
+}
+
+// createErrorResponse creates failed response of the method accepting the request.
+func (g *grpcServer) createErrorResponse(req interface{}, errorMessage string) interface{} {
+	if _, ok := req.(*generated.SeriesRequest); ok {
+		return g.createCountResponse(generated.Status_ERROR, 0, errorMessage)
+	}
+
+	return g.createMetricResponse(generated.Status_ERROR, nil, errorMessage)
+}
+
+// decryptRequests replaces encrypted metrics requests with the decrypted ones.
+// Plain metrics requests are rejected once the server has a private key, the same as http requests.
+func (g *grpcServer) decryptRequests(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+	request, ok := req.(*generated.MetricsRequest)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	if g.decryptor == nil {
+		if len(request.Encrypted) != 0 {
+			return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("decrypt request", ErrEncryptionNotSupported).Error()), nil
+		}
+
+		return handler(ctx, req)
+	}
+
+	if len(request.Encrypted) == 0 {
+		return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("decrypt request", ErrEncryptionRequired).Error()), nil
+	}
+
+	decrypted, err := g.decryptor.Decrypt(request.Encrypted)
+	if err != nil {
+		g.recorder.Count(ctx, telemetry.DecryptFailures, grpcTransportLabel)
+		return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("decrypt request", err).Error()), nil
+	}
+
+	decryptedRequest := &generated.MetricsRequest{}
+	err = proto.Unmarshal(decrypted, decryptedRequest)
+	if err != nil {
+		g.recorder.Count(ctx, telemetry.DecryptFailures, grpcTransportLabel)
+		return g.createMetricResponse(generated.Status_ERROR, nil, logger.WrapError("unmarshal decrypted request", err).Error()), nil
+	}
+
+	return handler(ctx, decryptedRequest)
+}
+
+// observeRequests records request count and latency by method, requests are counted by the response status.
+func observeRequests(recorder *telemetry.Recorder) rpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *rpc.UnaryServerInfo, handler rpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		responseStatus := grpcStatus.Code(err).String()
+		if holder, ok := resp.(statusHolder); err == nil && ok {
+			responseStatus = holder.GetStatus().String()
+		}
+
+		methodLabel := metrics.Label{Name: "method", Value: info.FullMethod}
+		recorder.Count(ctx, telemetry.GrpcRequests, methodLabel, metrics.Label{Name: "status", Value: responseStatus})
+		recorder.ObserveDuration(ctx, telemetry.GrpcRequestDuration, start, methodLabel)
+		return resp, err
+	}
+}
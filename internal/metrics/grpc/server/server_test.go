@@ -0,0 +1,538 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/rsa"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/memory"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/grpc/client"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/server/handler"
+	memoryStorage "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/memory"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/proto/generated"
+)
+
+type testConf struct {
+	agentID   string
+	serverURL string
+	certPath  string
+	keyPath   string
+	caPath    string
+	key       []byte
+	required  bool
+}
+
+func TestGrpcServer_TLS(t *testing.T) {
+	tests := []struct {
+		name            string
+		serverTLS       bool
+		mutual          bool
+		agentTLS        bool
+		agentCert       bool
+		expectedError   bool
+		expectedAgentID string
+	}{
+		{
+			name:            "insecure",
+			expectedAgentID: "declared",
+		},
+		{
+			name:            "tls",
+			serverTLS:       true,
+			agentTLS:        true,
+			expectedAgentID: "declared",
+		},
+		{
+			name:            "mutual_tls",
+			serverTLS:       true,
+			mutual:          true,
+			agentTLS:        true,
+			agentCert:       true,
+			expectedAgentID: "agent1",
+		},
+		{
+			name:          "mutual_tls_without_agent_certificate",
+			serverTLS:     true,
+			mutual:        true,
+			agentTLS:      true,
+			expectedError: true,
+		},
+		{
+			name:          "insecure_agent",
+			serverTLS:     true,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dir := t.TempDir()
+			authority := test.NewAuthority(t, dir, "ca")
+
+			serverConf := &testConf{}
+			if tt.serverTLS {
+				serverConf.certPath, serverConf.keyPath = authority.Issue(t, "server", "127.0.0.1")
+			}
+			if tt.mutual {
+				serverConf.caPath = authority.CertPath
+			}
+
+			registry := agents.NewRegistry()
+			requestHandler := handler.NewHandler(memory.NewInMemoryDataBase(), memoryStorage.NewInMemoryStorage(),
+				telemetry.NewRecorder(memoryStorage.NewInMemoryStorage()), registry)
+			server, err := New(serverConf, grpc.NewMetricsConverter(serverConf, hash.NewSigner(serverConf)), nil, nil,
+				telemetry.NewRecorder(memoryStorage.NewInMemoryStorage()), tracing.NewTracer(nil), requestHandler)
+			require.NoError(t, err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go server.server.Serve(listener)
+			defer server.server.Stop()
+
+			agentConf := &testConf{
+				agentID:   "declared",
+				serverURL: listener.Addr().String(),
+			}
+			if tt.agentTLS {
+				agentConf.caPath = authority.CertPath
+			}
+			if tt.agentCert {
+				agentConf.certPath, agentConf.keyPath = authority.Issue(t, "agent1")
+			}
+
+			pusher, err := client.NewPusher(agentConf, grpc.NewMetricsConverter(agentConf, hash.NewSigner(agentConf)), nil, nil, tracing.NewTracer(nil))
+			require.NoError(t, err)
+
+			err = pusher.Push(ctx, test.ArrayToChan([]metrics.Metric{test.CreateCounterMetric("counter", 1)}))
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Empty(t, registry.GetAgents())
+				return
+			}
+
+			require.NoError(t, err)
+			agentsList := registry.GetAgents()
+			require.Len(t, agentsList, 1)
+			assert.Equal(t, tt.expectedAgentID, agentsList[0].ID)
+		})
+	}
+}
+
+func TestGrpcServer_Encryption(t *testing.T) {
+	tests := []struct {
+		name          string
+		agentKey      bool
+		serverKey     bool
+		otherKey      bool
+		expectedError error
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:      "encrypted",
+			agentKey:  true,
+			serverKey: true,
+		},
+		{
+			name:          "plain_request_to_encrypted_server",
+			serverKey:     true,
+			expectedError: ErrEncryptionRequired,
+		},
+		{
+			name:          "encrypted_request_to_plain_server",
+			agentKey:      true,
+			expectedError: ErrEncryptionNotSupported,
+		},
+		{
+			name:          "unknown_key",
+			agentKey:      true,
+			serverKey:     true,
+			otherKey:      true,
+			expectedError: crypto.ErrUnknownKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			dir := t.TempDir()
+			publicKeyPath, privateKeyPath := test.NewRSAKeyPair(t, dir, "server")
+			if tt.otherKey {
+				_, privateKeyPath = test.NewRSAKeyPair(t, dir, "other")
+			}
+
+			var decryptor crypto.Decryptor
+			if tt.serverKey {
+				var err error
+				decryptor, err = rsa.NewDecryptor(privateKeyPath)
+				require.NoError(t, err)
+			}
+
+			var encryptor crypto.Encryptor
+			if tt.agentKey {
+				var err error
+				encryptor, err = rsa.NewEncryptor(publicKeyPath)
+				require.NoError(t, err)
+			}
+
+			metricsStorage := memoryStorage.NewInMemoryStorage()
+			serverConf := &testConf{}
+			recorder := telemetry.NewRecorder(memoryStorage.NewInMemoryStorage())
+			requestHandler := handler.NewHandler(memory.NewInMemoryDataBase(), metricsStorage, recorder, agents.NewRegistry())
+			server, err := New(serverConf, grpc.NewMetricsConverter(serverConf, hash.NewSigner(serverConf)), decryptor, nil,
+				recorder, tracing.NewTracer(nil), requestHandler)
+			require.NoError(t, err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go server.server.Serve(listener)
+			defer server.server.Stop()
+
+			agentConf := &testConf{serverURL: listener.Addr().String()}
+			pusher, err := client.NewPusher(agentConf, grpc.NewMetricsConverter(agentConf, hash.NewSigner(agentConf)), encryptor, nil, tracing.NewTracer(nil))
+			require.NoError(t, err)
+
+			err = pusher.Push(ctx, test.ArrayToChan([]metrics.Metric{test.CreateCounterMetric("counter", 5)}))
+			if tt.expectedError != nil {
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			actual, err := metricsStorage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]map[string]string{"counter": {"counter": "5"}}, actual)
+		})
+	}
+}
+
+func TestGrpcServer_RequestSign(t *testing.T) {
+	tests := []struct {
+		name          string
+		agentKey      string
+		serverKey     string
+		required      bool
+		expectedError error
+	}{
+		{
+			name:      "signed_request",
+			agentKey:  "test secret key",
+			serverKey: "test secret key",
+		},
+		{
+			name:      "unsigned_request",
+			serverKey: "test secret key",
+		},
+		{
+			name:          "unsigned_request_required",
+			serverKey:     "test secret key",
+			required:      true,
+			expectedError: hash.ErrMissedRequestSign,
+		},
+		{
+			name:          "other_key",
+			agentKey:      "other secret key",
+			serverKey:     "test secret key",
+			expectedError: hash.ErrInvalidRequestSign,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			metricsStorage := memoryStorage.NewInMemoryStorage()
+			serverConf := &testConf{key: []byte(tt.serverKey), required: tt.required}
+			serverSigner := hash.NewSigner(serverConf)
+			recorder := telemetry.NewRecorder(memoryStorage.NewInMemoryStorage())
+			requestHandler := handler.NewHandler(memory.NewInMemoryDataBase(), metricsStorage, recorder, agents.NewRegistry())
+			server, err := New(serverConf, grpc.NewMetricsConverter(serverConf, serverSigner), nil, hash.NewRequestVerifier(serverConf, serverSigner),
+				recorder, tracing.NewTracer(nil), requestHandler)
+			require.NoError(t, err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go server.server.Serve(listener)
+			defer server.server.Stop()
+
+			agentConf := &testConf{serverURL: listener.Addr().String(), key: []byte(tt.agentKey)}
+			agentSigner := hash.NewSigner(agentConf)
+			var requestSigner *hash.Signer
+			if tt.agentKey != "" {
+				requestSigner = agentSigner
+			}
+			pusher, err := client.NewPusher(agentConf, grpc.NewMetricsConverter(agentConf, agentSigner), nil, requestSigner, tracing.NewTracer(nil))
+			require.NoError(t, err)
+
+			err = pusher.Push(ctx, test.ArrayToChan([]metrics.Metric{test.CreateCounterMetric("counter", 5)}))
+			if tt.expectedError != nil {
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			actual, err := metricsStorage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]map[string]string{"counter": {"counter": "5"}}, actual)
+		})
+	}
+}
+
+func TestGrpcServer_SignedMaintenanceRequests(t *testing.T) {
+	counterType := generated.MetricType_COUNTER
+	tests := []struct {
+		name           string
+		method         string
+		signed         bool
+		replay         bool
+		expectedError  error
+		expectedValues map[string]string
+	}{
+		{
+			name:           "unsigned_remove",
+			method:         generated.MetricServer_Remove_FullMethodName,
+			expectedError:  hash.ErrMissedRequestSign,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "unsigned_reset",
+			method:         generated.MetricServer_ResetCounters_FullMethodName,
+			expectedError:  hash.ErrMissedRequestSign,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "signed_remove",
+			method:         generated.MetricServer_Remove_FullMethodName,
+			signed:         true,
+			expectedValues: map[string]string{"errors": "5"},
+		},
+		{
+			name:           "signed_reset",
+			method:         generated.MetricServer_ResetCounters_FullMethodName,
+			signed:         true,
+			expectedValues: map[string]string{"requests": "0", "errors": "5"},
+		},
+		{
+			name:           "replayed_remove",
+			method:         generated.MetricServer_Remove_FullMethodName,
+			signed:         true,
+			replay:         true,
+			expectedError:  hash.ErrReplayedRequest,
+			expectedValues: map[string]string{"errors": "5"},
+		},
+		{
+			name:           "replayed_reset",
+			method:         generated.MetricServer_ResetCounters_FullMethodName,
+			signed:         true,
+			replay:         true,
+			expectedError:  hash.ErrReplayedRequest,
+			expectedValues: map[string]string{"requests": "0", "errors": "5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			metricsStorage := memoryStorage.NewInMemoryStorage()
+			_, err := metricsStorage.AddMetricValues(ctx, []metrics.Metric{
+				test.CreateCounterMetric("requests", 10),
+				test.CreateCounterMetric("errors", 5),
+			})
+			require.NoError(t, err)
+
+			serverConf := &testConf{key: []byte("test secret key"), required: true}
+			signer := hash.NewSigner(serverConf)
+			recorder := telemetry.NewRecorder(memoryStorage.NewInMemoryStorage())
+			requestHandler := handler.NewHandler(memory.NewInMemoryDataBase(), metricsStorage, recorder, agents.NewRegistry())
+			server, err := New(serverConf, grpc.NewMetricsConverter(serverConf, signer), nil, hash.NewRequestVerifier(serverConf, signer),
+				recorder, tracing.NewTracer(nil), requestHandler)
+			require.NoError(t, err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go server.server.Serve(listener)
+			defer server.server.Stop()
+
+			connection, err := rpc.Dial(listener.Addr().String(), rpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer connection.Close()
+
+			request := &generated.SeriesRequest{Type: &counterType, Name: "requests"}
+			callCtx := ctx
+			if tt.signed {
+				body, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+				require.NoError(t, err)
+				sign, signature, err := signer.SignRequest(tt.method, body)
+				require.NoError(t, err)
+				callCtx = metadata.AppendToOutgoingContext(ctx,
+					hash.RequestSignHeader, signature,
+					hash.RequestTimestampHeader, sign.TimestampString(),
+					hash.RequestNonceHeader, sign.Nonce,
+				)
+			}
+
+			call := func() *generated.CountResponse {
+				response := &generated.CountResponse{}
+				require.NoError(t, connection.Invoke(callCtx, tt.method, request, response))
+				return response
+			}
+
+			if tt.replay {
+				require.Equal(t, generated.Status_OK, call().Status)
+			}
+
+			response := call()
+			if tt.expectedError != nil {
+				assert.Equal(t, generated.Status_ERROR, response.Status)
+				assert.Contains(t, response.GetError(), tt.expectedError.Error())
+			} else {
+				assert.Equal(t, generated.Status_OK, response.Status)
+				assert.Equal(t, uint64(1), response.Count)
+			}
+
+			values, err := metricsStorage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, values["counter"])
+		})
+	}
+}
+
+func TestGrpcServer_SummaryObservations(t *testing.T) {
+	tests := []struct {
+		name           string
+		observations   []float64
+		expectedError  error
+		expectedValues map[string]map[string]string
+	}{
+		{
+			name:         "finite_observations",
+			observations: []float64{1, 2},
+			expectedValues: map[string]map[string]string{"summary": {
+				"duration": types.NewSummaryMetricFromObservations("duration", []float64{1, 2}).GetStringValue(),
+			}},
+		},
+		{
+			name:           "nan_observation",
+			observations:   []float64{1, math.NaN()},
+			expectedError:  metrics.ErrInvalidObservation,
+			expectedValues: map[string]map[string]string{},
+		},
+		{
+			name:           "infinite_observation",
+			observations:   []float64{math.Inf(-1), 2},
+			expectedError:  metrics.ErrInvalidObservation,
+			expectedValues: map[string]map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			metricsStorage := memoryStorage.NewInMemoryStorage()
+			serverConf := &testConf{}
+			recorder := telemetry.NewRecorder(memoryStorage.NewInMemoryStorage())
+			requestHandler := handler.NewHandler(memory.NewInMemoryDataBase(), metricsStorage, recorder, agents.NewRegistry())
+			server, err := New(serverConf, grpc.NewMetricsConverter(serverConf, hash.NewSigner(serverConf)), nil, nil,
+				recorder, tracing.NewTracer(nil), requestHandler)
+			require.NoError(t, err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			go server.server.Serve(listener)
+			defer server.server.Stop()
+
+			connection, err := rpc.Dial(listener.Addr().String(), rpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer connection.Close()
+
+			response, err := generated.NewMetricServerClient(connection).UpdateValues(ctx, &generated.MetricsRequest{
+				Metrics: []*generated.Metric{{Name: "duration", Type: generated.MetricType_SUMMARY, Observations: tt.observations}},
+			})
+			require.NoError(t, err)
+			if tt.expectedError != nil {
+				assert.Equal(t, generated.Status_ERROR, response.Status)
+				assert.Contains(t, response.GetError(), tt.expectedError.Error())
+			} else {
+				assert.Equal(t, generated.Status_OK, response.Status)
+			}
+
+			actual, err := metricsStorage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, actual)
+		})
+	}
+}
+
+func (c *testConf) AgentID() string {
+	return c.agentID
+}
+
+func (c *testConf) GrpcServerURL() string {
+	return c.serverURL
+}
+
+func (c *testConf) ListenTCP() string {
+	return c.serverURL
+}
+
+func (c *testConf) GrpcCertPath() string {
+	return c.certPath
+}
+
+func (c *testConf) GrpcKeyPath() string {
+	return c.keyPath
+}
+
+func (c *testConf) GrpcCAPath() string {
+	return c.caPath
+}
+
+func (c *testConf) SignMetrics() bool {
+	return false
+}
+
+func (c *testConf) GetKey() []byte {
+	return c.key
+}
+
+func (c *testConf) RequireRequestSign() bool {
+	return c.required
+}
+
+func (c *testConf) RequestMaxSkew() time.Duration {
+	return time.Minute
+}
+
+func (c *testConf) NonceCacheSize() int {
+	return 100
+}
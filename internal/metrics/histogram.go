@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Histogram is a state of histogram metric.
+type Histogram struct {
+	// Bounds contains sorted bucket upper bounds, +Inf bucket is implicit.
+	Bounds []float64 `json:"bounds"`
+	// Counts contains cumulative observation counts for every bucket in Bounds.
+	Counts []uint64 `json:"counts"`
+	// Sum is a sum of all observed values.
+	Sum float64 `json:"sum"`
+	// Count is a total observations count.
+	Count uint64 `json:"count"`
+}
+
+// HistogramMetric samples observations into configurable buckets.
+type HistogramMetric interface {
+	Metric
+	MergeableMetric
+
+	// GetHistogram returns histogram state snapshot.
+	GetHistogram() *Histogram
+}
+
+// ParseHistogram restores histogram state from string representation.
+func ParseHistogram(str string) (*Histogram, error) {
+	histogram := &Histogram{}
+	err := json.Unmarshal([]byte(str), histogram)
+	if err != nil {
+		return nil, err
+	}
+
+	err = histogram.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return histogram, nil
+}
+
+// String returns histogram state string representation.
+func (h *Histogram) String() string {
+	result, err := json.Marshal(h)
+	if err != nil {
+		return ""
+	}
+
+	return string(result)
+}
+
+// Validate checks histogram state consistency.
+func (h *Histogram) Validate() error {
+	err := ValidateBounds(h.Bounds)
+	if err != nil {
+		return err
+	}
+
+	if len(h.Counts) != len(h.Bounds) {
+		return ErrInvalidHistogram
+	}
+
+	var previous uint64
+	for _, count := range h.Counts {
+		if count < previous {
+			return ErrInvalidHistogram
+		}
+		previous = count
+	}
+
+	if previous > h.Count {
+		return ErrInvalidHistogram
+	}
+
+	return nil
+}
+
+// ValidateBounds checks that bucket bounds are finite and sorted in ascending order.
+func ValidateBounds(bounds []float64) error {
+	for i, bound := range bounds {
+		if math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return ErrInvalidHistogramBounds
+		}
+
+		if i > 0 && bounds[i-1] >= bound {
+			return ErrInvalidHistogramBounds
+		}
+	}
+
+	return nil
+}
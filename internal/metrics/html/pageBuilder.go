@@ -1,7 +1,13 @@
 package html
 
+// ContentType is a media type of html report page.
+const ContentType = "text/html"
+
 // PageBuilder is a provider of served metrics report.
 type PageBuilder interface {
 	// BuildMetricsPage build and return served metrics report.
 	BuildMetricsPage(metricsByType map[string]map[string]string) string
+
+	// ContentType returns media type of built report.
+	ContentType() string
 }
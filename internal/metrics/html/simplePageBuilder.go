@@ -2,6 +2,7 @@ package html
 
 import (
 	"fmt"
+	"html"
 	"sort"
 	"strings"
 )
@@ -35,10 +36,15 @@ func (s simplePageBuilder) BuildMetricsPage(metricsByType map[string]map[string]
 		sort.Strings(metricNames)
 
 		for _, metricName := range metricNames {
-			sb.WriteString(fmt.Sprintf("%v: %v<br>", metricName, metricsList[metricName]))
+			// series keys contain agent provided label values
+			sb.WriteString(fmt.Sprintf("%v: %v<br>", html.EscapeString(metricName), html.EscapeString(metricsList[metricName])))
 		}
 	}
 
 	sb.WriteString("</html>")
 	return sb.String()
 }
+
+func (s simplePageBuilder) ContentType() string {
+	return ContentType
+}
@@ -41,6 +41,15 @@ func TestSimplePageBuilder_BuildMetricsPage(t *testing.T) {
 				"metricName5: 300.003<br>" +
 				"metricName6: -400.004<br>" +
 				"</html>",
+		}, {
+			name:           "labeled_metric",
+			counterMetrics: map[string]string{},
+			gaugeMetrics: map[string]string{
+				`CPUutilization{cpu="<1>"}`: "10",
+			},
+			expected: "<html>" +
+				"CPUutilization{cpu=&#34;&lt;1&gt;&#34;}: 10<br>" +
+				"</html>",
 		},
 	}
 
@@ -9,37 +9,66 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/certs"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	metricsHttp "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/model"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/pusher"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 )
 
 type metricsPusherConfig interface {
+	AgentID() string
 	ParallelLimit() int
 	MetricsServerURL() string
 	PushMetricsTimeout() time.Duration
+	HTTPCertPath() string
+	HTTPKeyPath() string
+	HTTPCAPath() string
 }
 
+const (
+	httpScheme  = "http"
+	httpsScheme = "https"
+)
+
 type httpMetricsPusher struct {
 	converter        *metricsHttp.Converter
 	client           http.Client
 	encryptor        crypto.Encryptor
+	requestSigner    *hash.Signer
+	tracer           *tracing.Tracer
 	metricsServerURL string
 	clientIP         string
+	agentID          string
 	parallelLimit    int
 	pushTimeout      time.Duration
 }
 
 // NewPusher create new instance of http metrics pusher.
-func NewPusher(config metricsPusherConfig, converter *metricsHttp.Converter, encryptor crypto.Encryptor) (pusher.MetricsPusher, error) {
-	serverURL, err := normalizeURL(config.MetricsServerURL())
+func NewPusher(
+	config metricsPusherConfig,
+	converter *metricsHttp.Converter,
+	encryptor crypto.Encryptor,
+	requestSigner *hash.Signer,
+	tracer *tracing.Tracer,
+) (pusher.MetricsPusher, error) {
+	// https is used by default once any certificate is configured
+	defaultScheme := httpScheme
+	if config.HTTPCAPath() != "" || config.HTTPCertPath() != "" {
+		defaultScheme = httpsScheme
+	}
+
+	serverURL, err := normalizeURL(config.MetricsServerURL(), defaultScheme)
 	if err != nil {
 		return nil, logger.WrapError("normalize url", err)
 	}
@@ -48,12 +77,28 @@ func NewPusher(config metricsPusherConfig, converter *metricsHttp.Converter, enc
 		return nil, logger.WrapError("get client ip", err)
 	}
 
+	client := http.Client{}
+	if serverURL.Scheme == httpsScheme {
+		tlsConfig, err := certs.NewClientConfig(config.HTTPCertPath(), config.HTTPKeyPath(), config.HTTPCAPath())
+		if err != nil {
+			return nil, logger.WrapError("create tls config", err)
+		}
+
+		client.Transport = &http.Transport{
+			TLSClientConfig:   tlsConfig,
+			ForceAttemptHTTP2: true,
+		}
+	}
+
 	return &httpMetricsPusher{
 		parallelLimit:    config.ParallelLimit(),
-		client:           http.Client{},
+		client:           client,
 		encryptor:        encryptor,
+		requestSigner:    requestSigner,
+		tracer:           tracer,
 		metricsServerURL: serverURL.String(),
 		clientIP:         clientIP.String(),
+		agentID:          config.AgentID(),
 		pushTimeout:      config.PushMetricsTimeout(),
 		converter:        converter,
 	}, nil
@@ -85,12 +130,26 @@ func (p *httpMetricsPusher) Push(ctx context.Context, metricsChan <-chan metrics
 	return eg.Wait()
 }
 
-func (p *httpMetricsPusher) pushMetrics(ctx context.Context, metricsList []metrics.Metric) error {
+func (p *httpMetricsPusher) pushMetrics(ctx context.Context, metricsList []metrics.Metric) (err error) {
+	ctx, span := p.tracer.Start(ctx, "http_push_metrics")
+	defer func() {
+		span.SetError(err)
+		endSpan(span)
+	}()
+
+	spanContext := span.Context()
+	ctx = logger.WithFields(ctx,
+		logger.Field{Key: "request_id", Value: spanContext.TraceID},
+		logger.Field{Key: "span_id", Value: spanContext.SpanID},
+	)
+	log := logger.FromContext(ctx)
+
 	metricsCount := len(metricsList)
 	if metricsCount == 0 {
-		logger.Info("Nothing to push")
+		log.Info("Nothing to push")
 	}
-	logger.InfoFormat("Push %v metrics", metricsCount)
+	log.InfoFormat("Push %v metrics", metricsCount)
+	span.SetAttribute("metrics_count", strconv.Itoa(metricsCount))
 
 	pushCtx, cancel := context.WithTimeout(ctx, p.pushTimeout)
 	defer cancel()
@@ -106,7 +165,7 @@ func (p *httpMetricsPusher) pushMetrics(ctx context.Context, metricsList []metri
 	}
 
 	buffer := &bytes.Buffer{}
-	err := json.NewEncoder(buffer).Encode(modelMetrics)
+	err = json.NewEncoder(buffer).Encode(modelMetrics)
 	if err != nil {
 		return logger.WrapError("serialize model request", err)
 	}
@@ -120,12 +179,27 @@ func (p *httpMetricsPusher) pushMetrics(ctx context.Context, metricsList []metri
 		buffer = bytes.NewBuffer(encrypted)
 	}
 
+	body := buffer.Bytes()
 	request, err := http.NewRequestWithContext(pushCtx, http.MethodPost, p.metricsServerURL+"/updates", buffer)
 	if err != nil {
 		return logger.WrapError("create push request", err)
 	}
 	request.Header.Add("Content-Type", "application/json")
 	request.Header.Add("X-Real-IP", p.clientIP)
+	request.Header.Add(tracing.TraceparentHeader, spanContext.Traceparent())
+	if p.agentID != "" {
+		request.Header.Add(agents.AgentIDHeader, p.agentID)
+	}
+	if p.requestSigner != nil {
+		sign, signature, err := p.requestSigner.SignRequest(request.Method+" "+request.URL.RequestURI(), body)
+		if err != nil {
+			return logger.WrapError("sign request", err)
+		}
+
+		request.Header.Add(hash.RequestSignHeader, signature)
+		request.Header.Add(hash.RequestTimestampHeader, sign.TimestampString())
+		request.Header.Add(hash.RequestNonceHeader, sign.Nonce)
+	}
 
 	response, err := p.client.Do(request)
 	if err != nil {
@@ -140,26 +214,33 @@ func (p *httpMetricsPusher) pushMetrics(ctx context.Context, metricsList []metri
 
 	stringContent := string(content)
 	if response.StatusCode != http.StatusOK {
-		logger.ErrorFormat("Unexpected response status code: %v %v", response.Status, stringContent)
+		log.ErrorFormat("Unexpected response status code: %v %v", response.Status, stringContent)
 		return logger.WrapError(fmt.Sprintf("push metric: %s", stringContent), metrics.ErrUnexpectedStatusCode)
 	}
 
 	for _, metric := range metricsList {
-		logger.InfoFormat("Pushed metric: %v. value: %v, status: %v", metric.GetName(), metric.GetStringValue(), response.Status)
+		log.InfoFormat("Pushed metric: %v. value: %v, status: %v", metric.GetName(), metric.GetStringValue(), response.Status)
 		metric.Flush()
 	}
 
 	return nil
 }
 
-func normalizeURL(urlStr string) (*url.URL, error) {
+func endSpan(span *tracing.Span) {
+	err := span.End()
+	if err != nil {
+		logger.ErrorFormat("Failed to export span: %v", err)
+	}
+}
+
+func normalizeURL(urlStr string, defaultScheme string) (*url.URL, error) {
 	if urlStr == "" {
 		return nil, logger.WrapError("normalize url", metrics.ErrEmptyURL)
 	}
 
 	result, err := url.ParseRequestURI(urlStr)
 	if err != nil {
-		result, err = url.ParseRequestURI("http://" + urlStr)
+		result, err = url.ParseRequestURI(defaultScheme + "://" + urlStr)
 		if err != nil {
 			return nil, logger.WrapError("parse request url", err)
 		}
@@ -167,11 +248,11 @@ func normalizeURL(urlStr string) (*url.URL, error) {
 
 	if result.Scheme == "localhost" {
 		// =)
-		return normalizeURL("http://" + result.String())
+		return normalizeURL(defaultScheme+"://"+result.String(), defaultScheme)
 	}
 
 	if result.Scheme == "" {
-		result.Scheme = "http"
+		result.Scheme = defaultScheme
 	}
 
 	return result, nil
@@ -6,23 +6,32 @@ import (
 	"hash"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/certs"
 	internalHash "github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	metricsHttp "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/model"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 )
 
 type testConf struct {
+	agentID          string
 	connectionString string
+	certPath         string
+	keyPath          string
+	caPath           string
 	key              []byte
 	timeout          time.Duration
 	parallelLimit    int
@@ -104,9 +113,12 @@ func TestHttpMetricsPusher_Push(t *testing.T) {
 
 			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, "agent1", r.Header.Get(agents.AgentIDHeader))
+				_, err := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader))
+				assert.NoError(t, err)
 				defer r.Body.Close()
 				modelRequest := []*model.Metrics{}
-				err := json.NewDecoder(r.Body).Decode(&modelRequest)
+				err = json.NewDecoder(r.Body).Decode(&modelRequest)
 				assert.NoError(t, err)
 				for _, modelMetric := range modelRequest {
 					lock.Lock()
@@ -120,6 +132,7 @@ func TestHttpMetricsPusher_Push(t *testing.T) {
 			defer server.Close()
 
 			conf := &testConf{
+				agentID:          "agent1",
 				connectionString: server.URL,
 				timeout:          10 * time.Second,
 				signEnabled:      false,
@@ -128,7 +141,7 @@ func TestHttpMetricsPusher_Push(t *testing.T) {
 			}
 			signer := internalHash.NewSigner(conf)
 			converter := metricsHttp.NewMetricsConverter(conf, signer)
-			pusher, err := NewPusher(conf, converter, nil)
+			pusher, err := NewPusher(conf, converter, nil, nil, tracing.NewTracer(nil))
 			assert.NoError(t, err)
 
 			err = pusher.Push(ctx, test.ArrayToChan(tt.metricsToPush))
@@ -144,48 +157,168 @@ func TestHttpMetricsPusher_Push(t *testing.T) {
 	}
 }
 
+func TestHttpMetricsPusher_HTTPS(t *testing.T) {
+	tests := []struct {
+		name          string
+		mutual        bool
+		agentCert     bool
+		trustedCA     bool
+		withoutScheme bool
+		expectedError bool
+	}{
+		{
+			name:      "tls",
+			trustedCA: true,
+		},
+		{
+			name:          "tls_by_default",
+			trustedCA:     true,
+			withoutScheme: true,
+		},
+		{
+			name:      "mutual_tls",
+			mutual:    true,
+			agentCert: true,
+			trustedCA: true,
+		},
+		{
+			name:          "mutual_tls_without_agent_certificate",
+			mutual:        true,
+			trustedCA:     true,
+			expectedError: true,
+		},
+		{
+			name:          "unknown_authority",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			authority := test.NewAuthority(t, dir, "ca")
+			serverCert, serverKey := authority.Issue(t, "server", "127.0.0.1")
+
+			serverCA := ""
+			if tt.mutual {
+				serverCA = authority.CertPath
+			}
+			tlsConfig, err := certs.NewServerConfig(serverCert, serverKey, serverCA, certs.HTTP1Protocol)
+			require.NoError(t, err)
+
+			agentCN := ""
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(r.TLS.PeerCertificates) > 0 {
+					agentCN = r.TLS.PeerCertificates[0].Subject.CommonName
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			server.TLS = tlsConfig
+			server.StartTLS()
+			defer server.Close()
+
+			conf := &testConf{
+				agentID:          "agent1",
+				connectionString: server.URL,
+				timeout:          10 * time.Second,
+				parallelLimit:    1,
+			}
+			if tt.withoutScheme {
+				conf.connectionString = strings.TrimPrefix(server.URL, "https://")
+			}
+			if tt.trustedCA {
+				conf.caPath = authority.CertPath
+			} else {
+				conf.caPath = test.NewAuthority(t, dir, "other").CertPath
+			}
+			if tt.agentCert {
+				conf.certPath, conf.keyPath = authority.Issue(t, "agent1")
+			}
+
+			pusher, err := NewPusher(conf, metricsHttp.NewMetricsConverter(conf, internalHash.NewSigner(conf)), nil, nil, tracing.NewTracer(nil))
+			require.NoError(t, err)
+
+			err = pusher.Push(context.Background(), test.ArrayToChan([]metrics.Metric{createCounterMetric("counter", 1)}))
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.agentCert {
+				assert.Equal(t, "agent1", agentCN)
+			}
+		})
+	}
+}
+
 func Test_URLNormalization(t *testing.T) {
 	tests := []struct {
 		name          string
 		input         string
+		defaultScheme string
 		expectedError string
 		expectedURL   string
 	}{
 		{
 			name:          "empty_url",
 			input:         "",
+			defaultScheme: httpScheme,
 			expectedError: "failed to normalize url: empty url string",
 		},
 		{
-			name:        "no_schema_no_port",
-			input:       "127.0.0.1",
-			expectedURL: "http://127.0.0.1",
+			name:          "no_schema_no_port",
+			input:         "127.0.0.1",
+			defaultScheme: httpScheme,
+			expectedURL:   "http://127.0.0.1",
+		},
+		{
+			name:          "no_schema_port",
+			input:         "127.0.0.1:1234",
+			defaultScheme: httpScheme,
+			expectedURL:   "http://127.0.0.1:1234",
+		},
+		{
+			name:          "no_schema_https",
+			input:         "127.0.0.1:1234",
+			defaultScheme: httpsScheme,
+			expectedURL:   "https://127.0.0.1:1234",
+		},
+		{
+			name:          "schema_port",
+			input:         "ftp://127.0.0.1:1234",
+			defaultScheme: httpScheme,
+			expectedURL:   "ftp://127.0.0.1:1234",
 		},
 		{
-			name:        "no_schema_port",
-			input:       "127.0.0.1:1234",
-			expectedURL: "http://127.0.0.1:1234",
+			name:          "explicit_http",
+			input:         "http://127.0.0.1:1234",
+			defaultScheme: httpsScheme,
+			expectedURL:   "http://127.0.0.1:1234",
 		},
 		{
-			name:        "schema_port",
-			input:       "ftp://127.0.0.1:1234",
-			expectedURL: "ftp://127.0.0.1:1234",
+			name:          "localhost",
+			input:         "localhost:1234",
+			defaultScheme: httpScheme,
+			expectedURL:   "http://localhost:1234",
 		},
 		{
-			name:        "localhost",
-			input:       "localhost:1234",
-			expectedURL: "http://localhost:1234",
+			name:          "localhost_https",
+			input:         "localhost:1234",
+			defaultScheme: httpsScheme,
+			expectedURL:   "https://localhost:1234",
 		},
 		{
-			name:        "valid",
-			input:       "https://ya.ru",
-			expectedURL: "https://ya.ru",
+			name:          "valid",
+			input:         "https://ya.ru",
+			defaultScheme: httpScheme,
+			expectedURL:   "https://ya.ru",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			actual, err := normalizeURL(tt.input)
+			actual, err := normalizeURL(tt.input, tt.defaultScheme)
 
 			if tt.expectedError != "" {
 				assert.Error(t, err)
@@ -209,6 +342,10 @@ func createGaugeMetric(name string, value float64) metrics.Metric {
 	return metric
 }
 
+func (c *testConf) AgentID() string {
+	return c.agentID
+}
+
 func (c *testConf) MetricsServerURL() string {
 	return c.connectionString
 }
@@ -217,10 +354,26 @@ func (c *testConf) PushMetricsTimeout() time.Duration {
 	return c.timeout
 }
 
+func (c *testConf) HTTPCertPath() string {
+	return c.certPath
+}
+
+func (c *testConf) HTTPKeyPath() string {
+	return c.keyPath
+}
+
+func (c *testConf) HTTPCAPath() string {
+	return c.caPath
+}
+
 func (t *testMetric) GetName() string {
 	return t.name
 }
 
+func (t *testMetric) GetLabels() metrics.Labels {
+	return nil
+}
+
 func (t *testMetric) GetType() string {
 	return t.metricType
 }
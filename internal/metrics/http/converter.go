@@ -6,6 +6,7 @@ import (
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/model"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 )
@@ -32,8 +33,9 @@ func NewMetricsConverter(conf MetricsConverterConfig, signer *hash.Signer) *Conv
 // ToModelMetric convert internal dsl metric to model metric.
 func (c *Converter) ToModelMetric(metric metrics.Metric) (*model.Metrics, error) {
 	modelMetric := &model.Metrics{
-		ID:    metric.GetName(),
-		MType: metric.GetType(),
+		ID:     metric.GetName(),
+		MType:  metric.GetType(),
+		Labels: metric.GetLabels().Map(),
 	}
 
 	metricValue := metric.GetValue()
@@ -43,6 +45,28 @@ func (c *Converter) ToModelMetric(metric metrics.Metric) (*model.Metrics, error)
 		modelMetric.Delta = &counterValue
 	case "gauge":
 		modelMetric.Value = &metricValue
+	case "histogram":
+		histogramMetric, ok := metric.(metrics.HistogramMetric)
+		if !ok {
+			return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", modelMetric.MType), metrics.ErrUnknownMetricType)
+		}
+
+		histogram := histogramMetric.GetHistogram()
+		modelMetric.Buckets = histogram.Bounds
+		modelMetric.Counts = histogram.Counts
+		modelMetric.Sum = &histogram.Sum
+		modelMetric.Count = &histogram.Count
+	case "summary":
+		summaryMetric, ok := metric.(metrics.SummaryMetric)
+		if !ok {
+			return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", modelMetric.MType), metrics.ErrUnknownMetricType)
+		}
+
+		summary := summaryMetric.GetSummary()
+		modelMetric.Observations = summaryMetric.GetObservations()
+		modelMetric.Quantiles = ToModelQuantiles(summaryMetric, metrics.DefaultQuantiles...)
+		modelMetric.Sum = &summary.Sum
+		modelMetric.Count = &summary.Count
 	default:
 		return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", modelMetric.MType), metrics.ErrUnknownMetricType)
 	}
@@ -64,27 +88,58 @@ func (c *Converter) FromModelMetric(modelMetric *model.Metrics) (metrics.Metric,
 	var metric metrics.Metric
 	var value float64
 
+	labels := metrics.LabelsFromMap(modelMetric.Labels)
+	err := metrics.ValidateSeries(modelMetric.ID, labels)
+	if err != nil {
+		return nil, logger.WrapError("convert metric series", err)
+	}
+
 	switch modelMetric.MType {
 	case "counter":
 		if modelMetric.Delta == nil {
 			return nil, logger.WrapError("convert metric", metrics.ErrMetricValueMissed)
 		}
 
-		metric = types.NewCounterMetric(modelMetric.ID)
+		metric = types.NewCounterMetric(modelMetric.ID, labels...)
 		value = float64(*modelMetric.Delta)
 	case "gauge":
 		if modelMetric.Value == nil {
 			return nil, logger.WrapError("convert metric", metrics.ErrMetricValueMissed)
 		}
 
-		metric = types.NewGaugeMetric(modelMetric.ID)
+		metric = types.NewGaugeMetric(modelMetric.ID, labels...)
 		value = *modelMetric.Value
+	case "histogram":
+		if modelMetric.Sum == nil || modelMetric.Count == nil {
+			return nil, logger.WrapError("convert metric", metrics.ErrMetricValueMissed)
+		}
+
+		histogramMetric, err := types.RestoreHistogramMetric(modelMetric.ID, &metrics.Histogram{
+			Bounds: modelMetric.Buckets,
+			Counts: modelMetric.Counts,
+			Sum:    *modelMetric.Sum,
+			Count:  *modelMetric.Count,
+		}, labels...)
+		if err != nil {
+			return nil, logger.WrapError("convert metric", err)
+		}
+
+		metric = histogramMetric
+	case "summary":
+		err := metrics.ValidateObservations(modelMetric.Observations)
+		if err != nil {
+			return nil, logger.WrapError("convert metric", err)
+		}
+
+		metric = types.NewSummaryMetricFromObservations(modelMetric.ID, modelMetric.Observations, labels...)
 	default:
 
 		return nil, logger.WrapError(fmt.Sprintf("convert metric with type %s", modelMetric.MType), metrics.ErrUnknownMetricType)
 	}
 
-	metric.SetValue(value)
+	if _, ok := metric.(metrics.MergeableMetric); !ok {
+		metric.SetValue(value)
+	}
 
 	if c.signMetrics && modelMetric.Hash != "" {
 		ok, err := c.signer.CheckSignString(metric, modelMetric.Hash)
@@ -99,3 +154,32 @@ func (c *Converter) FromModelMetric(modelMetric *model.Metrics) (metrics.Metric,
 
 	return metric, nil
 }
+
+// ToModelQuantiles calculates summary metric quantile values.
+// Quantiles are not calculated for an empty summary.
+func ToModelQuantiles(metric metrics.SummaryMetric, quantiles ...float64) []*model.Quantile {
+	if metric.GetSummary().Count == 0 {
+		return nil
+	}
+
+	result := make([]*model.Quantile, len(quantiles))
+	for i, quantile := range quantiles {
+		result[i] = &model.Quantile{
+			Quantile: quantile,
+			Value:    metric.GetQuantile(quantile),
+		}
+	}
+
+	return result
+}
+
+// ToModelAgent converts agent activity to the API model.
+func ToModelAgent(agent *agents.Agent) *model.Agent {
+	return &model.Agent{
+		ID:           agent.ID,
+		Source:       agent.Source,
+		LastSeen:     agent.LastSeen,
+		HeartbeatAge: agent.HeartbeatAge.Seconds(),
+		SeriesCount:  agent.SeriesCount,
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateContentType returns the offer that best matches the Accept header value.
+// Offers are listed in server preference order, the first one is used if the header is empty.
+// Empty string means that no offer is acceptable.
+func negotiateContentType(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	bestOffer := ""
+	bestQuality := 0.0
+	for _, offer := range offers {
+		quality := offerQuality(ranges, offer)
+		if quality > bestQuality {
+			bestOffer = offer
+			bestQuality = quality
+		}
+	}
+
+	return bestOffer
+}
+
+func parseAccept(accept string) []acceptRange {
+	var result []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		result = append(result, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	return result
+}
+
+func offerQuality(ranges []acceptRange, offer string) float64 {
+	offerType, _, err := mime.ParseMediaType(offer)
+	if err != nil {
+		return 0
+	}
+
+	quality := 0.0
+	specificity := -1
+	for _, r := range ranges {
+		current := matchSpecificity(r.mediaType, offerType)
+		if current > specificity {
+			specificity = current
+			quality = r.quality
+		}
+	}
+
+	return quality
+}
+
+func matchSpecificity(mediaRange string, mediaType string) int {
+	if mediaRange == mediaType {
+		return 2
+	}
+
+	if mediaRange == "*/*" {
+		return 0
+	}
+
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	offerType, _, _ := strings.Cut(mediaType, "/")
+	if rangeSubtype == "*" && rangeType == offerType {
+		return 1
+	}
+
+	return -1
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_negotiateContentType(t *testing.T) {
+	const (
+		textType = "text/plain; version=0.0.4"
+		htmlType = "text/html"
+	)
+
+	tests := []struct {
+		name     string
+		accept   string
+		offers   []string
+		expected string
+	}{
+		{
+			name:     "no_offers",
+			accept:   "text/html",
+			expected: "",
+		},
+		{
+			name:     "empty_accept",
+			offers:   []string{textType, htmlType},
+			expected: textType,
+		},
+		{
+			name:     "any",
+			accept:   "*/*",
+			offers:   []string{textType, htmlType},
+			expected: textType,
+		},
+		{
+			name:     "browser",
+			accept:   "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			offers:   []string{textType, htmlType},
+			expected: htmlType,
+		},
+		{
+			name:     "prometheus_scraper",
+			accept:   "application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1",
+			offers:   []string{textType, htmlType},
+			expected: textType,
+		},
+		{
+			name:     "subtype_wildcard",
+			accept:   "text/*;q=0.5, text/html",
+			offers:   []string{textType, htmlType},
+			expected: htmlType,
+		},
+		{
+			name:     "rejected_offer",
+			accept:   "text/plain;q=0, */*",
+			offers:   []string{textType, htmlType},
+			expected: htmlType,
+		},
+		{
+			name:     "not_acceptable",
+			accept:   "application/json",
+			offers:   []string{textType, htmlType},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := negotiateContentType(tt.accept, tt.offers...)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const defaultHTTPSPort = "443"
+
+type RedirectServerConfig interface {
+	ListenURL() string
+	HTTPRedirectURL() string
+}
+
+type redirectServer struct {
+	srv *http.Server
+}
+
+// NewRedirect creates plain http server redirecting every request to the https listener.
+// Permanent redirect keeps request method and body, so agents can follow it.
+func NewRedirect(conf RedirectServerConfig) *redirectServer {
+	return &redirectServer{
+		srv: &http.Server{
+			Addr:    conf.HTTPRedirectURL(),
+			Handler: redirectToHTTPS(conf.ListenURL()),
+		},
+	}
+}
+
+func (s *redirectServer) Start(ctx context.Context) error {
+	logger.Info("Start https redirect service")
+	return s.srv.ListenAndServe()
+}
+
+func (s *redirectServer) Stop(ctx context.Context) error {
+	logger.Info("Stopping https redirect service")
+	return s.srv.Shutdown(ctx)
+}
+
+func redirectToHTTPS(listenURL string) http.Handler {
+	_, port, err := net.SplitHostPort(listenURL)
+	if err != nil {
+		port = defaultHTTPSPort
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Trim(r.Host, "[]")
+		if hostname, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = hostname
+		}
+
+		if port != defaultHTTPSPort {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			// ipv6 literal must stay bracketed in url
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		logger.DebugFormat("Redirect %s %s to %s", r.Method, r.URL.Path, target)
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name             string
+		listenURL        string
+		requestURL       string
+		expectedLocation string
+	}{
+		{
+			name:             "custom_port",
+			listenURL:        ":8443",
+			requestURL:       "http://metrics.local:8080/updates",
+			expectedLocation: "https://metrics.local:8443/updates",
+		},
+		{
+			name:             "default_port",
+			listenURL:        "0.0.0.0:443",
+			requestURL:       "http://metrics.local/value/counter/requests?quantile=0.5",
+			expectedLocation: "https://metrics.local/value/counter/requests?quantile=0.5",
+		},
+		{
+			name:             "ipv6_host",
+			listenURL:        "127.0.0.1:8443",
+			requestURL:       "http://[::1]:8080/",
+			expectedLocation: "https://[::1]:8443/",
+		},
+		{
+			name:             "ipv6_host_default_port",
+			listenURL:        "[::]:443",
+			requestURL:       "http://[::1]/updates",
+			expectedLocation: "https://[::1]/updates",
+		},
+		{
+			name:             "ipv6_host_with_port_default_port",
+			listenURL:        "[::]:443",
+			requestURL:       "http://[::1]:8080/updates",
+			expectedLocation: "https://[::1]/updates",
+		},
+		{
+			name:             "invalid_listen_url",
+			listenURL:        "invalid",
+			requestURL:       "http://127.0.0.1:8080/ping",
+			expectedLocation: "https://127.0.0.1/ping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			redirectToHTTPS(tt.listenURL).ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.requestURL, nil))
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, http.StatusPermanentRedirect, actual.StatusCode)
+			assert.Equal(t, tt.expectedLocation, actual.Header.Get("Location"))
+		})
+	}
+}
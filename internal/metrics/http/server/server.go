@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/json"
@@ -9,24 +10,37 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/certs"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/html"
 	metricsHttp "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/model"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/prometheus"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/server"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 )
 
 const (
 	counterMetricName = "counter"
 	gaugeMetricName   = "gauge"
+	unmatchedRoute    = "unmatched"
 )
 
+var httpTransportLabel = metrics.Label{Name: "transport", Value: "http"}
+
 var compressContentTypes = []string{
 	"application/javascript",
 	"application/json",
@@ -44,11 +58,15 @@ type metricsRequestContext struct {
 	body           []byte
 	requestMetrics []*model.Metrics
 	resultMetrics  []*model.Metrics
+	resultValues   []metrics.Metric
 }
 
 type ServerConfig interface {
 	ListenURL() string
 	ClientsTrustedSubnet() *net.IPNet
+	HTTPCertPath() string
+	HTTPKeyPath() string
+	HTTPCAPath() string
 }
 
 type httpServer struct {
@@ -58,17 +76,38 @@ type httpServer struct {
 func New(conf ServerConfig,
 	converter *metricsHttp.Converter,
 	decryptor crypto.Decryptor,
+	verifier *hash.RequestVerifier,
+	recorder *telemetry.Recorder,
+	tracer *tracing.Tracer,
 	requestHandler server.RequestHandler,
-) *httpServer {
-	return &httpServer{
-		srv: &http.Server{
-			Addr:    conf.ListenURL(),
-			Handler: createRouter(converter, decryptor, conf.ClientsTrustedSubnet(), requestHandler),
-		},
+) (*httpServer, error) {
+	srv := &http.Server{
+		Addr:    conf.ListenURL(),
+		Handler: createRouter(converter, decryptor, verifier, conf.ClientsTrustedSubnet(), recorder, tracer, requestHandler),
+	}
+
+	if conf.HTTPCertPath() != "" {
+		tlsConfig, err := certs.NewServerConfig(conf.HTTPCertPath(), conf.HTTPKeyPath(), conf.HTTPCAPath(),
+			certs.HTTP2Protocol, certs.HTTP1Protocol)
+		if err != nil {
+			return nil, logger.WrapError("create tls config", err)
+		}
+
+		srv.TLSConfig = tlsConfig
 	}
+
+	return &httpServer{
+		srv: srv,
+	}, nil
 }
 
 func (s *httpServer) Start(ctx context.Context) error {
+	if s.srv.TLSConfig != nil {
+		logger.Info("Start secure web service")
+		// certificates are provided by the tls config
+		return s.srv.ListenAndServeTLS("", "")
+	}
+
 	logger.Info("Start web service")
 	return s.srv.ListenAndServe()
 }
@@ -81,41 +120,65 @@ func (s *httpServer) Stop(ctx context.Context) error {
 func createRouter(
 	converter *metricsHttp.Converter,
 	decryptor crypto.Decryptor,
+	verifier *hash.RequestVerifier,
 	clientSubnet *net.IPNet,
+	recorder *telemetry.Recorder,
+	tracer *tracing.Tracer,
 	requestHandler server.RequestHandler,
 ) *chi.Mux {
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
+	router.Use(traceRequests(tracer))
+	router.Use(logRequests)
+	router.Use(observeRequests(recorder))
 	if clientSubnet != nil {
 		router.Use(middleware.RealIP)
 		router.Use(checkClientSubnet(clientSubnet))
 	}
 	router.Use(middleware.Compress(gzip.BestSpeed, compressContentTypes...))
 	router.Route("/update", func(r chi.Router) {
-		r.With(decrypt(decryptor), fillSingleJSONContext, updateMetrics(requestHandler, converter)).
+		r.Use(verifyRequests(verifier, recorder))
+		r.With(decrypt(decryptor, recorder), fillSingleJSONContext, updateMetrics(requestHandler, converter, recorder)).
 			Post("/", successSingleJSONResponse())
-		r.With(fillCommonURLContext, fillGaugeURLContext, updateMetrics(requestHandler, converter)).
+		r.With(fillCommonURLContext, fillGaugeURLContext, updateMetrics(requestHandler, converter, recorder)).
 			Post("/gauge/{metricName}/{metricValue}", successURLResponse())
-		r.With(fillCommonURLContext, fillCounterURLContext, updateMetrics(requestHandler, converter)).
+		r.With(fillCommonURLContext, fillCounterURLContext, updateMetrics(requestHandler, converter, recorder)).
 			Post("/counter/{metricName}/{metricValue}", successURLResponse())
 		r.Post("/{metricType}/{metricName}/{metricValue}", func(w http.ResponseWriter, r *http.Request) {
 			message := fmt.Sprintf("unknown metric type: %s", chi.URLParam(r, "metricType"))
-			logger.Error("failed to update metric: " + message)
+			logger.FromContext(r.Context()).Error("failed to update metric: " + message)
 			http.Error(w, message, http.StatusNotImplemented)
 		})
 	})
 
 	router.Route("/updates", func(r chi.Router) {
-		r.With(decrypt(decryptor), fillMultiJSONContext, updateMetrics(requestHandler, converter)).
+		r.Use(verifyRequests(verifier, recorder))
+		r.With(decrypt(decryptor, recorder), fillMultiJSONContext, updateMetrics(requestHandler, converter, recorder)).
 			Post("/", successMultiJSONResponse())
 	})
 
 	router.Route("/value", func(r chi.Router) {
-		r.With(decrypt(decryptor), fillSingleJSONContext, fillMetricValues(requestHandler, converter)).
+		r.With(decrypt(decryptor, recorder), fillSingleJSONContext, fillMetricValues(requestHandler, converter)).
 			Post("/", successSingleJSONResponse())
 
 		r.With(fillCommonURLContext, fillMetricValues(requestHandler, converter)).
-			Get("/{metricType}/{metricName}", successURLValueResponse(converter))
+			Get("/{metricType}/{metricName}", successURLValueResponse())
+
+		r.With(verifyRequests(verifier, recorder)).Delete("/", handleRemoveMetrics(requestHandler))
+		r.With(verifyRequests(verifier, recorder)).Delete("/{metricType}/{metricName}", handleRemoveMetrics(requestHandler))
+	})
+
+	router.Route("/reset", func(r chi.Router) {
+		r.Use(verifyRequests(verifier, recorder))
+		r.Post("/", handleResetCounters(requestHandler))
+		r.Post("/counter/{metricName}", handleResetCounters(requestHandler))
+	})
+
+	router.Route("/query_range", func(r chi.Router) {
+		r.Get("/", handleQueryRange(requestHandler))
+	})
+
+	router.Route("/agents", func(r chi.Router) {
+		r.Get("/", handleAgents(requestHandler))
 	})
 
 	router.Route("/ping", func(r chi.Router) {
@@ -123,18 +186,136 @@ func createRouter(
 	})
 
 	router.Route("/debug", func(r chi.Router) {
+		r.Get("/loglevel", handleGetLogLevel())
+		r.Put("/loglevel", handleSetLogLevel())
 		r.Handle("/*", http.DefaultServeMux)
 	})
 
 	router.Route("/", func(r chi.Router) {
-		r.Get("/", handleMetricsPage(requestHandler))
-		r.Get("/metrics", handleMetricsPage(requestHandler))
+		r.Get("/", handleMetricsPage(requestHandler, html.ContentType))
+		r.Get("/metrics", handleMetricsPage(requestHandler, prometheus.TextContentType, html.ContentType))
 	})
 
 	return router
 }
 
-func decrypt(decryptor crypto.Decryptor) func(next http.Handler) http.Handler {
+// logRequests attaches request id and agent address to the request log fields and logs handled requests.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		source := requestSource(r)
+		fields := []logger.Field{{Key: "agent_ip", Value: source.Address}}
+		if source.AgentID != "" {
+			fields = append(fields, logger.Field{Key: "agent_id", Value: source.AgentID})
+		}
+
+		ctx := logger.WithFields(r.Context(), fields...)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := responseStatus(ww)
+		message := fmt.Sprintf("%s %s - %d %dB in %v", r.Method, r.URL.Path, status, ww.BytesWritten(), time.Since(start))
+		if status >= http.StatusInternalServerError {
+			logger.FromContext(ctx).Error(message)
+		} else {
+			logger.FromContext(ctx).Info(message)
+		}
+	})
+}
+
+// traceRequests continues the agent trace passed in the traceparent header or starts a new one.
+// Trace id is attached to the request log fields and returned in the X-Request-Id header of every response.
+func traceRequests(tracer *tracing.Tracer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracer.StartRemote(r.Context(), "http_request", r.Header.Get(tracing.TraceparentHeader))
+			spanContext := span.Context()
+			ctx = logger.WithFields(ctx,
+				logger.Field{Key: "request_id", Value: spanContext.TraceID},
+				logger.Field{Key: "span_id", Value: spanContext.SpanID},
+			)
+
+			w.Header().Set(tracing.RequestIDHeader, spanContext.TraceID)
+			w.Header().Set(tracing.TraceparentHeader, spanContext.Traceparent())
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := responseStatus(ww)
+			span.SetAttribute("method", r.Method)
+			span.SetAttribute("path", r.URL.Path)
+			span.SetAttribute("status", strconv.Itoa(status))
+			if status >= http.StatusBadRequest {
+				span.SetError(fmt.Errorf("%w: %d", metrics.ErrUnexpectedStatusCode, status))
+			}
+
+			err := span.End()
+			if err != nil {
+				logger.FromContext(ctx).ErrorFormat("Failed to export span: %v", err)
+			}
+		})
+	}
+}
+
+// observeRequests records request count and latency by route pattern, unmatched requests share the same route label.
+func observeRequests(recorder *telemetry.Recorder) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := chi.RouteContext(r.Context()).RoutePattern()
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			status := responseStatus(ww)
+			routeLabel := metrics.Label{Name: "route", Value: route}
+			methodLabel := metrics.Label{Name: "method", Value: r.Method}
+			recorder.Count(r.Context(), telemetry.HTTPRequests, routeLabel, methodLabel, metrics.Label{Name: "code", Value: strconv.Itoa(status)})
+			recorder.ObserveDuration(r.Context(), telemetry.HTTPRequestDuration, start, routeLabel, methodLabel)
+		})
+	}
+}
+
+// verifyRequests validates whole request signature over the request uri and the raw body, the body is restored for the next handlers.
+func verifyRequests(verifier *hash.RequestVerifier, recorder *telemetry.Recorder) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if verifier == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, logger.WrapError("read body data", err).Error(), http.StatusInternalServerError)
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			err = verifier.Verify(r.Method+" "+r.URL.RequestURI(), body,
+				r.Header.Get(hash.RequestSignHeader),
+				r.Header.Get(hash.RequestTimestampHeader),
+				r.Header.Get(hash.RequestNonceHeader))
+			if err != nil {
+				recorder.Count(r.Context(), telemetry.RejectedRequests, httpTransportLabel, metrics.Label{Name: "reason", Value: hash.RejectReason(err)})
+				status := http.StatusUnauthorized
+				if errors.Is(err, hash.ErrReplayedRequest) {
+					status = http.StatusConflict
+				}
+
+				http.Error(w, logger.WrapError("verify request", err).Error(), status)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func decrypt(decryptor crypto.Decryptor, recorder *telemetry.Recorder) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var reader io.Reader
@@ -165,6 +346,7 @@ func decrypt(decryptor crypto.Decryptor) func(next http.Handler) http.Handler {
 			if decryptor != nil {
 				body, err = decryptor.Decrypt(body)
 				if err != nil {
+					recorder.Count(r.Context(), telemetry.DecryptFailures, httpTransportLabel)
 					http.Error(w, logger.WrapError("decrypt body data", err).Error(), http.StatusBadRequest)
 					return
 				}
@@ -177,12 +359,21 @@ func decrypt(decryptor crypto.Decryptor) func(next http.Handler) http.Handler {
 	}
 }
 
+// fillCommonURLContext reads series identity from the url, labels are passed in the labels query parameter.
 func fillCommonURLContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		labelsString := r.URL.Query().Get("labels")
+		labels, err := metrics.ParseLabels(labelsString)
+		if err != nil {
+			http.Error(w, logger.WrapError(fmt.Sprintf("parse labels: %v", labelsString), err).Error(), http.StatusBadRequest)
+			return
+		}
+
 		ctx, metricsContext := ensureMetricsContext(r)
 		metricsContext.requestMetrics = append(metricsContext.requestMetrics, &model.Metrics{
-			ID:    chi.URLParam(r, "metricName"),
-			MType: chi.URLParam(r, "metricType"),
+			ID:     chi.URLParam(r, "metricName"),
+			MType:  chi.URLParam(r, "metricType"),
+			Labels: labels.Map(),
 		})
 
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -193,7 +384,7 @@ func fillGaugeURLContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, metricsContext := ensureMetricsContext(r)
 		if len(metricsContext.requestMetrics) != 1 {
-			logger.Error("fillGaugeURLContext: wrong context")
+			logger.FromContext(r.Context()).Error("fillGaugeURLContext: wrong context")
 			http.Error(w, "fillGaugeURLContext: wrong context", http.StatusInternalServerError)
 			return
 		}
@@ -215,7 +406,7 @@ func fillCounterURLContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, metricsContext := ensureMetricsContext(r)
 		if len(metricsContext.requestMetrics) != 1 {
-			logger.Error("fillCounterURLContext: wrong context")
+			logger.FromContext(r.Context()).Error("fillCounterURLContext: wrong context")
 			http.Error(w, "fillCounterURLContext: wrong context", http.StatusInternalServerError)
 			return
 		}
@@ -237,7 +428,7 @@ func fillSingleJSONContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, metricsContext := ensureMetricsContext(r)
 		if metricsContext.body == nil {
-			logger.Error("fillSingleJSONContext: wrong context")
+			logger.FromContext(r.Context()).Error("fillSingleJSONContext: wrong context")
 			http.Error(w, "fillSingleJSONContext: wrong context", http.StatusInternalServerError)
 			return
 		}
@@ -252,13 +443,13 @@ func fillSingleJSONContext(next http.Handler) http.Handler {
 		}
 
 		if metricContext.ID == "" {
-			logger.Error("Fail to collect json context: metric name is missed")
+			logger.FromContext(r.Context()).Error("Fail to collect json context: metric name is missed")
 			http.Error(w, "metric name is missed", http.StatusBadRequest)
 			return
 		}
 
 		if metricContext.MType == "" {
-			logger.Error("Fail to collect json context: metric type is missed")
+			logger.FromContext(r.Context()).Error("Fail to collect json context: metric type is missed")
 			http.Error(w, "metric types is missed", http.StatusBadRequest)
 			return
 		}
@@ -271,7 +462,7 @@ func fillMultiJSONContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, metricsContext := ensureMetricsContext(r)
 		if metricsContext.body == nil {
-			logger.Error("fillMultiJSONContext: wrong context")
+			logger.FromContext(r.Context()).Error("fillMultiJSONContext: wrong context")
 			http.Error(w, "fillMultiJSONContext: wrong context", http.StatusInternalServerError)
 			return
 		}
@@ -285,13 +476,13 @@ func fillMultiJSONContext(next http.Handler) http.Handler {
 
 		for _, requestMetric := range metricsContext.requestMetrics {
 			if requestMetric.ID == "" {
-				logger.Error("Fail to collect json context: metric name is missed")
+				logger.FromContext(r.Context()).Error("Fail to collect json context: metric name is missed")
 				http.Error(w, "metric name is missed", http.StatusBadRequest)
 				return
 			}
 
 			if requestMetric.MType == "" {
-				logger.Error("Fail to collect json context: metric type is missed")
+				logger.FromContext(r.Context()).Error("Fail to collect json context: metric type is missed")
 				http.Error(w, "metric types is missed", http.StatusBadRequest)
 				return
 			}
@@ -301,7 +492,7 @@ func fillMultiJSONContext(next http.Handler) http.Handler {
 	})
 }
 
-func updateMetrics(requestHandler server.RequestHandler, converter *metricsHttp.Converter) func(next http.Handler) http.Handler {
+func updateMetrics(requestHandler server.RequestHandler, converter *metricsHttp.Converter, recorder *telemetry.Recorder) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx, metricsContext := ensureMetricsContext(r)
@@ -309,7 +500,10 @@ func updateMetrics(requestHandler server.RequestHandler, converter *metricsHttp.
 			for i, metricContext := range metricsContext.requestMetrics {
 				metric, err := converter.FromModelMetric(metricContext)
 				if err != nil {
-					logger.ErrorFormat("Fail to parse metric: %v", err)
+					logger.FromContext(r.Context()).ErrorFormat("Fail to parse metric: %v", err)
+					if errors.Is(err, metrics.ErrInvalidSignature) {
+						recorder.Count(ctx, telemetry.SignatureFailures, httpTransportLabel)
+					}
 
 					if errors.Is(err, metrics.ErrUnknownMetricType) {
 						http.Error(w, fmt.Sprintf("unknown metric type: %s", metricContext.MType), http.StatusNotImplemented)
@@ -322,9 +516,14 @@ func updateMetrics(requestHandler server.RequestHandler, converter *metricsHttp.
 				metricsList[i] = metric
 			}
 
-			resultMetrics, err := requestHandler.UpdateMetricValues(ctx, metricsList)
+			resultMetrics, err := requestHandler.UpdateMetricValues(ctx, requestSource(r), metricsList)
 			if err != nil {
-				http.Error(w, logger.WrapError("update metric", err).Error(), http.StatusInternalServerError)
+				status := http.StatusInternalServerError
+				if errors.Is(err, metrics.ErrIncompatibleMetrics) || errors.Is(err, server.ErrReservedMetricName) {
+					status = http.StatusBadRequest
+				}
+
+				http.Error(w, logger.WrapError("update metric", err).Error(), status)
 				return
 			}
 
@@ -336,7 +535,7 @@ func updateMetrics(requestHandler server.RequestHandler, converter *metricsHttp.
 					return
 				}
 
-				logger.InfoFormat("Updated metric: %v. newValue: %v", resultMetric.GetName(), newValue)
+				logger.FromContext(r.Context()).InfoFormat("Updated metric: %v. newValue: %v", resultMetric.GetName(), newValue)
 				metricsContext.resultMetrics[i] = newValue
 			}
 
@@ -350,8 +549,9 @@ func fillMetricValues(requestHandler server.RequestHandler, converter *metricsHt
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx, metricsContext := ensureMetricsContext(r)
 			metricsContext.resultMetrics = make([]*model.Metrics, len(metricsContext.requestMetrics))
+			metricsContext.resultValues = make([]metrics.Metric, len(metricsContext.requestMetrics))
 			for i, metricContext := range metricsContext.requestMetrics {
-				metric, err := requestHandler.GetMetricValue(ctx, metricContext.MType, metricContext.ID)
+				metric, err := requestHandler.GetMetricValue(ctx, metricContext.MType, metricContext.ID, metrics.LabelsFromMap(metricContext.Labels))
 				if err != nil {
 					var status int
 					if errors.Is(err, server.ErrMetricNotFound) {
@@ -371,6 +571,7 @@ func fillMetricValues(requestHandler server.RequestHandler, converter *metricsHt
 				}
 
 				metricsContext.resultMetrics[i] = resultValue
+				metricsContext.resultValues[i] = metric
 			}
 
 			next.ServeHTTP(w, r)
@@ -378,34 +579,65 @@ func fillMetricValues(requestHandler server.RequestHandler, converter *metricsHt
 	}
 }
 
-func successURLValueResponse(converter *metricsHttp.Converter) func(w http.ResponseWriter, r *http.Request) {
+func successURLValueResponse() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_, metricsContext := ensureMetricsContext(r)
 
-		if len(metricsContext.resultMetrics) != 1 {
-			logger.Error("successURLValueResponse: wrong context")
+		if len(metricsContext.resultValues) != 1 {
+			logger.FromContext(r.Context()).Error("successURLValueResponse: wrong context")
 			http.Error(w, "successURLValueResponse: wrong context", http.StatusInternalServerError)
 			return
 		}
 
-		metric, err := converter.FromModelMetric(metricsContext.resultMetrics[0])
+		metric := metricsContext.resultValues[0]
+		strQuantile := r.URL.Query().Get("q")
+		if strQuantile == "" {
+			successResponse(w, "text/plain", metric.GetStringValue())
+			return
+		}
+
+		summaryMetric, ok := metric.(metrics.SummaryMetric)
+		if !ok {
+			http.Error(w, fmt.Sprintf("quantile is not supported by %s metric", metric.GetType()), http.StatusBadRequest)
+			return
+		}
+
+		quantile, err := parser.ToFloat64(strQuantile)
 		if err != nil {
-			http.Error(w, logger.WrapError("convert result metric", err).Error(), http.StatusInternalServerError)
+			http.Error(w, logger.WrapError(fmt.Sprintf("parse quantile: %v", strQuantile), err).Error(), http.StatusBadRequest)
 			return
 		}
 
-		successResponse(w, "text/plain", metric.GetStringValue())
+		err = metrics.ValidateQuantile(quantile)
+		if err != nil {
+			http.Error(w, logger.WrapError(fmt.Sprintf("validate quantile: %v", strQuantile), err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		if summaryMetric.GetSummary().Count == 0 {
+			http.Error(w, fmt.Sprintf("summary %s has no observations", metric.GetName()), http.StatusNotFound)
+			return
+		}
+
+		successResponse(w, "text/plain", parser.FloatToString(summaryMetric.GetQuantile(quantile)))
 	}
 }
 
-func handleMetricsPage(requestHandler server.RequestHandler) func(w http.ResponseWriter, r *http.Request) {
+func handleMetricsPage(requestHandler server.RequestHandler, contentTypes ...string) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		page, err := requestHandler.GetReportPage(r.Context())
+		contentType := negotiateContentType(r.Header.Get("Accept"), contentTypes...)
+		if contentType == "" {
+			logger.FromContext(r.Context()).ErrorFormat("failed to negotiate content type: %s", r.Header.Get("Accept"))
+			http.Error(w, "not acceptable", http.StatusNotAcceptable)
+			return
+		}
+
+		page, err := requestHandler.GetReportPage(r.Context(), contentType)
 		if err != nil {
 			http.Error(w, logger.WrapError("get metric values", err).Error(), http.StatusInternalServerError)
 			return
 		}
-		successResponse(w, "text/html", page)
+		successResponse(w, contentType, page)
 	}
 }
 
@@ -420,7 +652,7 @@ func successSingleJSONResponse() func(w http.ResponseWriter, r *http.Request) {
 		_, metricsContext := ensureMetricsContext(r)
 
 		if len(metricsContext.resultMetrics) != 1 {
-			logger.Error("successSingleJSONResponse: wrong context")
+			logger.FromContext(r.Context()).Error("successSingleJSONResponse: wrong context")
 			http.Error(w, "successSingleJSONResponse: wrong context", http.StatusInternalServerError)
 			return
 		}
@@ -435,7 +667,7 @@ func successSingleJSONResponse() func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, err = w.Write(result)
 		if err != nil {
-			logger.ErrorFormat("failed to write response: %v", err)
+			logger.FromContext(r.Context()).ErrorFormat("failed to write response: %v", err)
 		}
 	}
 }
@@ -453,7 +685,7 @@ func successMultiJSONResponse() func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, err = w.Write(result)
 		if err != nil {
-			logger.ErrorFormat("failed to write response: %v", err)
+			logger.FromContext(r.Context()).ErrorFormat("failed to write response: %v", err)
 		}
 	}
 }
@@ -467,6 +699,203 @@ func successResponse(w http.ResponseWriter, contentType string, message string)
 	}
 }
 
+func handleQueryRange(requestHandler server.RequestHandler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		result := &model.Range{
+			ID:    query.Get("name"),
+			MType: query.Get("type"),
+		}
+
+		if result.ID == "" {
+			http.Error(w, "metric name is missed", http.StatusBadRequest)
+			return
+		}
+
+		if result.MType == "" {
+			http.Error(w, "metric types is missed", http.StatusBadRequest)
+			return
+		}
+
+		labels, err := metrics.ParseLabels(query.Get("labels"))
+		if err != nil {
+			http.Error(w, logger.WrapError(fmt.Sprintf("parse labels: %v", query.Get("labels")), err).Error(), http.StatusBadRequest)
+			return
+		}
+		result.Labels = labels.Map()
+
+		start, err := parser.ToTime(query.Get("start"))
+		if err != nil {
+			http.Error(w, logger.WrapError(fmt.Sprintf("parse start: %v", query.Get("start")), err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		end, err := parser.ToTime(query.Get("end"))
+		if err != nil {
+			http.Error(w, logger.WrapError(fmt.Sprintf("parse end: %v", query.Get("end")), err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		step, err := parser.ToDuration(query.Get("step"))
+		if err != nil {
+			http.Error(w, logger.WrapError(fmt.Sprintf("parse step: %v", query.Get("step")), err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		samples, err := requestHandler.QueryRange(r.Context(), result.MType, result.ID, labels, start, end, step)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, metrics.ErrInvalidRange) {
+				status = http.StatusBadRequest
+			} else if errors.Is(err, metrics.ErrHistoryNotSupported) {
+				status = http.StatusNotImplemented
+			}
+
+			http.Error(w, logger.WrapError("query range", err).Error(), status)
+			return
+		}
+
+		result.Points = make([]*model.Point, len(samples))
+		for i, sample := range samples {
+			result.Points[i] = &model.Point{Timestamp: sample.Timestamp, Value: sample.Value}
+		}
+
+		content, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, logger.WrapError("serialise result", err).Error(), http.StatusInternalServerError)
+			return
+		}
+
+		successResponse(w, "application/json", string(content))
+	}
+}
+
+// handleRemoveMetrics removes a single metric selected by the url path or series selected by the query,
+// like /value/?prefix=cpu_&labels={host="local"}, and responds with the number of removed series.
+func handleRemoveMetrics(requestHandler server.RequestHandler) func(w http.ResponseWriter, r *http.Request) {
+	return handleSeriesOperation("remove metrics", requestHandler.RemoveMetrics)
+}
+
+// handleResetCounters sets counters selected by the url path or query to zero and responds with the number of reset series.
+func handleResetCounters(requestHandler server.RequestHandler) func(w http.ResponseWriter, r *http.Request) {
+	return handleSeriesOperation("reset counters", requestHandler.ResetCounters)
+}
+
+func handleSeriesOperation(
+	operation string,
+	apply func(ctx context.Context, filter *metrics.SeriesFilter) (int, error),
+) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseSeriesFilter(r)
+		if err != nil {
+			http.Error(w, logger.WrapError("parse series filter", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		count, err := apply(r.Context(), filter)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, server.ErrInvalidSeriesFilter) {
+				status = http.StatusBadRequest
+			}
+
+			http.Error(w, logger.WrapError(operation, err).Error(), status)
+			return
+		}
+
+		// exact name addresses the single metric, it has to exist
+		if count == 0 && filter.Name != "" {
+			http.Error(w, fmt.Sprintf("metric '%s' not found", filter.Name), http.StatusNotFound)
+			return
+		}
+
+		successResponse(w, "text/plain", parser.IntToString(int64(count)))
+	}
+}
+
+func parseSeriesFilter(r *http.Request) (*metrics.SeriesFilter, error) {
+	query := r.URL.Query()
+	filter := &metrics.SeriesFilter{
+		Type:       chi.URLParam(r, "metricType"),
+		Name:       chi.URLParam(r, "metricName"),
+		NamePrefix: query.Get("prefix"),
+	}
+
+	if filter.Type == "" {
+		filter.Type = query.Get("type")
+	}
+
+	if filter.Name == "" {
+		filter.Name = query.Get("name")
+	}
+
+	if pattern := query.Get("regex"); pattern != "" {
+		namePattern, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse regex: %v", pattern), err)
+		}
+		filter.NamePattern = namePattern
+	}
+
+	labels, err := metrics.ParseLabels(query.Get("labels"))
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("parse labels: %v", query.Get("labels")), err)
+	}
+	filter.Labels = labels
+
+	return filter, nil
+}
+
+func handleAgents(requestHandler server.RequestHandler) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		agentsList, err := requestHandler.GetAgents(r.Context())
+		if err != nil {
+			http.Error(w, logger.WrapError("get agents", err).Error(), http.StatusInternalServerError)
+			return
+		}
+
+		modelAgents := make([]*model.Agent, len(agentsList))
+		for i, agent := range agentsList {
+			modelAgents[i] = metricsHttp.ToModelAgent(agent)
+		}
+
+		result, err := json.Marshal(modelAgents)
+		if err != nil {
+			http.Error(w, logger.WrapError("serialise result", err).Error(), http.StatusInternalServerError)
+			return
+		}
+
+		successResponse(w, "application/json", string(result))
+	}
+}
+
+func handleGetLogLevel() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successResponse(w, "text/plain", logger.GetLevel().String())
+	}
+}
+
+// handleSetLogLevel changes log level at runtime, the level name is passed in the request body.
+func handleSetLogLevel() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, logger.WrapError("read body data", err).Error(), http.StatusInternalServerError)
+			return
+		}
+
+		level, err := logger.ParseLevel(string(body))
+		if err != nil {
+			http.Error(w, logger.WrapError("parse log level", err).Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger.SetLevel(level)
+		logger.FromContext(r.Context()).InfoFormat("Log level changed to %s", level)
+		successResponse(w, "text/plain", level.String())
+	}
+}
+
 func handleDBPing(requestHandler server.RequestHandler) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := requestHandler.Ping(r.Context())
@@ -478,6 +907,16 @@ func handleDBPing(requestHandler server.RequestHandler) func(w http.ResponseWrit
 	}
 }
 
+func responseStatus(ww middleware.WrapResponseWriter) int {
+	status := ww.Status()
+	if status == 0 {
+		// nothing is written, net/http replies with OK
+		return http.StatusOK
+	}
+
+	return status
+}
+
 func ensureMetricsContext(r *http.Request) (context.Context, *metricsRequestContext) {
 	const metricsContextKey = "metricsContextKey"
 	ctx := r.Context()
@@ -490,19 +929,43 @@ func ensureMetricsContext(r *http.Request) (context.Context, *metricsRequestCont
 	return ctx, metricsContext
 }
 
+func requestSource(r *http.Request) agents.Source {
+	address := r.Header.Get("X-Real-IP")
+	if address == "" {
+		address = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			address = host
+		}
+	}
+
+	source := agents.Source{
+		AgentID: r.Header.Get(agents.AgentIDHeader),
+		Address: address,
+	}
+
+	// verified client certificate identifies the agent over the declared id
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName; commonName != "" {
+			source.AgentID = commonName
+		}
+	}
+
+	return source
+}
+
 func checkClientSubnet(clientSubnet *net.IPNet) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			clientIP := net.ParseIP(r.RemoteAddr)
 			if clientIP == nil {
-				logger.Error("failed to receive client ip")
+				logger.FromContext(r.Context()).Error("failed to receive client ip")
 				http.Error(w, "failed to receive client ip", http.StatusForbidden)
 
 				return
 			}
 
 			if !clientSubnet.Contains(clientIP) {
-				logger.Error("client net not trusted")
+				logger.FromContext(r.Context()).Error("client net not trusted")
 				http.Error(w, "client net not trusted", http.StatusForbidden)
 				return
 			}
@@ -10,23 +10,32 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/certs"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/hash"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/html"
 	metricsHttp "github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/http"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/model"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/prometheus"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/server/handler"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/memory"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/tracing"
 )
 
 type callResult struct {
@@ -63,6 +72,7 @@ type testDescription struct {
 type testConf struct {
 	key         []byte
 	singEnabled bool
+	requireSign bool
 }
 
 type testDBStorage struct{}
@@ -164,7 +174,7 @@ func Test_UpdateUrlRequest(t *testing.T) {
 			converter := metricsHttp.NewMetricsConverter(conf, signer)
 			_, subnet, err := net.ParseCIDR("127.0.0.1/8")
 			assert.NoError(t, err)
-			router := createRouter(converter, nil, subnet, handler.NewHandler(&testDBStorage{}, htmlPageBuilder, metricsStorage))
+			router := createRouter(converter, nil, nil, subnet, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), htmlPageBuilder))
 			router.ServeHTTP(w, request)
 			actual := w.Result()
 
@@ -393,43 +403,73 @@ func Test_UpdatesJsonRequest(t *testing.T) {
 
 func Test_GetMetricUrlRequest(t *testing.T) {
 	tests := []struct {
-		name          string
-		metricType    string
-		metricName    string
-		expectSuccess bool
+		name           string
+		metricType     string
+		metricName     string
+		query          string
+		expectedStatus int
+		expectedBody   string
 	}{
 		{
-			name:          "type_not_found",
-			metricType:    "not_existed_type",
-			metricName:    "metricName",
-			expectSuccess: false,
+			name:           "type_not_found",
+			metricType:     "not_existed_type",
+			metricName:     "metricName",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "failed to get metric value: failed to get metric with type 'not_existed_type' and name 'metricName': metric not found\n",
 		},
 		{
-			name:          "metric_name_not_found",
-			metricType:    counterMetricName,
-			metricName:    "not_existed_metric_name",
-			expectSuccess: false,
+			name:           "metric_name_not_found",
+			metricType:     counterMetricName,
+			metricName:     "not_existed_metric_name",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "failed to get metric value: failed to get metric with type 'counter' and name 'not_existed_metric_name': metric not found\n",
 		},
 		{
-			name:          "success_get_value",
-			metricType:    counterMetricName,
-			metricName:    "metricName",
-			expectSuccess: true,
+			name:           "success_get_value",
+			metricType:     counterMetricName,
+			metricName:     "metricName",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "100",
+		},
+		{
+			name:           "success_get_labeled_value",
+			metricType:     counterMetricName,
+			metricName:     "metricName",
+			query:          "?labels=" + url.QueryEscape(`{cpu="3"}`),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "7",
+		},
+		{
+			name:           "labels_not_found",
+			metricType:     counterMetricName,
+			metricName:     "metricName",
+			query:          "?labels=" + url.QueryEscape(`{cpu="4"}`),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "failed to get metric value: failed to get metric with type 'counter' and name 'metricName{cpu=\"4\"}': metric not found\n",
+		},
+		{
+			name:           "invalid_labels",
+			metricType:     counterMetricName,
+			metricName:     "metricName",
+			query:          "?labels=" + url.QueryEscape(`{cpu=3}`),
+			expectedStatus: http.StatusBadRequest,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			url := fmt.Sprintf("http://localhost:8080/value/%v/%v", tt.metricType, tt.metricName)
+			requestURL := fmt.Sprintf("http://localhost:8080/value/%v/%v%v", tt.metricType, tt.metricName, tt.query)
 
 			htmlPageBuilder := html.NewSimplePageBuilder()
 			metricsStorage := memory.NewInMemoryStorage()
-			metricsList := []metrics.Metric{createCounterMetric("metricName", 100)}
+			labeledMetric := types.NewCounterMetric("metricName", metrics.Label{Name: "cpu", Value: "3"})
+			labeledMetric.SetValue(7)
+			metricsList := []metrics.Metric{createCounterMetric("metricName", 100), labeledMetric}
 
 			_, err := metricsStorage.AddMetricValues(context.Background(), metricsList)
 			assert.NoError(t, err)
 
-			request := httptest.NewRequest(http.MethodGet, url, nil)
+			request := httptest.NewRequest(http.MethodGet, requestURL, nil)
 			request.Header.Add("X-Real-IP", "127.0.0.1")
 			w := httptest.NewRecorder()
 
@@ -438,30 +478,1078 @@ func Test_GetMetricUrlRequest(t *testing.T) {
 			converter := metricsHttp.NewMetricsConverter(conf, signer)
 			_, subnet, err := net.ParseCIDR("127.0.0.1/8")
 			assert.NoError(t, err)
-			router := createRouter(converter, nil, subnet, handler.NewHandler(&testDBStorage{}, htmlPageBuilder, metricsStorage))
+			router := createRouter(converter, nil, nil, subnet, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), htmlPageBuilder))
 			router.ServeHTTP(w, request)
 			actual := w.Result()
+			defer actual.Body.Close()
 
-			if tt.expectSuccess {
-				assert.Equal(t, http.StatusOK, actual.StatusCode)
-				defer actual.Body.Close()
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedBody != "" {
 				body, err := io.ReadAll(actual.Body)
-				if err != nil {
-					t.Fatal(err)
-				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func Test_UpdateHistogramJsonRequest(t *testing.T) {
+	sum := 2.0
+	count := uint64(2)
+	request := model.Metrics{
+		ID:      "latency",
+		MType:   "histogram",
+		Buckets: []float64{1, 2},
+		Counts:  []uint64{1, 2},
+		Sum:     &sum,
+		Count:   &count,
+	}
+
+	tests := []struct {
+		name           string
+		request        model.Metrics
+		metrics        []metrics.Metric
+		expectedStatus int
+		expectedCounts []uint64
+	}{
+		{
+			name:           "new_metric",
+			request:        request,
+			expectedStatus: http.StatusOK,
+			expectedCounts: []uint64{1, 2},
+		},
+		{
+			name:           "merge_metric",
+			request:        request,
+			metrics:        []metrics.Metric{test.CreateHistogramMetric("latency", []float64{1, 2}, 0.5)},
+			expectedStatus: http.StatusOK,
+			expectedCounts: []uint64{2, 3},
+		},
+		{
+			name:           "bounds_mismatch",
+			request:        request,
+			metrics:        []metrics.Metric{test.CreateHistogramMetric("latency", []float64{1, 3}, 0.5)},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "value_missed",
+			request: model.Metrics{
+				ID:    "latency",
+				MType: "histogram",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			if tt.metrics != nil {
+				_, err := metricsStorage.AddMetricValues(context.Background(), tt.metrics)
+				require.NoError(t, err)
+			}
+
+			body, err := json.Marshal(tt.request)
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/update", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				result := &model.Metrics{}
+				err = json.NewDecoder(actual.Body).Decode(result)
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.expectedCounts, result.Counts)
+				assert.Equal(t, tt.expectedCounts[len(tt.expectedCounts)-1], *result.Count)
+			}
+		})
+	}
+}
+
+func Test_UpdateSummaryJsonRequest(t *testing.T) {
+	tests := []struct {
+		name              string
+		metrics           []metrics.Metric
+		expectedStatus    int
+		expectedCount     uint64
+		expectedQuantiles []*model.Quantile
+	}{
+		{
+			name:           "new_metric",
+			expectedStatus: http.StatusOK,
+			expectedCount:  3,
+			expectedQuantiles: []*model.Quantile{
+				{Quantile: 0.5, Value: 2},
+				{Quantile: 0.9, Value: 3},
+				{Quantile: 0.99, Value: 3},
+			},
+		},
+		{
+			name:           "merge_metric",
+			metrics:        []metrics.Metric{types.NewSummaryMetricFromObservations("duration", []float64{4, 5})},
+			expectedStatus: http.StatusOK,
+			expectedCount:  5,
+			expectedQuantiles: []*model.Quantile{
+				{Quantile: 0.5, Value: 3},
+				{Quantile: 0.9, Value: 5},
+				{Quantile: 0.99, Value: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			if tt.metrics != nil {
+				_, err := metricsStorage.AddMetricValues(context.Background(), tt.metrics)
+				require.NoError(t, err)
+			}
+
+			body, err := json.Marshal(model.Metrics{
+				ID:           "duration",
+				MType:        "summary",
+				Observations: []float64{1, 2, 3},
+			})
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/update", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				result := &model.Metrics{}
+				err = json.NewDecoder(actual.Body).Decode(result)
+				require.NoError(t, err)
+
+				assert.Empty(t, result.Observations)
+				assert.Equal(t, tt.expectedCount, *result.Count)
+				assert.Equal(t, tt.expectedQuantiles, result.Quantiles)
+			}
+		})
+	}
+}
+
+func Test_GetSummaryQuantileUrlRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "median",
+			path:           "/value/summary/duration?q=0.5",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "3",
+		},
+		{
+			name:           "max",
+			path:           "/value/summary/duration?q=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "5",
+		},
+		{
+			name:           "invalid_quantile",
+			path:           "/value/summary/duration?q=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "quantile_out_of_range",
+			path:           "/value/summary/duration?q=1.5",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "empty_summary",
+			path:           "/value/summary/empty?q=0.5",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "not_summary",
+			path:           "/value/gauge/gaugeName?q=0.5",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "gauge_value",
+			path:           "/value/gauge/gaugeName",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			_, err := metricsStorage.AddMetricValues(context.Background(), []metrics.Metric{
+				types.NewSummaryMetricFromObservations("duration", []float64{1, 2, 3, 4, 5}),
+				types.NewSummaryMetric("empty"),
+				test.CreateGaugeMetric("gaugeName", 100),
+			})
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "http://localhost:8080"+tt.path, nil)
+			w := httptest.NewRecorder()
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				body, err := io.ReadAll(actual.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+		})
+	}
+}
 
-				assert.Equal(t, "100", string(body))
+func Test_GetMetricsPage(t *testing.T) {
+	tests := []struct {
+		name                string
+		path                string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "report_page",
+			path:                "/",
+			accept:              "*/*",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: html.ContentType,
+			expectedBody:        "<html>metricName: 100<br>metrics_server_stored_series: 1<br></html>",
+		},
+		{
+			name:                "metrics_default",
+			path:                "/metrics",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: prometheus.TextContentType,
+			expectedBody:        "# TYPE metricName counter\nmetricName 100\n# TYPE metrics_server_stored_series gauge\nmetrics_server_stored_series 1\n",
+		},
+		{
+			name:                "metrics_text",
+			path:                "/metrics",
+			accept:              "text/plain;version=0.0.4;q=0.5,*/*;q=0.1",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: prometheus.TextContentType,
+			expectedBody:        "# TYPE metricName counter\nmetricName 100\n# TYPE metrics_server_stored_series gauge\nmetrics_server_stored_series 1\n",
+		},
+		{
+			name:                "metrics_html",
+			path:                "/metrics",
+			accept:              "text/html,*/*;q=0.8",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: html.ContentType,
+			expectedBody:        "<html>metricName: 100<br>metrics_server_stored_series: 1<br></html>",
+		},
+		{
+			name:           "metrics_not_acceptable",
+			path:           "/metrics",
+			accept:         "application/json",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			_, err := metricsStorage.AddMetricValues(context.Background(), []metrics.Metric{createCounterMetric("metricName", 100)})
+			require.NoError(t, err)
+
+			request := httptest.NewRequest(http.MethodGet, "http://localhost:8080"+tt.path, nil)
+			if tt.accept != "" {
+				request.Header.Add("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			requestHandler := handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder(), prometheus.NewTextPageBuilder())
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), requestHandler)
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				body, err := io.ReadAll(actual.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.expectedContentType, actual.Header.Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func Test_ServerMetrics(t *testing.T) {
+	conf := &testConf{}
+	converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+	recorder := telemetry.NewRecorder(memory.NewInMemoryStorage())
+	requestHandler := handler.NewHandler(&testDBStorage{}, memory.NewInMemoryStorage(), recorder, agents.NewRegistry(), prometheus.NewTextPageBuilder())
+	router := createRouter(converter, nil, nil, nil, recorder, tracing.NewTracer(nil), requestHandler)
+
+	for _, path := range []string{"/update/counter/requests/1", "/update/counter/metrics_server_requests/1"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:8080"+path, nil))
+		w.Result().Body.Close()
+	}
+
+	// failed requests are counted by status code
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/value/counter/metrics_server_stored_series", nil))
+	actual := w.Result()
+	defer actual.Body.Close()
+	assert.Equal(t, http.StatusNotFound, actual.StatusCode)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/metrics", nil))
+	actual = w.Result()
+	defer actual.Body.Close()
+
+	body, err := io.ReadAll(actual.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `metrics_server_http_requests_total{code="200",method="POST",route="/update/counter/{metricName}/{metricValue}"} 1`)
+	assert.Contains(t, string(body), `metrics_server_http_requests_total{code="400",method="POST",route="/update/counter/{metricName}/{metricValue}"} 1`)
+	assert.Contains(t, string(body), `metrics_server_http_requests_total{code="404",method="GET",route="/value/{metricType}/{metricName}"} 1`)
+	assert.Contains(t, string(body), "metrics_server_stored_series 1\n")
+	assert.NotContains(t, string(body), "metrics_server_requests")
+
+	// own metrics are readable as regular ones
+	counter, err := requestHandler.GetMetricValue(context.Background(), "counter", telemetry.HTTPRequests, metrics.NewLabels(
+		metrics.Label{Name: "route", Value: "/update/counter/{metricName}/{metricValue}"},
+		metrics.Label{Name: "method", Value: http.MethodPost},
+		metrics.Label{Name: "code", Value: "200"},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), counter.GetValue())
+}
+
+func Test_SignedUpdatesRequest(t *testing.T) {
+	body := []byte(`[{"id":"counterMetricName","type":"counter","delta":100}]`)
+	signer := hash.NewSigner(&testConf{key: []byte("test secret key")})
+	otherSigner := hash.NewSigner(&testConf{key: []byte("other secret key")})
+
+	tests := []struct {
+		name           string
+		required       bool
+		signer         *hash.Signer
+		replay         bool
+		expectedStatus int
+		expectedReason string
+	}{
+		{
+			name:           "unsigned_optional",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unsigned_required",
+			required:       true,
+			expectedStatus: http.StatusUnauthorized,
+			expectedReason: "unsigned",
+		},
+		{
+			name:           "signed",
+			required:       true,
+			signer:         signer,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "other_key",
+			signer:         otherSigner,
+			expectedStatus: http.StatusUnauthorized,
+			expectedReason: "signature",
+		},
+		{
+			name:           "replayed",
+			signer:         signer,
+			replay:         true,
+			expectedStatus: http.StatusConflict,
+			expectedReason: "replayed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &testConf{key: []byte("test secret key"), requireSign: tt.required}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			recorder := telemetry.NewRecorder(memory.NewInMemoryStorage())
+			requestHandler := handler.NewHandler(&testDBStorage{}, memory.NewInMemoryStorage(), recorder, agents.NewRegistry(), prometheus.NewTextPageBuilder())
+			verifier := hash.NewRequestVerifier(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, verifier, nil, recorder, tracing.NewTracer(nil), requestHandler)
+
+			headers := http.Header{}
+			if tt.signer != nil {
+				sign, signature, err := tt.signer.SignRequest(http.MethodPost+" /updates", body)
+				require.NoError(t, err)
+				headers.Set(hash.RequestSignHeader, signature)
+				headers.Set(hash.RequestTimestampHeader, sign.TimestampString())
+				headers.Set(hash.RequestNonceHeader, sign.Nonce)
+			}
+
+			send := func() *http.Response {
+				request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/updates", bytes.NewReader(body))
+				request.Header = headers.Clone()
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, request)
+				return w.Result()
+			}
+
+			if tt.replay {
+				first := send()
+				first.Body.Close()
+				require.Equal(t, http.StatusOK, first.StatusCode)
+			}
+
+			actual := send()
+			defer actual.Body.Close()
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/metrics", nil))
+			page := w.Result()
+			defer page.Body.Close()
+
+			content, err := io.ReadAll(page.Body)
+			require.NoError(t, err)
+			if tt.expectedReason == "" {
+				assert.NotContains(t, string(content), "metrics_server_rejected_requests_total")
 			} else {
-				assert.Equal(t, http.StatusNotFound, actual.StatusCode)
-				defer actual.Body.Close()
+				assert.Contains(t, string(content), `metrics_server_rejected_requests_total{reason="`+tt.expectedReason+`",transport="http"} 1`)
+			}
+		})
+	}
+}
+
+func Test_LogLevel(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+		expectedLevel  logger.Level
+	}{
+		{
+			name:           "get",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "info",
+			expectedLevel:  logger.InfoLevel,
+		},
+		{
+			name:           "set",
+			method:         http.MethodPut,
+			body:           "debug",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "debug",
+			expectedLevel:  logger.DebugLevel,
+		},
+		{
+			name:           "set_unknown",
+			method:         http.MethodPut,
+			body:           "trace",
+			expectedStatus: http.StatusBadRequest,
+			expectedLevel:  logger.InfoLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.SetLevel(logger.InfoLevel)
+			defer logger.SetLevel(logger.InfoLevel)
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil),
+				handler.NewHandler(&testDBStorage{}, memory.NewInMemoryStorage(), telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry()))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, "http://localhost:8080/debug/loglevel", strings.NewReader(tt.body)))
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedStatus == http.StatusOK {
 				body, err := io.ReadAll(actual.Body)
-				if err != nil {
-					t.Fatal(err)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+			assert.Equal(t, tt.expectedLevel, logger.GetLevel())
+		})
+	}
+}
+
+func Test_TraceRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		traceparent     string
+		expectedStatus  int
+		expectedTraceID string
+		expectedParent  string
+	}{
+		{
+			name:           "new_trace",
+			url:            "http://localhost:8080/update/counter/metric/1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "remote_trace",
+			url:             "http://localhost:8080/update/counter/metric/1",
+			traceparent:     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			expectedStatus:  http.StatusOK,
+			expectedTraceID: "0af7651916cd43dd8448eb211c80319c",
+			expectedParent:  "b7ad6b7169203331",
+		},
+		{
+			name:           "invalid_traceparent",
+			url:            "http://localhost:8080/update/counter/metric/1",
+			traceparent:    "00-00000000000000000000000000000000-b7ad6b7169203331-01",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "error_response",
+			url:             "http://localhost:8080/update/unknown/metric/1",
+			traceparent:     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			expectedStatus:  http.StatusNotImplemented,
+			expectedTraceID: "0af7651916cd43dd8448eb211c80319c",
+			expectedParent:  "b7ad6b7169203331",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := &bytes.Buffer{}
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(tracing.NewWriterExporter(spans)),
+				handler.NewHandler(&testDBStorage{}, memory.NewInMemoryStorage(), telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry()))
+
+			request := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			if tt.traceparent != "" {
+				request.Header.Add(tracing.TraceparentHeader, tt.traceparent)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+
+			requestID := actual.Header.Get(tracing.RequestIDHeader)
+			if tt.expectedTraceID != "" {
+				assert.Equal(t, tt.expectedTraceID, requestID)
+			} else {
+				assert.Len(t, requestID, 32)
+				assert.NotEqual(t, "00000000000000000000000000000000", requestID)
+			}
+
+			spanContext, err := tracing.ParseTraceparent(actual.Header.Get(tracing.TraceparentHeader))
+			require.NoError(t, err)
+			assert.Equal(t, requestID, spanContext.TraceID)
+
+			span := &tracing.SpanData{}
+			require.NoError(t, json.Unmarshal(spans.Bytes(), span))
+			assert.Equal(t, requestID, span.TraceID)
+			assert.Equal(t, spanContext.SpanID, span.SpanID)
+			assert.Equal(t, tt.expectedParent, span.ParentSpanID)
+			assert.Equal(t, strconv.Itoa(tt.expectedStatus), span.Attributes["status"])
+			assert.Equal(t, tt.expectedStatus != http.StatusOK, span.Error != "")
+		})
+	}
+}
+
+func Test_GetAgents(t *testing.T) {
+	conf := &testConf{}
+	converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+	router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, memory.NewInMemoryStorage(), telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+
+	value := float64(1)
+	body, err := json.Marshal([]model.Metrics{
+		{ID: "Alloc", MType: "gauge", Value: &value},
+		{ID: "CPUutilization", MType: "gauge", Value: &value, Labels: map[string]string{"cpu": "0"}},
+	})
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/updates", bytes.NewReader(body))
+	request.Header.Add("X-Real-IP", "10.0.0.1")
+	request.Header.Add(agents.AgentIDHeader, "agent1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	w.Result().Body.Close()
+
+	request = httptest.NewRequest(http.MethodPost, "http://localhost:8080/update/counter/Manual/1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	w.Result().Body.Close()
+
+	request = httptest.NewRequest(http.MethodGet, "http://localhost:8080/agents", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+	actual := w.Result()
+	defer actual.Body.Close()
+
+	assert.Equal(t, http.StatusOK, actual.StatusCode)
+	assert.Equal(t, "application/json", actual.Header.Get("Content-Type"))
+
+	result := []*model.Agent{}
+	err = json.NewDecoder(actual.Body).Decode(&result)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "agent1", result[0].ID)
+	assert.Equal(t, "10.0.0.1", result[0].Source)
+	assert.Equal(t, 2, result[0].SeriesCount)
+	assert.GreaterOrEqual(t, result[0].HeartbeatAge, float64(0))
+	assert.False(t, result[0].LastSeen.IsZero())
+}
+
+func Test_HTTPSAgentIdentity(t *testing.T) {
+	dir := t.TempDir()
+	authority := test.NewAuthority(t, dir, "ca")
+	serverCert, serverKey := authority.Issue(t, "server", "127.0.0.1")
+	agentCert, agentKey := authority.Issue(t, "agent-cn")
+
+	tlsConfig, err := certs.NewServerConfig(serverCert, serverKey, authority.CertPath, certs.HTTP1Protocol)
+	require.NoError(t, err)
+
+	conf := &testConf{}
+	converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+	router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, memory.NewInMemoryStorage(), telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+	server := httptest.NewUnstartedServer(router)
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	clientConfig, err := certs.NewClientConfig(agentCert, agentKey, authority.CertPath)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/update/counter/Manual/1", nil)
+	require.NoError(t, err)
+	request.Header.Add(agents.AgentIDHeader, "declared")
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	response.Body.Close()
+
+	response, err = client.Get(server.URL + "/agents")
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	result := []*model.Agent{}
+	err = json.NewDecoder(response.Body).Decode(&result)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "agent-cn", result[0].ID)
+}
+
+func Test_QueryRangeRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedPoints []float64
+	}{
+		{
+			name:           "success",
+			query:          "name=cpu&type=gauge&labels=%7Bcore%3D%220%22%7D&start=1000&end=1020&step=10",
+			expectedStatus: http.StatusOK,
+			expectedPoints: []float64{1, 2, 3},
+		},
+		{
+			name:           "other_series",
+			query:          "name=cpu&type=gauge&start=1000&end=1020&step=10s",
+			expectedStatus: http.StatusOK,
+			expectedPoints: []float64{},
+		},
+		{
+			name:           "name_missed",
+			query:          "type=gauge&start=1000&end=1020&step=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "type_missed",
+			query:          "name=cpu&start=1000&end=1020&step=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid_start",
+			query:          "name=cpu&type=gauge&start=abc&end=1020&step=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid_step",
+			query:          "name=cpu&type=gauge&start=1000&end=1020&step=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "end_before_start",
+			query:          "name=cpu&type=gauge&start=1020&end=1000&step=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			for i, value := range []float64{1, 2, 3} {
+				metric := types.NewGaugeMetric("cpu", metrics.Label{Name: "core", Value: "0"})
+				metric.SetValue(value)
+				err := metricsStorage.AddSamples(context.Background(), time.Unix(int64(1000+i*10), 0), []metrics.Metric{metric})
+				require.NoError(t, err)
+			}
+
+			request := httptest.NewRequest(http.MethodGet, "http://localhost:8080/query_range?"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				result := &model.Range{}
+				err := json.NewDecoder(actual.Body).Decode(result)
+				require.NoError(t, err)
+
+				points := make([]float64, len(result.Points))
+				for i, point := range result.Points {
+					points[i] = point.Value
 				}
+				assert.Equal(t, tt.expectedPoints, points)
+			}
+		})
+	}
+}
 
-				assert.Equal(t, fmt.Sprintf("failed to get metric value: failed to get metric with type '%s' and name '%s': metric not found\n",
-					tt.metricType, tt.metricName), string(body))
+func Test_RemoveMetricsRequest(t *testing.T) {
+	tests := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedResponse string
+		expectedSeries   map[string][]string
+	}{
+		{
+			name:             "single_metric",
+			path:             "value/gauge/cpu_usage",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "2",
+			expectedSeries:   map[string][]string{"counter": {"cpu_usage", "requests"}, "gauge": {"memory_usage"}},
+		},
+		{
+			name:             "single_metric_with_labels",
+			path:             "value/gauge/cpu_usage?labels=" + url.QueryEscape(`{cpu="0"}`),
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "1",
+			expectedSeries:   map[string][]string{"counter": {"cpu_usage", "requests"}, "gauge": {`cpu_usage{cpu="1"}`, "memory_usage"}},
+		},
+		{
+			name:             "missed_metric",
+			path:             "value/gauge/requests",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: "metric 'requests' not found\n",
+		},
+		{
+			name:             "bulk_by_prefix",
+			path:             "value/?prefix=cpu_",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "3",
+			expectedSeries:   map[string][]string{"counter": {"requests"}, "gauge": {"memory_usage"}},
+		},
+		{
+			name:             "bulk_by_regex_and_type",
+			path:             "value/?type=gauge&regex=" + url.QueryEscape("_usage$"),
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "3",
+			expectedSeries:   map[string][]string{"counter": {"cpu_usage", "requests"}},
+		},
+		{
+			name:             "bulk_nothing_matched",
+			path:             "value/?prefix=disk_",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "0",
+		},
+		{
+			name:           "bulk_without_filter",
+			path:           "value/?type=gauge",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bulk_invalid_regex",
+			path:           "value/?regex=" + url.QueryEscape("(cpu"),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			_, err := metricsStorage.AddMetricValues(context.Background(), []metrics.Metric{
+				types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "0"}),
+				types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "1"}),
+				types.NewGaugeMetric("memory_usage"),
+				types.NewCounterMetric("cpu_usage"),
+				types.NewCounterMetric("requests"),
+			})
+			require.NoError(t, err)
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+
+			request := httptest.NewRequest(http.MethodDelete, "http://localhost:8080/"+tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+			if tt.expectedResponse != "" {
+				body, err := io.ReadAll(actual.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedResponse, string(body))
 			}
+
+			if tt.expectedSeries == nil {
+				return
+			}
+
+			values, err := metricsStorage.GetMetricValues(context.Background())
+			require.NoError(t, err)
+
+			actualSeries := map[string][]string{}
+			for metricType, typedValues := range values {
+				for seriesKey := range typedValues {
+					actualSeries[metricType] = append(actualSeries[metricType], seriesKey)
+				}
+			}
+			for metricType := range actualSeries {
+				assert.ElementsMatch(t, tt.expectedSeries[metricType], actualSeries[metricType])
+			}
+			assert.Len(t, actualSeries, len(tt.expectedSeries))
+		})
+	}
+}
+
+func Test_ResetCountersRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedValues map[string]string
+	}{
+		{
+			name:           "single_counter",
+			path:           "reset/counter/requests",
+			expectedStatus: http.StatusOK,
+			expectedValues: map[string]string{"requests": "0", "requests_failed": "3", "errors": "5"},
+		},
+		{
+			name:           "missed_counter",
+			path:           "reset/counter/latency",
+			expectedStatus: http.StatusNotFound,
+			expectedValues: map[string]string{"requests": "10", "requests_failed": "3", "errors": "5"},
+		},
+		{
+			name:           "bulk_by_prefix",
+			path:           "reset/?prefix=requests",
+			expectedStatus: http.StatusOK,
+			expectedValues: map[string]string{"requests": "0", "requests_failed": "0", "errors": "5"},
+		},
+		{
+			name:           "bulk_without_filter",
+			path:           "reset/",
+			expectedStatus: http.StatusBadRequest,
+			expectedValues: map[string]string{"requests": "10", "requests_failed": "3", "errors": "5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			_, err := metricsStorage.AddMetricValues(context.Background(), []metrics.Metric{
+				createCounterMetric("requests", 10),
+				createCounterMetric("requests_failed", 3),
+				createCounterMetric("errors", 5),
+				createGaugeMetric("requests", 100),
+			})
+			require.NoError(t, err)
+
+			conf := &testConf{}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, nil, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), html.NewSimplePageBuilder()))
+
+			request := httptest.NewRequest(http.MethodPost, "http://localhost:8080/"+tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+			actual := w.Result()
+			defer actual.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+
+			values, err := metricsStorage.GetMetricValues(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, values["counter"])
+			assert.Equal(t, map[string]string{"requests": "100"}, values["gauge"])
+		})
+	}
+}
+
+func Test_SignedMaintenanceRequests(t *testing.T) {
+	signer := hash.NewSigner(&testConf{key: []byte("test secret key")})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		signedPath     string
+		signed         bool
+		replay         bool
+		expectedStatus int
+		expectedValues map[string]string
+	}{
+		{
+			name:           "unsigned_remove",
+			method:         http.MethodDelete,
+			path:           "/value/counter/requests",
+			expectedStatus: http.StatusUnauthorized,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "unsigned_bulk_remove",
+			method:         http.MethodDelete,
+			path:           "/value/?prefix=requests",
+			expectedStatus: http.StatusUnauthorized,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "unsigned_reset",
+			method:         http.MethodPost,
+			path:           "/reset/counter/requests",
+			expectedStatus: http.StatusUnauthorized,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "unsigned_bulk_reset",
+			method:         http.MethodPost,
+			path:           "/reset/?prefix=requests",
+			expectedStatus: http.StatusUnauthorized,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "signed_remove",
+			method:         http.MethodDelete,
+			path:           "/value/counter/requests",
+			signed:         true,
+			expectedStatus: http.StatusOK,
+			expectedValues: map[string]string{"errors": "5"},
+		},
+		{
+			name:           "signed_reset",
+			method:         http.MethodPost,
+			path:           "/reset/counter/requests",
+			signed:         true,
+			expectedStatus: http.StatusOK,
+			expectedValues: map[string]string{"requests": "0", "errors": "5"},
+		},
+		{
+			name:           "modified_filter",
+			method:         http.MethodDelete,
+			path:           "/value/?prefix=",
+			signedPath:     "/value/?prefix=requests",
+			signed:         true,
+			expectedStatus: http.StatusUnauthorized,
+			expectedValues: map[string]string{"requests": "10", "errors": "5"},
+		},
+		{
+			name:           "replayed_reset",
+			method:         http.MethodPost,
+			path:           "/reset/counter/requests",
+			signed:         true,
+			replay:         true,
+			expectedStatus: http.StatusConflict,
+			expectedValues: map[string]string{"requests": "0", "errors": "5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metricsStorage := memory.NewInMemoryStorage()
+			_, err := metricsStorage.AddMetricValues(context.Background(), []metrics.Metric{
+				createCounterMetric("requests", 10),
+				createCounterMetric("errors", 5),
+			})
+			require.NoError(t, err)
+
+			conf := &testConf{key: []byte("test secret key"), requireSign: true}
+			converter := metricsHttp.NewMetricsConverter(conf, hash.NewSigner(conf))
+			recorder := telemetry.NewRecorder(memory.NewInMemoryStorage())
+			verifier := hash.NewRequestVerifier(conf, hash.NewSigner(conf))
+			router := createRouter(converter, nil, verifier, nil, recorder, tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, recorder, agents.NewRegistry(), html.NewSimplePageBuilder()))
+
+			headers := http.Header{}
+			if tt.signed {
+				signedPath := tt.path
+				if tt.signedPath != "" {
+					signedPath = tt.signedPath
+				}
+
+				sign, signature, err := signer.SignRequest(tt.method+" "+signedPath, nil)
+				require.NoError(t, err)
+				headers.Set(hash.RequestSignHeader, signature)
+				headers.Set(hash.RequestTimestampHeader, sign.TimestampString())
+				headers.Set(hash.RequestNonceHeader, sign.Nonce)
+			}
+
+			send := func() *http.Response {
+				request := httptest.NewRequest(tt.method, "http://localhost:8080"+tt.path, nil)
+				request.Header = headers.Clone()
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, request)
+				return w.Result()
+			}
+
+			if tt.replay {
+				first := send()
+				first.Body.Close()
+				require.Equal(t, http.StatusOK, first.StatusCode)
+			}
+
+			actual := send()
+			defer actual.Body.Close()
+			assert.Equal(t, tt.expectedStatus, actual.StatusCode)
+
+			values, err := metricsStorage.GetMetricValues(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValues, values["counter"])
 		})
 	}
 }
@@ -469,7 +1557,8 @@ func Test_GetMetricUrlRequest(t *testing.T) {
 func Test_GetMetricJsonRequest_MethodNotAllowed(t *testing.T) {
 	expected := expectedNotAllowed()
 	for _, method := range getMethods() {
-		if method == http.MethodPost {
+		// DELETE removes series selected by the query
+		if method == http.MethodPost || method == http.MethodDelete {
 			continue
 		}
 
@@ -680,7 +1769,7 @@ func runJSONTest(t *testing.T, apiRequest jsonAPIRequest) *callResult {
 	converter := metricsHttp.NewMetricsConverter(conf, signer)
 	_, subnet, err := net.ParseCIDR("127.0.0.1/8")
 	assert.NoError(t, err)
-	router := createRouter(converter, nil, subnet, handler.NewHandler(&testDBStorage{}, htmlPageBuilder, metricsStorage))
+	router := createRouter(converter, nil, nil, subnet, telemetry.NewRecorder(memory.NewInMemoryStorage()), tracing.NewTracer(nil), handler.NewHandler(&testDBStorage{}, metricsStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry(), htmlPageBuilder))
 	router.ServeHTTP(w, request)
 	actual := w.Result()
 	result := &callResult{status: actual.StatusCode}
@@ -800,7 +1889,7 @@ func createGaugeMetric(name string, value float64) metrics.Metric {
 	return createMetric(types.NewGaugeMetric, name, value)
 }
 
-func createMetric(metricFactory func(string) metrics.Metric, name string, value float64) metrics.Metric {
+func createMetric(metricFactory func(string, ...metrics.Label) metrics.Metric, name string, value float64) metrics.Metric {
 	metric := metricFactory(name)
 	metric.SetValue(value)
 	return metric
@@ -814,6 +1903,18 @@ func (t *testConf) GetKey() []byte {
 	return t.key
 }
 
+func (t *testConf) RequireRequestSign() bool {
+	return t.requireSign
+}
+
+func (t *testConf) RequestMaxSkew() time.Duration {
+	return time.Minute
+}
+
+func (t *testConf) NonceCacheSize() int {
+	return 100
+}
+
 func (t testDBStorage) Ping(context.Context) error {
 	return nil
 }
@@ -827,7 +1928,17 @@ func (t *testDBStorage) UpdateRecords(ctx context.Context, records []*database.D
 	panic("implement me")
 }
 
-func (t *testDBStorage) ReadRecord(ctx context.Context, metricType string, metricName string) (*database.DBRecord, error) {
+func (t *testDBStorage) AccumulateRecords(ctx context.Context, records []*database.DBRecord, merge database.MergeFunc) ([]*database.DBRecord, error) {
+	// TODO implement me
+	panic("implement me")
+}
+
+func (t *testDBStorage) RemoveRecords(ctx context.Context, records []*database.DBRecord) error {
+	// TODO implement me
+	panic("implement me")
+}
+
+func (t *testDBStorage) ReadRecord(ctx context.Context, metricType string, metricName string, metricLabels string) (*database.DBRecord, error) {
 	// TODO implement me
 	panic("implement me")
 }
@@ -836,3 +1947,28 @@ func (t *testDBStorage) ReadAll(ctx context.Context) ([]*database.DBRecord, erro
 	// TODO implement me
 	panic("implement me")
 }
+
+func (t *testDBStorage) AddSamples(ctx context.Context, samples []*database.DBSample) error {
+	// TODO implement me
+	panic("implement me")
+}
+
+func (t *testDBStorage) ReadSamples(ctx context.Context, metricType string, metricName string, metricLabels string, start time.Time, end time.Time) ([]*database.DBSample, error) {
+	// TODO implement me
+	panic("implement me")
+}
+
+func (t *testDBStorage) ReadAllSamples(ctx context.Context) ([]*database.DBSample, error) {
+	// TODO implement me
+	panic("implement me")
+}
+
+func (t *testDBStorage) ReplaceSamples(ctx context.Context, samples []*database.DBSample) error {
+	// TODO implement me
+	panic("implement me")
+}
+
+func (t *testDBStorage) RemoveSamples(ctx context.Context, before time.Time) error {
+	// TODO implement me
+	panic("implement me")
+}
@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// Label is a single metric dimension.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Labels is a list of metric labels, sorted by name.
+type Labels []Label
+
+// NewLabels creates sorted labels list. The last value wins for duplicated label names.
+func NewLabels(labels ...Label) Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]string, len(labels))
+	for _, label := range labels {
+		byName[label.Name] = label.Value
+	}
+
+	return LabelsFromMap(byName)
+}
+
+// LabelsFromMap creates sorted labels list from name to value map.
+func LabelsFromMap(labels map[string]string) Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	result := make(Labels, 0, len(labels))
+	for name, value := range labels {
+		result = append(result, Label{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+// Map returns labels as name to value map.
+func (l Labels) Map() map[string]string {
+	if len(l) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(l))
+	for _, label := range l {
+		result[label.Name] = label.Value
+	}
+
+	return result
+}
+
+// Validate checks that all label names are valid prometheus label names.
+func (l Labels) Validate() error {
+	for _, label := range l {
+		if !isLabelName(label.Name) {
+			return ErrInvalidLabel
+		}
+	}
+
+	return nil
+}
+
+// String returns canonical labels representation, like {cpu="1",host="local"}.
+// Empty string is returned for empty labels list.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, label := range l {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(label.Name)
+		sb.WriteString("=\"")
+		sb.WriteString(escapeLabelValue(label.Value))
+		sb.WriteString("\"")
+	}
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
+// ValidateSeries checks that metric name and labels can be combined into unambiguous series key.
+func ValidateSeries(name string, labels Labels) error {
+	if strings.Contains(name, "{") {
+		return ErrInvalidMetricName
+	}
+
+	return labels.Validate()
+}
+
+// SeriesKey returns unique metric series identifier within metric type.
+func SeriesKey(name string, labels Labels) string {
+	return name + labels.String()
+}
+
+// ParseSeriesKey splits series identifier to metric name and labels.
+func ParseSeriesKey(key string) (string, Labels, error) {
+	name, labelsString, found := strings.Cut(key, "{")
+	if !found {
+		return key, nil, nil
+	}
+
+	labels, err := ParseLabels("{" + labelsString)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return name, labels, nil
+}
+
+// ParseLabels restores labels from canonical string representation.
+func ParseLabels(str string) (Labels, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(str, "{") || !strings.HasSuffix(str, "}") {
+		return nil, ErrInvalidLabel
+	}
+
+	var labels []Label
+	rest := str[1 : len(str)-1]
+	for rest != "" {
+		name, value, found := strings.Cut(rest, "=\"")
+		if !found || !isLabelName(name) {
+			return nil, ErrInvalidLabel
+		}
+
+		unescaped, tail, ok := unescapeLabelValue(value)
+		if !ok {
+			return nil, ErrInvalidLabel
+		}
+
+		labels = append(labels, Label{Name: name, Value: unescaped})
+
+		rest = tail
+		if rest != "" {
+			if !strings.HasPrefix(rest, ",") {
+				return nil, ErrInvalidLabel
+			}
+			rest = rest[1:]
+		}
+	}
+
+	return NewLabels(labels...), nil
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// unescapeLabelValue reads quoted label value up to the closing quote and returns the remaining string.
+func unescapeLabelValue(str string) (string, string, bool) {
+	sb := strings.Builder{}
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '"':
+			return sb.String(), str[i+1:], true
+		case '\\':
+			i++
+			if i == len(str) {
+				return "", "", false
+			}
+
+			switch str[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case '\\', '"':
+				sb.WriteByte(str[i])
+			default:
+				return "", "", false
+			}
+		default:
+			sb.WriteByte(str[i])
+		}
+	}
+
+	return "", "", false
+}
+
+func isLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, ch := range name {
+		isLetter := ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_'
+		if !isLetter && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+
+	return true
+}
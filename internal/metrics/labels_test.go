@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   []Label
+		expected Labels
+	}{
+		{
+			name:     "no_labels",
+			expected: nil,
+		},
+		{
+			name:     "sorted",
+			labels:   []Label{{Name: "host", Value: "local"}, {Name: "cpu", Value: "1"}},
+			expected: Labels{{Name: "cpu", Value: "1"}, {Name: "host", Value: "local"}},
+		},
+		{
+			name:     "duplicated",
+			labels:   []Label{{Name: "cpu", Value: "1"}, {Name: "cpu", Value: "2"}},
+			expected: Labels{{Name: "cpu", Value: "2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewLabels(tt.labels...))
+		})
+	}
+}
+
+func TestLabels_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  Labels
+		isValid bool
+	}{
+		{name: "no_labels", isValid: true},
+		{name: "valid", labels: Labels{{Name: "agent_id"}, {Name: "_cpu1"}}, isValid: true},
+		{name: "empty_name", labels: Labels{{Name: ""}}},
+		{name: "leading_digit", labels: Labels{{Name: "1cpu"}}},
+		{name: "invalid_char", labels: Labels{{Name: "agent-id"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.labels.Validate()
+			if tt.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidLabel)
+			}
+		})
+	}
+}
+
+func TestValidateSeries(t *testing.T) {
+	assert.NoError(t, ValidateSeries("metric", NewLabels(Label{Name: "cpu", Value: "{1}"})))
+	assert.ErrorIs(t, ValidateSeries("metric{", nil), ErrInvalidMetricName)
+	assert.ErrorIs(t, ValidateSeries("metric", Labels{{Name: "cpu-1"}}), ErrInvalidLabel)
+}
+
+func TestSeriesKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		metricName string
+		labels     Labels
+		expected   string
+	}{
+		{
+			name:       "no_labels",
+			metricName: "Alloc",
+			expected:   "Alloc",
+		},
+		{
+			name:       "labels",
+			metricName: "CPUutilization",
+			labels:     NewLabels(Label{Name: "host", Value: "local"}, Label{Name: "cpu", Value: "3"}),
+			expected:   `CPUutilization{cpu="3",host="local"}`,
+		},
+		{
+			name:       "escaped_value",
+			metricName: "metric",
+			labels:     NewLabels(Label{Name: "path", Value: "a\"b\\c\nd,e}"}),
+			expected:   `metric{path="a\"b\\c\nd,e}"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := SeriesKey(tt.metricName, tt.labels)
+			assert.Equal(t, tt.expected, key)
+
+			metricName, labels, err := ParseSeriesKey(key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.metricName, metricName)
+			assert.Equal(t, tt.labels, labels)
+		})
+	}
+}
+
+func TestParseSeriesKey_Invalid(t *testing.T) {
+	tests := []string{
+		`metric{`,
+		`metric{cpu}`,
+		`metric{cpu="1"`,
+		`metric{cpu="1"host="2"}`,
+		`metric{1cpu="1"}`,
+		`metric{cpu="\x"}`,
+	}
+
+	for _, key := range tests {
+		t.Run(key, func(t *testing.T) {
+			_, _, err := ParseSeriesKey(key)
+			assert.ErrorIs(t, err, ErrInvalidLabel)
+		})
+	}
+}
@@ -9,6 +9,9 @@ type Metric interface {
 	// GetName returns metric name.
 	GetName() string
 
+	// GetLabels returns metric labels, sorted by name.
+	GetLabels() Labels
+
 	// GetType returns metric type.
 	GetType() string
 
@@ -24,3 +27,9 @@ type Metric interface {
 	// Flush reset metric state, if needed,
 	Flush()
 }
+
+// MergeableMetric is a metric with composite state, that can not be updated by single value.
+type MergeableMetric interface {
+	// Merge appends other metric state to the current one.
+	Merge(metric Metric) error
+}
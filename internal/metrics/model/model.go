@@ -1,9 +1,43 @@
 package model
 
+import "time"
+
 type Metrics struct {
-	ID    string   `json:"id"`              // имя метрики
-	MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
-	Delta *int64   `json:"delta,omitempty"` // значение метрики в случае передачи counter
-	Value *float64 `json:"value,omitempty"` // значение метрики в случае передачи gauge
-	Hash  string   `json:"hash,omitempty"`  // значение хеш-функции
+	ID           string            `json:"id"`                     // имя метрики
+	MType        string            `json:"type"`                   // параметр, принимающий значение gauge, counter, histogram или summary
+	Labels       map[string]string `json:"labels,omitempty"`       // метки метрики, входящие в её идентификатор
+	Delta        *int64            `json:"delta,omitempty"`        // значение метрики в случае передачи counter
+	Value        *float64          `json:"value,omitempty"`        // значение метрики в случае передачи gauge
+	Buckets      []float64         `json:"buckets,omitempty"`      // верхние границы корзин в случае передачи histogram
+	Counts       []uint64          `json:"counts,omitempty"`       // накопленное число наблюдений по корзинам в случае передачи histogram
+	Sum          *float64          `json:"sum,omitempty"`          // сумма наблюдений в случае передачи histogram или summary
+	Count        *uint64           `json:"count,omitempty"`        // общее число наблюдений в случае передачи histogram или summary
+	Observations []float64         `json:"observations,omitempty"` // сырые наблюдения в случае передачи summary
+	Quantiles    []*Quantile       `json:"quantiles,omitempty"`    // рассчитанные сервером квантили в случае передачи summary
+	Hash         string            `json:"hash,omitempty"`         // значение хеш-функции
+}
+
+type Quantile struct {
+	Quantile float64 `json:"quantile"` // квантиль в диапазоне [0, 1]
+	Value    float64 `json:"value"`    // оценка значения квантиля
+}
+
+type Agent struct {
+	ID           string    `json:"id"`            // идентификатор агента
+	Source       string    `json:"source"`        // адрес, с которого агент последний раз присылал метрики
+	LastSeen     time.Time `json:"last_seen"`     // время последней отправки метрик
+	HeartbeatAge float64   `json:"heartbeat_age"` // число секунд, прошедших с последней отправки метрик
+	SeriesCount  int       `json:"series_count"`  // число серий, последним источником которых является агент
+}
+
+type Range struct {
+	ID     string            `json:"id"`               // имя метрики
+	MType  string            `json:"type"`             // тип метрики
+	Labels map[string]string `json:"labels,omitempty"` // метки метрики
+	Points []*Point          `json:"points"`           // значения метрики с шагом запроса
+}
+
+type Point struct {
+	Timestamp time.Time `json:"timestamp"` // момент времени
+	Value     float64   `json:"value"`     // последнее полученное к этому моменту значение метрики
 }
@@ -0,0 +1,201 @@
+package prometheus
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
+)
+
+// TextContentType is a media type of prometheus text exposition format.
+const TextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var metricTypes = map[string]string{
+	"counter":   "counter",
+	"gauge":     "gauge",
+	"histogram": "histogram",
+	"summary":   "summary",
+}
+
+type textPageBuilder struct{}
+
+type series struct {
+	key      string
+	typeName string
+	name     string
+	labels   metrics.Labels
+	value    string
+}
+
+// NewTextPageBuilder creates new instance of prometheus text exposition format page builder.
+func NewTextPageBuilder() *textPageBuilder {
+	return &textPageBuilder{}
+}
+
+func (t textPageBuilder) BuildMetricsPage(metricsByType map[string]map[string]string) string {
+	sb := strings.Builder{}
+
+	familyTypes := map[string]string{}
+	written := map[string]bool{}
+	for _, s := range collectSeries(metricsByType) {
+		metricType, ok := metricTypes[s.typeName]
+		if !ok {
+			metricType = "untyped"
+		}
+
+		seriesKey := metrics.SeriesKey(s.name, s.labels)
+		familyType, familyExists := familyTypes[s.name]
+		if (familyExists && familyType != s.typeName) || written[seriesKey] {
+			logger.WarnFormat("Skip metric %s with type %s: series %s is already exposed", s.key, s.typeName, seriesKey)
+			continue
+		}
+		written[seriesKey] = true
+
+		switch metricType {
+		case "histogram":
+			histogram, err := metrics.ParseHistogram(s.value)
+			if err != nil {
+				logger.ErrorFormat("failed to parse histogram %s: %v", seriesKey, err)
+				continue
+			}
+
+			writeFamilyType(&sb, familyTypes, s, metricType)
+			writeHistogram(&sb, s.name, s.labels, histogram)
+		case "summary":
+			summary, err := types.ParseSummaryMetric(s.name, s.value)
+			if err != nil {
+				logger.ErrorFormat("failed to parse summary %s: %v", seriesKey, err)
+				continue
+			}
+
+			writeFamilyType(&sb, familyTypes, s, metricType)
+			writeSummary(&sb, s.name, s.labels, summary)
+		default:
+			writeFamilyType(&sb, familyTypes, s, metricType)
+			writeSample(&sb, s.name, s.labels.String(), s.value)
+		}
+	}
+
+	return sb.String()
+}
+
+// collectSeries returns series ordered by type, sanitized name and labels, so metric families are contiguous.
+func collectSeries(metricsByType map[string]map[string]string) []series {
+	var result []series
+	for typeName, metricsList := range metricsByType {
+		for seriesKey, value := range metricsList {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				logger.ErrorFormat("failed to parse series key %s: %v", seriesKey, err)
+				continue
+			}
+
+			result = append(result, series{
+				key:      seriesKey,
+				typeName: typeName,
+				name:     SanitizeName(metricName),
+				labels:   labels,
+				value:    value,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].typeName != result[j].typeName {
+			return result[i].typeName < result[j].typeName
+		}
+		if result[i].name != result[j].name {
+			return result[i].name < result[j].name
+		}
+		if labels := result[i].labels.String(); labels != result[j].labels.String() {
+			return labels < result[j].labels.String()
+		}
+		return result[i].key < result[j].key
+	})
+
+	return result
+}
+
+func writeFamilyType(sb *strings.Builder, familyTypes map[string]string, s series, metricType string) {
+	if _, ok := familyTypes[s.name]; ok {
+		return
+	}
+
+	familyTypes[s.name] = s.typeName
+	writeType(sb, s.name, metricType)
+}
+
+func writeType(sb *strings.Builder, name string, metricType string) {
+	sb.WriteString("# TYPE ")
+	sb.WriteString(name)
+	sb.WriteString(" ")
+	sb.WriteString(metricType)
+	sb.WriteString("\n")
+}
+
+func writeSample(sb *strings.Builder, name string, labels string, value string) {
+	sb.WriteString(name)
+	sb.WriteString(labels)
+	sb.WriteString(" ")
+	sb.WriteString(value)
+	sb.WriteString("\n")
+}
+
+func writeHistogram(sb *strings.Builder, name string, labels metrics.Labels, histogram *metrics.Histogram) {
+	for i, bound := range histogram.Bounds {
+		writeSample(sb, name+"_bucket", withLabel(labels, "le", parser.FloatToString(bound)), parser.UintToString(histogram.Counts[i]))
+	}
+	writeSample(sb, name+"_bucket", withLabel(labels, "le", "+Inf"), parser.UintToString(histogram.Count))
+	writeSample(sb, name+"_sum", labels.String(), parser.FloatToString(histogram.Sum))
+	writeSample(sb, name+"_count", labels.String(), parser.UintToString(histogram.Count))
+}
+
+func writeSummary(sb *strings.Builder, name string, labels metrics.Labels, metric metrics.SummaryMetric) {
+	summary := metric.GetSummary()
+	if summary.Count > 0 {
+		for _, quantile := range metrics.DefaultQuantiles {
+			writeSample(sb, name, withLabel(labels, "quantile", parser.FloatToString(quantile)), parser.FloatToString(metric.GetQuantile(quantile)))
+		}
+	}
+	writeSample(sb, name+"_sum", labels.String(), parser.FloatToString(summary.Sum))
+	writeSample(sb, name+"_count", labels.String(), parser.UintToString(summary.Count))
+}
+
+func withLabel(labels metrics.Labels, name string, value string) string {
+	return metrics.NewLabels(append(append(metrics.Labels{}, labels...), metrics.Label{Name: name, Value: value})...).String()
+}
+
+func (t textPageBuilder) ContentType() string {
+	return TextContentType
+}
+
+// SanitizeName converts metric name to valid prometheus metric name.
+func SanitizeName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	result := strings.Map(func(ch rune) rune {
+		if isNameChar(ch) {
+			return ch
+		}
+		return '_'
+	}, name)
+
+	if isDigit(rune(result[0])) {
+		return "_" + result
+	}
+
+	return result
+}
+
+func isNameChar(ch rune) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' || ch == ':' || isDigit(ch)
+}
+
+func isDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,169 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+)
+
+func TestTextPageBuilder_BuildMetricsPage(t *testing.T) {
+	tests := []struct {
+		name          string
+		metricsByType map[string]map[string]string
+		expected      string
+	}{
+		{
+			name:          "no_metric",
+			metricsByType: map[string]map[string]string{},
+			expected:      "",
+		},
+		{
+			name: "all_metric",
+			metricsByType: map[string]map[string]string{
+				"gauge": {
+					"metricName2": "300.003",
+					"metricName1": "-100.001",
+				},
+				"counter": {
+					"metricName3": "200",
+				},
+			},
+			expected: "" +
+				"# TYPE metricName3 counter\n" +
+				"metricName3 200\n" +
+				"# TYPE metricName1 gauge\n" +
+				"metricName1 -100.001\n" +
+				"# TYPE metricName2 gauge\n" +
+				"metricName2 300.003\n",
+		},
+		{
+			name: "histogram",
+			metricsByType: map[string]map[string]string{
+				"histogram": {
+					"latency": `{"bounds":[0.1,1],"counts":[1,3],"sum":2.5,"count":4}`,
+					"invalid": `100`,
+				},
+			},
+			expected: "" +
+				"# TYPE latency histogram\n" +
+				"latency_bucket{le=\"0.1\"} 1\n" +
+				"latency_bucket{le=\"1\"} 3\n" +
+				"latency_bucket{le=\"+Inf\"} 4\n" +
+				"latency_sum 2.5\n" +
+				"latency_count 4\n",
+		},
+		{
+			name: "summary",
+			metricsByType: map[string]map[string]string{
+				"summary": {
+					"duration": types.NewSummaryMetricFromObservations("duration", []float64{1, 2, 3, 4, 5}).GetStringValue(),
+					"empty":    types.NewSummaryMetric("empty").GetStringValue(),
+					"invalid":  `100`,
+				},
+			},
+			expected: "" +
+				"# TYPE duration summary\n" +
+				"duration{quantile=\"0.5\"} 3\n" +
+				"duration{quantile=\"0.9\"} 5\n" +
+				"duration{quantile=\"0.99\"} 5\n" +
+				"duration_sum 15\n" +
+				"duration_count 5\n" +
+				"# TYPE empty summary\n" +
+				"empty_sum 0\n" +
+				"empty_count 0\n",
+		},
+		{
+			name: "labeled_series",
+			metricsByType: map[string]map[string]string{
+				"gauge": {
+					`CPUutilization{cpu="1"}`: "20",
+					`CPUutilization{cpu="0"}`: "10",
+					"CPUutilizationTotal":     "30",
+					`metric.name{a="b"}`:      "40",
+					`metric_name{a="b"}`:      "50",
+				},
+				"histogram": {
+					`latency{path="/update"}`: `{"bounds":[1],"counts":[1],"sum":0.5,"count":1}`,
+				},
+			},
+			expected: "" +
+				"# TYPE CPUutilization gauge\n" +
+				"CPUutilization{cpu=\"0\"} 10\n" +
+				"CPUutilization{cpu=\"1\"} 20\n" +
+				"# TYPE CPUutilizationTotal gauge\n" +
+				"CPUutilizationTotal 30\n" +
+				"# TYPE metric_name gauge\n" +
+				"metric_name{a=\"b\"} 40\n" +
+				"# TYPE latency histogram\n" +
+				"latency_bucket{le=\"1\",path=\"/update\"} 1\n" +
+				"latency_bucket{le=\"+Inf\",path=\"/update\"} 1\n" +
+				"latency_sum{path=\"/update\"} 0.5\n" +
+				"latency_count{path=\"/update\"} 1\n",
+		},
+		{
+			name: "unknown_type",
+			metricsByType: map[string]map[string]string{
+				"custom": {"metricName": "100"},
+			},
+			expected: "" +
+				"# TYPE metricName untyped\n" +
+				"metricName 100\n",
+		},
+		{
+			name: "sanitized_names",
+			metricsByType: map[string]map[string]string{
+				"gauge": {
+					"metric.name-1": "100",
+					"1metric":       "200",
+				},
+			},
+			expected: "" +
+				"# TYPE _1metric gauge\n" +
+				"_1metric 200\n" +
+				"# TYPE metric_name_1 gauge\n" +
+				"metric_name_1 100\n",
+		},
+		{
+			name: "duplicated_names",
+			metricsByType: map[string]map[string]string{
+				"counter": {"metricName": "100"},
+				"gauge":   {"metricName": "200"},
+			},
+			expected: "" +
+				"# TYPE metricName counter\n" +
+				"metricName 100\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewTextPageBuilder()
+
+			actual := builder.BuildMetricsPage(tt.metricsByType)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "", expected: "_"},
+		{name: "Alloc", expected: "Alloc"},
+		{name: "http:requests_total", expected: "http:requests_total"},
+		{name: "CPUutilization1", expected: "CPUutilization1"},
+		{name: "my metric.value", expected: "my_metric_value"},
+		{name: "9lives", expected: "_9lives"},
+		{name: "метрика", expected: "_______"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SanitizeName(tt.name))
+		})
+	}
+}
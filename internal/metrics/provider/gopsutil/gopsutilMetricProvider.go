@@ -2,8 +2,8 @@ package gopsutil
 
 import (
 	"context"
-	"fmt"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -29,7 +29,7 @@ func NewGopsutilMetricsProvider() *GopsutilMetricsProvider {
 	numCPU := runtime.NumCPU()
 	cpuUtilizationMetrics := make(map[int]metrics.Metric, numCPU)
 	for i := 0; i < numCPU; i++ {
-		cpuUtilizationMetrics[i] = types.NewGaugeMetric(fmt.Sprintf("CPUutilization%v", i+1))
+		cpuUtilizationMetrics[i] = types.NewGaugeMetric("CPUutilization", metrics.Label{Name: "cpu", Value: strconv.Itoa(i)})
 	}
 
 	return &GopsutilMetricsProvider{
@@ -85,7 +85,7 @@ func (g *GopsutilMetricsProvider) updateCPUMetrics(ctx context.Context) error {
 	for i, val := range cpuStats {
 		metric := g.cpuUtilizationMetrics[i]
 		metric.SetValue(val)
-		logger.InfoFormat("Updated metric: %v. value: %v", metric.GetName(), metric.GetStringValue())
+		logger.InfoFormat("Updated metric: %v. value: %v", metrics.SeriesKey(metric.GetName(), metric.GetLabels()), metric.GetStringValue())
 	}
 
 	return nil
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"runtime"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
 )
 
@@ -17,8 +17,8 @@ func TestGopsutilMetricsProvider_GetMetrics(t *testing.T) {
 		"FreeMemory",
 		"TotalMemory",
 	}
-	for i := 1; i < runtime.NumCPU()+1; i++ {
-		expected = append(expected, fmt.Sprintf("CPUutilization%d", i))
+	for i := 0; i < runtime.NumCPU(); i++ {
+		expected = append(expected, fmt.Sprintf("CPUutilization{cpu=\"%d\"}", i))
 	}
 
 	provider := NewGopsutilMetricsProvider()
@@ -26,7 +26,7 @@ func TestGopsutilMetricsProvider_GetMetrics(t *testing.T) {
 
 	assert.Len(t, expected, len(actual))
 	for _, actualMetric := range actual {
-		assert.Contains(t, expected, actualMetric.GetName())
+		assert.Contains(t, expected, metrics.SeriesKey(actualMetric.GetName(), actualMetric.GetLabels()))
 		assert.Equal(t, actualMetric.GetStringValue(), "0")
 	}
 }
@@ -45,7 +45,7 @@ func TestGopsutilMetricsProvider_Update(t *testing.T) {
 			assert.NotEqual(t, actualMetric.GetStringValue(), "0")
 		}
 
-		if strings.HasPrefix(name, "CPUutilization") && !cpuChecked {
+		if name == "CPUutilization" && !cpuChecked {
 			cpuChecked = actualMetric.GetStringValue() != "0"
 		}
 	}
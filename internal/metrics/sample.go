@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// MaxRangePoints limits the number of points returned by a single range query.
+	MaxRangePoints = 11000
+	// RangeLookback is the maximum age of a sample used as a value of a range point.
+	RangeLookback = 5 * time.Minute
+)
+
+// Sample is a timestamped metric value.
+type Sample struct {
+	// Timestamp is a moment when the value was received.
+	Timestamp time.Time `json:"timestamp"`
+	// Value is a metric value, the sum of observations for histograms and summaries.
+	Value float64 `json:"value"`
+}
+
+// ValidateRange checks range query boundaries and resolution.
+func ValidateRange(start time.Time, end time.Time, step time.Duration) error {
+	if step <= 0 || end.Before(start) {
+		return ErrInvalidRange
+	}
+
+	if end.Sub(start)/step >= MaxRangePoints {
+		return ErrInvalidRange
+	}
+
+	return nil
+}
+
+// SampleRange returns points evenly spaced by step in the [start, end] range.
+// Every point takes the value of the latest sample not older than RangeLookback,
+// points without such sample are skipped. Samples must be sorted by time.
+func SampleRange(samples []Sample, start time.Time, end time.Time, step time.Duration) []Sample {
+	result := []Sample{}
+	next := 0
+	for point := start; !point.After(end); point = point.Add(step) {
+		for next < len(samples) && !samples[next].Timestamp.After(point) {
+			next++
+		}
+
+		if next == 0 {
+			continue
+		}
+
+		latest := samples[next-1]
+		if point.Sub(latest.Timestamp) > RangeLookback {
+			continue
+		}
+
+		result = append(result, Sample{Timestamp: point, Value: latest.Value})
+	}
+
+	return result
+}
+
+// FilterSamples returns samples in the [start, end] range. Samples must be sorted by time.
+func FilterSamples(samples []Sample, start time.Time, end time.Time) []Sample {
+	from := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Before(start) })
+	to := sort.Search(len(samples), func(i int) bool { return samples[i].Timestamp.After(end) })
+	if from >= to {
+		return []Sample{}
+	}
+
+	result := make([]Sample, to-from)
+	copy(result, samples[from:to])
+	return result
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRange(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		end     time.Time
+		step    time.Duration
+		isValid bool
+	}{
+		{name: "valid", end: start.Add(time.Hour), step: time.Minute, isValid: true},
+		{name: "single_point", end: start, step: time.Second, isValid: true},
+		{name: "zero_step", end: start.Add(time.Hour)},
+		{name: "negative_step", end: start.Add(time.Hour), step: -time.Minute},
+		{name: "end_before_start", end: start.Add(-time.Hour), step: time.Minute},
+		{name: "too_many_points", end: start.Add(24 * time.Hour), step: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRange(start, tt.end, tt.step)
+			if tt.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidRange)
+			}
+		})
+	}
+}
+
+func TestSampleRange(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	samples := []Sample{
+		{Timestamp: at(5 * time.Second), Value: 1},
+		{Timestamp: at(10 * time.Second), Value: 2},
+		{Timestamp: at(12 * time.Second), Value: 3},
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		end      time.Time
+		step     time.Duration
+		expected []Sample
+	}{
+		{
+			name:  "sampled",
+			start: at(0),
+			end:   at(20 * time.Second),
+			step:  5 * time.Second,
+			expected: []Sample{
+				{Timestamp: at(5 * time.Second), Value: 1},
+				{Timestamp: at(10 * time.Second), Value: 2},
+				{Timestamp: at(15 * time.Second), Value: 3},
+				{Timestamp: at(20 * time.Second), Value: 3},
+			},
+		},
+		{
+			name:     "stale",
+			start:    at(RangeLookback + 13*time.Second),
+			end:      at(RangeLookback + 20*time.Second),
+			step:     5 * time.Second,
+			expected: []Sample{},
+		},
+		{
+			name:     "no_samples_before_range",
+			start:    at(0),
+			end:      at(4 * time.Second),
+			step:     time.Second,
+			expected: []Sample{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SampleRange(samples, tt.start, tt.end, tt.step))
+		})
+	}
+}
+
+func TestFilterSamples(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{Timestamp: start, Value: 1},
+		{Timestamp: start.Add(time.Second), Value: 2},
+		{Timestamp: start.Add(2 * time.Second), Value: 3},
+	}
+
+	assert.Equal(t, samples[1:], FilterSamples(samples, start.Add(time.Second), start.Add(time.Hour)))
+	assert.Equal(t, samples[:2], FilterSamples(samples, start.Add(-time.Hour), start.Add(time.Second)))
+	assert.Equal(t, []Sample{}, FilterSamples(samples, start.Add(time.Hour), start.Add(2*time.Hour)))
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SeriesFilter selects series to remove or reset. Empty fields match any series,
+// but at least one of Name, NamePrefix or NamePattern has to be set.
+type SeriesFilter struct {
+	// Type is a metric type, empty matches all types.
+	Type string
+	// Name is an exact metric name.
+	Name string
+	// NamePrefix matches metric names starting with the prefix.
+	NamePrefix string
+	// NamePattern matches metric names by the regular expression.
+	NamePattern *regexp.Regexp
+	// Labels have to be present in the series with the same values, other series labels are ignored.
+	Labels Labels
+	// ExactLabels requires series to have no labels except Labels.
+	ExactLabels bool
+}
+
+// Validate checks that filter doesn't select all series at once.
+func (f *SeriesFilter) Validate() error {
+	if f.Name == "" && f.NamePrefix == "" && f.NamePattern == nil {
+		return ErrEmptySeriesFilter
+	}
+
+	return f.Labels.Validate()
+}
+
+// Match reports whether the series is selected by the filter.
+func (f *SeriesFilter) Match(metricType string, name string, labels Labels) bool {
+	if f.Type != "" && f.Type != metricType {
+		return false
+	}
+
+	if f.Name != "" && f.Name != name {
+		return false
+	}
+
+	if f.NamePrefix != "" && !strings.HasPrefix(name, f.NamePrefix) {
+		return false
+	}
+
+	if f.NamePattern != nil && !f.NamePattern.MatchString(name) {
+		return false
+	}
+
+	if f.ExactLabels && len(labels) != len(f.Labels) {
+		return false
+	}
+
+	seriesLabels := labels.Map()
+	for _, label := range f.Labels {
+		if value, ok := seriesLabels[label.Name]; !ok || value != label.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchKey reports whether the series with the key is selected by the filter, invalid keys never match.
+func (f *SeriesFilter) MatchKey(metricType string, seriesKey string) bool {
+	name, labels, err := ParseSeriesKey(seriesKey)
+	if err != nil {
+		return false
+	}
+
+	return f.Match(metricType, name, labels)
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesFilter_Match(t *testing.T) {
+	labels := Labels{{Name: "cpu", Value: "1"}, {Name: "host", Value: "local"}}
+	tests := []struct {
+		name     string
+		filter   SeriesFilter
+		expected bool
+	}{
+		{
+			name:     "name",
+			filter:   SeriesFilter{Name: "cpu_usage"},
+			expected: true,
+		},
+		{
+			name:     "other_name",
+			filter:   SeriesFilter{Name: "cpu"},
+			expected: false,
+		},
+		{
+			name:     "type",
+			filter:   SeriesFilter{Type: "counter", Name: "cpu_usage"},
+			expected: false,
+		},
+		{
+			name:     "prefix",
+			filter:   SeriesFilter{Type: "gauge", NamePrefix: "cpu_"},
+			expected: true,
+		},
+		{
+			name:     "other_prefix",
+			filter:   SeriesFilter{NamePrefix: "mem_"},
+			expected: false,
+		},
+		{
+			name:     "pattern",
+			filter:   SeriesFilter{NamePattern: regexp.MustCompile(`^cpu_(usage|idle)$`)},
+			expected: true,
+		},
+		{
+			name:     "labels_subset",
+			filter:   SeriesFilter{Name: "cpu_usage", Labels: Labels{{Name: "host", Value: "local"}}},
+			expected: true,
+		},
+		{
+			name:     "other_labels",
+			filter:   SeriesFilter{Name: "cpu_usage", Labels: Labels{{Name: "cpu", Value: "2"}}},
+			expected: false,
+		},
+		{
+			name:     "exact_labels",
+			filter:   SeriesFilter{Name: "cpu_usage", Labels: labels, ExactLabels: true},
+			expected: true,
+		},
+		{
+			name:     "exact_labels_subset",
+			filter:   SeriesFilter{Name: "cpu_usage", Labels: Labels{{Name: "host", Value: "local"}}, ExactLabels: true},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.Match("gauge", "cpu_usage", labels))
+			assert.Equal(t, tt.expected, tt.filter.MatchKey("gauge", SeriesKey("cpu_usage", labels)))
+		})
+	}
+}
+
+func TestSeriesFilter_Validate(t *testing.T) {
+	assert.ErrorIs(t, (&SeriesFilter{Type: "counter"}).Validate(), ErrEmptySeriesFilter)
+	assert.ErrorIs(t, (&SeriesFilter{Name: "cpu", Labels: Labels{{Name: "1", Value: "1"}}}).Validate(), ErrInvalidLabel)
+	assert.NoError(t, (&SeriesFilter{NamePrefix: "cpu"}).Validate())
+}
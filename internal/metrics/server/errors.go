@@ -2,4 +2,9 @@ package server
 
 import "errors"
 
-var ErrMetricNotFound = errors.New("metric not found")
+var (
+	ErrInvalidSeriesFilter    = errors.New("invalid series filter")
+	ErrMetricNotFound         = errors.New("metric not found")
+	ErrReservedMetricName     = errors.New("metric name prefix is reserved by the server")
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
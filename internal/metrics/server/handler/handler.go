@@ -3,55 +3,175 @@ package handler
 import (
 	"context"
 	"fmt"
+	"mime"
+	"time"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/html"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/server"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
 )
 
 type requestHandler struct {
-	dbStorage   database.DataBase
-	pageBuilder html.PageBuilder
-	storage     storage.MetricsStorage
+	agents       agents.Registry
+	dbStorage    database.DataBase
+	pageBuilders map[string]html.PageBuilder
+	recorder     *telemetry.Recorder
+	storage      storage.MetricsStorage
 }
 
-func NewHandler(dbStorage database.DataBase, pageBuilder html.PageBuilder, storage storage.MetricsStorage) *requestHandler {
+// NewHandler creates request handler, the server own metrics are read from the recorder.
+func NewHandler(
+	dbStorage database.DataBase,
+	storage storage.MetricsStorage,
+	recorder *telemetry.Recorder,
+	agentsRegistry agents.Registry,
+	pageBuilders ...html.PageBuilder,
+) *requestHandler {
+	buildersByType := make(map[string]html.PageBuilder, len(pageBuilders))
+	for _, pageBuilder := range pageBuilders {
+		buildersByType[mediaType(pageBuilder.ContentType())] = pageBuilder
+	}
+
 	return &requestHandler{
-		dbStorage:   dbStorage,
-		pageBuilder: pageBuilder,
-		storage:     storage,
+		agents:       agentsRegistry,
+		dbStorage:    dbStorage,
+		pageBuilders: buildersByType,
+		recorder:     recorder,
+		storage:      storage,
 	}
 }
 
-func (h *requestHandler) UpdateMetricValues(ctx context.Context, metricValues []metrics.Metric) ([]metrics.Metric, error) {
+func (h *requestHandler) UpdateMetricValues(ctx context.Context, source agents.Source, metricValues []metrics.Metric) ([]metrics.Metric, error) {
+	for _, metric := range metricValues {
+		if telemetry.IsReserved(metric.GetName()) {
+			return nil, logger.WrapError(fmt.Sprintf("update metric '%s'", metric.GetName()), server.ErrReservedMetricName)
+		}
+	}
+
 	resultMetrics, err := h.storage.AddMetricValues(ctx, metricValues)
 	if err != nil {
 		return nil, logger.WrapError("update metric", err)
 	}
 
+	h.agents.Track(source, metricValues)
 	return resultMetrics, nil
 }
 
-func (h *requestHandler) GetMetricValue(ctx context.Context, metricType string, metricName string) (metrics.Metric, error) {
-	metric, err := h.storage.GetMetric(ctx, metricType, metricName)
+func (h *requestHandler) GetAgents(context.Context) ([]*agents.Agent, error) {
+	return h.agents.GetAgents(), nil
+}
+
+func (h *requestHandler) GetMetricValue(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	var metric metrics.Metric
+	var err error
+	if telemetry.IsReserved(metricName) {
+		metric, err = h.recorder.GetMetric(ctx, metricType, metricName, labels)
+	} else {
+		metric, err = h.storage.GetMetric(ctx, metricType, metricName, labels)
+	}
 	if err != nil {
-		return nil, logger.WrapError(fmt.Sprintf("get metric with type '%s' and name '%s'", metricType, metricName),
+		return nil, logger.WrapError(fmt.Sprintf("get metric with type '%s' and name '%s'", metricType, metrics.SeriesKey(metricName, labels)),
 			server.ErrMetricNotFound)
 	}
 
 	return metric, nil
 }
 
-func (h *requestHandler) GetReportPage(ctx context.Context) (string, error) {
+func (h *requestHandler) QueryRange(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	labels metrics.Labels,
+	start time.Time,
+	end time.Time,
+	step time.Duration,
+) ([]metrics.Sample, error) {
+	err := metrics.ValidateRange(start, end, step)
+	if err != nil {
+		return nil, logger.WrapError("validate range", err)
+	}
+
+	history, ok := h.storage.(storage.HistoryStorage)
+	if !ok {
+		return nil, logger.WrapError("query range", metrics.ErrHistoryNotSupported)
+	}
+
+	samples, err := history.GetSamples(ctx, metricType, metricName, labels, start.Add(-metrics.RangeLookback), end)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("get samples with type '%s' and name '%s'", metricType, metrics.SeriesKey(metricName, labels)), err)
+	}
+
+	return metrics.SampleRange(samples, start, end, step), nil
+}
+
+func (h *requestHandler) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	err := filter.Validate()
+	if err != nil {
+		return 0, logger.WrapError(fmt.Sprintf("validate series filter: %v", err), server.ErrInvalidSeriesFilter)
+	}
+
+	removed, err := h.storage.RemoveMetrics(ctx, filter)
+	if err != nil {
+		return 0, logger.WrapError("remove metrics", err)
+	}
+
+	logger.FromContext(ctx).InfoFormat("Removed %d series", removed)
+	return removed, nil
+}
+
+func (h *requestHandler) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	err := filter.Validate()
+	if err != nil {
+		return 0, logger.WrapError(fmt.Sprintf("validate series filter: %v", err), server.ErrInvalidSeriesFilter)
+	}
+
+	reset, err := h.storage.ResetCounters(ctx, filter)
+	if err != nil {
+		return 0, logger.WrapError("reset counters", err)
+	}
+
+	logger.FromContext(ctx).InfoFormat("Reset %d counters", reset)
+	return reset, nil
+}
+
+func (h *requestHandler) GetReportPage(ctx context.Context, contentType string) (string, error) {
+	pageBuilder, ok := h.pageBuilders[mediaType(contentType)]
+	if !ok {
+		return "", logger.WrapError(fmt.Sprintf("get page builder for '%s'", contentType), server.ErrUnsupportedContentType)
+	}
+
 	values, err := h.storage.GetMetricValues(ctx)
 	if err != nil {
 		return "", logger.WrapError("get metric values", err)
 	}
 
-	return h.pageBuilder.BuildMetricsPage(values), nil
+	storedSeries := 0
+	for _, typedValues := range values {
+		storedSeries += len(typedValues)
+	}
+	h.recorder.Set(ctx, telemetry.StoredSeries, float64(storedSeries))
+
+	ownValues, err := h.recorder.GetMetricValues(ctx)
+	if err != nil {
+		return "", logger.WrapError("get server metric values", err)
+	}
+
+	for metricType, typedValues := range ownValues {
+		if values[metricType] == nil {
+			values[metricType] = map[string]string{}
+		}
+
+		for seriesKey, value := range typedValues {
+			values[metricType][seriesKey] = value
+		}
+	}
+
+	return pageBuilder.BuildMetricsPage(values), nil
 }
 
 func (h *requestHandler) Ping(ctx context.Context) error {
@@ -62,3 +182,12 @@ func (h *requestHandler) Ping(ctx context.Context) error {
 
 	return nil
 }
+
+func mediaType(contentType string) string {
+	result, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+
+	return result
+}
@@ -2,14 +2,20 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
 )
 
 type RequestHandler interface {
-	GetMetricValue(ctx context.Context, metricType string, metricName string) (metrics.Metric, error)
-	UpdateMetricValues(ctx context.Context, metricValues []metrics.Metric) ([]metrics.Metric, error)
+	GetMetricValue(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error)
+	UpdateMetricValues(ctx context.Context, source agents.Source, metricValues []metrics.Metric) ([]metrics.Metric, error)
+	GetAgents(ctx context.Context) ([]*agents.Agent, error)
+	QueryRange(ctx context.Context, metricType string, metricName string, labels metrics.Labels, start time.Time, end time.Time, step time.Duration) ([]metrics.Sample, error)
+	RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error)
+	ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error)
 
-	GetReportPage(ctx context.Context) (string, error)
+	GetReportPage(ctx context.Context, contentType string) (string, error)
 	Ping(ctx context.Context) error
 }
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const tmpFileSuffix = ".tmp"
+
+// WriteFileAtomically writes content to the temporary file, flushes it to disk and renames it over the target,
+// so readers observe either the previous or the new file content, but never the partially written one.
+func WriteFileAtomically(path string, mode os.FileMode, write func(writer io.Writer) error) error {
+	tmpPath := path + tmpFileSuffix
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return logger.WrapError("create temporary file", err)
+	}
+
+	err = write(file)
+	if err == nil {
+		err = file.Sync()
+	}
+
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return logger.WrapError("write temporary file", err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return logger.WrapError("rename temporary file", err)
+	}
+
+	return SyncDir(filepath.Dir(path))
+}
+
+// SyncDir flushes directory entries, so completed renames survive the crash.
+func SyncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return logger.WrapError("open directory", err)
+	}
+	defer dir.Close()
+
+	err = dir.Sync()
+	if err != nil {
+		return logger.WrapError("sync directory", err)
+	}
+
+	return nil
+}
@@ -3,19 +3,28 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
 )
 
 func toDBRecord(metric metrics.Metric) *database.DBRecord {
-	return &database.DBRecord{
+	record := &database.DBRecord{
 		MetricType: sql.NullString{String: metric.GetType(), Valid: true},
 		Name:       sql.NullString{String: metric.GetName(), Valid: true},
+		Labels:     sql.NullString{String: metric.GetLabels().String(), Valid: true},
 		Value:      sql.NullFloat64{Float64: metric.GetValue(), Valid: true},
 	}
+
+	if _, ok := metric.(metrics.MergeableMetric); ok {
+		record.Payload = sql.NullString{String: metric.GetStringValue(), Valid: true}
+	}
+
+	return record
 }
 
 func fromDBRecord(record *database.DBRecord) (metrics.Metric, error) {
@@ -29,6 +38,11 @@ func fromDBRecord(record *database.DBRecord) (metrics.Metric, error) {
 	}
 	metricName := record.Name.String
 
+	labels, err := metrics.ParseLabels(record.Labels.String)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("parse record '%s' labels", metricName), err)
+	}
+
 	if !record.Value.Valid {
 		return nil, logger.WrapError("read record", metrics.ErrInvalidRecordMetricValue)
 	}
@@ -37,9 +51,21 @@ func fromDBRecord(record *database.DBRecord) (metrics.Metric, error) {
 	var metric metrics.Metric
 	switch metricType {
 	case "gauge":
-		metric = types.NewGaugeMetric(metricName)
+		metric = types.NewGaugeMetric(metricName, labels...)
 	case "counter":
-		metric = types.NewCounterMetric(metricName)
+		metric = types.NewCounterMetric(metricName, labels...)
+	case "histogram":
+		if !record.Payload.Valid {
+			return nil, logger.WrapError("read record", metrics.ErrInvalidRecordMetricValue)
+		}
+
+		return types.ParseHistogramMetric(metricName, record.Payload.String, labels...)
+	case "summary":
+		if !record.Payload.Valid {
+			return nil, logger.WrapError("read record", metrics.ErrInvalidRecordMetricValue)
+		}
+
+		return types.ParseSummaryMetric(metricName, record.Payload.String, labels...)
 	default:
 		return nil, logger.WrapError(fmt.Sprintf("read record with type '%s'", metricType), metrics.ErrUnknownMetricType)
 	}
@@ -47,3 +73,54 @@ func fromDBRecord(record *database.DBRecord) (metrics.Metric, error) {
 	metric.SetValue(value)
 	return metric, nil
 }
+
+// mergeDBRecords merges composite metric update into the stored state.
+func mergeDBRecords(current *database.DBRecord, update *database.DBRecord) (*database.DBRecord, error) {
+	currentMetric, err := fromDBRecord(current)
+	if err != nil {
+		return nil, logger.WrapError("convert current record", err)
+	}
+
+	updateMetric, err := fromDBRecord(update)
+	if err != nil {
+		return nil, logger.WrapError("convert update record", err)
+	}
+
+	mergeableMetric, ok := currentMetric.(metrics.MergeableMetric)
+	if !ok {
+		return nil, logger.WrapError(fmt.Sprintf("merge record with type '%s'", currentMetric.GetType()), metrics.ErrUnknownMetricType)
+	}
+
+	err = mergeableMetric.Merge(updateMetric)
+	if err != nil {
+		return nil, logger.WrapError("merge metrics", err)
+	}
+
+	return toDBRecord(currentMetric), nil
+}
+
+func recordStringValue(record *database.DBRecord) string {
+	if record.Payload.Valid {
+		return record.Payload.String
+	}
+
+	return parser.FloatToString(record.Value.Float64)
+}
+
+func toDBSample(timestamp time.Time, metric metrics.Metric) *database.DBSample {
+	return &database.DBSample{
+		MetricType: sql.NullString{String: metric.GetType(), Valid: true},
+		Name:       sql.NullString{String: metric.GetName(), Valid: true},
+		Labels:     sql.NullString{String: metric.GetLabels().String(), Valid: true},
+		Timestamp:  sql.NullTime{Time: timestamp, Valid: true},
+		Value:      sql.NullFloat64{Float64: metric.GetValue(), Valid: true},
+	}
+}
+
+func fromDBSample(sample *database.DBSample) (metrics.Sample, error) {
+	if !sample.Timestamp.Valid || !sample.Value.Valid {
+		return metrics.Sample{}, logger.WrapError("read sample", metrics.ErrInvalidRecordMetricValue)
+	}
+
+	return metrics.Sample{Timestamp: sample.Timestamp.Time, Value: sample.Value.Float64}, nil
+}
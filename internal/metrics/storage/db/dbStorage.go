@@ -3,23 +3,37 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
 )
 
 type dbStorage struct {
 	dataBase database.DataBase
+	shared   bool
 }
 
 func NewDBStorage(dataBase database.DataBase) storage.MetricsStorage {
 	return &dbStorage{dataBase: dataBase}
 }
 
+// NewSharedDBStorage creates storage used as a primary one by several server replicas.
+// Counters and composite metrics are accumulated by the database itself, so replicas don't overwrite each other's values.
+func NewSharedDBStorage(dataBase database.DataBase) storage.MetricsStorage {
+	return &dbStorage{dataBase: dataBase, shared: true}
+}
+
 func (d *dbStorage) AddMetricValues(ctx context.Context, metricsList []metrics.Metric) ([]metrics.Metric, error) {
+	if d.shared {
+		return d.accumulateMetricValues(ctx, metricsList)
+	}
+
 	dbRecords := make([]*database.DBRecord, len(metricsList))
 	for i, metric := range metricsList {
 		dbRecords[i] = toDBRecord(metric)
@@ -55,31 +69,63 @@ func (d *dbStorage) GetMetricValues(ctx context.Context) (map[string]map[string]
 		if !record.Name.Valid {
 			return nil, logger.WrapError("read record", metrics.ErrInvalidRecordMetricName)
 		}
-		metricName := record.Name.String
+		labels, err := metrics.ParseLabels(record.Labels.String)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse record '%s' labels", record.Name.String), err)
+		}
 
 		if !record.Value.Valid {
 			return nil, logger.WrapError("read record", metrics.ErrInvalidRecordMetricValue)
 		}
 
-		metricsByType[metricName] = parser.FloatToString(record.Value.Float64)
+		metricsByType[metrics.SeriesKey(record.Name.String, labels)] = recordStringValue(record)
 	}
 
 	return result, nil
 }
 
-func (d *dbStorage) GetMetric(ctx context.Context, metricType string, metricName string) (metrics.Metric, error) {
-	result, err := d.dataBase.ReadRecord(ctx, metricType, metricName)
+func (d *dbStorage) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	result, err := d.dataBase.ReadRecord(ctx, metricType, metricName, labels.String())
 	if err != nil {
 		return nil, logger.WrapError("read db record", err)
 	}
 
+	if result == nil {
+		return nil, logger.WrapError(fmt.Sprintf("read metric '%s' with type %s", metrics.SeriesKey(metricName, labels), metricType), metrics.ErrMetricNotFound)
+	}
+
 	return fromDBRecord(result)
 }
 
 func (d *dbStorage) Restore(ctx context.Context, metricValues map[string]map[string]string) error {
 	records := []*database.DBRecord{}
 	for metricType, metricsByType := range metricValues {
-		for metricName, metricValue := range metricsByType {
+		for seriesKey, metricValue := range metricsByType {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			if metricType == "histogram" {
+				metric, err := types.ParseHistogramMetric(metricName, metricValue, labels...)
+				if err != nil {
+					return logger.WrapError("parse histogram metric", err)
+				}
+
+				records = append(records, toDBRecord(metric))
+				continue
+			}
+
+			if metricType == "summary" {
+				metric, err := types.ParseSummaryMetric(metricName, metricValue, labels...)
+				if err != nil {
+					return logger.WrapError("parse summary metric", err)
+				}
+
+				records = append(records, toDBRecord(metric))
+				continue
+			}
+
 			value, err := parser.ToFloat64(metricValue)
 			if err != nil {
 				return logger.WrapError("parse metric value", err)
@@ -88,6 +134,7 @@ func (d *dbStorage) Restore(ctx context.Context, metricValues map[string]map[str
 			records = append(records, &database.DBRecord{
 				MetricType: sql.NullString{String: metricType, Valid: true},
 				Name:       sql.NullString{String: metricName, Valid: true},
+				Labels:     sql.NullString{String: labels.String(), Valid: true},
 				Value:      sql.NullFloat64{Float64: value, Valid: true},
 			})
 		}
@@ -100,3 +147,227 @@ func (d *dbStorage) Restore(ctx context.Context, metricValues map[string]map[str
 
 	return nil
 }
+
+func (d *dbStorage) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	records, err := d.matchRecords(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	err = d.dataBase.RemoveRecords(ctx, records)
+	if err != nil {
+		return 0, logger.WrapError("remove db records", err)
+	}
+
+	return len(records), nil
+}
+
+func (d *dbStorage) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	records, err := d.matchRecords(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	counters := []*database.DBRecord{}
+	for _, record := range records {
+		if record.MetricType.String == "counter" {
+			record.Value = sql.NullFloat64{Float64: 0, Valid: true}
+			counters = append(counters, record)
+		}
+	}
+
+	if len(counters) == 0 {
+		return 0, nil
+	}
+
+	err = d.dataBase.UpdateRecords(ctx, counters)
+	if err != nil {
+		return 0, logger.WrapError("update db records", err)
+	}
+
+	return len(counters), nil
+}
+
+func (d *dbStorage) AddSamples(ctx context.Context, timestamp time.Time, metricsList []metrics.Metric) error {
+	samples := make([]*database.DBSample, len(metricsList))
+	for i, metric := range metricsList {
+		samples[i] = toDBSample(timestamp, metric)
+	}
+
+	err := d.dataBase.AddSamples(ctx, samples)
+	if err != nil {
+		return logger.WrapError("add db samples", err)
+	}
+
+	return nil
+}
+
+func (d *dbStorage) GetSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	labels metrics.Labels,
+	start time.Time,
+	end time.Time,
+) ([]metrics.Sample, error) {
+	dbSamples, err := d.dataBase.ReadSamples(ctx, metricType, metricName, labels.String(), start, end)
+	if err != nil {
+		return nil, logger.WrapError("read db samples", err)
+	}
+
+	result := make([]metrics.Sample, len(dbSamples))
+	for i, dbSample := range dbSamples {
+		result[i], err = fromDBSample(dbSample)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (d *dbStorage) GetHistory(ctx context.Context) (map[string]map[string][]metrics.Sample, error) {
+	dbSamples, err := d.dataBase.ReadAllSamples(ctx)
+	if err != nil {
+		return nil, logger.WrapError("read all db samples", err)
+	}
+
+	result := map[string]map[string][]metrics.Sample{}
+	for _, dbSample := range dbSamples {
+		if !dbSample.MetricType.Valid {
+			return nil, logger.WrapError("read sample", metrics.ErrInvalidRecordMetricType)
+		}
+		if !dbSample.Name.Valid {
+			return nil, logger.WrapError("read sample", metrics.ErrInvalidRecordMetricName)
+		}
+
+		labels, err := metrics.ParseLabels(dbSample.Labels.String)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse sample '%s' labels", dbSample.Name.String), err)
+		}
+
+		sample, err := fromDBSample(dbSample)
+		if err != nil {
+			return nil, err
+		}
+
+		typedSamples, ok := result[dbSample.MetricType.String]
+		if !ok {
+			typedSamples = map[string][]metrics.Sample{}
+			result[dbSample.MetricType.String] = typedSamples
+		}
+
+		seriesKey := metrics.SeriesKey(dbSample.Name.String, labels)
+		typedSamples[seriesKey] = append(typedSamples[seriesKey], sample)
+	}
+
+	return result, nil
+}
+
+func (d *dbStorage) RestoreHistory(ctx context.Context, history map[string]map[string][]metrics.Sample) error {
+	dbSamples := []*database.DBSample{}
+	for metricType, typedSamples := range history {
+		for seriesKey, samples := range typedSamples {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			for _, sample := range samples {
+				dbSamples = append(dbSamples, &database.DBSample{
+					MetricType: sql.NullString{String: metricType, Valid: true},
+					Name:       sql.NullString{String: metricName, Valid: true},
+					Labels:     sql.NullString{String: labels.String(), Valid: true},
+					Timestamp:  sql.NullTime{Time: sample.Timestamp, Valid: true},
+					Value:      sql.NullFloat64{Float64: sample.Value, Valid: true},
+				})
+			}
+		}
+	}
+
+	err := d.dataBase.ReplaceSamples(ctx, dbSamples)
+	if err != nil {
+		return logger.WrapError("replace db samples", err)
+	}
+
+	return nil
+}
+
+func (d *dbStorage) RemoveSamples(ctx context.Context, before time.Time) error {
+	err := d.dataBase.RemoveSamples(ctx, before)
+	if err != nil {
+		return logger.WrapError("remove db samples", err)
+	}
+
+	return nil
+}
+
+// matchRecords reads stored records selected by the filter.
+func (d *dbStorage) matchRecords(ctx context.Context, filter *metrics.SeriesFilter) ([]*database.DBRecord, error) {
+	records, err := d.dataBase.ReadAll(ctx)
+	if err != nil {
+		return nil, logger.WrapError("read all db records", err)
+	}
+
+	result := []*database.DBRecord{}
+	for _, record := range records {
+		labels, err := metrics.ParseLabels(record.Labels.String)
+		if err != nil {
+			return nil, logger.WrapError(fmt.Sprintf("parse record '%s' labels", record.Name.String), err)
+		}
+
+		if filter.Match(record.MetricType.String, record.Name.String, labels) {
+			result = append(result, record)
+		}
+	}
+
+	return result, nil
+}
+
+// accumulateMetricValues replaces gauges and adds counters and composite metrics to the stored ones.
+func (d *dbStorage) accumulateMetricValues(ctx context.Context, metricsList []metrics.Metric) ([]metrics.Metric, error) {
+	result := make([]metrics.Metric, len(metricsList))
+	replaced := []*database.DBRecord{}
+	accumulated := []*database.DBRecord{}
+	accumulatedIndexes := []int{}
+	for i, metric := range metricsList {
+		_, isMergeable := metric.(metrics.MergeableMetric)
+		if metric.GetType() != "counter" && !isMergeable {
+			replaced = append(replaced, toDBRecord(metric))
+			result[i] = metric
+			continue
+		}
+
+		accumulated = append(accumulated, toDBRecord(metric))
+		accumulatedIndexes = append(accumulatedIndexes, i)
+	}
+
+	if len(replaced) > 0 {
+		err := d.dataBase.UpdateRecords(ctx, replaced)
+		if err != nil {
+			return nil, logger.WrapError("update db records", err)
+		}
+	}
+
+	if len(accumulated) == 0 {
+		return result, nil
+	}
+
+	records, err := d.dataBase.AccumulateRecords(ctx, accumulated, mergeDBRecords)
+	if err != nil {
+		return nil, logger.WrapError("accumulate db records", err)
+	}
+
+	for i, record := range records {
+		result[accumulatedIndexes[i]], err = fromDBRecord(record)
+		if err != nil {
+			return nil, logger.WrapError("convert accumulated db record", err)
+		}
+	}
+
+	return result, nil
+}
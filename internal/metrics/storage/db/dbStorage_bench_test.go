@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/postgres"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+)
+
+// benchmarkBatchSize is a count of metrics written by one call.
+const benchmarkBatchSize = 10_000
+
+// benchmarkDatabaseEnv points to the postgres instance used by database benchmarks, they are skipped if empty.
+const benchmarkDatabaseEnv = "TEST_DATABASE_DSN"
+
+type benchmarkConfig struct {
+	connectionString string
+}
+
+func BenchmarkDbStorage_AddMetricValues(b *testing.B) {
+	storage := NewDBStorage(openBenchmarkDatabase(b))
+	metricsList := createBenchmarkMetrics()
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := storage.AddMetricValues(ctx, metricsList)
+		require.NoError(b, err)
+	}
+
+	b.ReportMetric(float64(b.N*benchmarkBatchSize)/b.Elapsed().Seconds(), "metrics/s")
+}
+
+func BenchmarkDbStorage_AddSamples(b *testing.B) {
+	history, ok := NewDBStorage(openBenchmarkDatabase(b)).(storage.HistoryStorage)
+	require.True(b, ok)
+	metricsList := createBenchmarkMetrics()
+
+	ctx := context.Background()
+	timestamp := time.Now()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := history.AddSamples(ctx, timestamp.Add(time.Duration(i)*time.Millisecond), metricsList)
+		require.NoError(b, err)
+	}
+
+	b.ReportMetric(float64(b.N*benchmarkBatchSize)/b.Elapsed().Seconds(), "samples/s")
+}
+
+// BenchmarkDbStorage_AddMetricValuesConversion measures storage overhead without a database round trip.
+func BenchmarkDbStorage_AddMetricValuesConversion(b *testing.B) {
+	dbMock := new(databaseMock)
+	dbMock.On("UpdateRecords", mock.Anything, mock.Anything).Return(nil)
+
+	storage := NewDBStorage(dbMock)
+	metricsList := createBenchmarkMetrics()
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := storage.AddMetricValues(ctx, metricsList)
+		require.NoError(b, err)
+	}
+
+	b.ReportMetric(float64(b.N*benchmarkBatchSize)/b.Elapsed().Seconds(), "metrics/s")
+}
+
+func openBenchmarkDatabase(b *testing.B) database.DataBase {
+	b.Helper()
+
+	connectionString := os.Getenv(benchmarkDatabaseEnv)
+	if connectionString == "" {
+		b.Skipf("%s is not set", benchmarkDatabaseEnv)
+	}
+
+	dataBase, err := postgres.NewPostgresDataBase(context.Background(), &benchmarkConfig{connectionString: connectionString})
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = dataBase.Close() })
+
+	return dataBase
+}
+
+func createBenchmarkMetrics() []metrics.Metric {
+	result := make([]metrics.Metric, benchmarkBatchSize)
+	for i := range result {
+		gauge := types.NewGaugeMetric("benchmarkGauge", metrics.Label{Name: "instance", Value: fmt.Sprint(i)})
+		gauge.SetValue(float64(i))
+		result[i] = gauge
+	}
+
+	return result
+}
+
+func (c *benchmarkConfig) GetConnectionString() string {
+	return c.connectionString
+}
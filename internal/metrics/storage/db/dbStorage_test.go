@@ -3,13 +3,19 @@ package db
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/database"
+	memoryDataBase "github.com/MaxReX92/go-yandex-aka-prometheus/internal/database/memory"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
 )
 
@@ -18,20 +24,24 @@ type databaseMock struct {
 }
 
 func TestDbStorage_AddMetricValues(t *testing.T) {
+	labeledGauge := types.NewGaugeMetric("gaugeMetricName", metrics.Label{Name: "cpu", Value: "1"})
+	labeledGauge.SetValue(200)
 	metricsList := []metrics.Metric{
 		test.CreateCounterMetric("counterMetricName", 100),
-		test.CreateGaugeMetric("gaugeMetricName", 200),
+		labeledGauge,
 	}
 
 	records := []*database.DBRecord{
 		{
 			MetricType: sql.NullString{Valid: true, String: "counter"},
 			Name:       sql.NullString{Valid: true, String: "counterMetricName"},
+			Labels:     sql.NullString{Valid: true, String: ""},
 			Value:      sql.NullFloat64{Valid: true, Float64: 100},
 		},
 		{
 			MetricType: sql.NullString{Valid: true, String: "gauge"},
 			Name:       sql.NullString{Valid: true, String: "gaugeMetricName"},
+			Labels:     sql.NullString{Valid: true, String: `{cpu="1"}`},
 			Value:      sql.NullFloat64{Valid: true, Float64: 200},
 		},
 	}
@@ -72,6 +82,161 @@ func TestDbStorage_AddMetricValues(t *testing.T) {
 	}
 }
 
+func TestDbStorage_SharedAddMetricValues(t *testing.T) {
+	gauge := types.NewGaugeMetric("gaugeMetricName")
+	gauge.SetValue(200)
+	histogram, err := types.ParseHistogramMetric("histogramMetricName", `{"bounds":[1],"counts":[1],"sum":2,"count":1}`)
+	require.NoError(t, err)
+	metricsList := []metrics.Metric{
+		test.CreateCounterMetric("counterMetricName", 100),
+		gauge,
+		histogram,
+	}
+
+	gaugeRecord := &database.DBRecord{
+		MetricType: sql.NullString{Valid: true, String: "gauge"},
+		Name:       sql.NullString{Valid: true, String: "gaugeMetricName"},
+		Labels:     sql.NullString{Valid: true, String: ""},
+		Value:      sql.NullFloat64{Valid: true, Float64: 200},
+	}
+	accumulatedRecords := []*database.DBRecord{toDBRecord(metricsList[0]), toDBRecord(histogram)}
+	resultRecords := []*database.DBRecord{
+		{
+			MetricType: sql.NullString{Valid: true, String: "counter"},
+			Name:       sql.NullString{Valid: true, String: "counterMetricName"},
+			Labels:     sql.NullString{Valid: true, String: ""},
+			Value:      sql.NullFloat64{Valid: true, Float64: 300},
+		},
+		toDBRecord(histogram),
+	}
+
+	tests := []struct {
+		updateError     error
+		accumulateError error
+		expectedError   error
+		name            string
+	}{
+		{
+			name:          "update_error",
+			updateError:   test.ErrTest,
+			expectedError: test.ErrTest,
+		},
+		{
+			name:            "accumulate_error",
+			accumulateError: test.ErrTest,
+			expectedError:   test.ErrTest,
+		},
+		{
+			name: "success",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			dbMock := new(databaseMock)
+			dbMock.On("UpdateRecords", ctx, []*database.DBRecord{gaugeRecord}).Return(tt.updateError)
+			dbMock.On("AccumulateRecords", ctx, accumulatedRecords, mock.Anything).Return(resultRecords, tt.accumulateError)
+
+			storage := NewSharedDBStorage(dbMock)
+			actualResult, actualError := storage.AddMetricValues(ctx, metricsList)
+
+			assert.ErrorIs(t, actualError, tt.expectedError)
+			if tt.expectedError != nil {
+				assert.Empty(t, actualResult)
+				return
+			}
+
+			require.Len(t, actualResult, 3)
+			assert.Equal(t, "300", actualResult[0].GetStringValue())
+			assert.Equal(t, gauge, actualResult[1])
+			assert.Equal(t, histogram.GetStringValue(), actualResult[2].GetStringValue())
+		})
+	}
+}
+
+func TestDbStorage_InMemoryDataBase(t *testing.T) {
+	ctx := context.Background()
+	values := map[string]map[string]string{
+		"counter":   {"counterMetricName": "100"},
+		"gauge":     {`gaugeMetricName{cpu="1"}`: "0.5"},
+		"histogram": {"histogramMetricName": `{"bounds":[1],"counts":[1],"sum":2,"count":1}`},
+	}
+
+	dataBase := memoryDataBase.NewInMemoryDataBase()
+	require.NoError(t, NewDBStorage(dataBase).Restore(ctx, values))
+
+	shared := NewSharedDBStorage(dataBase)
+	update, err := types.ParseHistogramMetric("histogramMetricName", `{"bounds":[1],"counts":[0],"sum":3,"count":1}`)
+	require.NoError(t, err)
+	_, err = shared.AddMetricValues(ctx, []metrics.Metric{test.CreateCounterMetric("counterMetricName", 10), update})
+	require.NoError(t, err)
+
+	actual, err := shared.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter":   {"counterMetricName": "110"},
+		"gauge":     {`gaugeMetricName{cpu="1"}`: "0.5"},
+		"histogram": {"histogramMetricName": `{"bounds":[1],"counts":[1],"sum":5,"count":2}`},
+	}, actual)
+
+	_, err = shared.GetMetric(ctx, "counter", "missedMetricName", nil)
+	assert.ErrorIs(t, err, metrics.ErrMetricNotFound)
+}
+
+func TestDbStorage_RemoveMetrics(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	dbStorage := NewDBStorage(memoryDataBase.NewInMemoryDataBase())
+	history, ok := dbStorage.(storage.HistoryStorage)
+	require.True(t, ok)
+
+	metricsList := []metrics.Metric{
+		test.CreateCounterMetric("requests", 10),
+		test.CreateCounterMetric("errors", 5),
+		types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "1"}),
+		test.CreateGaugeMetric("memory_usage", 1),
+	}
+	_, err := dbStorage.AddMetricValues(ctx, metricsList)
+	require.NoError(t, err)
+	require.NoError(t, history.AddSamples(ctx, start, metricsList))
+
+	removed, err := dbStorage.RemoveMetrics(ctx, &metrics.SeriesFilter{NamePattern: regexp.MustCompile("_usage$"), Labels: metrics.Labels{{Name: "cpu", Value: "1"}}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	reset, err := dbStorage.ResetCounters(ctx, &metrics.SeriesFilter{Name: "requests"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, reset)
+
+	actual, err := dbStorage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {"requests": "0", "errors": "5"},
+		"gauge":   {"memory_usage": "1"},
+	}, actual)
+
+	actualHistory, err := history.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, actualHistory["gauge"], `cpu_usage{cpu="1"}`)
+	assert.Len(t, actualHistory["counter"], 2)
+}
+
+func TestMergeDBRecords(t *testing.T) {
+	current, err := types.ParseHistogramMetric("histogramMetricName", `{"bounds":[1],"counts":[1],"sum":2,"count":1}`)
+	require.NoError(t, err)
+	update, err := types.ParseHistogramMetric("histogramMetricName", `{"bounds":[1],"counts":[0],"sum":3,"count":1}`)
+	require.NoError(t, err)
+
+	actual, err := mergeDBRecords(toDBRecord(current), toDBRecord(update))
+	require.NoError(t, err)
+	assert.Equal(t, `{"bounds":[1],"counts":[1],"sum":5,"count":2}`, actual.Payload.String)
+
+	_, err = mergeDBRecords(toDBRecord(test.CreateCounterMetric("counterMetricName", 1)), toDBRecord(update))
+	assert.ErrorIs(t, err, metrics.ErrUnknownMetricType)
+}
+
 func TestDbStorage_GetMetricValues(t *testing.T) {
 	tests := []struct {
 		getRecordsError      error
@@ -119,10 +284,16 @@ func TestDbStorage_GetMetricValues(t *testing.T) {
 				MetricType: sql.NullString{Valid: true, String: "gauge"},
 				Name:       sql.NullString{Valid: true, String: "testMetricName2"},
 				Value:      sql.NullFloat64{Valid: true, Float64: 200},
+			}, {
+				MetricType: sql.NullString{Valid: true, String: "histogram"},
+				Name:       sql.NullString{Valid: true, String: "testMetricName3"},
+				Value:      sql.NullFloat64{Valid: true, Float64: 2},
+				Payload:    sql.NullString{Valid: true, String: `{"bounds":[1],"counts":[1],"sum":2,"count":2}`},
 			}},
 			expectedResult: map[string]map[string]string{
-				"counter": {"testMetricName1": "100"},
-				"gauge":   {"testMetricName2": "200"},
+				"counter":   {"testMetricName1": "100"},
+				"gauge":     {"testMetricName2": "200"},
+				"histogram": {"testMetricName3": `{"bounds":[1],"counts":[1],"sum":2,"count":2}`},
 			},
 		},
 	}
@@ -213,6 +384,34 @@ func TestDbStorage_GetMetric(t *testing.T) {
 			},
 			expectedResult: test.CreateGaugeMetric(metricName, metricValie),
 		},
+		{
+			name: "invalid_histogram_payload",
+			dbRecord: &database.DBRecord{
+				MetricType: sql.NullString{Valid: true, String: "histogram"},
+				Name:       sql.NullString{Valid: true, String: metricName},
+				Value:      sql.NullFloat64{Valid: true, Float64: metricValie},
+			},
+			expectedErrorMessage: "invalid record metric value",
+		},
+		{
+			name: "success_histogram_metric",
+			dbRecord: &database.DBRecord{
+				MetricType: sql.NullString{Valid: true, String: "histogram"},
+				Name:       sql.NullString{Valid: true, String: metricName},
+				Value:      sql.NullFloat64{Valid: true, Float64: 2},
+				Payload:    sql.NullString{Valid: true, String: `{"bounds":[1,2],"counts":[1,2],"sum":2,"count":2}`},
+			},
+			expectedResult: test.CreateHistogramMetric(metricName, []float64{1, 2}, 0.5, 1.5),
+		},
+		{
+			name: "invalid_summary_payload",
+			dbRecord: &database.DBRecord{
+				MetricType: sql.NullString{Valid: true, String: "summary"},
+				Name:       sql.NullString{Valid: true, String: metricName},
+				Value:      sql.NullFloat64{Valid: true, Float64: metricValie},
+			},
+			expectedErrorMessage: "invalid record metric value",
+		},
 	}
 
 	for _, tt := range tests {
@@ -220,21 +419,64 @@ func TestDbStorage_GetMetric(t *testing.T) {
 			ctx := context.Background()
 
 			dbMock := new(databaseMock)
-			dbMock.On("ReadRecord", ctx, metricType, metricName).Return(tt.dbRecord, tt.readRecordError)
+			dbMock.On("ReadRecord", ctx, metricType, metricName, "").Return(tt.dbRecord, tt.readRecordError)
 
 			storage := NewDBStorage(dbMock)
-			actualResult, actualError := storage.GetMetric(ctx, metricType, metricName)
+			actualResult, actualError := storage.GetMetric(ctx, metricType, metricName, nil)
 
 			assert.Equal(t, tt.expectedResult, actualResult)
 			if tt.expectedErrorMessage != "" {
 				assert.ErrorContains(t, actualError, tt.expectedErrorMessage)
 			}
 
-			dbMock.AssertCalled(t, "ReadRecord", ctx, metricType, metricName)
+			dbMock.AssertCalled(t, "ReadRecord", ctx, metricType, metricName, "")
 		})
 	}
 }
 
+func TestDbStorage_History(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	labels := metrics.NewLabels(metrics.Label{Name: "cpu", Value: "1"})
+	dbSamples := []*database.DBSample{
+		{
+			MetricType: sql.NullString{Valid: true, String: "gauge"},
+			Name:       sql.NullString{Valid: true, String: "gaugeMetricName"},
+			Labels:     sql.NullString{Valid: true, String: `{cpu="1"}`},
+			Timestamp:  sql.NullTime{Valid: true, Time: start},
+			Value:      sql.NullFloat64{Valid: true, Float64: 200},
+		},
+	}
+	samples := []metrics.Sample{{Timestamp: start, Value: 200}}
+
+	labeledGauge := types.NewGaugeMetric("gaugeMetricName", labels...)
+	labeledGauge.SetValue(200)
+
+	dbMock := new(databaseMock)
+	dbMock.On("AddSamples", ctx, dbSamples).Return(nil)
+	dbMock.On("ReadSamples", ctx, "gauge", "gaugeMetricName", `{cpu="1"}`, start, start.Add(time.Hour)).Return(dbSamples, nil)
+	dbMock.On("ReadAllSamples", ctx).Return(dbSamples, nil)
+	dbMock.On("ReplaceSamples", ctx, dbSamples).Return(nil)
+	dbMock.On("RemoveSamples", ctx, start).Return(test.ErrTest)
+
+	history, ok := NewDBStorage(dbMock).(storage.HistoryStorage)
+	require.True(t, ok)
+
+	assert.NoError(t, history.AddSamples(ctx, start, []metrics.Metric{labeledGauge}))
+
+	actualSamples, err := history.GetSamples(ctx, "gauge", "gaugeMetricName", labels, start, start.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, samples, actualSamples)
+
+	actualHistory, err := history.GetHistory(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]metrics.Sample{"gauge": {`gaugeMetricName{cpu="1"}`: samples}}, actualHistory)
+
+	assert.NoError(t, history.RestoreHistory(ctx, actualHistory))
+	assert.ErrorIs(t, history.RemoveSamples(ctx, start), test.ErrTest)
+	dbMock.AssertExpectations(t)
+}
+
 func (d *databaseMock) Ping(ctx context.Context) error {
 	args := d.Called(ctx)
 	return args.Error(0)
@@ -250,8 +492,18 @@ func (d *databaseMock) UpdateRecords(ctx context.Context, records []*database.DB
 	return args.Error(0)
 }
 
-func (d *databaseMock) ReadRecord(ctx context.Context, metricType string, metricName string) (*database.DBRecord, error) {
-	args := d.Called(ctx, metricType, metricName)
+func (d *databaseMock) AccumulateRecords(ctx context.Context, records []*database.DBRecord, merge database.MergeFunc) ([]*database.DBRecord, error) {
+	args := d.Called(ctx, records, merge)
+	return args.Get(0).([]*database.DBRecord), args.Error(1)
+}
+
+func (d *databaseMock) RemoveRecords(ctx context.Context, records []*database.DBRecord) error {
+	args := d.Called(ctx, records)
+	return args.Error(0)
+}
+
+func (d *databaseMock) ReadRecord(ctx context.Context, metricType string, metricName string, metricLabels string) (*database.DBRecord, error) {
+	args := d.Called(ctx, metricType, metricName, metricLabels)
 	return args.Get(0).(*database.DBRecord), args.Error(1)
 }
 
@@ -259,3 +511,28 @@ func (d *databaseMock) ReadAll(ctx context.Context) ([]*database.DBRecord, error
 	args := d.Called(ctx)
 	return args.Get(0).([]*database.DBRecord), args.Error(1)
 }
+
+func (d *databaseMock) AddSamples(ctx context.Context, samples []*database.DBSample) error {
+	args := d.Called(ctx, samples)
+	return args.Error(0)
+}
+
+func (d *databaseMock) ReadSamples(ctx context.Context, metricType string, metricName string, metricLabels string, start time.Time, end time.Time) ([]*database.DBSample, error) {
+	args := d.Called(ctx, metricType, metricName, metricLabels, start, end)
+	return args.Get(0).([]*database.DBSample), args.Error(1)
+}
+
+func (d *databaseMock) ReadAllSamples(ctx context.Context) ([]*database.DBSample, error) {
+	args := d.Called(ctx)
+	return args.Get(0).([]*database.DBSample), args.Error(1)
+}
+
+func (d *databaseMock) ReplaceSamples(ctx context.Context, samples []*database.DBSample) error {
+	args := d.Called(ctx, samples)
+	return args.Error(0)
+}
+
+func (d *databaseMock) RemoveSamples(ctx context.Context, before time.Time) error {
+	args := d.Called(ctx, before)
+	return args.Error(0)
+}
@@ -0,0 +1,13 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// ExpiringStorage keeps the last update time of every series.
+type ExpiringStorage interface {
+	// RemoveStaleMetrics removes series last updated before the boundary of their type together with their samples,
+	// zero boundary keeps series of the type. Removed series keys are returned by type.
+	RemoveStaleMetrics(ctx context.Context, boundary func(metricType string) time.Time) (map[string][]string, error)
+}
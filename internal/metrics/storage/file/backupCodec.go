@@ -0,0 +1,124 @@
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	encryptedMagic = []byte("MBAKENC1")
+	gzipMagic      = []byte{0x1f, 0x8b}
+	zstdMagic      = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// backupCodec transforms backup file content: json is compressed first and then encrypted.
+// Decode detects the format by the content prefix, so backups written with any settings stay readable.
+type backupCodec struct {
+	compression string
+	encryptor   crypto.Encryptor
+	decryptor   crypto.Decryptor
+}
+
+func (c *backupCodec) encode(content []byte) ([]byte, error) {
+	switch c.compression {
+	case CompressionNone:
+	case CompressionGzip:
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		_, err := writer.Write(content)
+		if err != nil {
+			return nil, logger.WrapError("compress content", err)
+		}
+
+		err = writer.Close()
+		if err != nil {
+			return nil, logger.WrapError("close gzip writer", err)
+		}
+
+		content = buffer.Bytes()
+	case CompressionZstd:
+		writer, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, logger.WrapError("create zstd writer", err)
+		}
+
+		content = writer.EncodeAll(content, nil)
+		err = writer.Close()
+		if err != nil {
+			return nil, logger.WrapError("close zstd writer", err)
+		}
+	default:
+		return nil, logger.WrapError(fmt.Sprintf("compress with %s", c.compression), metrics.ErrUnsupportedBackupFormat)
+	}
+
+	if c.encryptor == nil {
+		return content, nil
+	}
+
+	encrypted, err := c.encryptor.Encrypt(content)
+	if err != nil {
+		return nil, logger.WrapError("encrypt content", err)
+	}
+
+	return append(append([]byte{}, encryptedMagic...), encrypted...), nil
+}
+
+func (c *backupCodec) decode(content []byte) ([]byte, error) {
+	if bytes.HasPrefix(content, encryptedMagic) {
+		if c.decryptor == nil {
+			return nil, logger.WrapError("decrypt content", metrics.ErrEncryptedBackup)
+		}
+
+		decrypted, err := c.decryptor.Decrypt(content[len(encryptedMagic):])
+		if err != nil {
+			return nil, logger.WrapError("decrypt content", err)
+		}
+
+		content = decrypted
+	}
+
+	switch {
+	case bytes.HasPrefix(content, gzipMagic):
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, logger.WrapError("create gzip reader", err)
+		}
+		defer reader.Close()
+
+		decompressed, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, logger.WrapError("decompress content", err)
+		}
+
+		return decompressed, nil
+	case bytes.HasPrefix(content, zstdMagic):
+		reader, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, logger.WrapError("create zstd reader", err)
+		}
+		defer reader.Close()
+
+		decompressed, err := reader.DecodeAll(content, nil)
+		if err != nil {
+			return nil, logger.WrapError("decompress content", err)
+		}
+
+		return decompressed, nil
+	default:
+		return content, nil
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
@@ -16,33 +20,56 @@ import (
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
 )
 
-const fileMode os.FileMode = 0o644
+const (
+	fileMode          os.FileMode = 0o644
+	historyFileSuffix             = ".history"
+)
 
 type storageRecord struct {
-	Type  string `json:"types"`
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Type   string            `json:"types"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  string            `json:"value"`
 }
 
 type storageRecords []*storageRecord
 
+type historyRecord struct {
+	Type    string            `json:"types"`
+	Name    string            `json:"name"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Samples []metrics.Sample  `json:"samples"`
+}
+
 type fileStorageConfig interface {
 	StoreFilePath() string
+	StoreFileGenerations() int
+	StoreFileCompression() string
 }
 
 type fileStorage struct {
-	filePath string
-	lock     sync.Mutex
+	filePath    string
+	generations int
+	codec       *backupCodec
+	lock        sync.Mutex
 }
 
-func NewFileStorage(config fileStorageConfig) storage.MetricsStorage {
+// NewFileStorage creates storage of metrics in json file.
+// Encryptor and decryptor are optional, backup is stored unencrypted without them.
+func NewFileStorage(config fileStorageConfig, encryptor crypto.Encryptor, decryptor crypto.Decryptor) storage.MetricsStorage {
 	result := &fileStorage{
-		filePath: config.StoreFilePath(),
+		filePath:    config.StoreFilePath(),
+		generations: config.StoreFileGenerations(),
+		codec: &backupCodec{
+			compression: config.StoreFileCompression(),
+			encryptor:   encryptor,
+			decryptor:   decryptor,
+		},
 	}
 
-	if _, err := os.Stat(result.filePath); err != nil && result.filePath != "" && errors.Is(err, os.ErrNotExist) {
+	if result.filePath != "" && !result.hasBackup() {
 		logger.InfoFormat("Init storage file in %v", result.filePath)
-		err = result.writeRecordsToFile(storageRecords{})
+		err := result.writeRecordsToFile(storageRecords{})
 		if err != nil {
 			logger.ErrorFormat("failed to init storage file: %v", err)
 		}
@@ -55,15 +82,16 @@ func (f *fileStorage) AddMetricValues(ctx context.Context, metricsList []metrics
 	return metricsList, f.updateMetrics(metricsList)
 }
 
-func (f *fileStorage) GetMetric(ctx context.Context, metricType string, metricName string) (metrics.Metric, error) {
+func (f *fileStorage) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	seriesKey := metrics.SeriesKey(metricName, labels)
 	records, err := f.readRecordsFromFile(func(record *storageRecord) bool {
-		return record.Type == metricType && record.Name == metricName
+		return record.Type == metricType && record.seriesKey() == seriesKey
 	})
 	if err != nil {
 		return nil, logger.WrapError("read records from file", err)
 	}
 	if len(records) != 1 {
-		return nil, logger.WrapError(fmt.Sprintf("get metric with name '%s' and type '%s'", metricName, metricType), metrics.ErrMetricNotFound)
+		return nil, logger.WrapError(fmt.Sprintf("get metric with name '%s' and type '%s'", seriesKey, metricType), metrics.ErrMetricNotFound)
 	}
 
 	return f.toMetric(*records[0])
@@ -83,7 +111,7 @@ func (f *fileStorage) GetMetricValues(context.Context) (map[string]map[string]st
 			result[record.Type] = metricsByType
 		}
 
-		metricsByType[record.Name] = record.Value
+		metricsByType[record.seriesKey()] = record.Value
 	}
 
 	return result, nil
@@ -92,11 +120,17 @@ func (f *fileStorage) GetMetricValues(context.Context) (map[string]map[string]st
 func (f *fileStorage) Restore(ctx context.Context, metricValues map[string]map[string]string) error {
 	var records storageRecords
 	for metricType, metricsByType := range metricValues {
-		for metricName, metricValue := range metricsByType {
+		for seriesKey, metricValue := range metricsByType {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
 			records = append(records, &storageRecord{
-				Type:  metricType,
-				Name:  metricName,
-				Value: metricValue,
+				Type:   metricType,
+				Name:   metricName,
+				Labels: labels.Map(),
+				Value:  metricValue,
 			})
 		}
 	}
@@ -104,53 +138,223 @@ func (f *fileStorage) Restore(ctx context.Context, metricValues map[string]map[s
 	return f.writeRecordsToFile(records)
 }
 
-func (f *fileStorage) updateMetrics(metricsList []metrics.Metric) error {
-	// Read and write
-	return f.workWithFile(os.O_CREATE|os.O_RDWR, func(fileStream *os.File) error {
-		metricsMap := map[string]metrics.Metric{} // contains?
-		for _, metric := range metricsList {
-			metricsMap[metric.GetType()+metric.GetName()] = metric
+func (f *fileStorage) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	removed, err := f.updateRecords(func(records storageRecords) (storageRecords, int) {
+		result := storageRecords{}
+		for _, record := range records {
+			if !filter.Match(record.Type, record.Name, metrics.LabelsFromMap(record.Labels)) {
+				result = append(result, record)
+			}
 		}
 
-		records, err := f.readRecords(fileStream, func(record *storageRecord) bool {
-			_, found := metricsMap[record.Type+record.Name]
-			return !found
-		})
-		if err != nil {
-			return logger.WrapError("read records", err)
-		}
+		return result, len(records) - len(result)
+	})
+	if err != nil {
+		return 0, logger.WrapError("remove records", err)
+	}
 
-		_, err = fileStream.Seek(0, io.SeekStart)
-		if err != nil {
-			return logger.WrapError("seek pointer", err)
+	err = f.updateHistory(func(history map[string]map[string][]metrics.Sample) error {
+		for metricType, typedSamples := range history {
+			for seriesKey := range typedSamples {
+				if filter.MatchKey(metricType, seriesKey) {
+					delete(typedSamples, seriesKey)
+				}
+			}
+
+			if len(typedSamples) == 0 {
+				delete(history, metricType)
+			}
 		}
-		err = fileStream.Truncate(0)
-		if err != nil {
-			return logger.WrapError("truncate file stream", err)
+
+		return nil
+	})
+	if err != nil {
+		return 0, logger.WrapError("remove history", err)
+	}
+
+	return removed, nil
+}
+
+func (f *fileStorage) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	reset, err := f.updateRecords(func(records storageRecords) (storageRecords, int) {
+		reset := 0
+		for _, record := range records {
+			if record.Type == "counter" && filter.Match(record.Type, record.Name, metrics.LabelsFromMap(record.Labels)) {
+				record.Value = "0"
+				reset++
+			}
 		}
 
+		return records, reset
+	})
+	if err != nil {
+		return 0, logger.WrapError("reset records", err)
+	}
+
+	return reset, nil
+}
+
+func (f *fileStorage) AddSamples(ctx context.Context, timestamp time.Time, metricsList []metrics.Metric) error {
+	return f.updateHistory(func(history map[string]map[string][]metrics.Sample) error {
 		for _, metric := range metricsList {
-			records = append(records, &storageRecord{
-				Type:  metric.GetType(),
-				Name:  metric.GetName(),
-				Value: metric.GetStringValue(),
-			})
+			metricType := metric.GetType()
+			typedSamples, ok := history[metricType]
+			if !ok {
+				typedSamples = map[string][]metrics.Sample{}
+				history[metricType] = typedSamples
+			}
+
+			seriesKey := metrics.SeriesKey(metric.GetName(), metric.GetLabels())
+			samples := typedSamples[seriesKey]
+			last := len(samples) - 1
+			if last >= 0 && !samples[last].Timestamp.Before(timestamp) {
+				samples[last].Value = metric.GetValue()
+				continue
+			}
+
+			typedSamples[seriesKey] = append(samples, metrics.Sample{Timestamp: timestamp, Value: metric.GetValue()})
 		}
 
-		return f.writeRecords(fileStream, records)
+		return nil
 	})
 }
 
-func (f *fileStorage) readRecordsFromFile(isValid func(*storageRecord) bool) (storageRecords, error) {
-	// ReadOnly
-	return f.workWithFileResult(os.O_CREATE|os.O_RDONLY, func(fileStream *os.File) (storageRecords, error) {
-		return f.readRecords(fileStream, isValid)
+func (f *fileStorage) GetSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	labels metrics.Labels,
+	start time.Time,
+	end time.Time,
+) ([]metrics.Sample, error) {
+	history, err := f.GetHistory(ctx)
+	if err != nil {
+		return nil, logger.WrapError("read history", err)
+	}
+
+	return metrics.FilterSamples(history[metricType][metrics.SeriesKey(metricName, labels)], start, end), nil
+}
+
+func (f *fileStorage) GetHistory(context.Context) (map[string]map[string][]metrics.Sample, error) {
+	if f.filePath == "" {
+		return map[string]map[string][]metrics.Sample{}, nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.readHistory()
+}
+
+func (f *fileStorage) RestoreHistory(ctx context.Context, history map[string]map[string][]metrics.Sample) error {
+	if f.filePath == "" {
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.writeHistory(history)
+}
+
+func (f *fileStorage) RemoveSamples(ctx context.Context, before time.Time) error {
+	return f.updateHistory(func(history map[string]map[string][]metrics.Sample) error {
+		for metricType, typedSamples := range history {
+			for seriesKey, samples := range typedSamples {
+				expired := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Before(before) })
+				if expired == len(samples) {
+					delete(typedSamples, seriesKey)
+				} else {
+					typedSamples[seriesKey] = samples[expired:]
+				}
+			}
+
+			if len(typedSamples) == 0 {
+				delete(history, metricType)
+			}
+		}
+
+		return nil
 	})
 }
 
-func (f *fileStorage) readRecords(fileStream *os.File, isValid func(*storageRecord) bool) (storageRecords, error) {
-	var records storageRecords
-	err := json.NewDecoder(fileStream).Decode(&records)
+func (f *fileStorage) updateMetrics(metricsList []metrics.Metric) error {
+	if f.filePath == "" {
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	metricsMap := map[string]metrics.Metric{} // contains?
+	for _, metric := range metricsList {
+		metricsMap[metric.GetType()+metrics.SeriesKey(metric.GetName(), metric.GetLabels())] = metric
+	}
+
+	records, err := f.readRecords(func(record *storageRecord) bool {
+		_, found := metricsMap[record.Type+record.seriesKey()]
+		return !found
+	})
+	if err != nil {
+		return logger.WrapError("read records", err)
+	}
+
+	for _, metric := range metricsList {
+		records = append(records, &storageRecord{
+			Type:   metric.GetType(),
+			Name:   metric.GetName(),
+			Labels: metric.GetLabels().Map(),
+			Value:  metric.GetStringValue(),
+		})
+	}
+
+	return f.writeRecords(records)
+}
+
+// updateRecords rewrites stored records, the file is left untouched when nothing was changed.
+func (f *fileStorage) updateRecords(update func(records storageRecords) (storageRecords, int)) (int, error) {
+	if f.filePath == "" {
+		return 0, nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records, err := f.readRecords(func(record *storageRecord) bool { return true })
+	if err != nil {
+		return 0, logger.WrapError("read records", err)
+	}
+
+	records, changed := update(records)
+	if changed == 0 {
+		return 0, nil
+	}
+
+	return changed, f.writeRecords(records)
+}
+
+func (f *fileStorage) readRecordsFromFile(isValid func(*storageRecord) bool) (storageRecords, error) {
+	if f.filePath == "" {
+		return nil, nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.readRecords(isValid)
+}
+
+// readRecords reads the newest readable backup generation.
+func (f *fileStorage) readRecords(isValid func(*storageRecord) bool) (storageRecords, error) {
+	records, err := f.readGeneration(0)
+	for generation := 1; err != nil && generation <= f.generations; generation++ {
+		var fallbackErr error
+		records, fallbackErr = f.readGeneration(generation)
+		if fallbackErr == nil {
+			logger.ErrorFormat("failed to read backup file, fallback to generation %d: %v", generation, err)
+			err = nil
+		}
+	}
 	if err != nil {
 		return nil, logger.WrapError("decode storage", err)
 	}
@@ -165,60 +369,117 @@ func (f *fileStorage) readRecords(fileStream *os.File, isValid func(*storageReco
 	return result, nil
 }
 
-func (f *fileStorage) writeRecordsToFile(records storageRecords) error {
-	// WriteOnly
-	return f.workWithFile(os.O_CREATE|os.O_WRONLY, func(fileStream *os.File) error {
-		return f.writeRecords(fileStream, records)
-	})
-}
+func (f *fileStorage) readGeneration(generation int) (storageRecords, error) {
+	content, err := os.ReadFile(f.generationPath(generation))
+	if err != nil {
+		return nil, logger.WrapError("read backup file", err)
+	}
 
-func (f *fileStorage) writeRecords(fileStream *os.File, records storageRecords) error {
-	encoder := json.NewEncoder(fileStream)
-	encoder.SetIndent("", " ")
-	err := encoder.Encode(records)
+	content, err = f.codec.decode(content)
 	if err != nil {
-		return logger.WrapError("write records", err)
+		return nil, logger.WrapError("decode backup file", err)
 	}
 
-	return nil
-}
+	var records storageRecords
+	err = json.Unmarshal(content, &records)
+	if err != nil {
+		return nil, logger.WrapError("unmarshal records", err)
+	}
 
-func (f *fileStorage) workWithFile(flag int, work func(file *os.File) error) error {
-	_, err := f.workWithFileResult(flag, func(fileStream *os.File) (storageRecords, error) {
-		return nil, work(fileStream)
-	})
-	return err
+	return records, nil
 }
 
-func (f *fileStorage) workWithFileResult(flag int, work func(file *os.File) (storageRecords, error)) (storageRecords, error) {
+func (f *fileStorage) writeRecordsToFile(records storageRecords) error {
 	if f.filePath == "" {
-		return nil, nil
+		return nil
 	}
 
 	f.lock.Lock()
 	defer f.lock.Unlock()
 
-	fileStream, err := os.OpenFile(f.filePath, flag, fileMode)
+	err := f.rotate()
 	if err != nil {
-		return nil, logger.WrapError("open file", err)
+		return logger.WrapError("rotate backup files", err)
 	}
-	defer func(fileStream *os.File) {
-		err = fileStream.Close()
-		if err != nil {
-			logger.ErrorFormat("failed to close file: %v", err)
+
+	return f.writeRecords(records)
+}
+
+func (f *fileStorage) writeRecords(records storageRecords) error {
+	content, err := json.MarshalIndent(records, "", " ")
+	if err != nil {
+		return logger.WrapError("marshal records", err)
+	}
+
+	return f.writeFile(f.filePath, content)
+}
+
+func (f *fileStorage) writeFile(path string, content []byte) error {
+	content, err := f.codec.encode(content)
+	if err != nil {
+		return logger.WrapError("encode backup file", err)
+	}
+
+	err = storage.WriteFileAtomically(path, fileMode, func(writer io.Writer) error {
+		_, err := writer.Write(content)
+		return err
+	})
+	if err != nil {
+		return logger.WrapError("write backup file", err)
+	}
+
+	return nil
+}
+
+// rotate shifts backup generations: store.json becomes store.json.1, store.json.1 becomes store.json.2 and so on.
+// The oldest generation is overwritten.
+func (f *fileStorage) rotate() error {
+	if f.generations <= 0 {
+		return nil
+	}
+
+	for generation := f.generations; generation > 0; generation-- {
+		err := os.Rename(f.generationPath(generation-1), f.generationPath(generation))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return logger.WrapError(fmt.Sprintf("rotate backup generation %d", generation-1), err)
 		}
-	}(fileStream)
+	}
+
+	return storage.SyncDir(filepath.Dir(f.filePath))
+}
+
+// hasBackup checks any backup generation exists, so the interrupted rotation is not masked by the empty file.
+func (f *fileStorage) hasBackup() bool {
+	for generation := 0; generation <= f.generations; generation++ {
+		if _, err := os.Stat(f.generationPath(generation)); err == nil || !errors.Is(err, os.ErrNotExist) {
+			return true
+		}
+	}
 
-	return work(fileStream)
+	return false
+}
+
+func (f *fileStorage) generationPath(generation int) string {
+	if generation == 0 {
+		return f.filePath
+	}
+
+	return fmt.Sprintf("%s.%d", f.filePath, generation)
 }
 
 func (f *fileStorage) toMetric(record storageRecord) (metrics.Metric, error) {
+	labels := metrics.LabelsFromMap(record.Labels)
+
 	var metric metrics.Metric
 	switch record.Type {
 	case "counter":
-		metric = types.NewCounterMetric(record.Name)
+		metric = types.NewCounterMetric(record.Name, labels...)
 	case "gauge":
-		metric = types.NewGaugeMetric(record.Name)
+		metric = types.NewGaugeMetric(record.Name, labels...)
+	case "histogram":
+		return types.ParseHistogramMetric(record.Name, record.Value, labels...)
+	case "summary":
+		return types.ParseSummaryMetric(record.Name, record.Value, labels...)
 	default:
 		return nil, logger.WrapError(fmt.Sprintf("convert to metric with type %s", record.Type), metrics.ErrUnknownMetricType)
 	}
@@ -231,3 +492,98 @@ func (f *fileStorage) toMetric(record storageRecord) (metrics.Metric, error) {
 	metric.SetValue(value)
 	return metric, nil
 }
+
+func (f *fileStorage) updateHistory(update func(history map[string]map[string][]metrics.Sample) error) error {
+	if f.filePath == "" {
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	history, err := f.readHistory()
+	if err != nil {
+		return logger.WrapError("read history", err)
+	}
+
+	err = update(history)
+	if err != nil {
+		return err
+	}
+
+	return f.writeHistory(history)
+}
+
+func (f *fileStorage) readHistory() (map[string]map[string][]metrics.Sample, error) {
+	history := map[string]map[string][]metrics.Sample{}
+	content, err := os.ReadFile(f.filePath + historyFileSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, logger.WrapError("read history file", err)
+	}
+
+	content, err = f.codec.decode(content)
+	if err != nil {
+		return nil, logger.WrapError("decode history file", err)
+	}
+
+	var records []*historyRecord
+	err = json.Unmarshal(content, &records)
+	if err != nil {
+		return nil, logger.WrapError("decode history", err)
+	}
+
+	for _, record := range records {
+		typedSamples, ok := history[record.Type]
+		if !ok {
+			typedSamples = map[string][]metrics.Sample{}
+			history[record.Type] = typedSamples
+		}
+
+		typedSamples[record.seriesKey()] = record.Samples
+	}
+
+	return history, nil
+}
+
+func (f *fileStorage) writeHistory(history map[string]map[string][]metrics.Sample) error {
+	records := []*historyRecord{}
+	for metricType, typedSamples := range history {
+		for seriesKey, samples := range typedSamples {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			records = append(records, &historyRecord{
+				Type:    metricType,
+				Name:    metricName,
+				Labels:  labels.Map(),
+				Samples: samples,
+			})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].seriesKey() < records[j].seriesKey()
+	})
+
+	content, err := json.MarshalIndent(records, "", " ")
+	if err != nil {
+		return logger.WrapError("encode history", err)
+	}
+
+	return f.writeFile(f.filePath+historyFileSuffix, content)
+}
+
+func (r *storageRecord) seriesKey() string {
+	return metrics.SeriesKey(r.Name, metrics.LabelsFromMap(r.Labels))
+}
+
+func (r *historyRecord) seriesKey() string {
+	return metrics.SeriesKey(r.Name, metrics.LabelsFromMap(r.Labels))
+}
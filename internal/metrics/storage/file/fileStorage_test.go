@@ -1,19 +1,29 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/crypto/aes"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
 )
 
 type config struct {
-	filePath string
+	filePath    string
+	generations int
+	compression string
 }
 
 func TestFileStorage_New(t *testing.T) {
@@ -32,7 +42,7 @@ func TestFileStorage_New(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			storage := NewFileStorage(&config{filePath: tt.filePath})
+			storage := NewFileStorage(&config{filePath: tt.filePath}, nil, nil)
 			assert.NotNil(t, storage)
 
 			if tt.filePath != "" {
@@ -84,7 +94,7 @@ func TestFileStorage_AddGaugeMetricValue(t *testing.T) {
 				_ = os.Remove(name)
 			}(filePath)
 
-			storage := NewFileStorage(&config{filePath: filePath})
+			storage := NewFileStorage(&config{filePath: filePath}, nil, nil)
 
 			metricsList := make([]metrics.Metric, len(tt.values))
 			for i, m := range tt.values {
@@ -137,7 +147,7 @@ func TestFileStorage_AddCounterMetricValue(t *testing.T) {
 				assert.NoError(t, os.Remove(name))
 			}(filePath)
 
-			storage := NewFileStorage(&config{filePath: filePath})
+			storage := NewFileStorage(&config{filePath: filePath}, nil, nil)
 
 			metricsList := make([]metrics.Metric, len(tt.values))
 			for i, m := range tt.values {
@@ -192,8 +202,8 @@ func TestFileStorage_GetMetric(t *testing.T) {
 			}(filePath)
 			writeRecords(t, filePath, tt.stored)
 
-			storage := NewFileStorage(&config{filePath: filePath})
-			actualValue, err := storage.GetMetric(context.Background(), expectedMetricType, expectedMetricName)
+			storage := NewFileStorage(&config{filePath: filePath}, nil, nil)
+			actualValue, err := storage.GetMetric(context.Background(), expectedMetricType, expectedMetricName, nil)
 
 			if tt.expectedErrorMessage == "" {
 				assert.Equal(t, expectedValue, actualValue.GetValue())
@@ -204,6 +214,247 @@ func TestFileStorage_GetMetric(t *testing.T) {
 	}
 }
 
+func TestFileStorage_History(t *testing.T) {
+	ctx := context.Background()
+	filePath := os.TempDir() + "TestFileStorage_History"
+	defer func(name string) {
+		_ = os.Remove(name)
+		_ = os.Remove(name + historyFileSuffix)
+	}(filePath)
+
+	history, ok := NewFileStorage(&config{filePath: filePath}, nil, nil).(storage.HistoryStorage)
+	require.True(t, ok)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	labeledGauge := types.NewGaugeMetric("gauge", metrics.Label{Name: "cpu", Value: "1"})
+	for i := 0; i < 3; i++ {
+		labeledGauge.SetValue(float64(i))
+		err := history.AddSamples(ctx, start.Add(time.Duration(i)*time.Minute), []metrics.Metric{labeledGauge})
+		require.NoError(t, err)
+	}
+
+	expected := []metrics.Sample{
+		{Timestamp: start, Value: 0},
+		{Timestamp: start.Add(time.Minute), Value: 1},
+		{Timestamp: start.Add(2 * time.Minute), Value: 2},
+	}
+	actual, err := history.GetSamples(ctx, "gauge", "gauge", labeledGauge.GetLabels(), start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	require.NoError(t, history.RemoveSamples(ctx, start.Add(time.Minute)))
+	actualHistory, err := history.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]metrics.Sample{
+		"gauge": {`gauge{cpu="1"}`: expected[1:]},
+	}, actualHistory)
+
+	restored := map[string]map[string][]metrics.Sample{
+		"counter": {"counter": {{Timestamp: start, Value: 100}}},
+	}
+	require.NoError(t, history.RestoreHistory(ctx, restored))
+	actualHistory, err = history.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, restored, actualHistory)
+}
+
+func TestFileStorage_RemoveMetrics(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "store.json")
+	fileStorage := NewFileStorage(&config{filePath: filePath}, nil, nil)
+	history, ok := fileStorage.(storage.HistoryStorage)
+	require.True(t, ok)
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	metricsList := []metrics.Metric{
+		test.CreateCounterMetric("requests", 10),
+		test.CreateCounterMetric("errors", 5),
+		types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "1"}),
+	}
+	_, err := fileStorage.AddMetricValues(ctx, metricsList)
+	require.NoError(t, err)
+	require.NoError(t, history.AddSamples(ctx, start, metricsList))
+
+	removed, err := fileStorage.RemoveMetrics(ctx, &metrics.SeriesFilter{Labels: metrics.Labels{{Name: "cpu", Value: "1"}}, NamePrefix: "cpu"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	reset, err := fileStorage.ResetCounters(ctx, &metrics.SeriesFilter{Name: "requests"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, reset)
+
+	values, err := fileStorage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"counter": {"requests": "0", "errors": "5"}}, values)
+
+	actualHistory, err := history.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]metrics.Sample{
+		"counter": {
+			"requests": {{Timestamp: start, Value: 10}},
+			"errors":   {{Timestamp: start, Value: 5}},
+		},
+	}, actualHistory)
+}
+
+func TestFileStorage_Generations(t *testing.T) {
+	tests := []struct {
+		name     string
+		corrupt  func(t *testing.T, filePath string)
+		expected map[string]map[string]string
+	}{
+		{
+			name:     "success",
+			corrupt:  func(t *testing.T, filePath string) {},
+			expected: map[string]map[string]string{"counter": {"counter": "3"}},
+		},
+		{
+			name: "truncated",
+			corrupt: func(t *testing.T, filePath string) {
+				require.NoError(t, os.Truncate(filePath, 10))
+			},
+			expected: map[string]map[string]string{"counter": {"counter": "2"}},
+		},
+		{
+			name: "missed",
+			corrupt: func(t *testing.T, filePath string) {
+				require.NoError(t, os.Remove(filePath))
+			},
+			expected: map[string]map[string]string{"counter": {"counter": "2"}},
+		},
+		{
+			name: "two_generations_broken",
+			corrupt: func(t *testing.T, filePath string) {
+				require.NoError(t, os.WriteFile(filePath, []byte("garbage"), 0o644))
+				require.NoError(t, os.WriteFile(filePath+".1", []byte("[{"), 0o644))
+			},
+			expected: map[string]map[string]string{"counter": {"counter": "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			filePath := filepath.Join(t.TempDir(), "store.json")
+			storage := NewFileStorage(&config{filePath: filePath, generations: 2}, nil, nil)
+
+			for _, value := range []string{"1", "2", "3"} {
+				err := storage.Restore(ctx, map[string]map[string]string{"counter": {"counter": value}})
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, storageRecords{{Type: "counter", Name: "counter", Value: "3"}}, readRecords(t, filePath))
+			assert.Equal(t, storageRecords{{Type: "counter", Name: "counter", Value: "2"}}, readRecords(t, filePath+".1"))
+			assert.Equal(t, storageRecords{{Type: "counter", Name: "counter", Value: "1"}}, readRecords(t, filePath+".2"))
+			assert.NoFileExists(t, filePath+".3")
+			assert.NoFileExists(t, filePath+".tmp")
+
+			tt.corrupt(t, filePath)
+
+			actual, err := storage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+
+			actual, err = NewFileStorage(&config{filePath: filePath, generations: 2}, nil, nil).GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestFileStorage_AllGenerationsBroken(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "store.json")
+	storage := NewFileStorage(&config{filePath: filePath, generations: 1}, nil, nil)
+	require.NoError(t, os.WriteFile(filePath, []byte("garbage"), 0o644))
+
+	_, err := storage.GetMetricValues(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFileStorage_Codec(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression string
+		encrypted   bool
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:        "gzip",
+			compression: CompressionGzip,
+		},
+		{
+			name:        "zstd",
+			compression: CompressionZstd,
+		},
+		{
+			name:      "encrypted",
+			encrypted: true,
+		},
+		{
+			name:        "gzip_encrypted",
+			compression: CompressionGzip,
+			encrypted:   true,
+		},
+		{
+			name:        "zstd_encrypted",
+			compression: CompressionZstd,
+			encrypted:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "store.json")
+			keyPath := filepath.Join(dir, "key")
+			require.NoError(t, os.WriteFile(keyPath, []byte("0123456789abcdef"), 0o600))
+
+			cipher, err := aes.NewCipher(keyPath)
+			require.NoError(t, err)
+
+			var encryptor crypto.Encryptor
+			var decryptor crypto.Decryptor
+			if tt.encrypted {
+				encryptor = cipher
+				decryptor = cipher
+			}
+
+			expected := map[string]map[string]string{"gauge": {"secretHostName": "1"}}
+			backup := NewFileStorage(&config{filePath: filePath, compression: tt.compression}, encryptor, decryptor)
+			require.NoError(t, backup.Restore(ctx, expected))
+
+			content, err := os.ReadFile(filePath)
+			require.NoError(t, err)
+			switch {
+			case tt.encrypted:
+				assert.True(t, bytes.HasPrefix(content, encryptedMagic))
+				assert.NotContains(t, string(content), "secretHostName")
+			case tt.compression == CompressionGzip:
+				assert.True(t, bytes.HasPrefix(content, gzipMagic))
+			case tt.compression == CompressionZstd:
+				assert.True(t, bytes.HasPrefix(content, zstdMagic))
+			default:
+				assert.Contains(t, string(content), "secretHostName")
+			}
+
+			// format is detected on read regardless of configured compression
+			actual, err := NewFileStorage(&config{filePath: filePath}, nil, cipher).GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+
+			_, err = NewFileStorage(&config{filePath: filePath}, nil, nil).GetMetricValues(ctx)
+			if tt.encrypted {
+				assert.ErrorIs(t, err, metrics.ErrEncryptedBackup)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func readRecords(t *testing.T, filePath string) storageRecords {
 	t.Helper()
 	_, err := os.Stat(filePath)
@@ -234,3 +485,11 @@ func writeRecords(t *testing.T, filePath string, records storageRecords) {
 func (c *config) StoreFilePath() string {
 	return c.filePath
 }
+
+func (c *config) StoreFileGenerations() int {
+	return c.generations
+}
+
+func (c *config) StoreFileCompression() string {
+	return c.compression
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+// HistoryStorage keeps timestamped metric samples.
+type HistoryStorage interface {
+	// AddSamples appends current metric values with the timestamp to the series history.
+	AddSamples(ctx context.Context, timestamp time.Time, metricsList []metrics.Metric) error
+
+	// GetSamples returns series samples in the [start, end] range ordered by time.
+	GetSamples(ctx context.Context, metricType string, metricName string, labels metrics.Labels, start time.Time, end time.Time) ([]metrics.Sample, error)
+
+	// GetHistory returns all samples by type and series key.
+	GetHistory(ctx context.Context) (map[string]map[string][]metrics.Sample, error)
+
+	// RestoreHistory replaces all stored samples.
+	RestoreHistory(ctx context.Context, history map[string]map[string][]metrics.Sample) error
+
+	// RemoveSamples removes samples received before the boundary.
+	RemoveSamples(ctx context.Context, before time.Time) error
+}
@@ -3,7 +3,9 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
@@ -13,18 +15,28 @@ import (
 
 type inMemoryStorage struct {
 	metricsByType map[string]map[string]metrics.Metric
+	samplesByType map[string]map[string][]metrics.Sample
+	updatesByType map[string]map[string]time.Time
+	now           func() time.Time
 	lock          sync.RWMutex
 }
 
 func NewInMemoryStorage() *inMemoryStorage {
 	return &inMemoryStorage{
 		metricsByType: map[string]map[string]metrics.Metric{},
+		samplesByType: map[string]map[string][]metrics.Sample{},
+		updatesByType: map[string]map[string]time.Time{},
+		now:           time.Now,
 		lock:          sync.RWMutex{},
 	}
 }
 
 func (s *inMemoryStorage) AddMetricValues(ctx context.Context, metricList []metrics.Metric) ([]metrics.Metric, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	result := make([]metrics.Metric, len(metricList))
+	now := s.now()
 
 	for i, metric := range metricList {
 		metricType := metric.GetType()
@@ -34,13 +46,22 @@ func (s *inMemoryStorage) AddMetricValues(ctx context.Context, metricList []metr
 			s.metricsByType[metricType] = typedMetrics
 		}
 
-		metricName := metric.GetName()
-		currentMetric, ok := typedMetrics[metricName]
+		seriesKey := metrics.SeriesKey(metric.GetName(), metric.GetLabels())
+		s.setUpdated(metricType, seriesKey, now)
+		currentMetric, ok := typedMetrics[seriesKey]
 		if ok {
-			currentMetric.SetValue(metric.GetValue())
+			mergeableMetric, isMergeable := currentMetric.(metrics.MergeableMetric)
+			if isMergeable {
+				err := mergeableMetric.Merge(metric)
+				if err != nil {
+					return nil, logger.WrapError(fmt.Sprintf("merge metric '%s'", seriesKey), err)
+				}
+			} else {
+				currentMetric.SetValue(metric.GetValue())
+			}
 		} else {
 			currentMetric = metric
-			typedMetrics[metricName] = currentMetric
+			typedMetrics[seriesKey] = currentMetric
 		}
 
 		result[i] = currentMetric
@@ -58,15 +79,15 @@ func (s *inMemoryStorage) GetMetricValues(context.Context) (map[string]map[strin
 		values := map[string]string{}
 		metricValues[metricsType] = values
 
-		for metricName, metric := range metricsList {
-			values[metricName] = metric.GetStringValue()
+		for seriesKey, metric := range metricsList {
+			values[seriesKey] = metric.GetStringValue()
 		}
 	}
 
 	return metricValues, nil
 }
 
-func (s *inMemoryStorage) GetMetric(ctx context.Context, metricType string, metricName string) (metrics.Metric, error) {
+func (s *inMemoryStorage) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
@@ -75,9 +96,10 @@ func (s *inMemoryStorage) GetMetric(ctx context.Context, metricType string, metr
 		return nil, logger.WrapError(fmt.Sprintf("get metric with type %s", metricType), metrics.ErrMetricNotFound)
 	}
 
-	metric, ok := metricsByName[metricName]
+	seriesKey := metrics.SeriesKey(metricName, labels)
+	metric, ok := metricsByName[seriesKey]
 	if !ok {
-		return nil, logger.WrapError(fmt.Sprintf("metrics with name %v and types %v not found", metricName, metricType), metrics.ErrMetricNotFound)
+		return nil, logger.WrapError(fmt.Sprintf("metrics with name %v and types %v not found", seriesKey, metricType), metrics.ErrMetricNotFound)
 	}
 
 	return metric, nil
@@ -90,6 +112,26 @@ func (s *inMemoryStorage) Restore(ctx context.Context, metricValues map[string]m
 	s.metricsByType = map[string]map[string]metrics.Metric{}
 
 	for metricType, metricsByType := range metricValues {
+		if metricType == "histogram" {
+			err := s.restoreComposite(metricType, metricsByType, func(name string, value string, labels metrics.Labels) (metrics.Metric, error) {
+				return types.ParseHistogramMetric(name, value, labels...)
+			})
+			if err != nil {
+				return logger.WrapError("restore histograms", err)
+			}
+			continue
+		}
+
+		if metricType == "summary" {
+			err := s.restoreComposite(metricType, metricsByType, func(name string, value string, labels metrics.Labels) (metrics.Metric, error) {
+				return types.ParseSummaryMetric(name, value, labels...)
+			})
+			if err != nil {
+				return logger.WrapError("restore summaries", err)
+			}
+			continue
+		}
+
 		metricFactory := types.NewGaugeMetric
 		if metricType == "counter" {
 			metricFactory = types.NewCounterMetric
@@ -97,7 +139,13 @@ func (s *inMemoryStorage) Restore(ctx context.Context, metricValues map[string]m
 			return logger.WrapError(fmt.Sprintf("handle backup metric with type '%s'", metricType), metrics.ErrUnknownMetricType)
 		}
 
-		for metricName, metricValue := range metricsByType {
+		for seriesKey, metricValue := range metricsByType {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+			seriesKey = metrics.SeriesKey(metricName, labels)
+
 			value, err := parser.ToFloat64(metricValue)
 			if err != nil {
 				return logger.WrapError("parse float metric value", err)
@@ -109,15 +157,247 @@ func (s *inMemoryStorage) Restore(ctx context.Context, metricValues map[string]m
 				s.metricsByType[metricType] = metricsList
 			}
 
-			currentMetric, ok := metricsList[metricName]
+			currentMetric, ok := metricsList[seriesKey]
 			if !ok {
-				currentMetric = metricFactory(metricName)
-				metricsList[metricName] = currentMetric
+				currentMetric = metricFactory(metricName, labels...)
+				metricsList[seriesKey] = currentMetric
 			}
 
 			currentMetric.SetValue(value)
 		}
 	}
 
+	// update time is not a part of the backup, restored series are considered fresh
+	s.updatesByType = map[string]map[string]time.Time{}
+	now := s.now()
+	for metricType, metricsList := range s.metricsByType {
+		for seriesKey := range metricsList {
+			s.setUpdated(metricType, seriesKey, now)
+		}
+	}
+
+	return nil
+}
+
+func (s *inMemoryStorage) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	removed := 0
+	for metricType, metricsList := range s.metricsByType {
+		for seriesKey := range metricsList {
+			if filter.MatchKey(metricType, seriesKey) {
+				s.removeSeries(metricType, seriesKey)
+				removed++
+			}
+		}
+	}
+
+	// samples may be kept for series without value
+	for metricType, typedSamples := range s.samplesByType {
+		for seriesKey := range typedSamples {
+			if filter.MatchKey(metricType, seriesKey) {
+				s.removeSeries(metricType, seriesKey)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+func (s *inMemoryStorage) RemoveStaleMetrics(ctx context.Context, boundary func(metricType string) time.Time) (map[string][]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := map[string][]string{}
+	for metricType, updates := range s.updatesByType {
+		typeBoundary := boundary(metricType)
+		if typeBoundary.IsZero() {
+			continue
+		}
+
+		for seriesKey, updated := range updates {
+			if updated.Before(typeBoundary) {
+				s.removeSeries(metricType, seriesKey)
+				result[metricType] = append(result[metricType], seriesKey)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (s *inMemoryStorage) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reset := 0
+	for seriesKey, metric := range s.metricsByType["counter"] {
+		if filter.MatchKey("counter", seriesKey) {
+			metric.Flush()
+			reset++
+		}
+	}
+
+	return reset, nil
+}
+
+func (s *inMemoryStorage) AddSamples(ctx context.Context, timestamp time.Time, metricsList []metrics.Metric) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, metric := range metricsList {
+		metricType := metric.GetType()
+		typedSamples, ok := s.samplesByType[metricType]
+		if !ok {
+			typedSamples = map[string][]metrics.Sample{}
+			s.samplesByType[metricType] = typedSamples
+		}
+
+		seriesKey := metrics.SeriesKey(metric.GetName(), metric.GetLabels())
+		samples := typedSamples[seriesKey]
+		sample := metrics.Sample{Timestamp: timestamp, Value: metric.GetValue()}
+
+		last := len(samples) - 1
+		if last >= 0 && !samples[last].Timestamp.Before(timestamp) {
+			// the same series is received more than once in a single batch
+			samples[last].Value = sample.Value
+			continue
+		}
+
+		typedSamples[seriesKey] = append(samples, sample)
+	}
+
+	return nil
+}
+
+func (s *inMemoryStorage) GetSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	labels metrics.Labels,
+	start time.Time,
+	end time.Time,
+) ([]metrics.Sample, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return metrics.FilterSamples(s.samplesByType[metricType][metrics.SeriesKey(metricName, labels)], start, end), nil
+}
+
+func (s *inMemoryStorage) GetHistory(context.Context) (map[string]map[string][]metrics.Sample, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := map[string]map[string][]metrics.Sample{}
+	for metricType, typedSamples := range s.samplesByType {
+		samplesByKey := map[string][]metrics.Sample{}
+		result[metricType] = samplesByKey
+
+		for seriesKey, samples := range typedSamples {
+			samplesByKey[seriesKey] = append([]metrics.Sample(nil), samples...)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *inMemoryStorage) RestoreHistory(ctx context.Context, history map[string]map[string][]metrics.Sample) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	samplesByType := map[string]map[string][]metrics.Sample{}
+	for metricType, typedSamples := range history {
+		samplesByKey := map[string][]metrics.Sample{}
+		samplesByType[metricType] = samplesByKey
+
+		for seriesKey, samples := range typedSamples {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			restored := append([]metrics.Sample(nil), samples...)
+			sort.SliceStable(restored, func(i, j int) bool { return restored[i].Timestamp.Before(restored[j].Timestamp) })
+			samplesByKey[metrics.SeriesKey(metricName, labels)] = restored
+		}
+	}
+
+	s.samplesByType = samplesByType
+	return nil
+}
+
+func (s *inMemoryStorage) RemoveSamples(ctx context.Context, before time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for metricType, typedSamples := range s.samplesByType {
+		for seriesKey, samples := range typedSamples {
+			expired := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Before(before) })
+			switch {
+			case expired == len(samples):
+				delete(typedSamples, seriesKey)
+			case expired > 0:
+				typedSamples[seriesKey] = append([]metrics.Sample(nil), samples[expired:]...)
+			}
+		}
+
+		if len(typedSamples) == 0 {
+			delete(s.samplesByType, metricType)
+		}
+	}
+
+	return nil
+}
+
+func (s *inMemoryStorage) setUpdated(metricType string, seriesKey string, updated time.Time) {
+	updates, ok := s.updatesByType[metricType]
+	if !ok {
+		updates = map[string]time.Time{}
+		s.updatesByType[metricType] = updates
+	}
+
+	updates[seriesKey] = updated
+}
+
+// removeSeries removes series value, samples and update time.
+func (s *inMemoryStorage) removeSeries(metricType string, seriesKey string) {
+	delete(s.metricsByType[metricType], seriesKey)
+	if len(s.metricsByType[metricType]) == 0 {
+		delete(s.metricsByType, metricType)
+	}
+
+	delete(s.samplesByType[metricType], seriesKey)
+	if len(s.samplesByType[metricType]) == 0 {
+		delete(s.samplesByType, metricType)
+	}
+
+	delete(s.updatesByType[metricType], seriesKey)
+	if len(s.updatesByType[metricType]) == 0 {
+		delete(s.updatesByType, metricType)
+	}
+}
+
+func (s *inMemoryStorage) restoreComposite(
+	metricType string,
+	metricValues map[string]string,
+	parse func(name string, value string, labels metrics.Labels) (metrics.Metric, error),
+) error {
+	metricsList := map[string]metrics.Metric{}
+	for seriesKey, metricValue := range metricValues {
+		metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+		if err != nil {
+			return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+		}
+
+		metric, err := parse(metricName, metricValue, labels)
+		if err != nil {
+			return logger.WrapError(fmt.Sprintf("parse %s metric", metricType), err)
+		}
+
+		metricsList[metrics.SeriesKey(metricName, labels)] = metric
+	}
+
+	s.metricsByType[metricType] = metricsList
 	return nil
 }
@@ -4,10 +4,13 @@ import (
 	"context"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
 )
@@ -144,6 +147,103 @@ func TestInMemoryStorage_AddGaugeMetricValue(t *testing.T) {
 	}
 }
 
+func TestInMemoryStorage_AddHistogramMetricValue(t *testing.T) {
+	tests := []struct {
+		expected      map[string]map[string]string
+		expectedError error
+		name          string
+		metricsList   []metrics.Metric
+	}{
+		{
+			name: "single_metric",
+			metricsList: []metrics.Metric{
+				test.CreateHistogramMetric("metricName1", []float64{1, 2}, 0.5, 1.5),
+			},
+			expected: map[string]map[string]string{
+				"histogram": {"metricName1": `{"bounds":[1,2],"counts":[1,2],"sum":2,"count":2}`},
+			},
+		},
+		{
+			name: "same_metrics",
+			metricsList: []metrics.Metric{
+				test.CreateHistogramMetric("metricName1", []float64{1, 2}, 0.5, 1.5),
+				test.CreateHistogramMetric("metricName1", []float64{1, 2}, 3),
+			},
+			expected: map[string]map[string]string{
+				"histogram": {"metricName1": `{"bounds":[1,2],"counts":[1,2],"sum":5,"count":3}`},
+			},
+		},
+		{
+			name: "different_bounds",
+			metricsList: []metrics.Metric{
+				test.CreateHistogramMetric("metricName1", []float64{1, 2}, 0.5),
+				test.CreateHistogramMetric("metricName1", []float64{1, 3}, 0.5),
+			},
+			expectedError: metrics.ErrIncompatibleMetrics,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewInMemoryStorage()
+
+			_, err := storage.AddMetricValues(context.Background(), tt.metricsList)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+
+			actual, _ := storage.GetMetricValues(context.Background())
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestInMemoryStorage_AddSummaryMetricValue(t *testing.T) {
+	storage := NewInMemoryStorage()
+
+	_, err := storage.AddMetricValues(context.Background(), []metrics.Metric{
+		types.NewSummaryMetricFromObservations("metricName1", []float64{1}),
+		types.NewSummaryMetricFromObservations("metricName1", []float64{2}),
+	})
+	assert.NoError(t, err)
+
+	actual, _ := storage.GetMetricValues(context.Background())
+	assert.Equal(t, map[string]map[string]string{
+		"summary": {"metricName1": `{"digest":{"compression":100,"min":1,"max":2,"centroids":[{"mean":1,"weight":1},{"mean":2,"weight":1}]},"sum":3,"count":2}`},
+	}, actual)
+}
+
+func TestInMemoryStorage_AddLabeledMetricValue(t *testing.T) {
+	storage := NewInMemoryStorage()
+
+	firstCPU := types.NewCounterMetric("metricName1", metrics.Label{Name: "cpu", Value: "1"})
+	firstCPU.SetValue(100)
+	secondCPU := types.NewCounterMetric("metricName1", metrics.Label{Name: "cpu", Value: "2"})
+	secondCPU.SetValue(200)
+
+	_, err := storage.AddMetricValues(context.Background(), []metrics.Metric{
+		firstCPU,
+		secondCPU,
+		test.CreateCounterMetric("metricName1", 300),
+	})
+	assert.NoError(t, err)
+
+	actual, _ := storage.GetMetricValues(context.Background())
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {
+			"metricName1":          "300",
+			`metricName1{cpu="1"}`: "100",
+			`metricName1{cpu="2"}`: "200",
+		},
+	}, actual)
+
+	metric, err := storage.GetMetric(context.Background(), "counter", "metricName1", metrics.NewLabels(metrics.Label{Name: "cpu", Value: "2"}))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(200), metric.GetValue())
+}
+
 func TestInMemoryStorage_GetMetricValues(t *testing.T) {
 	tests := []struct {
 		expected       map[string]map[string]string
@@ -232,6 +332,40 @@ func TestInMemoryStorage_Restore(t *testing.T) {
 					"metricName5": "300.003",
 					"metricName6": "-400.004",
 				},
+				"histogram": {
+					"metricName7": `{"bounds":[1,2],"counts":[1,2],"sum":2,"count":2}`,
+				},
+				"summary": {
+					`metricName9{cpu="1",host="local"}`: `{"digest":{"compression":100,"min":1,"max":2,"centroids":[{"mean":1,"weight":1},{"mean":2,"weight":1}]},"sum":3,"count":2}`,
+					"metricName8":                       `{"digest":{"compression":100,"min":1,"max":2,"centroids":[{"mean":1,"weight":1},{"mean":2,"weight":1}]},"sum":3,"count":2}`,
+				},
+			},
+		},
+		{
+			name:                 "invalid_labels",
+			expectedErrorMessage: "invalid metric label",
+			values: map[string]map[string]string{
+				"gauge": {
+					`metricName1{cpu="1"`: "1",
+				},
+			},
+		},
+		{
+			name:                 "invalid_histogram",
+			expectedErrorMessage: "invalid histogram state",
+			values: map[string]map[string]string{
+				"histogram": {
+					"metricName1": `{"bounds":[1,2],"counts":[1],"sum":2,"count":2}`,
+				},
+			},
+		},
+		{
+			name:                 "invalid_summary",
+			expectedErrorMessage: "invalid summary state",
+			values: map[string]map[string]string{
+				"summary": {
+					"metricName1": `{"sum":2,"count":2}`,
+				},
 			},
 		},
 	}
@@ -326,7 +460,7 @@ func TestInMemoryStorage_GetMetricValue(t *testing.T) {
 			assert.NoError(t, err)
 
 			for _, expectedCounter := range tt.expectedCounters {
-				actualValue, err := storage.GetMetric(context.Background(), "counter", expectedCounter.Key)
+				actualValue, err := storage.GetMetric(context.Background(), "counter", expectedCounter.Key, nil)
 				if tt.expectedOk {
 					assert.NoError(t, err)
 					assert.Equal(t, expectedCounter.Value, actualValue.GetValue())
@@ -336,7 +470,7 @@ func TestInMemoryStorage_GetMetricValue(t *testing.T) {
 			}
 
 			for _, expectedGauge := range tt.expectedGauges {
-				actualValue, err := storage.GetMetric(context.Background(), "gauge", expectedGauge.Key)
+				actualValue, err := storage.GetMetric(context.Background(), "gauge", expectedGauge.Key, nil)
 				if tt.expectedOk {
 					assert.NoError(t, err)
 					assert.Equal(t, expectedGauge.Value, actualValue.GetValue())
@@ -367,3 +501,164 @@ func BenchmarkInMemoryStorage_AddCounterMetricValue(b *testing.B) {
 		_, _ = storage.AddMetricValues(ctx, metricsList)
 	}
 }
+
+func TestInMemoryStorage_History(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewInMemoryStorage()
+
+	counter := test.CreateCounterMetric("metricName1", 100)
+	require.NoError(t, storage.AddSamples(ctx, start, []metrics.Metric{counter}))
+	counter.SetValue(100)
+	require.NoError(t, storage.AddSamples(ctx, start.Add(time.Minute), []metrics.Metric{counter, counter}))
+
+	actual, err := storage.GetSamples(ctx, "counter", "metricName1", nil, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []metrics.Sample{
+		{Timestamp: start, Value: 100},
+		{Timestamp: start.Add(time.Minute), Value: 200},
+	}, actual)
+
+	require.NoError(t, storage.RemoveSamples(ctx, start.Add(time.Minute)))
+	history, err := storage.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]metrics.Sample{
+		"counter": {"metricName1": {{Timestamp: start.Add(time.Minute), Value: 200}}},
+	}, history)
+
+	require.NoError(t, storage.RemoveSamples(ctx, start.Add(time.Hour)))
+	history, err = storage.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	err = storage.RestoreHistory(ctx, map[string]map[string][]metrics.Sample{
+		"gauge": {`metricName2{host="b",cpu="1"}`: {
+			{Timestamp: start.Add(time.Minute), Value: 2},
+			{Timestamp: start, Value: 1},
+		}},
+	})
+	require.NoError(t, err)
+
+	actual, err = storage.GetSamples(ctx, "gauge", "metricName2", metrics.NewLabels(
+		metrics.Label{Name: "cpu", Value: "1"},
+		metrics.Label{Name: "host", Value: "b"},
+	), start, start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []metrics.Sample{
+		{Timestamp: start, Value: 1},
+		{Timestamp: start.Add(time.Minute), Value: 2},
+	}, actual)
+}
+
+func TestInMemoryStorage_RemoveMetrics(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewInMemoryStorage()
+
+	metricsList := []metrics.Metric{
+		test.CreateCounterMetric("requests", 10),
+		test.CreateGaugeMetric("cpu_usage", 1),
+		types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "1"}),
+		test.CreateGaugeMetric("memory_usage", 1),
+	}
+	_, err := storage.AddMetricValues(ctx, metricsList)
+	require.NoError(t, err)
+	require.NoError(t, storage.AddSamples(ctx, start, metricsList))
+
+	removed, err := storage.RemoveMetrics(ctx, &metrics.SeriesFilter{NamePrefix: "cpu_"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+
+	values, err := storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {"requests": "10"},
+		"gauge":   {"memory_usage": "1"},
+	}, values)
+
+	history, err := storage.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]metrics.Sample{
+		"counter": {"requests": {{Timestamp: start, Value: 10}}},
+		"gauge":   {"memory_usage": {{Timestamp: start, Value: 1}}},
+	}, history)
+
+	removed, err = storage.RemoveMetrics(ctx, &metrics.SeriesFilter{Type: "gauge", Name: "requests"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestInMemoryStorage_RemoveStaleMetrics(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewInMemoryStorage()
+
+	storage.now = func() time.Time { return start }
+	staleMetrics := []metrics.Metric{
+		test.CreateCounterMetric("requests", 10),
+		types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "1"}),
+	}
+	_, err := storage.AddMetricValues(ctx, staleMetrics)
+	require.NoError(t, err)
+	require.NoError(t, storage.AddSamples(ctx, start, staleMetrics))
+
+	storage.now = func() time.Time { return start.Add(time.Hour) }
+	_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateGaugeMetric("memory_usage", 1)})
+	require.NoError(t, err)
+
+	boundaries := map[string]time.Time{"gauge": start.Add(time.Minute)}
+	evicted, err := storage.RemoveStaleMetrics(ctx, func(metricType string) time.Time { return boundaries[metricType] })
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"gauge": {`cpu_usage{cpu="1"}`}}, evicted)
+
+	values, err := storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {"requests": "10"},
+		"gauge":   {"memory_usage": "1"},
+	}, values)
+
+	history, err := storage.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]metrics.Sample{
+		"counter": {"requests": {{Timestamp: start, Value: 10}}},
+	}, history)
+
+	// update refreshes the series
+	storage.now = func() time.Time { return start.Add(2 * time.Hour) }
+	_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateCounterMetric("requests", 1)})
+	require.NoError(t, err)
+
+	boundaries = map[string]time.Time{"counter": start.Add(time.Hour), "gauge": start.Add(2 * time.Hour)}
+	evicted, err = storage.RemoveStaleMetrics(ctx, func(metricType string) time.Time { return boundaries[metricType] })
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"gauge": {"memory_usage"}}, evicted)
+
+	values, err = storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"counter": {"requests": "11"}}, values)
+}
+
+func TestInMemoryStorage_ResetCounters(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryStorage()
+
+	_, err := storage.AddMetricValues(ctx, []metrics.Metric{
+		test.CreateCounterMetric("requests", 10),
+		types.NewCounterMetric("requests", metrics.Label{Name: "code", Value: "500"}),
+		test.CreateCounterMetric("errors", 5),
+		test.CreateGaugeMetric("requests", 100),
+	})
+	require.NoError(t, err)
+
+	reset, err := storage.ResetCounters(ctx, &metrics.SeriesFilter{Name: "requests"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, reset)
+
+	values, err := storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {"requests": "0", `requests{code="500"}`: "0", "errors": "5"},
+		"gauge":   {"requests": "100"},
+	}, values)
+}
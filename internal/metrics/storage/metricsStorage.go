@@ -11,12 +11,19 @@ type MetricsStorage interface {
 	// AddMetricValues serve metrics.
 	AddMetricValues(ctx context.Context, metric []metrics.Metric) ([]metrics.Metric, error)
 
-	// GetMetricValues returns all metric values.
+	// GetMetricValues returns all metric values by type and series key.
 	GetMetricValues(ctx context.Context) (map[string]map[string]string, error)
 
-	// GetMetric returns single metric by metric type and name.
-	GetMetric(ctx context.Context, metricType string, metricName string) (metrics.Metric, error)
+	// GetMetric returns single metric by metric type, name and labels.
+	GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error)
 
 	// Restore recovers storage state.
 	Restore(ctx context.Context, metricValues map[string]map[string]string) error
+
+	// RemoveMetrics removes series selected by the filter together with their samples and returns the number of removed series.
+	RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error)
+
+	// ResetCounters sets counters selected by the filter to zero and returns the number of reset series.
+	// Metrics of other types are never reset.
+	ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error)
 }
@@ -3,33 +3,69 @@ package storage
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
 )
 
 type storageStrategyConfig interface {
 	SyncMode() bool
+	HistoryRetention() time.Duration
+	SeriesTTL(metricType string) time.Duration
+}
+
+type seriesRegistry interface {
+	Forget(filter *metrics.SeriesFilter)
+}
+
+type metricsRecorder interface {
+	Add(ctx context.Context, name string, delta float64, labels ...metrics.Label)
+	ObserveDuration(ctx context.Context, name string, start time.Time, labels ...metrics.Label)
 }
 
 // StorageStrategy combine in memory and long-term metrics storages.
+// Metric samples are recorded only when history retention is configured
+// and the storages implement HistoryStorage.
+// Backup storage is nil when the primary storage is durable and shared by itself.
+// Stale series are evicted only when the memory storage implements ExpiringStorage.
+// Removed and evicted series are forgotten by the registry, so it reports existing series only.
 type StorageStrategy struct {
-	backupStorage   MetricsStorage
-	inMemoryStorage MetricsStorage
-	syncMode        bool
-	lock            sync.RWMutex
+	backupStorage    MetricsStorage
+	registry         seriesRegistry
+	seriesTTL        func(metricType string) time.Duration
+	inMemoryStorage  MetricsStorage
+	recorder         metricsRecorder
+	now              func() time.Time
+	historyRetention time.Duration
+	syncMode         bool
+	lock             sync.RWMutex
 }
 
 // NewStorageStrategy creates new instance of StorageStrategy.
-func NewStorageStrategy(config storageStrategyConfig, inMemoryStorage MetricsStorage, fileStorage MetricsStorage) *StorageStrategy {
+// Operation latencies and backup durations are reported to the recorder.
+func NewStorageStrategy(
+	config storageStrategyConfig,
+	inMemoryStorage MetricsStorage,
+	fileStorage MetricsStorage,
+	recorder metricsRecorder,
+	registry seriesRegistry,
+) *StorageStrategy {
 	return &StorageStrategy{
-		backupStorage:   fileStorage,
-		inMemoryStorage: inMemoryStorage,
-		syncMode:        config.SyncMode(),
+		backupStorage:    fileStorage,
+		registry:         registry,
+		inMemoryStorage:  inMemoryStorage,
+		recorder:         recorder,
+		now:              time.Now,
+		seriesTTL:        config.SeriesTTL,
+		historyRetention: config.HistoryRetention(),
+		syncMode:         config.SyncMode(),
 	}
 }
 
 func (s *StorageStrategy) AddMetricValues(ctx context.Context, metric []metrics.Metric) ([]metrics.Metric, error) {
+	defer s.observe(ctx, "add", time.Now())
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -38,55 +74,314 @@ func (s *StorageStrategy) AddMetricValues(ctx context.Context, metric []metrics.
 		return result, logger.WrapError("add metric values to memory storage", err)
 	}
 
-	if s.syncMode {
+	timestamp := s.now()
+	err = s.addSamples(ctx, s.inMemoryStorage, timestamp, result)
+	if err != nil {
+		return nil, logger.WrapError("add metric samples to memory storage", err)
+	}
+
+	if s.syncMode && s.backupStorage != nil {
 		_, err = s.backupStorage.AddMetricValues(ctx, result)
 		if err != nil {
 			return nil, logger.WrapError("add metric values to backup storage", err)
 		}
+
+		err = s.addSamples(ctx, s.backupStorage, timestamp, result)
+		if err != nil {
+			return nil, logger.WrapError("add metric samples to backup storage", err)
+		}
 	}
 
 	return result, nil
 }
 
 func (s *StorageStrategy) GetMetricValues(ctx context.Context) (map[string]map[string]string, error) {
+	defer s.observe(ctx, "get_values", time.Now())
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	return s.inMemoryStorage.GetMetricValues(ctx)
 }
 
-func (s *StorageStrategy) GetMetric(ctx context.Context, metricType string, metricName string) (metrics.Metric, error) {
+func (s *StorageStrategy) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	defer s.observe(ctx, "get", time.Now())
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
-	return s.inMemoryStorage.GetMetric(ctx, metricType, metricName)
+	return s.inMemoryStorage.GetMetric(ctx, metricType, metricName, labels)
 }
 
 func (s *StorageStrategy) Restore(ctx context.Context, metricValues map[string]map[string]string) error {
+	defer s.observe(ctx, "restore", time.Now())
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
 	return s.inMemoryStorage.Restore(ctx, metricValues)
 }
 
+// RemoveMetrics removes series from the memory storage, the backup storage follows with the next backup.
+// In sync mode series are removed from the backup storage immediately.
+func (s *StorageStrategy) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	defer s.observe(ctx, "remove", time.Now())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	removed, err := s.inMemoryStorage.RemoveMetrics(ctx, filter)
+	if err != nil {
+		return 0, logger.WrapError("remove metrics from memory storage", err)
+	}
+	s.registry.Forget(filter)
+
+	if s.syncMode && s.backupStorage != nil {
+		_, err = s.backupStorage.RemoveMetrics(ctx, filter)
+		if err != nil {
+			return 0, logger.WrapError("remove metrics from backup storage", err)
+		}
+	}
+
+	return removed, nil
+}
+
+// ResetCounters resets counters in the memory storage, in sync mode the backup storage is reset immediately.
+func (s *StorageStrategy) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	defer s.observe(ctx, "reset", time.Now())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reset, err := s.inMemoryStorage.ResetCounters(ctx, filter)
+	if err != nil {
+		return 0, logger.WrapError("reset counters in memory storage", err)
+	}
+
+	if s.syncMode && s.backupStorage != nil {
+		_, err = s.backupStorage.ResetCounters(ctx, filter)
+		if err != nil {
+			return 0, logger.WrapError("reset counters in backup storage", err)
+		}
+	}
+
+	return reset, nil
+}
+
+// RemoveStaleMetrics evicts series not updated within the TTL of their type, in sync mode the series are removed
+// from the backup storage immediately. Evicted series are counted by the recorder.
+func (s *StorageStrategy) RemoveStaleMetrics(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	expiring, ok := s.inMemoryStorage.(ExpiringStorage)
+	if !ok {
+		return nil
+	}
+
+	now := s.now()
+	evicted, err := expiring.RemoveStaleMetrics(ctx, func(metricType string) time.Time {
+		ttl := s.seriesTTL(metricType)
+		if ttl <= 0 {
+			return time.Time{}
+		}
+
+		return now.Add(-ttl)
+	})
+	if err != nil {
+		return logger.WrapError("remove stale metrics from memory storage", err)
+	}
+
+	count := 0
+	for metricType, seriesKeys := range evicted {
+		count += len(seriesKeys)
+		for _, seriesKey := range seriesKeys {
+			name, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError("parse series key", err)
+			}
+
+			filter := &metrics.SeriesFilter{Type: metricType, Name: name, Labels: labels, ExactLabels: true}
+			s.registry.Forget(filter)
+			if !s.syncMode || s.backupStorage == nil {
+				continue
+			}
+
+			_, err = s.backupStorage.RemoveMetrics(ctx, filter)
+			if err != nil {
+				return logger.WrapError("remove stale metrics from backup storage", err)
+			}
+		}
+	}
+
+	s.recorder.Add(ctx, telemetry.EvictedSeries, float64(count))
+	return nil
+}
+
+func (s *StorageStrategy) AddSamples(ctx context.Context, timestamp time.Time, metricsList []metrics.Metric) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.addSamples(ctx, s.inMemoryStorage, timestamp, metricsList)
+}
+
+func (s *StorageStrategy) GetSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	labels metrics.Labels,
+	start time.Time,
+	end time.Time,
+) ([]metrics.Sample, error) {
+	defer s.observe(ctx, "get_samples", time.Now())
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	history, ok := s.inMemoryStorage.(HistoryStorage)
+	if !ok || s.historyRetention <= 0 {
+		return nil, metrics.ErrHistoryNotSupported
+	}
+
+	return history.GetSamples(ctx, metricType, metricName, labels, start, end)
+}
+
+func (s *StorageStrategy) GetHistory(ctx context.Context) (map[string]map[string][]metrics.Sample, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	history, ok := s.inMemoryStorage.(HistoryStorage)
+	if !ok {
+		return nil, metrics.ErrHistoryNotSupported
+	}
+
+	return history.GetHistory(ctx)
+}
+
+func (s *StorageStrategy) RestoreHistory(ctx context.Context, samples map[string]map[string][]metrics.Sample) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	history, ok := s.inMemoryStorage.(HistoryStorage)
+	if !ok {
+		return metrics.ErrHistoryNotSupported
+	}
+
+	return history.RestoreHistory(ctx, samples)
+}
+
+func (s *StorageStrategy) RemoveSamples(ctx context.Context, before time.Time) error {
+	for _, metricsStorage := range []MetricsStorage{s.inMemoryStorage, s.backupStorage} {
+		history, ok := metricsStorage.(HistoryStorage)
+		if !ok {
+			continue
+		}
+
+		err := history.RemoveSamples(ctx, before)
+		if err != nil {
+			return logger.WrapError("remove samples", err)
+		}
+	}
+
+	return nil
+}
+
+// RemoveExpiredSamples removes samples out of the history retention window.
+func (s *StorageStrategy) RemoveExpiredSamples(ctx context.Context) error {
+	if s.historyRetention <= 0 {
+		return nil
+	}
+
+	return s.RemoveSamples(ctx, s.now().Add(-s.historyRetention))
+}
+
 func (s *StorageStrategy) CreateBackup(ctx context.Context) error {
+	if s.backupStorage == nil {
+		return nil
+	}
+
+	defer s.recorder.ObserveDuration(ctx, telemetry.BackupDuration, time.Now())
 	currentState, err := s.inMemoryStorage.GetMetricValues(ctx)
 	if err != nil {
 		return logger.WrapError("get metrics from memory storage", err)
 	}
 
-	return s.backupStorage.Restore(ctx, currentState)
+	err = s.backupStorage.Restore(ctx, currentState)
+	if err != nil {
+		return logger.WrapError("restore backup storage", err)
+	}
+
+	// samples are already written to the backup storage in sync mode
+	sourceHistory, targetHistory, ok := s.histories(s.inMemoryStorage, s.backupStorage)
+	if !ok || s.syncMode {
+		return nil
+	}
+
+	currentHistory, err := sourceHistory.GetHistory(ctx)
+	if err != nil {
+		return logger.WrapError("get samples from memory storage", err)
+	}
+
+	return targetHistory.RestoreHistory(ctx, currentHistory)
 }
 
 func (s *StorageStrategy) RestoreFromBackup(ctx context.Context) error {
+	if s.backupStorage == nil {
+		return nil
+	}
+
 	restoredState, err := s.backupStorage.GetMetricValues(ctx)
 	if err != nil {
 		return logger.WrapError("get metrics from backup storage", err)
 	}
 
-	return s.inMemoryStorage.Restore(ctx, restoredState)
+	err = s.inMemoryStorage.Restore(ctx, restoredState)
+	if err != nil {
+		return logger.WrapError("restore memory storage", err)
+	}
+
+	sourceHistory, targetHistory, ok := s.histories(s.backupStorage, s.inMemoryStorage)
+	if !ok {
+		return nil
+	}
+
+	restoredHistory, err := sourceHistory.GetHistory(ctx)
+	if err != nil {
+		return logger.WrapError("get samples from backup storage", err)
+	}
+
+	return targetHistory.RestoreHistory(ctx, restoredHistory)
 }
 
 func (s *StorageStrategy) Close() error {
 	return s.CreateBackup(context.Background()) // force backup
 }
+
+func (s *StorageStrategy) observe(ctx context.Context, operation string, start time.Time) {
+	s.recorder.ObserveDuration(ctx, telemetry.StorageOperationDuration, start, metrics.Label{Name: "operation", Value: operation})
+}
+
+func (s *StorageStrategy) addSamples(ctx context.Context, metricsStorage MetricsStorage, timestamp time.Time, metricsList []metrics.Metric) error {
+	if s.historyRetention <= 0 {
+		return nil
+	}
+
+	history, ok := metricsStorage.(HistoryStorage)
+	if !ok {
+		return nil
+	}
+
+	return history.AddSamples(ctx, timestamp, metricsList)
+}
+
+func (s *StorageStrategy) histories(source MetricsStorage, target MetricsStorage) (HistoryStorage, HistoryStorage, bool) {
+	if s.historyRetention <= 0 {
+		return nil, nil, false
+	}
+
+	sourceHistory, ok := source.(HistoryStorage)
+	if !ok {
+		return nil, nil, false
+	}
+
+	targetHistory, ok := target.(HistoryStorage)
+	if !ok {
+		return nil, nil, false
+	}
+
+	return sourceHistory, targetHistory, true
+}
@@ -3,16 +3,24 @@ package storage
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/agents"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/memory"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/telemetry"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
 	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
 )
 
 type configMock struct {
 	mock.Mock
+	historyRetention time.Duration
+	seriesTTL        map[string]time.Duration
 }
 
 type metricStorageMock struct {
@@ -84,7 +92,7 @@ func TestStorageStrategy_AddGaugeMetricValue(t *testing.T) {
 			inMemoryStorageMock.On("AddMetricValues", ctx, metricsList).Return(tt.expectedResult, tt.inMemoryStorageError)
 			backupStorageMock.On("AddMetricValues", ctx, tt.expectedResult).Return(tt.expectedResult, tt.backupStorageErrorError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualResult, actualError := strategy.AddMetricValues(ctx, metricsList)
 
 			assert.Equal(t, tt.expectedResult, actualResult)
@@ -164,7 +172,7 @@ func TestStorageStrategy_AddCounterMetricValue(t *testing.T) {
 			inMemoryStorageMock.On("AddMetricValues", ctx, metricsList).Return(tt.expectedResult, tt.inMemoryStorageError)
 			backupStorageMock.On("AddMetricValues", ctx, tt.expectedResult).Return(tt.expectedResult, tt.backupStorageErrorError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualResult, actualError := strategy.AddMetricValues(ctx, metricsList)
 
 			assert.Equal(t, tt.expectedResult, actualResult)
@@ -234,7 +242,7 @@ func TestStorageStrategy_GetMetricValues(t *testing.T) {
 			inMemoryStorageMock.On("GetMetricValues", ctx).Return(tt.storageResult, tt.storageError)
 			backupStorageMock.On("GetMetricValues", ctx).Return(tt.storageResult, tt.storageError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualResult, actualError := strategy.GetMetricValues(ctx)
 
 			assert.Equal(t, tt.expectedResult, actualResult)
@@ -248,6 +256,7 @@ func TestStorageStrategy_GetMetricValues(t *testing.T) {
 
 func TestStorageStrategy_GetMetric(t *testing.T) {
 	resultMetric := test.CreateGaugeMetric(metricName, metricValue)
+	labels := metrics.NewLabels(metrics.Label{Name: "host", Value: "local"})
 	tests := []struct {
 		storageResult  metrics.Metric
 		storageError   error
@@ -291,17 +300,17 @@ func TestStorageStrategy_GetMetric(t *testing.T) {
 			backupStorageMock := new(metricStorageMock)
 
 			confMock.On("SyncMode").Return(tt.syncMode)
-			inMemoryStorageMock.On("GetMetric", ctx, metricType, metricName).Return(tt.storageResult, tt.storageError)
-			backupStorageMock.On("GetMetric", ctx, metricType, metricName).Return(tt.storageResult, tt.storageError)
+			inMemoryStorageMock.On("GetMetric", ctx, metricType, metricName, labels).Return(tt.storageResult, tt.storageError)
+			backupStorageMock.On("GetMetric", ctx, metricType, metricName, labels).Return(tt.storageResult, tt.storageError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
-			actualResult, actualError := strategy.GetMetric(ctx, metricType, metricName)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
+			actualResult, actualError := strategy.GetMetric(ctx, metricType, metricName, labels)
 
 			assert.Equal(t, tt.expectedResult, actualResult)
 			assert.Equal(t, tt.expectedError, actualError)
 
-			inMemoryStorageMock.AssertCalled(t, "GetMetric", ctx, metricType, metricName)
-			backupStorageMock.AssertNotCalled(t, "GetMetric", mock.Anything, mock.Anything, mock.Anything)
+			inMemoryStorageMock.AssertCalled(t, "GetMetric", ctx, metricType, metricName, labels)
+			backupStorageMock.AssertNotCalled(t, "GetMetric", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 		})
 	}
 }
@@ -349,7 +358,7 @@ func TestStorageStrategy_Restore(t *testing.T) {
 			inMemoryStorageMock.On("Restore", ctx, values).Return(tt.storageError)
 			backupStorageMock.On("Restore", ctx, values).Return(tt.storageError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualError := strategy.Restore(ctx, values)
 
 			assert.Equal(t, tt.expectedError, actualError)
@@ -421,7 +430,7 @@ func TestStorageStrategy_CreateBackup(t *testing.T) {
 			inMemoryStorageMock.On("GetMetricValues", ctx).Return(tt.currentStateValues, tt.currentStateError)
 			backupStorageMock.On("Restore", ctx, tt.currentStateValues).Return(tt.restoreError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualError := strategy.CreateBackup(ctx)
 
 			assert.ErrorIs(t, actualError, tt.expectedError)
@@ -498,7 +507,7 @@ func TestStorageStrategy_RestoreFromBackup(t *testing.T) {
 			backupStorageMock.On("GetMetricValues", ctx).Return(tt.currentStateValues, tt.currentStateError)
 			inMemoryStorageMock.On("Restore", ctx, tt.currentStateValues).Return(tt.restoreError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualError := strategy.RestoreFromBackup(ctx)
 
 			assert.ErrorIs(t, actualError, tt.expectedError)
@@ -575,7 +584,7 @@ func TestStorageStrategy_Close(t *testing.T) {
 			inMemoryStorageMock.On("GetMetricValues", ctx).Return(tt.currentStateValues, tt.currentStateError)
 			backupStorageMock.On("Restore", ctx, tt.currentStateValues).Return(tt.restoreError)
 
-			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock)
+			strategy := NewStorageStrategy(confMock, inMemoryStorageMock, backupStorageMock, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
 			actualError := strategy.Close()
 
 			assert.ErrorIs(t, actualError, tt.expectedError)
@@ -591,13 +600,232 @@ func TestStorageStrategy_Close(t *testing.T) {
 	}
 }
 
+func TestStorageStrategy_History(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	confMock := &configMock{historyRetention: time.Hour}
+	confMock.On("SyncMode").Return(false)
+
+	backupStorage := memory.NewInMemoryStorage()
+	strategy := NewStorageStrategy(confMock, memory.NewInMemoryStorage(), backupStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
+	strategy.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		now = start.Add(time.Duration(i) * 30 * time.Minute)
+		_, err := strategy.AddMetricValues(ctx, []metrics.Metric{test.CreateCounterMetric(metricName, metricValue)})
+		require.NoError(t, err)
+	}
+
+	expected := []metrics.Sample{
+		{Timestamp: start, Value: 100},
+		{Timestamp: start.Add(30 * time.Minute), Value: 200},
+		{Timestamp: start.Add(time.Hour), Value: 300},
+	}
+	actual, err := strategy.GetSamples(ctx, "counter", metricName, nil, start, now)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	require.NoError(t, strategy.CreateBackup(ctx))
+	backupSamples, err := backupStorage.GetSamples(ctx, "counter", metricName, nil, start, now)
+	require.NoError(t, err)
+	assert.Equal(t, expected, backupSamples)
+
+	now = start.Add(90 * time.Minute)
+	require.NoError(t, strategy.RemoveExpiredSamples(ctx))
+
+	actual, err = strategy.GetSamples(ctx, "counter", metricName, nil, start, now)
+	require.NoError(t, err)
+	assert.Equal(t, expected[1:], actual)
+
+	backupSamples, err = backupStorage.GetSamples(ctx, "counter", metricName, nil, start, now)
+	require.NoError(t, err)
+	assert.Equal(t, expected[1:], backupSamples)
+
+	restored := NewStorageStrategy(confMock, memory.NewInMemoryStorage(), backupStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
+	require.NoError(t, restored.RestoreFromBackup(ctx))
+
+	actual, err = restored.GetSamples(ctx, "counter", metricName, nil, start, now)
+	require.NoError(t, err)
+	assert.Equal(t, expected[1:], actual)
+}
+
+func TestStorageStrategy_HistoryDisabled(t *testing.T) {
+	ctx := context.Background()
+	confMock := new(configMock)
+	confMock.On("SyncMode").Return(false)
+
+	strategy := NewStorageStrategy(confMock, memory.NewInMemoryStorage(), memory.NewInMemoryStorage(), telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
+	_, err := strategy.AddMetricValues(ctx, []metrics.Metric{test.CreateCounterMetric(metricName, metricValue)})
+	require.NoError(t, err)
+
+	_, err = strategy.GetSamples(ctx, "counter", metricName, nil, time.Time{}, time.Now())
+	assert.ErrorIs(t, err, metrics.ErrHistoryNotSupported)
+}
+
+func TestStorageStrategy_WithoutBackup(t *testing.T) {
+	ctx := context.Background()
+	confMock := &configMock{historyRetention: time.Hour}
+	confMock.On("SyncMode").Return(true)
+
+	primaryStorage := memory.NewInMemoryStorage()
+	strategy := NewStorageStrategy(confMock, primaryStorage, nil, telemetry.NewRecorder(memory.NewInMemoryStorage()), agents.NewRegistry())
+	_, err := strategy.AddMetricValues(ctx, []metrics.Metric{test.CreateCounterMetric(metricName, metricValue)})
+	require.NoError(t, err)
+
+	require.NoError(t, strategy.RestoreFromBackup(ctx))
+	require.NoError(t, strategy.RemoveExpiredSamples(ctx))
+	require.NoError(t, strategy.Close())
+
+	actual, err := strategy.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"counter": {metricName: "100"}}, actual)
+}
+
+func TestStorageStrategy_RemoveMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		syncMode bool
+	}{
+		{
+			name:     "sync_mode",
+			syncMode: true,
+		},
+		{
+			name:     "async_mode",
+			syncMode: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			confMock := new(configMock)
+			confMock.On("SyncMode").Return(tt.syncMode)
+
+			backupStorage := memory.NewInMemoryStorage()
+			registry := agents.NewRegistry()
+			strategy := NewStorageStrategy(confMock, memory.NewInMemoryStorage(), backupStorage, telemetry.NewRecorder(memory.NewInMemoryStorage()), registry)
+			metricsList := []metrics.Metric{
+				test.CreateCounterMetric("requests", 10),
+				test.CreateGaugeMetric("cpu_usage", 1),
+			}
+			_, err := strategy.AddMetricValues(ctx, metricsList)
+			require.NoError(t, err)
+			registry.Track(agents.Source{AgentID: "agent"}, metricsList)
+			require.NoError(t, strategy.CreateBackup(ctx))
+
+			removed, err := strategy.RemoveMetrics(ctx, &metrics.SeriesFilter{Name: "cpu_usage"})
+			require.NoError(t, err)
+			assert.Equal(t, 1, removed)
+
+			_, ok := registry.GetSeries("gauge", "cpu_usage")
+			assert.False(t, ok)
+			_, ok = registry.GetSeries("counter", "requests")
+			assert.True(t, ok)
+
+			reset, err := strategy.ResetCounters(ctx, &metrics.SeriesFilter{Name: "requests"})
+			require.NoError(t, err)
+			assert.Equal(t, 1, reset)
+
+			expected := map[string]map[string]string{"counter": {"requests": "0"}}
+			actual, err := strategy.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+
+			if !tt.syncMode {
+				// backup storage follows with the next backup
+				require.NoError(t, strategy.CreateBackup(ctx))
+			}
+
+			backup, err := backupStorage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expected, backup)
+		})
+	}
+}
+
+func TestStorageStrategy_RemoveStaleMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		syncMode bool
+	}{
+		{
+			name:     "sync_mode",
+			syncMode: true,
+		},
+		{
+			name:     "async_mode",
+			syncMode: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			confMock := &configMock{seriesTTL: map[string]time.Duration{"gauge": time.Hour}}
+			confMock.On("SyncMode").Return(tt.syncMode)
+
+			backupStorage := memory.NewInMemoryStorage()
+			selfStorage := memory.NewInMemoryStorage()
+			registry := agents.NewRegistry()
+			strategy := NewStorageStrategy(confMock, memory.NewInMemoryStorage(), backupStorage, telemetry.NewRecorder(selfStorage), registry)
+			metricsList := []metrics.Metric{
+				test.CreateCounterMetric("requests", 10),
+				test.CreateGaugeMetric("cpu_usage", 1),
+				types.NewGaugeMetric("cpu_usage", metrics.Label{Name: "cpu", Value: "1"}),
+			}
+			_, err := strategy.AddMetricValues(ctx, metricsList)
+			require.NoError(t, err)
+			registry.Track(agents.Source{AgentID: "agent"}, metricsList)
+			require.NoError(t, strategy.CreateBackup(ctx))
+
+			strategy.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+			require.NoError(t, strategy.RemoveStaleMetrics(ctx))
+
+			// evicted series are not reported by the registry
+			_, ok := registry.GetSeries("gauge", `cpu_usage{cpu="1"}`)
+			assert.False(t, ok)
+			require.Len(t, registry.GetAgents(), 1)
+			assert.Equal(t, 1, registry.GetAgents()[0].SeriesCount)
+
+			expected := map[string]map[string]string{"counter": {"requests": "10"}}
+			actual, err := strategy.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+
+			evicted, err := selfStorage.GetMetric(ctx, "counter", "metrics_server_evicted_series_total", nil)
+			require.NoError(t, err)
+			assert.Equal(t, float64(2), evicted.GetValue())
+
+			if !tt.syncMode {
+				// backup storage follows with the next backup
+				require.NoError(t, strategy.CreateBackup(ctx))
+			}
+
+			backup, err := backupStorage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, expected, backup)
+		})
+	}
+}
+
 func (c *configMock) SyncMode() bool {
 	args := c.Called()
 	return args.Bool(0)
 }
 
-func (s *metricStorageMock) GetMetric(ctx context.Context, metricType string, metricName string) (metrics.Metric, error) {
-	args := s.Called(ctx, metricType, metricName)
+func (c *configMock) HistoryRetention() time.Duration {
+	return c.historyRetention
+}
+
+func (c *configMock) SeriesTTL(metricType string) time.Duration {
+	return c.seriesTTL[metricType]
+}
+
+func (s *metricStorageMock) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	args := s.Called(ctx, metricType, metricName, labels)
 	result := args.Get(0)
 	if result == nil {
 		return nil, args.Error(1)
@@ -629,3 +857,13 @@ func (s *metricStorageMock) Restore(ctx context.Context, metricValues map[string
 	args := s.Called(ctx, metricValues)
 	return args.Error(0)
 }
+
+func (s *metricStorageMock) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	args := s.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
+func (s *metricStorageMock) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	args := s.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
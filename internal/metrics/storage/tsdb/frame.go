@@ -0,0 +1,63 @@
+package tsdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	frameHeaderSize = 8
+	maxFrameSize    = 256 << 20
+)
+
+var (
+	errCorruptedFrame = errors.New("corrupted frame")
+	crcTable          = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// writeFrame writes payload prefixed with its length and crc32 checksum.
+func writeFrame(writer io.Writer, payload []byte) (int, error) {
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[frameHeaderSize:], payload)
+
+	return writer.Write(frame)
+}
+
+// readFrame reads single frame payload.
+// io.EOF is returned only when the reader is exhausted on the frame boundary,
+// any partially written or damaged frame produce errCorruptedFrame.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	header := make([]byte, frameHeaderSize)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errCorruptedFrame
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxFrameSize {
+		return nil, errCorruptedFrame
+	}
+
+	payload := make([]byte, size)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errCorruptedFrame
+		}
+		return nil, err
+	}
+
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errCorruptedFrame
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,663 @@
+package tsdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/parser"
+)
+
+const (
+	dirMode  os.FileMode = 0o755
+	fileMode os.FileMode = 0o644
+
+	segmentPrefix = "segment-"
+	walPrefix     = "wal-"
+	tmpSuffix     = ".tmp"
+
+	defaultCompactionSize int64 = 16 << 20
+
+	entryValues        = "values"
+	entrySamples       = "samples"
+	entryRemoveSamples = "remove_samples"
+	entryRemoveSeries  = "remove_series"
+)
+
+// segment is a compacted snapshot of the whole storage state.
+type segment struct {
+	Values  map[string]map[string]string           `json:"values"`
+	History map[string]map[string][]metrics.Sample `json:"history"`
+}
+
+// walEntry is a single write-ahead log operation.
+type walEntry struct {
+	Kind      string      `json:"kind"`
+	Timestamp time.Time   `json:"timestamp"`
+	Values    []*walValue `json:"values,omitempty"`
+}
+
+type walValue struct {
+	Type   string  `json:"type"`
+	Series string  `json:"series"`
+	Value  string  `json:"value,omitempty"`
+	Sample float64 `json:"sample,omitempty"`
+}
+
+type tsdbStorageConfig interface {
+	TSDBPath() string
+}
+
+// tsdbStorage keeps metric values and samples in the append-only write-ahead log.
+// The log is compacted into the new segment once it grows beyond the compaction size,
+// so restart cost is bounded by the segment size plus the log tail.
+type tsdbStorage struct {
+	dir            string
+	sequence       uint64
+	wal            *os.File
+	walSize        int64
+	compactionSize int64
+	values         map[string]map[string]string
+	history        map[string]map[string][]metrics.Sample
+	lock           sync.RWMutex
+}
+
+// NewTSDBStorage opens or creates storage in configured directory.
+// Damaged log tail is truncated to the last complete entry.
+func NewTSDBStorage(config tsdbStorageConfig) (*tsdbStorage, error) {
+	result := &tsdbStorage{
+		dir:            config.TSDBPath(),
+		compactionSize: defaultCompactionSize,
+		values:         map[string]map[string]string{},
+		history:        map[string]map[string][]metrics.Sample{},
+	}
+
+	err := result.open()
+	if err != nil {
+		return nil, logger.WrapError("open tsdb storage", err)
+	}
+
+	return result, nil
+}
+
+func (s *tsdbStorage) AddMetricValues(ctx context.Context, metricsList []metrics.Metric) ([]metrics.Metric, error) {
+	entry := &walEntry{Kind: entryValues, Values: make([]*walValue, len(metricsList))}
+	for i, metric := range metricsList {
+		entry.Values[i] = &walValue{
+			Type:   metric.GetType(),
+			Series: metrics.SeriesKey(metric.GetName(), metric.GetLabels()),
+			Value:  metric.GetStringValue(),
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.append(entry)
+	if err != nil {
+		return nil, logger.WrapError("append values", err)
+	}
+
+	return metricsList, nil
+}
+
+func (s *tsdbStorage) GetMetricValues(context.Context) (map[string]map[string]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := map[string]map[string]string{}
+	for metricType, typedValues := range s.values {
+		values := make(map[string]string, len(typedValues))
+		for seriesKey, value := range typedValues {
+			values[seriesKey] = value
+		}
+		result[metricType] = values
+	}
+
+	return result, nil
+}
+
+func (s *tsdbStorage) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	seriesKey := metrics.SeriesKey(metricName, labels)
+
+	s.lock.RLock()
+	value, ok := s.values[metricType][seriesKey]
+	s.lock.RUnlock()
+
+	if !ok {
+		return nil, logger.WrapError(fmt.Sprintf("get metric with name '%s' and type '%s'", seriesKey, metricType), metrics.ErrMetricNotFound)
+	}
+
+	return toMetric(metricType, metricName, labels, value)
+}
+
+func (s *tsdbStorage) Restore(ctx context.Context, metricValues map[string]map[string]string) error {
+	values := map[string]map[string]string{}
+	for metricType, typedValues := range metricValues {
+		restored := make(map[string]string, len(typedValues))
+		for seriesKey, value := range typedValues {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			restored[metrics.SeriesKey(metricName, labels)] = value
+		}
+		values[metricType] = restored
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.compact(&segment{Values: values, History: s.history})
+}
+
+func (s *tsdbStorage) RemoveMetrics(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := &walEntry{Kind: entryRemoveSeries}
+	for metricType, typedValues := range s.values {
+		for seriesKey := range typedValues {
+			if filter.MatchKey(metricType, seriesKey) {
+				entry.Values = append(entry.Values, &walValue{Type: metricType, Series: seriesKey})
+			}
+		}
+	}
+
+	removed := len(entry.Values)
+	for metricType, typedSamples := range s.history {
+		for seriesKey := range typedSamples {
+			_, hasValue := s.values[metricType][seriesKey]
+			if !hasValue && filter.MatchKey(metricType, seriesKey) {
+				entry.Values = append(entry.Values, &walValue{Type: metricType, Series: seriesKey})
+			}
+		}
+	}
+
+	if len(entry.Values) == 0 {
+		return 0, nil
+	}
+
+	err := s.append(entry)
+	if err != nil {
+		return 0, logger.WrapError("append series removal", err)
+	}
+
+	return removed, nil
+}
+
+func (s *tsdbStorage) ResetCounters(ctx context.Context, filter *metrics.SeriesFilter) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry := &walEntry{Kind: entryValues}
+	for seriesKey := range s.values["counter"] {
+		if filter.MatchKey("counter", seriesKey) {
+			entry.Values = append(entry.Values, &walValue{Type: "counter", Series: seriesKey, Value: "0"})
+		}
+	}
+
+	if len(entry.Values) == 0 {
+		return 0, nil
+	}
+
+	err := s.append(entry)
+	if err != nil {
+		return 0, logger.WrapError("append counters reset", err)
+	}
+
+	return len(entry.Values), nil
+}
+
+func (s *tsdbStorage) AddSamples(ctx context.Context, timestamp time.Time, metricsList []metrics.Metric) error {
+	entry := &walEntry{Kind: entrySamples, Timestamp: timestamp, Values: make([]*walValue, len(metricsList))}
+	for i, metric := range metricsList {
+		entry.Values[i] = &walValue{
+			Type:   metric.GetType(),
+			Series: metrics.SeriesKey(metric.GetName(), metric.GetLabels()),
+			Sample: metric.GetValue(),
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.append(entry)
+	if err != nil {
+		return logger.WrapError("append samples", err)
+	}
+
+	return nil
+}
+
+func (s *tsdbStorage) GetSamples(
+	ctx context.Context,
+	metricType string,
+	metricName string,
+	labels metrics.Labels,
+	start time.Time,
+	end time.Time,
+) ([]metrics.Sample, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return metrics.FilterSamples(s.history[metricType][metrics.SeriesKey(metricName, labels)], start, end), nil
+}
+
+func (s *tsdbStorage) GetHistory(context.Context) (map[string]map[string][]metrics.Sample, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return copyHistory(s.history), nil
+}
+
+func (s *tsdbStorage) RestoreHistory(ctx context.Context, history map[string]map[string][]metrics.Sample) error {
+	restored := map[string]map[string][]metrics.Sample{}
+	for metricType, typedSamples := range history {
+		samplesBySeries := make(map[string][]metrics.Sample, len(typedSamples))
+		for seriesKey, samples := range typedSamples {
+			metricName, labels, err := metrics.ParseSeriesKey(seriesKey)
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("parse series key '%s'", seriesKey), err)
+			}
+
+			sorted := make([]metrics.Sample, len(samples))
+			copy(sorted, samples)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+			samplesBySeries[metrics.SeriesKey(metricName, labels)] = sorted
+		}
+		restored[metricType] = samplesBySeries
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.compact(&segment{Values: s.values, History: restored})
+}
+
+func (s *tsdbStorage) RemoveSamples(ctx context.Context, before time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.append(&walEntry{Kind: entryRemoveSamples, Timestamp: before})
+	if err != nil {
+		return logger.WrapError("append samples removal", err)
+	}
+
+	return nil
+}
+
+func (s *tsdbStorage) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.wal == nil {
+		return nil
+	}
+
+	err := s.wal.Close()
+	s.wal = nil
+	if err != nil {
+		return logger.WrapError("close wal file", err)
+	}
+
+	return nil
+}
+
+func (s *tsdbStorage) open() error {
+	err := os.MkdirAll(s.dir, dirMode)
+	if err != nil {
+		return logger.WrapError("create storage directory", err)
+	}
+
+	segments, err := s.listFiles(segmentPrefix)
+	if err != nil {
+		return logger.WrapError("list segments", err)
+	}
+
+	if len(segments) > 0 {
+		s.sequence = segments[len(segments)-1]
+		err = s.readSegment(s.segmentPath(s.sequence))
+		if err != nil {
+			return logger.WrapError("read segment", err)
+		}
+	}
+
+	err = s.replay()
+	if err != nil {
+		return logger.WrapError("replay wal", err)
+	}
+
+	return s.removeStaleFiles()
+}
+
+func (s *tsdbStorage) readSegment(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return logger.WrapError("open segment file", err)
+	}
+	defer file.Close()
+
+	payload, err := readFrame(bufio.NewReader(file))
+	if err != nil {
+		return logger.WrapError(fmt.Sprintf("read segment '%s'", path), err)
+	}
+
+	state := &segment{}
+	err = json.Unmarshal(payload, state)
+	if err != nil {
+		return logger.WrapError("decode segment", err)
+	}
+
+	if state.Values != nil {
+		s.values = state.Values
+	}
+	if state.History != nil {
+		s.history = state.History
+	}
+
+	return nil
+}
+
+func (s *tsdbStorage) replay() error {
+	path := s.walPath(s.sequence)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, fileMode)
+	if err != nil {
+		return logger.WrapError("open wal file", err)
+	}
+
+	var offset int64
+	reader := bufio.NewReader(file)
+	for {
+		payload, err := readFrame(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err == nil {
+			entry := &walEntry{}
+			err = json.Unmarshal(payload, entry)
+			if err == nil {
+				s.apply(entry)
+				offset += int64(frameHeaderSize + len(payload))
+				continue
+			}
+		}
+
+		if !errors.Is(err, errCorruptedFrame) && !isJSONError(err) {
+			file.Close()
+			return logger.WrapError("read wal entry", err)
+		}
+
+		logger.ErrorFormat("wal '%s' is corrupted at offset %d, truncate: %v", path, offset, err)
+		err = file.Truncate(offset)
+		if err != nil {
+			file.Close()
+			return logger.WrapError("truncate wal file", err)
+		}
+		break
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return logger.WrapError("seek wal file", err)
+	}
+
+	s.wal = file
+	s.walSize = offset
+	return nil
+}
+
+func (s *tsdbStorage) append(entry *walEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return logger.WrapError("encode wal entry", err)
+	}
+
+	written, err := writeFrame(s.wal, payload)
+	if err != nil {
+		return logger.WrapError("write wal entry", err)
+	}
+
+	err = s.wal.Sync()
+	if err != nil {
+		return logger.WrapError("sync wal file", err)
+	}
+
+	s.walSize += int64(written)
+	s.apply(entry)
+
+	if s.walSize < s.compactionSize {
+		return nil
+	}
+
+	// entry is already durable, failed compaction will be retried with the next one
+	err = s.compact(&segment{Values: s.values, History: s.history})
+	if err != nil {
+		logger.ErrorFormat("failed to compact storage: %v", err)
+	}
+
+	return nil
+}
+
+func (s *tsdbStorage) apply(entry *walEntry) {
+	switch entry.Kind {
+	case entryValues:
+		for _, value := range entry.Values {
+			typedValues, ok := s.values[value.Type]
+			if !ok {
+				typedValues = map[string]string{}
+				s.values[value.Type] = typedValues
+			}
+
+			typedValues[value.Series] = value.Value
+		}
+	case entrySamples:
+		for _, value := range entry.Values {
+			typedSamples, ok := s.history[value.Type]
+			if !ok {
+				typedSamples = map[string][]metrics.Sample{}
+				s.history[value.Type] = typedSamples
+			}
+
+			samples := typedSamples[value.Series]
+			last := len(samples) - 1
+			if last >= 0 && !samples[last].Timestamp.Before(entry.Timestamp) {
+				samples[last].Value = value.Sample
+				continue
+			}
+
+			typedSamples[value.Series] = append(samples, metrics.Sample{Timestamp: entry.Timestamp, Value: value.Sample})
+		}
+	case entryRemoveSamples:
+		for metricType, typedSamples := range s.history {
+			for seriesKey, samples := range typedSamples {
+				expired := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Before(entry.Timestamp) })
+				if expired == len(samples) {
+					delete(typedSamples, seriesKey)
+				} else {
+					typedSamples[seriesKey] = samples[expired:]
+				}
+			}
+
+			if len(typedSamples) == 0 {
+				delete(s.history, metricType)
+			}
+		}
+	case entryRemoveSeries:
+		for _, value := range entry.Values {
+			delete(s.values[value.Type], value.Series)
+			if len(s.values[value.Type]) == 0 {
+				delete(s.values, value.Type)
+			}
+
+			delete(s.history[value.Type], value.Series)
+			if len(s.history[value.Type]) == 0 {
+				delete(s.history, value.Type)
+			}
+		}
+	default:
+		logger.ErrorFormat("unknown wal entry kind: %s", entry.Kind)
+	}
+}
+
+// compact writes state to the segment with the next sequence number,
+// switches the log to the empty one and removes previous files.
+// The new segment is renamed into place only after it was fully written,
+// so a crash at any step leaves the previous consistent state on disk.
+func (s *tsdbStorage) compact(state *segment) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return logger.WrapError("encode segment", err)
+	}
+
+	sequence := s.sequence + 1
+	segmentPath := s.segmentPath(sequence)
+	err = storage.WriteFileAtomically(segmentPath, fileMode, func(writer io.Writer) error {
+		_, err := writeFrame(writer, payload)
+		return err
+	})
+	if err != nil {
+		return logger.WrapError("write segment", err)
+	}
+
+	wal, err := os.OpenFile(s.walPath(sequence), os.O_CREATE|os.O_TRUNC|os.O_RDWR, fileMode)
+	if err != nil {
+		return logger.WrapError("create wal file", err)
+	}
+
+	if s.wal != nil {
+		err = s.wal.Close()
+		if err != nil {
+			logger.ErrorFormat("failed to close wal file: %v", err)
+		}
+	}
+
+	s.wal = wal
+	s.walSize = 0
+	s.sequence = sequence
+	s.values = state.Values
+	s.history = state.History
+
+	return s.removeStaleFiles()
+}
+
+func (s *tsdbStorage) removeStaleFiles() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return logger.WrapError("read storage directory", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		sequence, ok := parseSequence(name, segmentPrefix)
+		if !ok {
+			sequence, ok = parseSequence(name, walPrefix)
+		}
+
+		if (ok && sequence < s.sequence) || strings.HasSuffix(name, tmpSuffix) {
+			err = os.Remove(filepath.Join(s.dir, name))
+			if err != nil {
+				return logger.WrapError(fmt.Sprintf("remove stale file '%s'", name), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *tsdbStorage) listFiles(prefix string) ([]uint64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, logger.WrapError("read storage directory", err)
+	}
+
+	var result []uint64
+	for _, entry := range entries {
+		sequence, ok := parseSequence(entry.Name(), prefix)
+		if ok {
+			result = append(result, sequence)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result, nil
+}
+
+func (s *tsdbStorage) segmentPath(sequence uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%08d", segmentPrefix, sequence))
+}
+
+func (s *tsdbStorage) walPath(sequence uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%08d", walPrefix, sequence))
+}
+
+func parseSequence(name string, prefix string) (uint64, bool) {
+	if !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, tmpSuffix) {
+		return 0, false
+	}
+
+	var sequence uint64
+	_, err := fmt.Sscanf(strings.TrimPrefix(name, prefix), "%d", &sequence)
+	return sequence, err == nil
+}
+
+func isJSONError(err error) bool {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	return errors.As(err, &syntaxError) || errors.As(err, &typeError)
+}
+
+func copyHistory(history map[string]map[string][]metrics.Sample) map[string]map[string][]metrics.Sample {
+	result := make(map[string]map[string][]metrics.Sample, len(history))
+	for metricType, typedSamples := range history {
+		samplesBySeries := make(map[string][]metrics.Sample, len(typedSamples))
+		for seriesKey, samples := range typedSamples {
+			samplesCopy := make([]metrics.Sample, len(samples))
+			copy(samplesCopy, samples)
+			samplesBySeries[seriesKey] = samplesCopy
+		}
+		result[metricType] = samplesBySeries
+	}
+
+	return result
+}
+
+func toMetric(metricType string, metricName string, labels metrics.Labels, value string) (metrics.Metric, error) {
+	var metric metrics.Metric
+	switch metricType {
+	case "counter":
+		metric = types.NewCounterMetric(metricName, labels...)
+	case "gauge":
+		metric = types.NewGaugeMetric(metricName, labels...)
+	case "histogram":
+		return types.ParseHistogramMetric(metricName, value, labels...)
+	case "summary":
+		return types.ParseSummaryMetric(metricName, value, labels...)
+	default:
+		return nil, logger.WrapError(fmt.Sprintf("convert to metric with type %s", metricType), metrics.ErrUnknownMetricType)
+	}
+
+	floatValue, err := parser.ToFloat64(value)
+	if err != nil {
+		return nil, logger.WrapError("parse metric value", err)
+	}
+
+	metric.SetValue(floatValue)
+	return metric, nil
+}
@@ -0,0 +1,298 @@
+package tsdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/test"
+)
+
+type config struct {
+	path string
+}
+
+func TestTSDBStorage_Reopen(t *testing.T) {
+	ctx := context.Background()
+	conf := &config{path: t.TempDir()}
+	now := time.Unix(1000, 0).UTC()
+
+	storage, err := NewTSDBStorage(conf)
+	require.NoError(t, err)
+
+	labeled := types.NewGaugeMetric("cpu", metrics.Label{Name: "core", Value: "0"})
+	labeled.SetValue(0.5)
+	_, err = storage.AddMetricValues(ctx, []metrics.Metric{
+		test.CreateCounterMetric("counter", 1),
+		test.CreateGaugeMetric("gauge", 1),
+		labeled,
+	})
+	require.NoError(t, err)
+
+	_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateGaugeMetric("gauge", 2)})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.AddSamples(ctx, now, []metrics.Metric{test.CreateGaugeMetric("gauge", 1)}))
+	require.NoError(t, storage.AddSamples(ctx, now.Add(time.Second), []metrics.Metric{test.CreateGaugeMetric("gauge", 2)}))
+	require.NoError(t, storage.AddSamples(ctx, now.Add(2*time.Second), []metrics.Metric{test.CreateGaugeMetric("gauge", 3)}))
+	require.NoError(t, storage.RemoveSamples(ctx, now.Add(time.Second)))
+	require.NoError(t, storage.Close())
+
+	storage, err = NewTSDBStorage(conf)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	values, err := storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {"counter": "1"},
+		"gauge":   {"gauge": "2", `cpu{core="0"}`: "0.5"},
+	}, values)
+
+	metric, err := storage.GetMetric(ctx, "gauge", "cpu", metrics.NewLabels(metrics.Label{Name: "core", Value: "0"}))
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, metric.GetValue())
+
+	_, err = storage.GetMetric(ctx, "gauge", "cpu", nil)
+	assert.ErrorIs(t, err, metrics.ErrMetricNotFound)
+
+	samples, err := storage.GetSamples(ctx, "gauge", "gauge", nil, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []float64{2, 3}, sampleValues(samples))
+	assert.True(t, now.Add(time.Second).Equal(samples[0].Timestamp))
+}
+
+func TestTSDBStorage_RemoveMetrics(t *testing.T) {
+	ctx := context.Background()
+	conf := &config{path: t.TempDir()}
+	now := time.Unix(1000, 0).UTC()
+
+	storage, err := NewTSDBStorage(conf)
+	require.NoError(t, err)
+
+	metricsList := []metrics.Metric{
+		test.CreateCounterMetric("requests", 10),
+		test.CreateCounterMetric("errors", 5),
+		test.CreateGaugeMetric("cpu_usage", 1),
+		test.CreateGaugeMetric("memory_usage", 1),
+	}
+	_, err = storage.AddMetricValues(ctx, metricsList)
+	require.NoError(t, err)
+	require.NoError(t, storage.AddSamples(ctx, now, metricsList))
+
+	removed, err := storage.RemoveMetrics(ctx, &metrics.SeriesFilter{Type: "gauge", NamePattern: regexp.MustCompile("^cpu")})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	reset, err := storage.ResetCounters(ctx, &metrics.SeriesFilter{NamePrefix: "req"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, reset)
+	require.NoError(t, storage.Close())
+
+	// removal and reset are replayed from the log
+	storage, err = NewTSDBStorage(conf)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	values, err := storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"counter": {"requests": "0", "errors": "5"},
+		"gauge":   {"memory_usage": "1"},
+	}, values)
+
+	history, err := storage.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, history["gauge"], "cpu_usage")
+	assert.Contains(t, history["gauge"], "memory_usage")
+}
+
+func TestTSDBStorage_CorruptedTail(t *testing.T) {
+	tests := []struct {
+		name         string
+		corrupt      func(t *testing.T, path string)
+		expectedTail bool
+	}{
+		{
+			name: "partial_frame",
+			corrupt: func(t *testing.T, path string) {
+				stat, err := os.Stat(path)
+				require.NoError(t, err)
+				require.NoError(t, os.Truncate(path, stat.Size()-3))
+			},
+		},
+		{
+			name: "garbage_tail",
+			corrupt: func(t *testing.T, path string) {
+				file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, fileMode)
+				require.NoError(t, err)
+				defer file.Close()
+
+				_, err = file.Write([]byte("garbage"))
+				require.NoError(t, err)
+			},
+			expectedTail: true,
+		},
+		{
+			name: "checksum_mismatch",
+			corrupt: func(t *testing.T, path string) {
+				content, err := os.ReadFile(path)
+				require.NoError(t, err)
+
+				content[len(content)-2] ^= 0xFF
+				require.NoError(t, os.WriteFile(path, content, fileMode))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			conf := &config{path: t.TempDir()}
+
+			storage, err := NewTSDBStorage(conf)
+			require.NoError(t, err)
+
+			_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateGaugeMetric("first", 1)})
+			require.NoError(t, err)
+			validSize := storage.walSize
+			expectedValues := map[string]string{"first": "1"}
+
+			_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateGaugeMetric("second", 2)})
+			require.NoError(t, err)
+			require.NoError(t, storage.Close())
+
+			if tt.expectedTail {
+				validSize = storage.walSize
+				expectedValues["second"] = "2"
+			}
+
+			walPath := filepath.Join(conf.path, "wal-00000000")
+			tt.corrupt(t, walPath)
+
+			storage, err = NewTSDBStorage(conf)
+			require.NoError(t, err)
+			defer storage.Close()
+
+			stat, err := os.Stat(walPath)
+			require.NoError(t, err)
+			assert.Equal(t, validSize, stat.Size())
+
+			values, err := storage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]map[string]string{"gauge": expectedValues}, values)
+
+			_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateGaugeMetric("third", 3)})
+			require.NoError(t, err)
+			require.NoError(t, storage.Close())
+
+			storage, err = NewTSDBStorage(conf)
+			require.NoError(t, err)
+			defer storage.Close()
+
+			expectedValues["third"] = "3"
+			values, err = storage.GetMetricValues(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]map[string]string{"gauge": expectedValues}, values)
+		})
+	}
+}
+
+func TestTSDBStorage_Compaction(t *testing.T) {
+	ctx := context.Background()
+	conf := &config{path: t.TempDir()}
+
+	storage, err := NewTSDBStorage(conf)
+	require.NoError(t, err)
+	storage.compactionSize = 256
+
+	for i := 0; i < 10; i++ {
+		_, err = storage.AddMetricValues(ctx, []metrics.Metric{test.CreateCounterMetric("counter", float64(i))})
+		require.NoError(t, err)
+	}
+
+	assert.Greater(t, storage.sequence, uint64(0))
+	assert.Less(t, storage.walSize, storage.compactionSize)
+	assert.Equal(t, []string{
+		filepath.Base(storage.segmentPath(storage.sequence)),
+		filepath.Base(storage.walPath(storage.sequence)),
+	}, listDir(t, conf.path))
+	require.NoError(t, storage.Close())
+
+	storage, err = NewTSDBStorage(conf)
+	require.NoError(t, err)
+
+	values, err := storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"counter": {"counter": "9"}}, values)
+
+	require.NoError(t, storage.Restore(ctx, map[string]map[string]string{"gauge": {"gauge": "1"}}))
+	require.NoError(t, storage.RestoreHistory(ctx, map[string]map[string][]metrics.Sample{
+		"gauge": {"gauge": {{Timestamp: time.Unix(2, 0).UTC(), Value: 2}, {Timestamp: time.Unix(1, 0).UTC(), Value: 1}}},
+	}))
+	require.NoError(t, storage.Close())
+
+	storage, err = NewTSDBStorage(conf)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	values, err = storage.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"gauge": {"gauge": "1"}}, values)
+
+	history, err := storage.GetHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1, 2}, sampleValues(history["gauge"]["gauge"]))
+}
+
+func TestTSDBStorage_CorruptedSegment(t *testing.T) {
+	ctx := context.Background()
+	conf := &config{path: t.TempDir()}
+
+	storage, err := NewTSDBStorage(conf)
+	require.NoError(t, err)
+	require.NoError(t, storage.Restore(ctx, map[string]map[string]string{"gauge": {"gauge": "1"}}))
+	segmentPath := storage.segmentPath(storage.sequence)
+	require.NoError(t, storage.Close())
+
+	content, err := os.ReadFile(segmentPath)
+	require.NoError(t, err)
+	content[len(content)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(segmentPath, content, fileMode))
+
+	_, err = NewTSDBStorage(conf)
+	assert.ErrorIs(t, err, errCorruptedFrame)
+}
+
+func listDir(t *testing.T, path string) []string {
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+
+	result := make([]string, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.Name()
+	}
+
+	return result
+}
+
+func sampleValues(samples []metrics.Sample) []float64 {
+	result := make([]float64, len(samples))
+	for i, sample := range samples {
+		result[i] = sample.Value
+	}
+
+	return result
+}
+
+func (c *config) TSDBPath() string {
+	return c.path
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/tdigest"
+)
+
+// DefaultQuantiles contains quantiles, reported for summary metrics by default.
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Summary is a state of summary metric.
+type Summary struct {
+	// Digest is a streaming quantile sketch of all observed values.
+	Digest *tdigest.State `json:"digest"`
+	// Sum is a sum of all observed values.
+	Sum float64 `json:"sum"`
+	// Count is a total observations count.
+	Count uint64 `json:"count"`
+}
+
+// SummaryMetric calculates quantiles of observed values.
+type SummaryMetric interface {
+	Metric
+	MergeableMetric
+
+	// GetObservations returns raw values, observed since the last flush.
+	GetObservations() []float64
+
+	// GetQuantile returns estimated value of the quantile in range [0, 1].
+	GetQuantile(quantile float64) float64
+
+	// GetSummary returns summary state snapshot.
+	GetSummary() *Summary
+}
+
+// ParseSummary restores summary state from string representation.
+func ParseSummary(str string) (*Summary, error) {
+	summary := &Summary{}
+	err := json.Unmarshal([]byte(str), summary)
+	if err != nil {
+		return nil, err
+	}
+
+	if summary.Digest == nil {
+		return nil, ErrInvalidSummary
+	}
+
+	return summary, nil
+}
+
+// String returns summary state string representation, the state is empty if it can not be serialized.
+func (s *Summary) String() string {
+	result, err := json.Marshal(s)
+	if err != nil {
+		// the failure is logged by the wrapper, otherwise the empty state is silently stored
+		_ = logger.WrapError("serialize summary state", err)
+		return ""
+	}
+
+	return string(result)
+}
+
+// ValidateObservations checks that observations are finite, the quantile sketch and json state do not accept others.
+func ValidateObservations(observations []float64) error {
+	for _, observation := range observations {
+		if math.IsNaN(observation) || math.IsInf(observation, 0) {
+			return ErrInvalidObservation
+		}
+	}
+
+	return nil
+}
+
+// ValidateQuantile checks that quantile is in range [0, 1].
+func ValidateQuantile(quantile float64) error {
+	if !(quantile >= 0 && quantile <= 1) {
+		return ErrInvalidQuantile
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/types"
+)
+
+// Prefix is reserved for the server own metrics, clients can not update metrics with the prefix.
+const Prefix = "metrics_server_"
+
+// Server own metric names.
+const (
+	HTTPRequests             = Prefix + "http_requests_total"
+	HTTPRequestDuration      = Prefix + "http_request_duration_seconds"
+	GrpcRequests             = Prefix + "grpc_requests_total"
+	GrpcRequestDuration      = Prefix + "grpc_request_duration_seconds"
+	DecryptFailures          = Prefix + "decrypt_failures_total"
+	SignatureFailures        = Prefix + "signature_failures_total"
+	RejectedRequests         = Prefix + "rejected_requests_total"
+	StorageOperationDuration = Prefix + "storage_operation_duration_seconds"
+	BackupDuration           = Prefix + "backup_duration_seconds"
+	EvictedSeries            = Prefix + "evicted_series_total"
+	StoredSeries             = Prefix + "stored_series"
+)
+
+// DurationBounds are histogram bucket upper bounds of recorded durations in seconds.
+var DurationBounds = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type metricsStorage interface {
+	AddMetricValues(ctx context.Context, metric []metrics.Metric) ([]metrics.Metric, error)
+	GetMetricValues(ctx context.Context) (map[string]map[string]string, error)
+	GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error)
+}
+
+// Recorder writes the server own metrics to the dedicated storage and reads them back.
+// Recording never fails the caller, storage errors are logged.
+type Recorder struct {
+	storage metricsStorage
+}
+
+// NewRecorder creates new instance of Recorder.
+func NewRecorder(storage metricsStorage) *Recorder {
+	return &Recorder{
+		storage: storage,
+	}
+}
+
+// IsReserved checks that metric name has the reserved prefix.
+func IsReserved(metricName string) bool {
+	return strings.HasPrefix(metricName, Prefix)
+}
+
+// Count increments the counter.
+func (r *Recorder) Count(ctx context.Context, name string, labels ...metrics.Label) {
+	r.Add(ctx, name, 1, labels...)
+}
+
+// Add increments the counter by delta.
+func (r *Recorder) Add(ctx context.Context, name string, delta float64, labels ...metrics.Label) {
+	metric := types.NewCounterMetric(name, labels...)
+	metric.SetValue(delta)
+	r.record(ctx, metric)
+}
+
+// Set updates the gauge value.
+func (r *Recorder) Set(ctx context.Context, name string, value float64, labels ...metrics.Label) {
+	metric := types.NewGaugeMetric(name, labels...)
+	metric.SetValue(value)
+	r.record(ctx, metric)
+}
+
+// ObserveDuration records the time elapsed since start to the histogram in seconds.
+func (r *Recorder) ObserveDuration(ctx context.Context, name string, start time.Time, labels ...metrics.Label) {
+	metric := types.NewHistogramMetric(name, DurationBounds, labels...)
+	metric.SetValue(time.Since(start).Seconds())
+	r.record(ctx, metric)
+}
+
+// GetMetricValues returns recorded metric values by type and series key.
+func (r *Recorder) GetMetricValues(ctx context.Context) (map[string]map[string]string, error) {
+	return r.storage.GetMetricValues(ctx)
+}
+
+// GetMetric returns single recorded metric.
+func (r *Recorder) GetMetric(ctx context.Context, metricType string, metricName string, labels metrics.Labels) (metrics.Metric, error) {
+	return r.storage.GetMetric(ctx, metricType, metricName, labels)
+}
+
+func (r *Recorder) record(ctx context.Context, metric metrics.Metric) {
+	_, err := r.storage.AddMetricValues(ctx, []metrics.Metric{metric})
+	if err != nil {
+		logger.ErrorFormat("failed to record server metric '%s': %v", metric.GetName(), err)
+	}
+}
@@ -0,0 +1,43 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics/storage/memory"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	recorder := NewRecorder(memory.NewInMemoryStorage())
+	label := metrics.Label{Name: "transport", Value: "http"}
+
+	recorder.Count(ctx, DecryptFailures, label)
+	recorder.Add(ctx, DecryptFailures, 2, label)
+	recorder.Set(ctx, StoredSeries, 10)
+	recorder.Set(ctx, StoredSeries, 5)
+	recorder.ObserveDuration(ctx, BackupDuration, time.Now().Add(-time.Second))
+	recorder.ObserveDuration(ctx, BackupDuration, time.Now())
+
+	values, err := recorder.GetMetricValues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "3", values["counter"][DecryptFailures+`{transport="http"}`])
+	assert.Equal(t, "5", values["gauge"][StoredSeries])
+
+	backup, err := recorder.GetMetric(ctx, "histogram", BackupDuration, nil)
+	require.NoError(t, err)
+	histogram, ok := backup.(metrics.HistogramMetric)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), histogram.GetHistogram().Count)
+	assert.Equal(t, DurationBounds, histogram.GetHistogram().Bounds)
+}
+
+func TestIsReserved(t *testing.T) {
+	assert.True(t, IsReserved(StoredSeries))
+	assert.False(t, IsReserved("metrics_client_requests"))
+}
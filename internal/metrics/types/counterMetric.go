@@ -10,18 +10,24 @@ import (
 )
 
 type counterMetric struct {
-	name  string
-	value int64
-	lock  sync.RWMutex
+	name   string
+	labels metrics.Labels
+	value  int64
+	lock   sync.RWMutex
 }
 
 // NewCounterMetric creates new instance of caunter metric.
-func NewCounterMetric(name string) metrics.Metric {
+func NewCounterMetric(name string, labels ...metrics.Label) metrics.Metric {
 	return &counterMetric{
-		name: name,
+		name:   name,
+		labels: metrics.NewLabels(labels...),
 	}
 }
 
+func (m *counterMetric) GetLabels() metrics.Labels {
+	return m.labels
+}
+
 func (m *counterMetric) GetType() string {
 	return "counter"
 }
@@ -56,7 +62,7 @@ func (m *counterMetric) GetHash(hash hash.Hash) ([]byte, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	_, err := fmt.Fprintf(hash, "%s:counter:%d", m.name, m.value)
+	_, err := fmt.Fprintf(hash, "%s:counter:%d", metrics.SeriesKey(m.name, m.labels), m.value)
 	if err != nil {
 		return nil, err
 	}
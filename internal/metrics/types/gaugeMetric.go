@@ -10,18 +10,24 @@ import (
 )
 
 type gaugeMetric struct {
-	name  string
-	value float64
-	lock  sync.RWMutex
+	name   string
+	labels metrics.Labels
+	value  float64
+	lock   sync.RWMutex
 }
 
 // NewGaugeMetric creates new instance of gauge metric.
-func NewGaugeMetric(name string) metrics.Metric {
+func NewGaugeMetric(name string, labels ...metrics.Label) metrics.Metric {
 	return &gaugeMetric{
-		name: name,
+		name:   name,
+		labels: metrics.NewLabels(labels...),
 	}
 }
 
+func (m *gaugeMetric) GetLabels() metrics.Labels {
+	return m.labels
+}
+
 func (m *gaugeMetric) GetType() string {
 	return "gauge"
 }
@@ -59,7 +65,7 @@ func (m *gaugeMetric) GetHash(hash hash.Hash) ([]byte, error) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
-	_, err := fmt.Fprintf(hash, "%s:gauge:%f", m.name, m.value)
+	_, err := fmt.Fprintf(hash, "%s:gauge:%f", metrics.SeriesKey(m.name, m.labels), m.value)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,185 @@
+package types
+
+import (
+	"fmt"
+	"hash"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+type histogramMetric struct {
+	name   string
+	labels metrics.Labels
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+	lock   sync.RWMutex
+}
+
+// NewHistogramMetric creates new instance of histogram metric with specified bucket upper bounds.
+// Bounds are sorted, duplicated and not finite values are ignored.
+func NewHistogramMetric(name string, bounds []float64, labels ...metrics.Label) metrics.HistogramMetric {
+	sorted := make([]float64, 0, len(bounds))
+	for _, bound := range bounds {
+		if !math.IsNaN(bound) && !math.IsInf(bound, 0) {
+			sorted = append(sorted, bound)
+		}
+	}
+	sort.Float64s(sorted)
+
+	normalized := make([]float64, 0, len(sorted))
+	for i, bound := range sorted {
+		if i == 0 || sorted[i-1] != bound {
+			normalized = append(normalized, bound)
+		}
+	}
+
+	return &histogramMetric{
+		name:   name,
+		labels: metrics.NewLabels(labels...),
+		bounds: normalized,
+		counts: make([]uint64, len(normalized)),
+	}
+}
+
+// RestoreHistogramMetric creates new instance of histogram metric with specified state.
+func RestoreHistogramMetric(name string, histogram *metrics.Histogram, labels ...metrics.Label) (metrics.HistogramMetric, error) {
+	err := histogram.Validate()
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("validate histogram '%s' state", name), err)
+	}
+
+	metric := &histogramMetric{
+		name:   name,
+		labels: metrics.NewLabels(labels...),
+		bounds: append([]float64{}, histogram.Bounds...),
+		counts: append([]uint64{}, histogram.Counts...),
+		sum:    histogram.Sum,
+		count:  histogram.Count,
+	}
+
+	return metric, nil
+}
+
+// ParseHistogramMetric creates new instance of histogram metric with state restored from string representation.
+func ParseHistogramMetric(name string, value string, labels ...metrics.Label) (metrics.HistogramMetric, error) {
+	histogram, err := metrics.ParseHistogram(value)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("parse histogram '%s' state", name), err)
+	}
+
+	return RestoreHistogramMetric(name, histogram, labels...)
+}
+
+func (m *histogramMetric) GetLabels() metrics.Labels {
+	return m.labels
+}
+
+func (m *histogramMetric) GetType() string {
+	return "histogram"
+}
+
+func (m *histogramMetric) GetName() string {
+	return m.name
+}
+
+func (m *histogramMetric) GetValue() float64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.sum
+}
+
+func (m *histogramMetric) GetStringValue() string {
+	return m.GetHistogram().String()
+}
+
+func (m *histogramMetric) GetHistogram() *metrics.Histogram {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return &metrics.Histogram{
+		Bounds: append([]float64{}, m.bounds...),
+		Counts: append([]uint64{}, m.counts...),
+		Sum:    m.sum,
+		Count:  m.count,
+	}
+}
+
+// SetValue observes a single value.
+func (m *histogramMetric) SetValue(value float64) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i := sort.SearchFloat64s(m.bounds, value); i < len(m.counts); i++ {
+		m.counts[i]++
+	}
+	m.sum += value
+	m.count++
+
+	return m.sum
+}
+
+func (m *histogramMetric) Merge(metric metrics.Metric) error {
+	other, ok := metric.(metrics.HistogramMetric)
+	if !ok {
+		return logger.WrapError(fmt.Sprintf("merge histogram with %s metric", metric.GetType()), metrics.ErrIncompatibleMetrics)
+	}
+
+	state := other.GetHistogram()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !equalBounds(m.bounds, state.Bounds) {
+		return logger.WrapError(fmt.Sprintf("merge histogram '%s' with different bounds", m.name), metrics.ErrIncompatibleMetrics)
+	}
+
+	for i, count := range state.Counts {
+		m.counts[i] += count
+	}
+	m.sum += state.Sum
+	m.count += state.Count
+
+	return nil
+}
+
+func (m *histogramMetric) Flush() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.counts = make([]uint64, len(m.bounds))
+	m.sum = 0
+	m.count = 0
+}
+
+func (m *histogramMetric) GetHash(hash hash.Hash) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	_, err := fmt.Fprintf(hash, "%s:histogram:%v:%v:%f:%d", metrics.SeriesKey(m.name, m.labels), m.bounds, m.counts, m.sum, m.count)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+func equalBounds(left []float64, right []float64) bool {
+	if len(left) != len(right) {
+		return false
+	}
+
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,197 @@
+package types
+
+import (
+	"crypto/sha256"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+func TestNewHistogramMetric(t *testing.T) {
+	tests := []struct {
+		name           string
+		bounds         []float64
+		expectedBounds []float64
+	}{
+		{
+			name:           "no_bounds",
+			expectedBounds: []float64{},
+		},
+		{
+			name:           "sorted_bounds",
+			bounds:         []float64{0.1, 0.5, 1},
+			expectedBounds: []float64{0.1, 0.5, 1},
+		},
+		{
+			name:           "unsorted_bounds",
+			bounds:         []float64{1, 0.1, 0.5, 0.1},
+			expectedBounds: []float64{0.1, 0.5, 1},
+		},
+		{
+			name:           "not_finite_bounds",
+			bounds:         []float64{math.Inf(1), 1, math.NaN(), math.Inf(-1)},
+			expectedBounds: []float64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric := NewHistogramMetric("histogramName", tt.bounds)
+
+			histogram := metric.GetHistogram()
+			assert.Equal(t, "histogram", metric.GetType())
+			assert.Equal(t, tt.expectedBounds, histogram.Bounds)
+			assert.Len(t, histogram.Counts, len(tt.expectedBounds))
+		})
+	}
+}
+
+func TestHistogramMetric_SetValue(t *testing.T) {
+	metric := NewHistogramMetric("histogramName", []float64{0.1, 0.5, 1})
+	for _, value := range []float64{0.05, 0.1, 0.3, 0.7, 5} {
+		metric.SetValue(value)
+	}
+
+	assert.Equal(t, &metrics.Histogram{
+		Bounds: []float64{0.1, 0.5, 1},
+		Counts: []uint64{2, 3, 4},
+		Sum:    6.15,
+		Count:  5,
+	}, metric.GetHistogram())
+	assert.Equal(t, 6.15, metric.GetValue())
+	assert.Equal(t, `{"bounds":[0.1,0.5,1],"counts":[2,3,4],"sum":6.15,"count":5}`, metric.GetStringValue())
+
+	metric.Flush()
+	assert.Equal(t, &metrics.Histogram{
+		Bounds: []float64{0.1, 0.5, 1},
+		Counts: []uint64{0, 0, 0},
+	}, metric.GetHistogram())
+}
+
+func TestHistogramMetric_Merge(t *testing.T) {
+	tests := []struct {
+		name          string
+		other         metrics.Metric
+		expected      *metrics.Histogram
+		expectedError error
+	}{
+		{
+			name:          "other_type",
+			other:         NewGaugeMetric("histogramName"),
+			expectedError: metrics.ErrIncompatibleMetrics,
+		},
+		{
+			name:          "other_bounds",
+			other:         NewHistogramMetric("histogramName", []float64{1, 2}),
+			expectedError: metrics.ErrIncompatibleMetrics,
+		},
+		{
+			name: "success",
+			other: func() metrics.Metric {
+				other := NewHistogramMetric("histogramName", []float64{0.5, 1})
+				other.SetValue(0.7)
+				other.SetValue(2)
+				return other
+			}(),
+			expected: &metrics.Histogram{
+				Bounds: []float64{0.5, 1},
+				Counts: []uint64{1, 2},
+				Sum:    3,
+				Count:  3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric := NewHistogramMetric("histogramName", []float64{0.5, 1})
+			metric.SetValue(0.3)
+
+			err := metric.Merge(tt.other)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expected.Sum, metric.GetHistogram().Sum, 1e-9)
+			assert.Equal(t, tt.expected.Counts, metric.GetHistogram().Counts)
+			assert.Equal(t, tt.expected.Count, metric.GetHistogram().Count)
+		})
+	}
+}
+
+func TestParseHistogramMetric(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expected      *metrics.Histogram
+		expectedError error
+	}{
+		{
+			name:  "invalid_json",
+			value: "100",
+		},
+		{
+			name:          "counts_mismatch",
+			value:         `{"bounds":[1,2],"counts":[1],"sum":1,"count":1}`,
+			expectedError: metrics.ErrInvalidHistogram,
+		},
+		{
+			name:          "not_cumulative_counts",
+			value:         `{"bounds":[1,2],"counts":[2,1],"sum":1,"count":2}`,
+			expectedError: metrics.ErrInvalidHistogram,
+		},
+		{
+			name:          "unsorted_bounds",
+			value:         `{"bounds":[2,1],"counts":[1,1],"sum":1,"count":1}`,
+			expectedError: metrics.ErrInvalidHistogramBounds,
+		},
+		{
+			name:  "success",
+			value: `{"bounds":[1,2],"counts":[1,2],"sum":3.5,"count":3}`,
+			expected: &metrics.Histogram{
+				Bounds: []float64{1, 2},
+				Counts: []uint64{1, 2},
+				Sum:    3.5,
+				Count:  3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric, err := ParseHistogramMetric("histogramName", tt.value)
+			if tt.expected == nil {
+				assert.Error(t, err)
+				if tt.expectedError != nil {
+					assert.ErrorIs(t, err, tt.expectedError)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, metric.GetHistogram())
+		})
+	}
+}
+
+func TestHistogramMetric_GetHash(t *testing.T) {
+	first := NewHistogramMetric("histogramName", []float64{1})
+	second := NewHistogramMetric("histogramName", []float64{1})
+
+	firstHash, err := first.GetHash(sha256.New())
+	require.NoError(t, err)
+	secondHash, err := second.GetHash(sha256.New())
+	require.NoError(t, err)
+	assert.Equal(t, firstHash, secondHash)
+
+	second.SetValue(0.5)
+	secondHash, err = second.GetHash(sha256.New())
+	require.NoError(t, err)
+	assert.NotEqual(t, firstHash, secondHash)
+}
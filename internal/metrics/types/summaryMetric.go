@@ -0,0 +1,209 @@
+package types
+
+import (
+	"fmt"
+	"hash"
+	"math"
+	"sync"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+	"github.com/MaxReX92/go-yandex-aka-prometheus/pkg/tdigest"
+)
+
+type summaryMetric struct {
+	name         string
+	labels       metrics.Labels
+	digest       *tdigest.TDigest
+	observations []float64
+	received     []float64
+	sum          float64
+	count        uint64
+	lock         sync.RWMutex
+}
+
+// NewSummaryMetric creates new instance of summary metric.
+func NewSummaryMetric(name string, labels ...metrics.Label) metrics.SummaryMetric {
+	return &summaryMetric{
+		name:   name,
+		labels: metrics.NewLabels(labels...),
+		digest: tdigest.New(tdigest.DefaultCompression),
+	}
+}
+
+// NewSummaryMetricFromObservations creates new instance of summary metric with observations, received from the agent.
+// Observations are accounted in the quantile sketch only, so they are not reported back as raw values,
+// received values are kept until the next merge to check the agent signature.
+func NewSummaryMetricFromObservations(name string, observations []float64, labels ...metrics.Label) metrics.SummaryMetric {
+	metric := &summaryMetric{
+		name:     name,
+		labels:   metrics.NewLabels(labels...),
+		digest:   tdigest.New(tdigest.DefaultCompression),
+		received: append([]float64{}, observations...),
+	}
+
+	for _, value := range observations {
+		metric.observe(value)
+	}
+
+	return metric
+}
+
+// RestoreSummaryMetric creates new instance of summary metric with specified state.
+func RestoreSummaryMetric(name string, summary *metrics.Summary, labels ...metrics.Label) (metrics.SummaryMetric, error) {
+	if summary.Digest == nil {
+		return nil, logger.WrapError(fmt.Sprintf("restore summary '%s' state", name), metrics.ErrInvalidSummary)
+	}
+
+	digest, err := tdigest.FromState(summary.Digest)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("restore summary '%s' digest", name), err)
+	}
+
+	metric := &summaryMetric{
+		name:   name,
+		labels: metrics.NewLabels(labels...),
+		digest: digest,
+		sum:    summary.Sum,
+		count:  summary.Count,
+	}
+
+	return metric, nil
+}
+
+// ParseSummaryMetric creates new instance of summary metric with state restored from string representation.
+func ParseSummaryMetric(name string, value string, labels ...metrics.Label) (metrics.SummaryMetric, error) {
+	summary, err := metrics.ParseSummary(value)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("parse summary '%s' state", name), err)
+	}
+
+	return RestoreSummaryMetric(name, summary, labels...)
+}
+
+func (m *summaryMetric) GetLabels() metrics.Labels {
+	return m.labels
+}
+
+func (m *summaryMetric) GetType() string {
+	return "summary"
+}
+
+func (m *summaryMetric) GetName() string {
+	return m.name
+}
+
+func (m *summaryMetric) GetValue() float64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.sum
+}
+
+func (m *summaryMetric) GetStringValue() string {
+	return m.GetSummary().String()
+}
+
+func (m *summaryMetric) GetObservations() []float64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return append([]float64{}, m.observations...)
+}
+
+func (m *summaryMetric) GetQuantile(quantile float64) float64 {
+	// digest compresses buffered values on read
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.digest.Quantile(quantile)
+}
+
+func (m *summaryMetric) GetSummary() *metrics.Summary {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return &metrics.Summary{
+		Digest: m.digest.State(),
+		Sum:    m.sum,
+		Count:  m.count,
+	}
+}
+
+// SetValue observes a single value, NaN and infinite values are ignored.
+func (m *summaryMetric) SetValue(value float64) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !isFinite(value) {
+		return m.sum
+	}
+
+	m.observations = append(m.observations, value)
+	m.observe(value)
+
+	return m.sum
+}
+
+func (m *summaryMetric) Merge(metric metrics.Metric) error {
+	other, ok := metric.(metrics.SummaryMetric)
+	if !ok {
+		return logger.WrapError(fmt.Sprintf("merge summary with %s metric", metric.GetType()), metrics.ErrIncompatibleMetrics)
+	}
+
+	state := other.GetSummary()
+	digest, err := tdigest.FromState(state.Digest)
+	if err != nil {
+		return logger.WrapError(fmt.Sprintf("restore summary '%s' digest", other.GetName()), err)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.digest.Merge(digest)
+	m.received = nil
+	m.sum += state.Sum
+	m.count += state.Count
+
+	return nil
+}
+
+func (m *summaryMetric) Flush() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.digest.Reset()
+	m.observations = nil
+	m.received = nil
+	m.sum = 0
+	m.count = 0
+}
+
+func (m *summaryMetric) GetHash(hash hash.Hash) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	// quantiles are built from the observations, so every observation is signed in order
+	observations := append(append([]float64{}, m.received...), m.observations...)
+	_, err := fmt.Fprintf(hash, "%s:summary:%v:%f:%d", metrics.SeriesKey(m.name, m.labels), observations, m.sum, m.count)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// observe accounts finite values only, the same as the quantile sketch does.
+func (m *summaryMetric) observe(value float64) {
+	if !isFinite(value) {
+		return
+	}
+
+	m.digest.Add(value, 1)
+	m.sum += value
+	m.count++
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
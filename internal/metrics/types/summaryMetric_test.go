@@ -0,0 +1,193 @@
+package types
+
+import (
+	"crypto/sha256"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/metrics"
+)
+
+func TestSummaryMetric_SetValue(t *testing.T) {
+	metric := NewSummaryMetric("summaryName")
+	for _, value := range []float64{5, 1, 4, 2, 3} {
+		metric.SetValue(value)
+	}
+
+	assert.Equal(t, "summary", metric.GetType())
+	assert.Equal(t, []float64{5, 1, 4, 2, 3}, metric.GetObservations())
+	assert.Equal(t, float64(15), metric.GetValue())
+	assert.Equal(t, float64(3), metric.GetQuantile(0.5))
+	assert.Equal(t, float64(5), metric.GetQuantile(1))
+	assert.Equal(t, uint64(5), metric.GetSummary().Count)
+
+	metric.Flush()
+	assert.Empty(t, metric.GetObservations())
+	assert.Equal(t, float64(0), metric.GetValue())
+	assert.Equal(t, uint64(0), metric.GetSummary().Count)
+	assert.Equal(t, `{"digest":{"compression":100,"min":0,"max":0,"centroids":[]},"sum":0,"count":0}`, metric.GetStringValue())
+}
+
+func TestNewSummaryMetricFromObservations(t *testing.T) {
+	metric := NewSummaryMetricFromObservations("summaryName", []float64{1, 2, 3})
+
+	assert.Empty(t, metric.GetObservations())
+	assert.Equal(t, float64(6), metric.GetValue())
+	assert.Equal(t, float64(2), metric.GetQuantile(0.5))
+	assert.Equal(t, uint64(3), metric.GetSummary().Count)
+}
+
+func TestSummaryMetric_Merge(t *testing.T) {
+	tests := []struct {
+		name             string
+		other            metrics.Metric
+		expectedSum      float64
+		expectedCount    uint64
+		expectedQuantile float64
+		expectedError    error
+	}{
+		{
+			name:          "other_type",
+			other:         NewGaugeMetric("summaryName"),
+			expectedError: metrics.ErrIncompatibleMetrics,
+		},
+		{
+			name:             "success",
+			other:            NewSummaryMetricFromObservations("summaryName", []float64{4, 5}),
+			expectedSum:      15,
+			expectedCount:    5,
+			expectedQuantile: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric := NewSummaryMetricFromObservations("summaryName", []float64{1, 2, 3})
+
+			err := metric.Merge(tt.other)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSum, metric.GetValue())
+			assert.Equal(t, tt.expectedCount, metric.GetSummary().Count)
+			assert.Equal(t, tt.expectedQuantile, metric.GetQuantile(0.5))
+		})
+	}
+}
+
+func TestParseSummaryMetric(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expectedError error
+	}{
+		{
+			name:  "invalid_json",
+			value: "100",
+		},
+		{
+			name:          "missed_digest",
+			value:         `{"sum":1,"count":1}`,
+			expectedError: metrics.ErrInvalidSummary,
+		},
+		{
+			name:  "invalid_digest",
+			value: `{"digest":{"compression":0},"sum":1,"count":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSummaryMetric("summaryName", tt.value)
+			assert.Error(t, err)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			}
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		original := NewSummaryMetricFromObservations("summaryName", []float64{1, 2, 3, 4})
+
+		restored, err := ParseSummaryMetric("summaryName", original.GetStringValue())
+		require.NoError(t, err)
+		assert.Equal(t, original.GetSummary(), restored.GetSummary())
+		assert.Equal(t, original.GetQuantile(0.9), restored.GetQuantile(0.9))
+	})
+}
+
+func TestSummaryMetric_GetHash(t *testing.T) {
+	agent := NewSummaryMetric("summaryName")
+	agent.SetValue(1)
+	agent.SetValue(2)
+
+	received := NewSummaryMetricFromObservations("summaryName", agent.GetObservations())
+
+	agentHash, err := agent.GetHash(sha256.New())
+	require.NoError(t, err)
+	receivedHash, err := received.GetHash(sha256.New())
+	require.NoError(t, err)
+	assert.Equal(t, agentHash, receivedHash)
+
+	agent.SetValue(3)
+	agentHash, err = agent.GetHash(sha256.New())
+	require.NoError(t, err)
+	assert.NotEqual(t, agentHash, receivedHash)
+}
+
+func TestSummaryMetric_GetHash_Observations(t *testing.T) {
+	agent := NewSummaryMetric("summaryName")
+	for _, value := range []float64{1, 2, 9} {
+		agent.SetValue(value)
+	}
+
+	agentHash, err := agent.GetHash(sha256.New())
+	require.NoError(t, err)
+
+	// the same sum and count with the other distribution
+	forged := NewSummaryMetricFromObservations("summaryName", []float64{4, 4, 4})
+	forgedHash, err := forged.GetHash(sha256.New())
+	require.NoError(t, err)
+	assert.Equal(t, agent.GetValue(), forged.GetValue())
+	assert.NotEqual(t, agentHash, forgedHash)
+
+	reordered := NewSummaryMetricFromObservations("summaryName", []float64{9, 2, 1})
+	reorderedHash, err := reordered.GetHash(sha256.New())
+	require.NoError(t, err)
+	assert.NotEqual(t, agentHash, reorderedHash)
+}
+
+func TestSummaryMetric_NonFiniteValues(t *testing.T) {
+	metric := NewSummaryMetric("summaryName")
+	for _, value := range []float64{1, math.NaN(), math.Inf(1), math.Inf(-1), 2} {
+		metric.SetValue(value)
+	}
+
+	assert.Equal(t, []float64{1, 2}, metric.GetObservations())
+	assert.Equal(t, float64(3), metric.GetValue())
+	assert.Equal(t, uint64(2), metric.GetSummary().Count)
+
+	// state stays serializable, so the series can be stored and restored
+	restored, err := ParseSummaryMetric("summaryName", metric.GetStringValue())
+	require.NoError(t, err)
+	assert.Equal(t, metric.GetSummary(), restored.GetSummary())
+
+	received := NewSummaryMetricFromObservations("summaryName", []float64{1, math.NaN()})
+	assert.Equal(t, float64(1), received.GetValue())
+	_, err = ParseSummaryMetric("summaryName", received.GetStringValue())
+	assert.NoError(t, err)
+}
+
+func TestValidateObservations(t *testing.T) {
+	assert.NoError(t, metrics.ValidateObservations(nil))
+	assert.NoError(t, metrics.ValidateObservations([]float64{-1, 0, 1.5}))
+	assert.ErrorIs(t, metrics.ValidateObservations([]float64{1, math.NaN()}), metrics.ErrInvalidObservation)
+	assert.ErrorIs(t, metrics.ValidateObservations([]float64{math.Inf(1)}), metrics.ErrInvalidObservation)
+	assert.ErrorIs(t, metrics.ValidateObservations([]float64{math.Inf(-1)}), metrics.ErrInvalidObservation)
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"time"
 )
 
 // ToFloat64 parse strings to float64.
@@ -15,6 +17,27 @@ func ToInt64(str string) (int64, error) {
 	return strconv.ParseInt(str, 10, 64)
 }
 
+// ToTime parse unix timestamp in seconds or RFC3339 string to time.
+func ToTime(str string) (time.Time, error) {
+	seconds, err := strconv.ParseFloat(str, 64)
+	if err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*float64(time.Second))).UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339Nano, str)
+}
+
+// ToDuration parse duration string, like 15s, or number of seconds to duration.
+func ToDuration(str string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(str, 64)
+	if err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	return time.ParseDuration(str)
+}
+
 // FloatToString convert float64 to string.
 func FloatToString(num float64) string {
 	return fmt.Sprintf("%g", num)
@@ -24,3 +47,8 @@ func FloatToString(num float64) string {
 func IntToString(num int64) string {
 	return strconv.FormatInt(num, 10)
 }
+
+// UintToString convert uint64 to string.
+func UintToString(num uint64) string {
+	return strconv.FormatUint(num, 10)
+}
@@ -2,6 +2,7 @@ package parser_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -128,6 +129,98 @@ func TestToInt64(t *testing.T) {
 	}
 }
 
+func TestToTime(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expectError bool
+		expected    time.Time
+	}{
+		{
+			name:     "unix_success",
+			value:    "1672531200",
+			expected: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "unix_float_success",
+			value:    "1672531200.5",
+			expected: time.Date(2023, 1, 1, 0, 0, 0, 500000000, time.UTC),
+		},
+		{
+			name:     "rfc3339_success",
+			value:    "2023-01-01T00:00:00Z",
+			expected: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "empty_fail",
+			value:       "",
+			expectError: true,
+		},
+		{
+			name:        "str_fail",
+			value:       "str",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.ToTime(tt.value)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.True(t, tt.expected.Equal(actual), "expected %v, actual %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestToDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expectError bool
+		expected    time.Duration
+	}{
+		{
+			name:     "seconds_success",
+			value:    "15",
+			expected: 15 * time.Second,
+		},
+		{
+			name:     "float_seconds_success",
+			value:    "0.5",
+			expected: 500 * time.Millisecond,
+		},
+		{
+			name:     "duration_success",
+			value:    "1m30s",
+			expected: 90 * time.Second,
+		},
+		{
+			name:        "empty_fail",
+			value:       "",
+			expectError: true,
+		},
+		{
+			name:        "str_fail",
+			value:       "str",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.ToDuration(tt.value)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
 func TestFloatToString(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -217,3 +310,33 @@ func TestIntToString(t *testing.T) {
 		})
 	}
 }
+
+func TestUintToString(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		value    uint64
+	}{
+		{
+			name:     "zero",
+			value:    0,
+			expected: "0",
+		},
+		{
+			name:     "positive",
+			value:    100,
+			expected: "100",
+		},
+		{
+			name:     "max",
+			value:    18446744073709551615,
+			expected: "18446744073709551615",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parser.UintToString(tt.value))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Authority is a test certificate authority generated in-process.
+type Authority struct {
+	CertPath    string
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	dir         string
+}
+
+// NewAuthority generates self-signed CA certificate in the directory.
+func NewAuthority(t testing.TB, dir string, name string) *Authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	content, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(content)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, name+".crt")
+	writePEM(t, certPath, "CERTIFICATE", content)
+
+	return &Authority{
+		CertPath:    certPath,
+		certificate: certificate,
+		key:         key,
+		dir:         dir,
+	}
+}
+
+// Issue generates certificate for server and client authentication signed by the authority.
+// Hosts are added as DNS or IP subject alternative names.
+func (a *Authority) Issue(t testing.TB, commonName string, hosts ...string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	content, err := x509.CreateCertificate(rand.Reader, template, a.certificate, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	keyContent, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(a.dir, commonName+".crt")
+	keyPath := filepath.Join(a.dir, commonName+".key")
+	writePEM(t, certPath, "CERTIFICATE", content)
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyContent)
+
+	return certPath, keyPath
+}
+
+func writePEM(t testing.TB, path string, blockType string, content []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: content}), 0o600)
+	require.NoError(t, err)
+}
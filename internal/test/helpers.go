@@ -18,7 +18,15 @@ func CreateGaugeMetric(name string, value float64) metrics.Metric {
 	return CreateMetric(types.NewGaugeMetric, name, value)
 }
 
-func CreateMetric(metricFactory func(string) metrics.Metric, name string, value float64) metrics.Metric {
+func CreateHistogramMetric(name string, bounds []float64, values ...float64) metrics.Metric {
+	metric := types.NewHistogramMetric(name, bounds)
+	for _, value := range values {
+		metric.SetValue(value)
+	}
+	return metric
+}
+
+func CreateMetric(metricFactory func(string, ...metrics.Label) metrics.Metric, name string, value float64) metrics.Metric {
 	metric := metricFactory(name)
 	metric.SetValue(value)
 	return metric
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// NewRSAKeyPair generates RSA key pair in the directory, returns public and private key paths.
+func NewRSAKeyPair(t testing.TB, dir string, name string) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	publicContent, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	publicKeyPath := filepath.Join(dir, name+".pub")
+	privateKeyPath := filepath.Join(dir, name+".pem")
+	writePEM(t, publicKeyPath, "PUBLIC KEY", publicContent)
+	writePEM(t, privateKeyPath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+	return publicKeyPath, privateKeyPath
+}
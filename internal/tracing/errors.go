@@ -0,0 +1,5 @@
+package tracing
+
+import "errors"
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent, expected 00-<trace id>-<span id>-<flags>")
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const (
+	// TraceparentHeader is a W3C trace context header name, it is also used as gRPC metadata key.
+	TraceparentHeader = "traceparent"
+	// RequestIDHeader is a response header name with the request trace id.
+	RequestIDHeader = "X-Request-Id"
+)
+
+const (
+	traceparentVersion = "00"
+	sampledFlags       = "01"
+	traceIDBytes       = 16
+	spanIDBytes        = 8
+)
+
+// SpanContext identifies span within the trace.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// ParseTraceparent parses W3C traceparent header value.
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return SpanContext{}, logger.WrapError(fmt.Sprintf("parse traceparent '%s'", value), ErrInvalidTraceparent)
+	}
+
+	result := SpanContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+	}
+	if !isValidID(result.TraceID, traceIDBytes) || !isValidID(result.SpanID, spanIDBytes) {
+		return SpanContext{}, logger.WrapError(fmt.Sprintf("parse traceparent '%s'", value), ErrInvalidTraceparent)
+	}
+
+	return result, nil
+}
+
+// Traceparent returns W3C traceparent header value, spans are always sampled.
+func (c SpanContext) Traceparent() string {
+	return traceparentVersion + "-" + c.TraceID + "-" + c.SpanID + "-" + sampledFlags
+}
+
+func newID(size int) string {
+	buffer := make([]byte, size)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		// crypto/rand never fails on supported platforms
+		panic(logger.WrapError("generate id", err))
+	}
+
+	return hex.EncodeToString(buffer)
+}
+
+func isValidID(id string, size int) bool {
+	if len(id) != size*2 || strings.ToLower(id) != id {
+		return false
+	}
+
+	decoded, err := hex.DecodeString(id)
+	if err != nil {
+		return false
+	}
+
+	// all zero identifiers are invalid
+	for _, b := range decoded {
+		if b != 0 {
+			return true
+		}
+	}
+
+	return false
+}
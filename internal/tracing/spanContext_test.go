@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		expected      SpanContext
+		expectedError error
+	}{
+		{
+			name:     "valid",
+			value:    "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			expected: SpanContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"},
+		},
+		{
+			name:     "not_sampled",
+			value:    "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00",
+			expected: SpanContext{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"},
+		},
+		{
+			name:          "empty",
+			value:         "",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "invalid_version",
+			value:         "ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "short_trace_id",
+			value:         "00-0af7651916cd43dd8448eb211c8031-b7ad6b7169203331-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "upper_case_span_id",
+			value:         "00-0af7651916cd43dd8448eb211c80319c-B7AD6B7169203331-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "zero_trace_id",
+			value:         "00-00000000000000000000000000000000-b7ad6b7169203331-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "zero_span_id",
+			value:         "00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "not_hex",
+			value:         "00-0af7651916cd43dd8448eb211c80319z-b7ad6b7169203331-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseTraceparent(tt.value)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+			assert.Equal(t, tt.value[:len(tt.value)-2]+sampledFlags, actual.Traceparent())
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type spanContextKey struct{}
+
+// Exporter receives finished spans.
+type Exporter interface {
+	// Export writes finished span.
+	Export(span *SpanData) error
+	// Close releases exporter resources.
+	Close() error
+}
+
+// SpanData is a finished span state.
+type SpanData struct {
+	Name         string            `json:"name"`
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// Span is a single traced operation.
+type Span struct {
+	tracer       *Tracer
+	name         string
+	context      SpanContext
+	parentSpanID string
+	start        time.Time
+	attributes   map[string]string
+	err          error
+	lock         sync.Mutex
+}
+
+// Tracer creates spans and exports them on end.
+// Spans are created and propagated even without exporter, so trace ids still correlate logs.
+type Tracer struct {
+	exporter Exporter
+	now      func() time.Time
+}
+
+// NewTracer creates new instance of Tracer, exporter is optional.
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{
+		exporter: exporter,
+		now:      time.Now,
+	}
+}
+
+// Start starts span as a child of the context span or as a root span of a new trace.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return t.start(ctx, name, newID(traceIDBytes), "")
+	}
+
+	return t.start(ctx, name, parent.context.TraceID, parent.context.SpanID)
+}
+
+// StartRemote starts span as a child of the remote span passed in the traceparent value.
+// New trace is started when the value is empty or invalid.
+func (t *Tracer) StartRemote(ctx context.Context, name string, traceparent string) (context.Context, *Span) {
+	if traceparent == "" {
+		return t.start(ctx, name, newID(traceIDBytes), "")
+	}
+
+	remote, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return t.start(ctx, name, newID(traceIDBytes), "")
+	}
+
+	return t.start(ctx, name, remote.TraceID, remote.SpanID)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, traceID string, parentSpanID string) (context.Context, *Span) {
+	span := &Span{
+		tracer: t,
+		name:   name,
+		context: SpanContext{
+			TraceID: traceID,
+			SpanID:  newID(spanIDBytes),
+		},
+		parentSpanID: parentSpanID,
+		start:        t.now(),
+		attributes:   map[string]string{},
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SpanFromContext returns current span of the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Context returns span identity.
+func (s *Span) Context() SpanContext {
+	return s.context
+}
+
+// SetAttribute attaches attribute to the span.
+func (s *Span) SetAttribute(key string, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attributes[key] = value
+}
+
+// SetError marks span as failed.
+func (s *Span) SetError(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+// End finishes the span and exports it.
+func (s *Span) End() error {
+	if s.tracer.exporter == nil {
+		return nil
+	}
+
+	s.lock.Lock()
+	data := &SpanData{
+		Name:         s.name,
+		TraceID:      s.context.TraceID,
+		SpanID:       s.context.SpanID,
+		ParentSpanID: s.parentSpanID,
+		Start:        s.start,
+		End:          s.tracer.now(),
+		Attributes:   make(map[string]string, len(s.attributes)),
+	}
+	for key, value := range s.attributes {
+		data.Attributes[key] = value
+	}
+	if s.err != nil {
+		data.Error = s.err.Error()
+	}
+	s.lock.Unlock()
+
+	return s.tracer.exporter.Export(data)
+}
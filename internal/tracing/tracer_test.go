@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracer_Start(t *testing.T) {
+	spans := &bytes.Buffer{}
+	tracer := NewTracer(NewWriterExporter(spans))
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.SetAttribute("key", "value")
+	child.SetError(errors.New("child error"))
+	require.NoError(t, child.End())
+	require.NoError(t, root.End())
+
+	assert.Equal(t, root, SpanFromContext(ctx))
+	assert.Equal(t, root.Context().TraceID, child.Context().TraceID)
+	assert.NotEqual(t, root.Context().SpanID, child.Context().SpanID)
+
+	exported := readSpans(t, spans)
+	require.Len(t, exported, 2)
+	assert.Equal(t, "child", exported[0].Name)
+	assert.Equal(t, root.Context().SpanID, exported[0].ParentSpanID)
+	assert.Equal(t, map[string]string{"key": "value"}, exported[0].Attributes)
+	assert.Equal(t, "child error", exported[0].Error)
+	assert.Equal(t, "root", exported[1].Name)
+	assert.Empty(t, exported[1].ParentSpanID)
+	assert.False(t, exported[1].End.Before(exported[1].Start))
+}
+
+func TestTracer_StartRemote(t *testing.T) {
+	tests := []struct {
+		name            string
+		traceparent     string
+		expectedTraceID string
+		expectedParent  string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:        "invalid",
+			traceparent: "invalid",
+		},
+		{
+			name:            "valid",
+			traceparent:     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			expectedTraceID: "0af7651916cd43dd8448eb211c80319c",
+			expectedParent:  "b7ad6b7169203331",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := &bytes.Buffer{}
+			tracer := NewTracer(NewWriterExporter(spans))
+
+			_, span := tracer.StartRemote(context.Background(), "remote", tt.traceparent)
+			require.NoError(t, span.End())
+
+			traceID := span.Context().TraceID
+			if tt.expectedTraceID != "" {
+				assert.Equal(t, tt.expectedTraceID, traceID)
+			} else {
+				assert.True(t, isValidID(traceID, traceIDBytes))
+			}
+
+			exported := readSpans(t, spans)
+			require.Len(t, exported, 1)
+			assert.Equal(t, traceID, exported[0].TraceID)
+			assert.Equal(t, tt.expectedParent, exported[0].ParentSpanID)
+		})
+	}
+}
+
+func TestTracer_NoExporter(t *testing.T) {
+	tracer := NewTracer(nil)
+
+	_, span := tracer.Start(context.Background(), "span")
+	assert.True(t, isValidID(span.Context().TraceID, traceIDBytes))
+	assert.True(t, isValidID(span.Context().SpanID, spanIDBytes))
+	assert.NoError(t, span.End())
+}
+
+func readSpans(t *testing.T, buffer *bytes.Buffer) []*SpanData {
+	result := []*SpanData{}
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		span := &SpanData{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), span))
+		result = append(result, span)
+	}
+
+	return result
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/MaxReX92/go-yandex-aka-prometheus/internal/logger"
+)
+
+const (
+	// OutputStdout is an exporter output name of the process standard output.
+	OutputStdout = "stdout"
+	// OutputStderr is an exporter output name of the process standard error.
+	OutputStderr = "stderr"
+)
+
+type writerExporter struct {
+	writer io.Writer
+	closer io.Closer
+	lock   sync.Mutex
+}
+
+// NewWriterExporter creates exporter writing spans as JSON lines.
+func NewWriterExporter(writer io.Writer) Exporter {
+	return &writerExporter{
+		writer: writer,
+	}
+}
+
+// NewExporter creates exporter writing spans to stdout, stderr or appending them to the file.
+func NewExporter(output string) (Exporter, error) {
+	switch output {
+	case OutputStdout:
+		return NewWriterExporter(os.Stdout), nil
+	case OutputStderr:
+		return NewWriterExporter(os.Stderr), nil
+	}
+
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, logger.WrapError(fmt.Sprintf("open trace file '%s'", output), err)
+	}
+
+	return &writerExporter{
+		writer: file,
+		closer: file,
+	}, nil
+}
+
+func (e *writerExporter) Export(span *SpanData) error {
+	content, err := json.Marshal(span)
+	if err != nil {
+		return logger.WrapError("serialize span", err)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	_, err = e.writer.Write(append(content, '\n'))
+	if err != nil {
+		return logger.WrapError("write span", err)
+	}
+
+	return nil
+}
+
+func (e *writerExporter) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+
+	return e.closer.Close()
+}
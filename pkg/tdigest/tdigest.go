@@ -0,0 +1,232 @@
+package tdigest
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// DefaultCompression is a compression, that keeps quantile error within a fraction of percent.
+const DefaultCompression = 100
+
+var ErrInvalidState = errors.New("invalid t-digest state")
+
+// Centroid is a cluster of observations with the same mean.
+type Centroid struct {
+	Mean   float64 `json:"mean"`
+	Weight float64 `json:"weight"`
+}
+
+// State is a serializable t-digest state.
+type State struct {
+	Compression float64    `json:"compression"`
+	Min         float64    `json:"min"`
+	Max         float64    `json:"max"`
+	Centroids   []Centroid `json:"centroids"`
+}
+
+// TDigest is a merging t-digest streaming quantile sketch.
+// TDigest is not thread safe.
+type TDigest struct {
+	compression float64
+	centroids   []Centroid
+	buffer      []Centroid
+	count       float64
+	min         float64
+	max         float64
+}
+
+// New creates new instance of empty TDigest.
+func New(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+
+	return &TDigest{
+		compression: compression,
+	}
+}
+
+// FromState restores TDigest from serialized state.
+func FromState(state *State) (*TDigest, error) {
+	if state.Compression <= 0 {
+		return nil, ErrInvalidState
+	}
+
+	digest := New(state.Compression)
+	for _, centroid := range state.Centroids {
+		if centroid.Weight <= 0 || math.IsNaN(centroid.Mean) || math.IsInf(centroid.Mean, 0) {
+			return nil, ErrInvalidState
+		}
+
+		digest.buffer = append(digest.buffer, centroid)
+		digest.count += centroid.Weight
+	}
+
+	if digest.count > 0 {
+		if state.Min > state.Max {
+			return nil, ErrInvalidState
+		}
+
+		digest.min = state.Min
+		digest.max = state.Max
+	}
+
+	digest.compress()
+	return digest, nil
+}
+
+// Add adds weighted value to the digest.
+func (t *TDigest) Add(value float64, weight float64) {
+	if weight <= 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	t.updateBounds(value, value)
+	t.buffer = append(t.buffer, Centroid{Mean: value, Weight: weight})
+	t.count += weight
+
+	if len(t.buffer) > t.bufferSize() {
+		t.compress()
+	}
+}
+
+// Merge adds all other digest observations to the current one.
+func (t *TDigest) Merge(other *TDigest) {
+	if other.count == 0 {
+		return
+	}
+
+	t.updateBounds(other.min, other.max)
+	t.buffer = append(t.buffer, other.centroids...)
+	t.buffer = append(t.buffer, other.buffer...)
+	t.count += other.count
+	t.compress()
+}
+
+// Quantile returns estimated value of the quantile q in range [0, 1].
+// NaN is returned for an empty digest.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.compress()
+
+	centroidsCount := len(t.centroids)
+	if centroidsCount == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+
+	if q <= 0 {
+		return t.min
+	}
+
+	if q >= 1 {
+		return t.max
+	}
+
+	if centroidsCount == 1 {
+		return t.centroids[0].Mean
+	}
+
+	index := q * t.count
+	first := t.centroids[0]
+	if index < first.Weight/2 {
+		return t.min + (first.Mean-t.min)*index/(first.Weight/2)
+	}
+
+	weightSoFar := first.Weight / 2
+	for i := 0; i < centroidsCount-1; i++ {
+		left := t.centroids[i]
+		right := t.centroids[i+1]
+		delta := (left.Weight + right.Weight) / 2
+		if weightSoFar+delta > index {
+			return left.Mean + (right.Mean-left.Mean)*(index-weightSoFar)/delta
+		}
+
+		weightSoFar += delta
+	}
+
+	last := t.centroids[centroidsCount-1]
+	return last.Mean + (t.max-last.Mean)*math.Min(1, (index-weightSoFar)/(last.Weight/2))
+}
+
+// Count returns total observations weight.
+func (t *TDigest) Count() float64 {
+	return t.count
+}
+
+// State returns serializable digest state.
+func (t *TDigest) State() *State {
+	t.compress()
+
+	return &State{
+		Compression: t.compression,
+		Min:         t.min,
+		Max:         t.max,
+		Centroids:   append([]Centroid{}, t.centroids...),
+	}
+}
+
+// Reset removes all observations.
+func (t *TDigest) Reset() {
+	t.centroids = nil
+	t.buffer = nil
+	t.count = 0
+	t.min = 0
+	t.max = 0
+}
+
+func (t *TDigest) updateBounds(minValue float64, maxValue float64) {
+	if t.count == 0 {
+		t.min = minValue
+		t.max = maxValue
+		return
+	}
+
+	t.min = math.Min(t.min, minValue)
+	t.max = math.Max(t.max, maxValue)
+}
+
+func (t *TDigest) bufferSize() int {
+	return int(t.compression) * 5
+}
+
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Mean < all[j].Mean })
+
+	result := make([]Centroid, 0, len(all))
+	result = append(result, all[0])
+	weightSoFar := 0.0
+	limit := t.inverseScale(t.scale(0) + 1)
+	for _, centroid := range all[1:] {
+		last := &result[len(result)-1]
+		if (weightSoFar+last.Weight+centroid.Weight)/t.count <= limit {
+			last.Weight += centroid.Weight
+			last.Mean += (centroid.Mean - last.Mean) * centroid.Weight / last.Weight
+			continue
+		}
+
+		weightSoFar += last.Weight
+		limit = t.inverseScale(t.scale(weightSoFar/t.count) + 1)
+		result = append(result, centroid)
+	}
+
+	t.centroids = result
+	t.buffer = nil
+}
+
+// scale is a k1 t-digest scale function, that keeps centroids small near the tails.
+func (t *TDigest) scale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func (t *TDigest) inverseScale(k float64) float64 {
+	if k >= t.compression/4 {
+		return 1
+	}
+
+	return (math.Sin(k*2*math.Pi/t.compression) + 1) / 2
+}
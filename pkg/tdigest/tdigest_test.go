@@ -0,0 +1,143 @@
+package tdigest
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTDigest_Quantile(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		quantile float64
+		expected float64
+	}{
+		{
+			name:     "empty",
+			quantile: 0.5,
+			expected: math.NaN(),
+		},
+		{
+			name:     "single_value",
+			values:   []float64{42},
+			quantile: 0.99,
+			expected: 42,
+		},
+		{
+			name:     "min",
+			values:   []float64{3, 1, 2},
+			quantile: 0,
+			expected: 1,
+		},
+		{
+			name:     "max",
+			values:   []float64{3, 1, 2},
+			quantile: 1,
+			expected: 3,
+		},
+		{
+			name:     "median",
+			values:   []float64{1, 2, 3, 4, 5},
+			quantile: 0.5,
+			expected: 3,
+		},
+		{
+			name:     "not_finite_values_ignored",
+			values:   []float64{math.NaN(), 5, math.Inf(1)},
+			quantile: 0.5,
+			expected: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest := New(DefaultCompression)
+			for _, value := range tt.values {
+				digest.Add(value, 1)
+			}
+
+			actual := digest.Quantile(tt.quantile)
+			if math.IsNaN(tt.expected) {
+				assert.True(t, math.IsNaN(actual))
+			} else {
+				assert.InDelta(t, tt.expected, actual, 1e-9)
+			}
+		})
+	}
+}
+
+func TestTDigest_Accuracy(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	values := make([]float64, 100000)
+	left := New(DefaultCompression)
+	right := New(DefaultCompression)
+	for i := range values {
+		values[i] = random.NormFloat64()
+		if i%2 == 0 {
+			left.Add(values[i], 1)
+		} else {
+			right.Add(values[i], 1)
+		}
+	}
+	sort.Float64s(values)
+
+	left.Merge(right)
+	assert.Equal(t, float64(len(values)), left.Count())
+	for _, quantile := range []float64{0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+		expected := values[int(quantile*float64(len(values)))]
+		assert.InDelta(t, expected, left.Quantile(quantile), 0.02, "quantile %v", quantile)
+	}
+}
+
+func TestTDigest_State(t *testing.T) {
+	digest := New(DefaultCompression)
+	for i := 1; i <= 1000; i++ {
+		digest.Add(float64(i), 1)
+	}
+
+	restored, err := FromState(digest.State())
+	require.NoError(t, err)
+
+	assert.Equal(t, digest.Count(), restored.Count())
+	assert.Equal(t, digest.State(), restored.State())
+	assert.Equal(t, digest.Quantile(0.9), restored.Quantile(0.9))
+
+	digest.Reset()
+	assert.Equal(t, &State{Compression: DefaultCompression, Centroids: []Centroid{}}, digest.State())
+}
+
+func TestFromState_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		state *State
+	}{
+		{
+			name:  "zero_compression",
+			state: &State{},
+		},
+		{
+			name:  "negative_weight",
+			state: &State{Compression: 100, Centroids: []Centroid{{Mean: 1, Weight: -1}}},
+		},
+		{
+			name:  "not_finite_mean",
+			state: &State{Compression: 100, Centroids: []Centroid{{Mean: math.Inf(1), Weight: 1}}},
+		},
+		{
+			name:  "invalid_bounds",
+			state: &State{Compression: 100, Min: 2, Max: 1, Centroids: []Centroid{{Mean: 1, Weight: 1}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromState(tt.state)
+			assert.ErrorIs(t, err, ErrInvalidState)
+		})
+	}
+}
@@ -70,8 +70,10 @@ func (Status) EnumDescriptor() ([]byte, []int) {
 type MetricType int32
 
 const (
-	MetricType_GAUGE   MetricType = 0
-	MetricType_COUNTER MetricType = 1
+	MetricType_GAUGE     MetricType = 0
+	MetricType_COUNTER   MetricType = 1
+	MetricType_HISTOGRAM MetricType = 2
+	MetricType_SUMMARY   MetricType = 3
 )
 
 // Enum value maps for MetricType.
@@ -79,10 +81,14 @@ var (
 	MetricType_name = map[int32]string{
 		0: "GAUGE",
 		1: "COUNTER",
+		2: "HISTOGRAM",
+		3: "SUMMARY",
 	}
 	MetricType_value = map[string]int32{
-		"GAUGE":   0,
-		"COUNTER": 1,
+		"GAUGE":     0,
+		"COUNTER":   1,
+		"HISTOGRAM": 2,
+		"SUMMARY":   3,
 	}
 )
 
@@ -151,22 +157,84 @@ func (*Nothing) Descriptor() ([]byte, []int) {
 	return file_proto_metrics_proto_rawDescGZIP(), []int{0}
 }
 
+type Quantile struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Quantile float64 `protobuf:"fixed64,1,opt,name=quantile,proto3" json:"quantile,omitempty"`
+	Value    float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Quantile) Reset() {
+	*x = Quantile{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Quantile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Quantile) ProtoMessage() {}
+
+func (x *Quantile) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Quantile.ProtoReflect.Descriptor instead.
+func (*Quantile) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Quantile) GetQuantile() float64 {
+	if x != nil {
+		return x.Quantile
+	}
+	return 0
+}
+
+func (x *Quantile) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
 type Metric struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name  string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Type  MetricType `protobuf:"varint,2,opt,name=type,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.MetricType" json:"type,omitempty"`
-	Delta *int64     `protobuf:"varint,3,opt,name=delta,proto3,oneof" json:"delta,omitempty"`
-	Value *float64   `protobuf:"fixed64,4,opt,name=value,proto3,oneof" json:"value,omitempty"`
-	Hash  []byte     `protobuf:"bytes,5,opt,name=hash,proto3,oneof" json:"hash,omitempty"`
+	Name         string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type         MetricType        `protobuf:"varint,2,opt,name=type,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.MetricType" json:"type,omitempty"`
+	Delta        *int64            `protobuf:"varint,3,opt,name=delta,proto3,oneof" json:"delta,omitempty"`
+	Value        *float64          `protobuf:"fixed64,4,opt,name=value,proto3,oneof" json:"value,omitempty"`
+	Hash         []byte            `protobuf:"bytes,5,opt,name=hash,proto3,oneof" json:"hash,omitempty"`
+	Buckets      []float64         `protobuf:"fixed64,6,rep,packed,name=buckets,proto3" json:"buckets,omitempty"`
+	Counts       []uint64          `protobuf:"varint,7,rep,packed,name=counts,proto3" json:"counts,omitempty"`
+	Sum          *float64          `protobuf:"fixed64,8,opt,name=sum,proto3,oneof" json:"sum,omitempty"`
+	Count        *uint64           `protobuf:"varint,9,opt,name=count,proto3,oneof" json:"count,omitempty"`
+	Observations []float64         `protobuf:"fixed64,10,rep,packed,name=observations,proto3" json:"observations,omitempty"`
+	Quantiles    []*Quantile       `protobuf:"bytes,11,rep,name=quantiles,proto3" json:"quantiles,omitempty"`
+	Labels       map[string]string `protobuf:"bytes,12,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 }
 
 func (x *Metric) Reset() {
 	*x = Metric{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_metrics_proto_msgTypes[1]
+		mi := &file_proto_metrics_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -179,7 +247,7 @@ func (x *Metric) String() string {
 func (*Metric) ProtoMessage() {}
 
 func (x *Metric) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_metrics_proto_msgTypes[1]
+	mi := &file_proto_metrics_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -192,7 +260,7 @@ func (x *Metric) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Metric.ProtoReflect.Descriptor instead.
 func (*Metric) Descriptor() ([]byte, []int) {
-	return file_proto_metrics_proto_rawDescGZIP(), []int{1}
+	return file_proto_metrics_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Metric) GetName() string {
@@ -230,6 +298,55 @@ func (x *Metric) GetHash() []byte {
 	return nil
 }
 
+func (x *Metric) GetBuckets() []float64 {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+func (x *Metric) GetCounts() []uint64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+func (x *Metric) GetSum() float64 {
+	if x != nil && x.Sum != nil {
+		return *x.Sum
+	}
+	return 0
+}
+
+func (x *Metric) GetCount() uint64 {
+	if x != nil && x.Count != nil {
+		return *x.Count
+	}
+	return 0
+}
+
+func (x *Metric) GetObservations() []float64 {
+	if x != nil {
+		return x.Observations
+	}
+	return nil
+}
+
+func (x *Metric) GetQuantiles() []*Quantile {
+	if x != nil {
+		return x.Quantiles
+	}
+	return nil
+}
+
+func (x *Metric) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
 type Response struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -242,7 +359,7 @@ type Response struct {
 func (x *Response) Reset() {
 	*x = Response{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_metrics_proto_msgTypes[2]
+		mi := &file_proto_metrics_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -255,7 +372,7 @@ func (x *Response) String() string {
 func (*Response) ProtoMessage() {}
 
 func (x *Response) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_metrics_proto_msgTypes[2]
+	mi := &file_proto_metrics_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -268,7 +385,7 @@ func (x *Response) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Response.ProtoReflect.Descriptor instead.
 func (*Response) Descriptor() ([]byte, []int) {
-	return file_proto_metrics_proto_rawDescGZIP(), []int{2}
+	return file_proto_metrics_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Response) GetStatus() Status {
@@ -298,7 +415,7 @@ type ReportResponse struct {
 func (x *ReportResponse) Reset() {
 	*x = ReportResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_metrics_proto_msgTypes[3]
+		mi := &file_proto_metrics_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -311,7 +428,7 @@ func (x *ReportResponse) String() string {
 func (*ReportResponse) ProtoMessage() {}
 
 func (x *ReportResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_metrics_proto_msgTypes[3]
+	mi := &file_proto_metrics_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -324,7 +441,7 @@ func (x *ReportResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportResponse.ProtoReflect.Descriptor instead.
 func (*ReportResponse) Descriptor() ([]byte, []int) {
-	return file_proto_metrics_proto_rawDescGZIP(), []int{3}
+	return file_proto_metrics_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ReportResponse) GetStatus() Status {
@@ -348,31 +465,35 @@ func (x *ReportResponse) GetError() string {
 	return ""
 }
 
-type MetricsRequest struct {
+type Agent struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Metrics []*Metric `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	Id           string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Source       string  `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	LastSeen     int64   `protobuf:"varint,3,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`              // unix time in milliseconds
+	HeartbeatAge float64 `protobuf:"fixed64,4,opt,name=heartbeat_age,json=heartbeatAge,proto3" json:"heartbeat_age,omitempty"` // seconds since the last push
+	SeriesCount  uint64  `protobuf:"varint,5,opt,name=series_count,json=seriesCount,proto3" json:"series_count,omitempty"`
 }
 
-func (x *MetricsRequest) Reset() {
-	*x = MetricsRequest{}
+func (x *Agent) Reset() {
+	*x = Agent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_metrics_proto_msgTypes[4]
+		mi := &file_proto_metrics_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *MetricsRequest) String() string {
+func (x *Agent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MetricsRequest) ProtoMessage() {}
+func (*Agent) ProtoMessage() {}
 
-func (x *MetricsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_metrics_proto_msgTypes[4]
+func (x *Agent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -383,45 +504,73 @@ func (x *MetricsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MetricsRequest.ProtoReflect.Descriptor instead.
-func (*MetricsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_metrics_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use Agent.ProtoReflect.Descriptor instead.
+func (*Agent) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *MetricsRequest) GetMetrics() []*Metric {
+func (x *Agent) GetId() string {
 	if x != nil {
-		return x.Metrics
+		return x.Id
 	}
-	return nil
+	return ""
 }
 
-type MetricsResponse struct {
+func (x *Agent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Agent) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *Agent) GetHeartbeatAge() float64 {
+	if x != nil {
+		return x.HeartbeatAge
+	}
+	return 0
+}
+
+func (x *Agent) GetSeriesCount() uint64 {
+	if x != nil {
+		return x.SeriesCount
+	}
+	return 0
+}
+
+type AgentsResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Status Status    `protobuf:"varint,1,opt,name=status,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.Status" json:"status,omitempty"`
-	Result []*Metric `protobuf:"bytes,2,rep,name=result,proto3" json:"result,omitempty"`
-	Error  *string   `protobuf:"bytes,3,opt,name=error,proto3,oneof" json:"error,omitempty"`
+	Status Status   `protobuf:"varint,1,opt,name=status,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.Status" json:"status,omitempty"`
+	Agents []*Agent `protobuf:"bytes,2,rep,name=agents,proto3" json:"agents,omitempty"`
+	Error  *string  `protobuf:"bytes,3,opt,name=error,proto3,oneof" json:"error,omitempty"`
 }
 
-func (x *MetricsResponse) Reset() {
-	*x = MetricsResponse{}
+func (x *AgentsResponse) Reset() {
+	*x = AgentsResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_proto_metrics_proto_msgTypes[5]
+		mi := &file_proto_metrics_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *MetricsResponse) String() string {
+func (x *AgentsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MetricsResponse) ProtoMessage() {}
+func (*AgentsResponse) ProtoMessage() {}
 
-func (x *MetricsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_metrics_proto_msgTypes[5]
+func (x *AgentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -432,224 +581,851 @@ func (x *MetricsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MetricsResponse.ProtoReflect.Descriptor instead.
-func (*MetricsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_metrics_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use AgentsResponse.ProtoReflect.Descriptor instead.
+func (*AgentsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *MetricsResponse) GetStatus() Status {
+func (x *AgentsResponse) GetStatus() Status {
 	if x != nil {
 		return x.Status
 	}
 	return Status_OK
 }
 
-func (x *MetricsResponse) GetResult() []*Metric {
+func (x *AgentsResponse) GetAgents() []*Agent {
 	if x != nil {
-		return x.Result
+		return x.Agents
 	}
 	return nil
 }
 
-func (x *MetricsResponse) GetError() string {
+func (x *AgentsResponse) GetError() string {
 	if x != nil && x.Error != nil {
 		return *x.Error
 	}
 	return ""
 }
 
-var File_proto_metrics_proto protoreflect.FileDescriptor
+type RangeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_proto_metrics_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x2c, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
-	0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61,
-	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68,
-	0x65, 0x75, 0x73, 0x22, 0x09, 0x0a, 0x07, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0xd6,
-	0x01, 0x0a, 0x06, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x4c, 0x0a,
-	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x38, 0x2e, 0x63, 0x6f,
-	0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39,
-	0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f,
-	0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69,
-	0x63, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x19, 0x0a, 0x05, 0x64,
-	0x65, 0x6c, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x05, 0x64, 0x65,
-	0x6c, 0x74, 0x61, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x01, 0x48, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x88, 0x01,
-	0x01, 0x12, 0x17, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x48,
-	0x02, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x88, 0x01, 0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x64,
-	0x65, 0x6c, 0x74, 0x61, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x42, 0x07,
-	0x0a, 0x05, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x22, 0x7d, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e,
-	0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65,
-	0x75, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88, 0x01, 0x01, 0x42, 0x08, 0x0a, 0x06,
-	0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xab, 0x01, 0x0a, 0x0e, 0x52, 0x65, 0x70, 0x6f, 0x72,
-	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x06, 0x73, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e,
-	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e,
-	0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72,
-	0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
-	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1b, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x6f, 0x72,
-	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x70, 0x6f, 0x72,
-	0x74, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88, 0x01, 0x01, 0x42,
-	0x09, 0x0a, 0x07, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x65,
-	0x72, 0x72, 0x6f, 0x72, 0x22, 0x60, 0x0a, 0x0e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x4e, 0x0a, 0x07, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f,
-	0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d,
-	0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x52, 0x07, 0x6d,
-	0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x22, 0xd2, 0x01, 0x0a, 0x0f, 0x4d, 0x65, 0x74, 0x72, 0x69,
-	0x63, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x06, 0x73, 0x74,
-	0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32,
-	0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70,
-	0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x4c, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75,
-	0x6c, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67,
-	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67,
-	0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f,
-	0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x52, 0x06,
-	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88, 0x01,
-	0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2a, 0x1b, 0x0a, 0x06, 0x53,
-	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x4b, 0x10, 0x00, 0x12, 0x09, 0x0a,
-	0x05, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x01, 0x2a, 0x24, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x72,
-	0x69, 0x63, 0x54, 0x79, 0x70, 0x65, 0x12, 0x09, 0x0a, 0x05, 0x47, 0x41, 0x55, 0x47, 0x45, 0x10,
-	0x00, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x4f, 0x55, 0x4e, 0x54, 0x45, 0x52, 0x10, 0x01, 0x32, 0xa4,
-	0x04, 0x0a, 0x0c, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12,
-	0x89, 0x01, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x3c, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58,
-	0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61,
-	0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72,
-	0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x3d, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32,
-	0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70,
-	0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63,
-	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x8d, 0x01, 0x0a, 0x0c,
-	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x3c, 0x2e, 0x63,
-	0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58,
-	0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61,
-	0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72,
-	0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x3d, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32,
-	0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70,
-	0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63,
-	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x77, 0x0a, 0x04, 0x50,
-	0x69, 0x6e, 0x67, 0x12, 0x35, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e,
-	0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65,
-	0x75, 0x73, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x36, 0x2e, 0x63, 0x6f, 0x6d,
-	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32,
-	0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70,
-	0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x12, 0x7f, 0x0a, 0x06, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x35,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52,
-	0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61,
-	0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4e, 0x6f,
-	0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x3c, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79,
-	0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74,
-	0x68, 0x65, 0x75, 0x73, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x11, 0x5a, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67,
-	0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	Metric *Metric `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	Start  int64   `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"` // unix time in milliseconds
+	End    int64   `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`     // unix time in milliseconds
+	Step   int64   `protobuf:"varint,4,opt,name=step,proto3" json:"step,omitempty"`   // milliseconds
 }
 
-var (
-	file_proto_metrics_proto_rawDescOnce sync.Once
-	file_proto_metrics_proto_rawDescData = file_proto_metrics_proto_rawDesc
-)
+func (x *RangeRequest) Reset() {
+	*x = RangeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_proto_metrics_proto_rawDescGZIP() []byte {
-	file_proto_metrics_proto_rawDescOnce.Do(func() {
-		file_proto_metrics_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_metrics_proto_rawDescData)
-	})
-	return file_proto_metrics_proto_rawDescData
+func (x *RangeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_proto_metrics_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_proto_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
-var file_proto_metrics_proto_goTypes = []interface{}{
-	(Status)(0),             // 0: com.github.MaxReX92.go_yandex_aka_prometheus.Status
-	(MetricType)(0),         // 1: com.github.MaxReX92.go_yandex_aka_prometheus.MetricType
-	(*Nothing)(nil),         // 2: com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
-	(*Metric)(nil),          // 3: com.github.MaxReX92.go_yandex_aka_prometheus.Metric
-	(*Response)(nil),        // 4: com.github.MaxReX92.go_yandex_aka_prometheus.Response
-	(*ReportResponse)(nil),  // 5: com.github.MaxReX92.go_yandex_aka_prometheus.ReportResponse
-	(*MetricsRequest)(nil),  // 6: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest
-	(*MetricsResponse)(nil), // 7: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse
+func (*RangeRequest) ProtoMessage() {}
+
+func (x *RangeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_proto_metrics_proto_depIdxs = []int32{
-	1,  // 0: com.github.MaxReX92.go_yandex_aka_prometheus.Metric.type:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricType
-	0,  // 1: com.github.MaxReX92.go_yandex_aka_prometheus.Response.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
-	0,  // 2: com.github.MaxReX92.go_yandex_aka_prometheus.ReportResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
-	3,  // 3: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest.metrics:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Metric
-	0,  // 4: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
-	3,  // 5: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse.result:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Metric
-	6,  // 6: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.GetValue:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest
-	6,  // 7: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.UpdateValues:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest
-	2,  // 8: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Ping:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
-	2,  // 9: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Report:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
-	7,  // 10: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.GetValue:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse
-	7,  // 11: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.UpdateValues:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse
-	4,  // 12: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Ping:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Response
-	5,  // 13: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Report:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.ReportResponse
-	10, // [10:14] is the sub-list for method output_type
-	6,  // [6:10] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+
+// Deprecated: Use RangeRequest.ProtoReflect.Descriptor instead.
+func (*RangeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{7}
 }
 
-func init() { file_proto_metrics_proto_init() }
-func file_proto_metrics_proto_init() {
-	if File_proto_metrics_proto != nil {
-		return
+func (x *RangeRequest) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_proto_metrics_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Nothing); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_metrics_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Metric); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_proto_metrics_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Response); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
+	return nil
+}
+
+func (x *RangeRequest) GetStart() int64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *RangeRequest) GetEnd() int64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *RangeRequest) GetStep() int64 {
+	if x != nil {
+		return x.Step
+	}
+	return 0
+}
+
+type Point struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timestamp int64   `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // unix time in milliseconds
+	Value     float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Point) Reset() {
+	*x = Point{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Point) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Point) ProtoMessage() {}
+
+func (x *Point) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Point.ProtoReflect.Descriptor instead.
+func (*Point) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Point) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Point) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type RangeResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status Status   `protobuf:"varint,1,opt,name=status,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.Status" json:"status,omitempty"`
+	Points []*Point `protobuf:"bytes,2,rep,name=points,proto3" json:"points,omitempty"`
+	Error  *string  `protobuf:"bytes,3,opt,name=error,proto3,oneof" json:"error,omitempty"`
+}
+
+func (x *RangeResponse) Reset() {
+	*x = RangeResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RangeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeResponse) ProtoMessage() {}
+
+func (x *RangeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeResponse.ProtoReflect.Descriptor instead.
+func (*RangeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *RangeResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_OK
+}
+
+func (x *RangeResponse) GetPoints() []*Point {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+func (x *RangeResponse) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+// MetricsRequest carries either plain metrics or the whole request serialized
+// and encrypted with the server public key, metrics are empty then.
+type MetricsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Metrics   []*Metric `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	Encrypted []byte    `protobuf:"bytes,2,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+}
+
+func (x *MetricsRequest) Reset() {
+	*x = MetricsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsRequest) ProtoMessage() {}
+
+func (x *MetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsRequest.ProtoReflect.Descriptor instead.
+func (*MetricsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MetricsRequest) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *MetricsRequest) GetEncrypted() []byte {
+	if x != nil {
+		return x.Encrypted
+	}
+	return nil
+}
+
+type MetricsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status Status    `protobuf:"varint,1,opt,name=status,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.Status" json:"status,omitempty"`
+	Result []*Metric `protobuf:"bytes,2,rep,name=result,proto3" json:"result,omitempty"`
+	Error  *string   `protobuf:"bytes,3,opt,name=error,proto3,oneof" json:"error,omitempty"`
+}
+
+func (x *MetricsResponse) Reset() {
+	*x = MetricsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetricsResponse) ProtoMessage() {}
+
+func (x *MetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetricsResponse.ProtoReflect.Descriptor instead.
+func (*MetricsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MetricsResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_OK
+}
+
+func (x *MetricsResponse) GetResult() []*Metric {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *MetricsResponse) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+// SeriesRequest selects series by exact name, name prefix or name regex,
+// series have to contain all requested labels, other labels are ignored.
+type SeriesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type   *MetricType       `protobuf:"varint,1,opt,name=type,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.MetricType,oneof" json:"type,omitempty"` // any type when missed
+	Name   string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Prefix string            `protobuf:"bytes,3,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Regex  string            `protobuf:"bytes,4,opt,name=regex,proto3" json:"regex,omitempty"`
+	Labels map[string]string `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *SeriesRequest) Reset() {
+	*x = SeriesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SeriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SeriesRequest) ProtoMessage() {}
+
+func (x *SeriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SeriesRequest.ProtoReflect.Descriptor instead.
+func (*SeriesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SeriesRequest) GetType() MetricType {
+	if x != nil && x.Type != nil {
+		return *x.Type
+	}
+	return MetricType_GAUGE
+}
+
+func (x *SeriesRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SeriesRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *SeriesRequest) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+func (x *SeriesRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status Status  `protobuf:"varint,1,opt,name=status,proto3,enum=com.github.MaxReX92.go_yandex_aka_prometheus.Status" json:"status,omitempty"`
+	Count  uint64  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Error  *string `protobuf:"bytes,3,opt,name=error,proto3,oneof" json:"error,omitempty"`
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_proto_metrics_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_metrics_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_proto_metrics_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CountResponse) GetStatus() Status {
+	if x != nil {
+		return x.Status
+	}
+	return Status_OK
+}
+
+func (x *CountResponse) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CountResponse) GetError() string {
+	if x != nil && x.Error != nil {
+		return *x.Error
+	}
+	return ""
+}
+
+var File_proto_metrics_proto protoreflect.FileDescriptor
+
+var file_proto_metrics_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x2c, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61,
+	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68,
+	0x65, 0x75, 0x73, 0x22, 0x09, 0x0a, 0x07, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x22, 0x3c,
+	0x0a, 0x08, 0x51, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x71, 0x75,
+	0x61, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08, 0x71, 0x75,
+	0x61, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xdb, 0x04, 0x0a,
+	0x06, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x4c, 0x0a, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x38, 0x2e, 0x63, 0x6f, 0x6d, 0x2e,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e,
+	0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72,
+	0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x54,
+	0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x19, 0x0a, 0x05, 0x64, 0x65, 0x6c,
+	0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x05, 0x64, 0x65, 0x6c, 0x74,
+	0x61, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x01, 0x48, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x88, 0x01, 0x01, 0x12,
+	0x17, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x02, 0x52,
+	0x04, 0x68, 0x61, 0x73, 0x68, 0x88, 0x01, 0x01, 0x12, 0x18, 0x0a, 0x07, 0x62, 0x75, 0x63, 0x6b,
+	0x65, 0x74, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x01, 0x52, 0x07, 0x62, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x18, 0x07, 0x20, 0x03,
+	0x28, 0x04, 0x52, 0x06, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x12, 0x15, 0x0a, 0x03, 0x73, 0x75,
+	0x6d, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x48, 0x03, 0x52, 0x03, 0x73, 0x75, 0x6d, 0x88, 0x01,
+	0x01, 0x12, 0x19, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04,
+	0x48, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x88, 0x01, 0x01, 0x12, 0x22, 0x0a, 0x0c,
+	0x6f, 0x62, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x0a, 0x20, 0x03,
+	0x28, 0x01, 0x52, 0x0c, 0x6f, 0x62, 0x73, 0x65, 0x72, 0x76, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x12, 0x54, 0x0a, 0x09, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x0b, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x36, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e,
+	0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65,
+	0x75, 0x73, 0x2e, 0x51, 0x75, 0x61, 0x6e, 0x74, 0x69, 0x6c, 0x65, 0x52, 0x09, 0x71, 0x75, 0x61,
+	0x6e, 0x74, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x58, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x40, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f,
+	0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65,
+	0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x2e, 0x4c, 0x61, 0x62,
+	0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x1a, 0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f,
+	0x64, 0x65, 0x6c, 0x74, 0x61, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x42,
+	0x07, 0x0a, 0x05, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x42, 0x06, 0x0a, 0x04, 0x5f, 0x73, 0x75, 0x6d,
+	0x42, 0x08, 0x0a, 0x06, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x7d, 0x0a, 0x08, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f,
+	0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65,
+	0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88, 0x01, 0x01, 0x42,
+	0x08, 0x0a, 0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xab, 0x01, 0x0a, 0x0e, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x06,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58,
+	0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61,
+	0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1b, 0x0a, 0x06, 0x72, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88,
+	0x01, 0x01, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x42, 0x08, 0x0a,
+	0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x94, 0x01, 0x0a, 0x05, 0x41, 0x67, 0x65, 0x6e,
+	0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6c, 0x61, 0x73,
+	0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6c, 0x61,
+	0x73, 0x74, 0x53, 0x65, 0x65, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x5f, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0c, 0x68,
+	0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x41, 0x67, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x73,
+	0x65, 0x72, 0x69, 0x65, 0x73, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x0b, 0x73, 0x65, 0x72, 0x69, 0x65, 0x73, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0xd0,
+	0x01, 0x0a, 0x0e, 0x41, 0x67, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x4c, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d,
+	0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65,
+	0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73,
+	0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x4b, 0x0a, 0x06, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x33, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78,
+	0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f,
+	0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x41,
+	0x67, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x19, 0x0a, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x88, 0x01, 0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x22, 0x98, 0x01, 0x0a, 0x0c, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x4c, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75,
+	0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63,
+	0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x74, 0x65, 0x70,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x74, 0x65, 0x70, 0x22, 0x3b, 0x0a, 0x05,
+	0x50, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xcf, 0x01, 0x0a, 0x0d, 0x52, 0x61,
+	0x6e, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c, 0x0a, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39,
+	0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f,
+	0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x4b, 0x0a, 0x06, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x33, 0x2e, 0x63, 0x6f, 0x6d, 0x2e,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e,
+	0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72,
+	0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x06,
+	0x70, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88, 0x01,
+	0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x7e, 0x0a, 0x0e, 0x4d,
+	0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x4e, 0x0a,
+	0x07, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52,
+	0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61,
+	0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65,
+	0x74, 0x72, 0x69, 0x63, 0x52, 0x07, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x1c, 0x0a,
+	0x09, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x09, 0x65, 0x6e, 0x63, 0x72, 0x79, 0x70, 0x74, 0x65, 0x64, 0x22, 0xd2, 0x01, 0x0a, 0x0f,
+	0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x4c, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x34, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78,
+	0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f,
+	0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x4c, 0x0a,
+	0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x34, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65,
+	0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b,
+	0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74,
+	0x72, 0x69, 0x63, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x19, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x88, 0x01, 0x01, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0xc9, 0x02, 0x0a, 0x0d, 0x53, 0x65, 0x72, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x51, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x38, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61,
+	0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78,
+	0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e,
+	0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x54, 0x79, 0x70, 0x65, 0x48, 0x00, 0x52, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x88, 0x01, 0x01, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x72, 0x65,
+	0x66, 0x69, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69,
+	0x78, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x65, 0x67, 0x65, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x72, 0x65, 0x67, 0x65, 0x78, 0x12, 0x5f, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x47, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f,
+	0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d,
+	0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x65, 0x72, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x1a, 0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62, 0x65,
+	0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x22, 0x98, 0x01, 0x0a,
+	0x0d, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4c,
+	0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52,
+	0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61,
+	0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x12, 0x19, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x88, 0x01, 0x01, 0x42, 0x08, 0x0a,
+	0x06, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x2a, 0x1b, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x4b, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x45, 0x52, 0x52,
+	0x4f, 0x52, 0x10, 0x01, 0x2a, 0x40, 0x0a, 0x0a, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x09, 0x0a, 0x05, 0x47, 0x41, 0x55, 0x47, 0x45, 0x10, 0x00, 0x12, 0x0b, 0x0a,
+	0x07, 0x43, 0x4f, 0x55, 0x4e, 0x54, 0x45, 0x52, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x48, 0x49,
+	0x53, 0x54, 0x4f, 0x47, 0x52, 0x41, 0x4d, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x55, 0x4d,
+	0x4d, 0x41, 0x52, 0x59, 0x10, 0x03, 0x32, 0xc4, 0x08, 0x0a, 0x0c, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x89, 0x01, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x3c, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61,
+	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68,
+	0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x3d, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75,
+	0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x12, 0x8d, 0x01, 0x0a, 0x0c, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x73, 0x12, 0x3c, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61,
+	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68,
+	0x65, 0x75, 0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x3d, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75,
+	0x73, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x12, 0x87, 0x01, 0x0a, 0x0a, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x61, 0x6e,
+	0x67, 0x65, 0x12, 0x3a, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75,
+	0x73, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x3b,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52,
+	0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61,
+	0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x52, 0x61,
+	0x6e, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x84, 0x01,
+	0x0a, 0x06, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x12, 0x3b, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67,
+	0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f,
+	0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x65, 0x72, 0x69, 0x65, 0x73, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x3b, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79,
+	0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74,
+	0x68, 0x65, 0x75, 0x73, 0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x8b, 0x01, 0x0a, 0x0d, 0x52, 0x65, 0x73, 0x65, 0x74, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x12, 0x3b, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f,
+	0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65,
+	0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x53, 0x65, 0x72, 0x69, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x3b, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e,
+	0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65,
+	0x75, 0x73, 0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x12, 0x77, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x35, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32,
+	0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70,
+	0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e,
+	0x67, 0x1a, 0x36, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d,
+	0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65,
+	0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73,
+	0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x7f, 0x0a, 0x06, 0x52,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x35, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79,
+	0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74,
+	0x68, 0x65, 0x75, 0x73, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x3c, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58,
+	0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61,
+	0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x52, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x7f, 0x0a, 0x06,
+	0x41, 0x67, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x35, 0x2e, 0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74,
+	0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65, 0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f,
+	0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b, 0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65,
+	0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x4e, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x1a, 0x3c, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2e, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x4d, 0x61, 0x78, 0x52, 0x65,
+	0x58, 0x39, 0x32, 0x2e, 0x67, 0x6f, 0x5f, 0x79, 0x61, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x61, 0x6b,
+	0x61, 0x5f, 0x70, 0x72, 0x6f, 0x6d, 0x65, 0x74, 0x68, 0x65, 0x75, 0x73, 0x2e, 0x41, 0x67, 0x65,
+	0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x11, 0x5a,
+	0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x65, 0x64,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_proto_metrics_proto_rawDescOnce sync.Once
+	file_proto_metrics_proto_rawDescData = file_proto_metrics_proto_rawDesc
+)
+
+func file_proto_metrics_proto_rawDescGZIP() []byte {
+	file_proto_metrics_proto_rawDescOnce.Do(func() {
+		file_proto_metrics_proto_rawDescData = protoimpl.X.CompressGZIP(file_proto_metrics_proto_rawDescData)
+	})
+	return file_proto_metrics_proto_rawDescData
+}
+
+var file_proto_metrics_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_proto_metrics_proto_goTypes = []interface{}{
+	(Status)(0),             // 0: com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	(MetricType)(0),         // 1: com.github.MaxReX92.go_yandex_aka_prometheus.MetricType
+	(*Nothing)(nil),         // 2: com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
+	(*Quantile)(nil),        // 3: com.github.MaxReX92.go_yandex_aka_prometheus.Quantile
+	(*Metric)(nil),          // 4: com.github.MaxReX92.go_yandex_aka_prometheus.Metric
+	(*Response)(nil),        // 5: com.github.MaxReX92.go_yandex_aka_prometheus.Response
+	(*ReportResponse)(nil),  // 6: com.github.MaxReX92.go_yandex_aka_prometheus.ReportResponse
+	(*Agent)(nil),           // 7: com.github.MaxReX92.go_yandex_aka_prometheus.Agent
+	(*AgentsResponse)(nil),  // 8: com.github.MaxReX92.go_yandex_aka_prometheus.AgentsResponse
+	(*RangeRequest)(nil),    // 9: com.github.MaxReX92.go_yandex_aka_prometheus.RangeRequest
+	(*Point)(nil),           // 10: com.github.MaxReX92.go_yandex_aka_prometheus.Point
+	(*RangeResponse)(nil),   // 11: com.github.MaxReX92.go_yandex_aka_prometheus.RangeResponse
+	(*MetricsRequest)(nil),  // 12: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest
+	(*MetricsResponse)(nil), // 13: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse
+	(*SeriesRequest)(nil),   // 14: com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest
+	(*CountResponse)(nil),   // 15: com.github.MaxReX92.go_yandex_aka_prometheus.CountResponse
+	nil,                     // 16: com.github.MaxReX92.go_yandex_aka_prometheus.Metric.LabelsEntry
+	nil,                     // 17: com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest.LabelsEntry
+}
+var file_proto_metrics_proto_depIdxs = []int32{
+	1,  // 0: com.github.MaxReX92.go_yandex_aka_prometheus.Metric.type:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricType
+	3,  // 1: com.github.MaxReX92.go_yandex_aka_prometheus.Metric.quantiles:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Quantile
+	16, // 2: com.github.MaxReX92.go_yandex_aka_prometheus.Metric.labels:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Metric.LabelsEntry
+	0,  // 3: com.github.MaxReX92.go_yandex_aka_prometheus.Response.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	0,  // 4: com.github.MaxReX92.go_yandex_aka_prometheus.ReportResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	0,  // 5: com.github.MaxReX92.go_yandex_aka_prometheus.AgentsResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	7,  // 6: com.github.MaxReX92.go_yandex_aka_prometheus.AgentsResponse.agents:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Agent
+	4,  // 7: com.github.MaxReX92.go_yandex_aka_prometheus.RangeRequest.metric:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Metric
+	0,  // 8: com.github.MaxReX92.go_yandex_aka_prometheus.RangeResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	10, // 9: com.github.MaxReX92.go_yandex_aka_prometheus.RangeResponse.points:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Point
+	4,  // 10: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest.metrics:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Metric
+	0,  // 11: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	4,  // 12: com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse.result:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Metric
+	1,  // 13: com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest.type:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricType
+	17, // 14: com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest.labels:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest.LabelsEntry
+	0,  // 15: com.github.MaxReX92.go_yandex_aka_prometheus.CountResponse.status:type_name -> com.github.MaxReX92.go_yandex_aka_prometheus.Status
+	12, // 16: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.GetValue:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest
+	12, // 17: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.UpdateValues:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsRequest
+	9,  // 18: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.QueryRange:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.RangeRequest
+	14, // 19: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Remove:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest
+	14, // 20: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.ResetCounters:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.SeriesRequest
+	2,  // 21: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Ping:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
+	2,  // 22: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Report:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
+	2,  // 23: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Agents:input_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Nothing
+	13, // 24: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.GetValue:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse
+	13, // 25: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.UpdateValues:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.MetricsResponse
+	11, // 26: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.QueryRange:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.RangeResponse
+	15, // 27: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Remove:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.CountResponse
+	15, // 28: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.ResetCounters:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.CountResponse
+	5,  // 29: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Ping:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.Response
+	6,  // 30: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Report:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.ReportResponse
+	8,  // 31: com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer.Agents:output_type -> com.github.MaxReX92.go_yandex_aka_prometheus.AgentsResponse
+	24, // [24:32] is the sub-list for method output_type
+	16, // [16:24] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_proto_metrics_proto_init() }
+func file_proto_metrics_proto_init() {
+	if File_proto_metrics_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_proto_metrics_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Nothing); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Quantile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Metric); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
 			default:
 				return nil
 			}
 		}
 		file_proto_metrics_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ReportResponse); i {
+			switch v := v.(*Response); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -661,7 +1437,7 @@ func file_proto_metrics_proto_init() {
 			}
 		}
 		file_proto_metrics_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MetricsRequest); i {
+			switch v := v.(*ReportResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -673,6 +1449,78 @@ func file_proto_metrics_proto_init() {
 			}
 		}
 		file_proto_metrics_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Agent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AgentsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RangeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Point); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RangeResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MetricsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*MetricsResponse); i {
 			case 0:
 				return &v.state
@@ -684,18 +1532,46 @@ func file_proto_metrics_proto_init() {
 				return nil
 			}
 		}
+		file_proto_metrics_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SeriesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_proto_metrics_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CountResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
-	file_proto_metrics_proto_msgTypes[1].OneofWrappers = []interface{}{}
 	file_proto_metrics_proto_msgTypes[2].OneofWrappers = []interface{}{}
 	file_proto_metrics_proto_msgTypes[3].OneofWrappers = []interface{}{}
-	file_proto_metrics_proto_msgTypes[5].OneofWrappers = []interface{}{}
+	file_proto_metrics_proto_msgTypes[4].OneofWrappers = []interface{}{}
+	file_proto_metrics_proto_msgTypes[6].OneofWrappers = []interface{}{}
+	file_proto_metrics_proto_msgTypes[9].OneofWrappers = []interface{}{}
+	file_proto_metrics_proto_msgTypes[11].OneofWrappers = []interface{}{}
+	file_proto_metrics_proto_msgTypes[12].OneofWrappers = []interface{}{}
+	file_proto_metrics_proto_msgTypes[13].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_metrics_proto_rawDesc,
 			NumEnums:      2,
-			NumMessages:   6,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
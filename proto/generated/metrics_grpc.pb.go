@@ -20,10 +20,14 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	MetricServer_GetValue_FullMethodName     = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/GetValue"
-	MetricServer_UpdateValues_FullMethodName = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/UpdateValues"
-	MetricServer_Ping_FullMethodName         = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/Ping"
-	MetricServer_Report_FullMethodName       = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/Report"
+	MetricServer_GetValue_FullMethodName      = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/GetValue"
+	MetricServer_UpdateValues_FullMethodName  = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/UpdateValues"
+	MetricServer_QueryRange_FullMethodName    = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/QueryRange"
+	MetricServer_Remove_FullMethodName        = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/Remove"
+	MetricServer_ResetCounters_FullMethodName = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/ResetCounters"
+	MetricServer_Ping_FullMethodName          = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/Ping"
+	MetricServer_Report_FullMethodName        = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/Report"
+	MetricServer_Agents_FullMethodName        = "/com.github.MaxReX92.go_yandex_aka_prometheus.MetricServer/Agents"
 )
 
 // MetricServerClient is the client API for MetricServer service.
@@ -32,8 +36,12 @@ const (
 type MetricServerClient interface {
 	GetValue(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error)
 	UpdateValues(ctx context.Context, in *MetricsRequest, opts ...grpc.CallOption) (*MetricsResponse, error)
+	QueryRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error)
+	Remove(ctx context.Context, in *SeriesRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	ResetCounters(ctx context.Context, in *SeriesRequest, opts ...grpc.CallOption) (*CountResponse, error)
 	Ping(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Response, error)
 	Report(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ReportResponse, error)
+	Agents(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*AgentsResponse, error)
 }
 
 type metricServerClient struct {
@@ -62,6 +70,33 @@ func (c *metricServerClient) UpdateValues(ctx context.Context, in *MetricsReques
 	return out, nil
 }
 
+func (c *metricServerClient) QueryRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error) {
+	out := new(RangeResponse)
+	err := c.cc.Invoke(ctx, MetricServer_QueryRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricServerClient) Remove(ctx context.Context, in *SeriesRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, MetricServer_Remove_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricServerClient) ResetCounters(ctx context.Context, in *SeriesRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, MetricServer_ResetCounters_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *metricServerClient) Ping(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*Response, error) {
 	out := new(Response)
 	err := c.cc.Invoke(ctx, MetricServer_Ping_FullMethodName, in, out, opts...)
@@ -80,14 +115,27 @@ func (c *metricServerClient) Report(ctx context.Context, in *Nothing, opts ...gr
 	return out, nil
 }
 
+func (c *metricServerClient) Agents(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*AgentsResponse, error) {
+	out := new(AgentsResponse)
+	err := c.cc.Invoke(ctx, MetricServer_Agents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricServerServer is the server API for MetricServer service.
 // All implementations must embed UnimplementedMetricServerServer
 // for forward compatibility
 type MetricServerServer interface {
 	GetValue(context.Context, *MetricsRequest) (*MetricsResponse, error)
 	UpdateValues(context.Context, *MetricsRequest) (*MetricsResponse, error)
+	QueryRange(context.Context, *RangeRequest) (*RangeResponse, error)
+	Remove(context.Context, *SeriesRequest) (*CountResponse, error)
+	ResetCounters(context.Context, *SeriesRequest) (*CountResponse, error)
 	Ping(context.Context, *Nothing) (*Response, error)
 	Report(context.Context, *Nothing) (*ReportResponse, error)
+	Agents(context.Context, *Nothing) (*AgentsResponse, error)
 	mustEmbedUnimplementedMetricServerServer()
 }
 
@@ -101,12 +149,24 @@ func (UnimplementedMetricServerServer) GetValue(context.Context, *MetricsRequest
 func (UnimplementedMetricServerServer) UpdateValues(context.Context, *MetricsRequest) (*MetricsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateValues not implemented")
 }
+func (UnimplementedMetricServerServer) QueryRange(context.Context, *RangeRequest) (*RangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryRange not implemented")
+}
+func (UnimplementedMetricServerServer) Remove(context.Context, *SeriesRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedMetricServerServer) ResetCounters(context.Context, *SeriesRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetCounters not implemented")
+}
 func (UnimplementedMetricServerServer) Ping(context.Context, *Nothing) (*Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
 func (UnimplementedMetricServerServer) Report(context.Context, *Nothing) (*ReportResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Report not implemented")
 }
+func (UnimplementedMetricServerServer) Agents(context.Context, *Nothing) (*AgentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Agents not implemented")
+}
 func (UnimplementedMetricServerServer) mustEmbedUnimplementedMetricServerServer() {}
 
 // UnsafeMetricServerServer may be embedded to opt out of forward compatibility for this service.
@@ -156,6 +216,60 @@ func _MetricServer_UpdateValues_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetricServer_QueryRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricServerServer).QueryRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricServer_QueryRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricServerServer).QueryRange(ctx, req.(*RangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricServer_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricServerServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricServer_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricServerServer).Remove(ctx, req.(*SeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricServer_ResetCounters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricServerServer).ResetCounters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricServer_ResetCounters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricServerServer).ResetCounters(ctx, req.(*SeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _MetricServer_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Nothing)
 	if err := dec(in); err != nil {
@@ -192,6 +306,24 @@ func _MetricServer_Report_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetricServer_Agents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricServerServer).Agents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricServer_Agents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricServerServer).Agents(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetricServer_ServiceDesc is the grpc.ServiceDesc for MetricServer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -207,6 +339,18 @@ var MetricServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateValues",
 			Handler:    _MetricServer_UpdateValues_Handler,
 		},
+		{
+			MethodName: "QueryRange",
+			Handler:    _MetricServer_QueryRange_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _MetricServer_Remove_Handler,
+		},
+		{
+			MethodName: "ResetCounters",
+			Handler:    _MetricServer_ResetCounters_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _MetricServer_Ping_Handler,
@@ -215,6 +359,10 @@ var MetricServer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Report",
 			Handler:    _MetricServer_Report_Handler,
 		},
+		{
+			MethodName: "Agents",
+			Handler:    _MetricServer_Agents_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/metrics.proto",